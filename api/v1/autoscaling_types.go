@@ -0,0 +1,35 @@
+package v1
+
+import (
+	autoscalingV2 "k8s.io/api/autoscaling/v2"
+)
+
+// CBContainersAutoscalingSpec makes the operator create a HorizontalPodAutoscaler that scales a deployment of the agent,
+// instead of applying the deployment with a fixed replicas count.
+type CBContainersAutoscalingSpec struct {
+	// +kubebuilder:default:=false
+	Enabled *bool `json:"enabled,omitempty"`
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// +kubebuilder:default:=5
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas,omitempty"`
+	// TargetCPUUtilizationPercentage is the average CPU utilization of the pods, as a percentage of their CPU requests,
+	// that the replicas are scaled by.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+	// TargetMemoryUtilizationPercentage is the average memory utilization of the pods, as a percentage of their memory
+	// requests, that the replicas are scaled by.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+	// Metrics are more metrics that the replicas are scaled by, e.g. custom or external metrics.
+	// When no metrics are set, the replicas are scaled by an average CPU utilization of 80%.
+	// +optional
+	Metrics []autoscalingV2.MetricSpec `json:"metrics,omitempty"`
+	// Behavior configures the scaling behavior in the up and down directions.
+	// +optional
+	Behavior *autoscalingV2.HorizontalPodAutoscalerBehavior `json:"behavior,omitempty"`
+}
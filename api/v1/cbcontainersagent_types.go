@@ -42,6 +42,11 @@ type CBContainersAgentSpec struct {
 	AccessTokenSecretName string `json:"accessTokenSecretName,omitempty"`
 	// +kubebuilder:default:=<>
 	Components CBContainersComponentsSpec `json:"components,omitempty"`
+	// Paused stops the operator from applying any change to the agent components, including remote configuration changes,
+	// e.g. while they are edited by hand during an incident. The state of the components is still reported in the status.
+	// Each component can also be paused on its own.
+	// +kubebuilder:default:=false
+	Paused *bool `json:"paused,omitempty"`
 }
 
 type CBContainersComponentsSpec struct {
@@ -56,9 +61,30 @@ type CBContainersComponentsSpec struct {
 	Settings CBContainersComponentsSettings `json:"settings,omitempty"`
 }
 
+// CBContainersTolerationsDiscoverySpec controls the discovery of the tolerations of the daemon sets from the taints of the nodes.
+type CBContainersTolerationsDiscoverySpec struct {
+	// Enabled adds a toleration of every NoSchedule and NoExecute taint of the nodes to the daemon sets.
+	// While it is enabled, tolerations without a key, which tolerate all the taints, are ignored, so the denied taints are respected.
+	// +kubebuilder:default:=false
+	Enabled *bool `json:"enabled,omitempty"`
+	// AllowedTaintKeys are the keys of the taints that are tolerated. When it is empty, the taints of all keys are tolerated.
+	// +optional
+	AllowedTaintKeys []string `json:"allowedTaintKeys,omitempty"`
+	// DeniedTaintKeys are the keys of the taints that are never tolerated, e.g. the taints of dedicated GPU node pools.
+	// +optional
+	DeniedTaintKeys []string `json:"deniedTaintKeys,omitempty"`
+}
+
 type CBContainersComponentsSettings struct {
 	// +kubebuilder:default:={{operator: "Exists"}}
 	DaemonSetsTolerations []coreV1.Toleration `json:"daemonSetsTolerations,omitempty"`
+	// DaemonSetsTolerationsDiscovery adds tolerations of the taints of the nodes to the daemon sets, in addition to DaemonSetsTolerations.
+	// +kubebuilder:default:=<>
+	DaemonSetsTolerationsDiscovery CBContainersTolerationsDiscoverySpec `json:"daemonSetsTolerationsDiscovery,omitempty"`
+	// Architectures are the node architectures that the components are scheduled on, unless a component sets its own.
+	// Components whose image is not a multi-architecture image are only scheduled on the 386, amd64 and amd64p32 architectures.
+	// +kubebuilder:default:={"386", "amd64", "amd64p32"}
+	Architectures []string `json:"architectures,omitempty"`
 	// CreateDefaultImagePullSecrets controls whether or not to create the secrets
 	// needed to pull the containers images from the default repository.
 	//
@@ -77,6 +103,12 @@ type CBContainersComponentsSettings struct {
 	// DefaultImagesRegistry is the default registry to use with the agent images
 	DefaultImagesRegistry string `json:"defaultImagesRegistry,omitempty"`
 
+	// NodeCleanupImage is the image of the Jobs that remove the CNDR sensor data from the nodes, after CNDR was disabled
+	// or the agent was deleted. The image must have `/usr/bin/rm`. Like the agent images, it's pulled from
+	// DefaultImagesRegistry when it is set, so a mirror of the image should be available there.
+	// +kubebuilder:default:={repository:"photon", tag:"4.0"}
+	NodeCleanupImage CBContainersImageSpec `json:"nodeCleanupImage,omitempty"`
+
 	// Proxy controls the optional centralized HTTP & HTTPS proxy settings, that can be applied
 	// to all components at once. One can still have a per-component proxy settings by using the
 	// good old environment variables. However, here we have an additional advantage of taking
@@ -145,6 +177,149 @@ type CBContainersAgentStatus struct {
 	// ObservedGeneration is the last Custom resource generation that was fully reconciled.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describe the overall state of the agent, aggregated from the conditions of all of its components.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Components describe the state of each of the agent workloads (deployments and daemon sets).
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Components []CBContainersComponentStatus `json:"components,omitempty"`
+
+	// NodeCleanup describes the removal of the data that the CNDR sensor left on the nodes, after CNDR was disabled or
+	// the agent was deleted.
+	// +optional
+	NodeCleanup *CBContainersNodeCleanupStatus `json:"nodeCleanup,omitempty"`
+
+	// EnforcerCertificates describes the TLS certificates that the enforcer webhooks are served with.
+	// +optional
+	EnforcerCertificates *CBContainersCertificatesStatus `json:"enforcerCertificates,omitempty"`
+
+	// NodesExcludedByArchitecture is the number of nodes that the components daemon set isn't scheduled on, because
+	// their architecture is not one of the architectures of its components.
+	// +optional
+	NodesExcludedByArchitecture *int32 `json:"nodesExcludedByArchitecture,omitempty"`
+
+	// NodeCoverage summarizes which nodes the components daemon set covers. The reason that each of the uncovered nodes
+	// is not covered is reported in the node coverage ConfigMap.
+	// +optional
+	NodeCoverage *CBContainersNodeCoverageStatus `json:"nodeCoverage,omitempty"`
+
+	// DaemonSetsTolerations are the tolerations that the daemon sets were applied with, including the discovered tolerations.
+	// +optional
+	DaemonSetsTolerations []coreV1.Toleration `json:"daemonSetsTolerations,omitempty"`
+
+	// RuntimeResolverScaling describes the replicas count of the runtime resolver, while it is derived from the number of nodes.
+	// +optional
+	RuntimeResolverScaling *CBContainersReplicasScalingStatus `json:"runtimeResolverScaling,omitempty"`
+
+	// PlanPublished is true while the plan ConfigMap that was published in plan mode exists, so it's deleted once the
+	// plan mode is disabled.
+	// +optional
+	PlanPublished bool `json:"planPublished,omitempty"`
+}
+
+// Condition types reported for the agent and for each of its components.
+const (
+	// ConditionTypeReady is True when all the desired replicas are updated and available.
+	ConditionTypeReady = "Ready"
+	// ConditionTypeProgressing is True while a rollout is in progress.
+	ConditionTypeProgressing = "Progressing"
+	// ConditionTypeDegraded is True when the desired state can't be reached.
+	ConditionTypeDegraded = "Degraded"
+	// ConditionTypePaused is True when the reconciliation of the agent or of any of its components is paused.
+	ConditionTypePaused = "Paused"
+	// ConditionTypePodTemplatePatchInvalid is True when the pod template patch of a component can't be applied, so the
+	// changes to its workload aren't applied. It is reported only for the components that have a pod template patch.
+	ConditionTypePodTemplatePatchInvalid = "PodTemplatePatchInvalid"
+	// ConditionTypeSecretsMissing is True when secrets that the agent refers to, e.g. the access token secret or the
+	// image pull secrets, don't exist in the agent namespace. It is reported only for the agent.
+	ConditionTypeSecretsMissing = "SecretsMissing"
+	// ConditionTypeEnforcerCertificatesPending is True while cert-manager hasn't issued the enforcer certificates, e.g.
+	// when it is not installed, so the enforcer webhooks are not applied. It is reported only for the agent, and only
+	// when the enforcer certificates are issued by cert-manager.
+	ConditionTypeEnforcerCertificatesPending = "EnforcerCertificatesPending"
+)
+
+const (
+	// PlanAnnotation can be set to "true" on the CBContainersAgent resource to enable the plan mode.
+	// In plan mode, the changes that applying the desired state would make are published to the plan ConfigMap
+	// instead of being applied. Removing the annotation applies the desired state.
+	PlanAnnotation = "operator.containers.carbonblack.io/plan"
+
+	// TeardownFinalizer is added to the CBContainersAgent resource, so when it is deleted, the agent components are
+	// deleted in order and the nodes are cleaned up before the resource is removed.
+	TeardownFinalizer = "operator.containers.carbonblack.io/teardown"
+)
+
+// CBContainersComponentStatus defines the observed state of a single agent workload
+type CBContainersComponentStatus struct {
+	// Name is the name of the workload that runs the component.
+	Name string `json:"name"`
+
+	// Conditions describe the state of the workload.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// CBContainersNodeCleanupStatus describes the removal of the CNDR sensor data from the nodes that ran the sensor.
+type CBContainersNodeCleanupStatus struct {
+	// PendingNodes are the nodes that ran the CNDR sensor and weren't cleaned up yet.
+	// +optional
+	PendingNodes []string `json:"pendingNodes,omitempty"`
+
+	// FailedNodes are the nodes whose cleanup Job failed during the last cleanup.
+	// +optional
+	FailedNodes []string `json:"failedNodes,omitempty"`
+
+	// CompletionTime is the time the last cleanup was completed.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// CBContainersNodeCoverageStatus summarizes which nodes the components daemon set covers.
+type CBContainersNodeCoverageStatus struct {
+	// Nodes is the number of nodes in the cluster.
+	Nodes int32 `json:"nodes"`
+
+	// CoveredNodes is the number of nodes that a ready pod of the components daemon set runs on.
+	CoveredNodes int32 `json:"coveredNodes"`
+
+	// UncoveredNodes is the number of nodes that are not covered, by the reason they are not covered,
+	// e.g. "UntoleratedTaint" or "CrashLoopBackOff".
+	// +optional
+	UncoveredNodes map[string]int32 `json:"uncoveredNodes,omitempty"`
+}
+
+// CBContainersReplicasScalingStatus describes a replicas count that is derived from the number of nodes.
+type CBContainersReplicasScalingStatus struct {
+	// Replicas is the replicas count that the workload was applied with.
+	Replicas int32 `json:"replicas"`
+
+	// Nodes is the number of nodes that the replicas count was derived from.
+	Nodes int32 `json:"nodes"`
+
+	// LastScaleTime is the time the replicas count was last changed.
+	LastScaleTime metav1.Time `json:"lastScaleTime"`
+}
+
+// CBContainersCertificatesStatus describes TLS certificates that the operator renews before they expire.
+type CBContainersCertificatesStatus struct {
+	// NotAfter is the time the first of the CA and the signed certificate expires.
+	NotAfter metav1.Time `json:"notAfter"`
+
+	// RenewalTime is the time the certificates will be renewed.
+	RenewalTime metav1.Time `json:"renewalTime"`
+
+	// LastRotationTime is the time the certificates were last renewed.
+	// +optional
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -153,6 +328,8 @@ type CBContainersAgentStatus struct {
 // +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".spec.version",description="Version of the deployed agent"
 // +kubebuilder:printcolumn:name="Cluster image scanning",type="boolean",JSONPath=".spec.components.clusterScanning.enabled",description="Whether cluster image scanning is enabled"
 // +kubebuilder:printcolumn:name="Runtime protection",type="string",JSONPath=".spec.components.runtimeProtection.enabled",description="Whether runtime protection is enabled"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether all the agent components are ready"
+// +kubebuilder:printcolumn:name="Paused",type="string",JSONPath=".status.conditions[?(@.type==\"Paused\")].status",description="Whether the reconciliation of the agent or of any of its components is paused"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // CBContainersAgent is the Schema for the cbcontainersagents API
@@ -1,6 +1,9 @@
 package v1
 
-import coreV1 "k8s.io/api/core/v1"
+import (
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
 
 type CBContainersImageScanningReporterSpec struct {
 	// +kubebuilder:default:=<>
@@ -11,8 +14,19 @@ type CBContainersImageScanningReporterSpec struct {
 	PodTemplateAnnotations map[string]string `json:"podTemplateAnnotations,omitempty"`
 	// +kubebuilder:default:=1
 	ReplicasCount *int32 `json:"replicasCount,omitempty"`
+	// Autoscaling scales the deployment with a HorizontalPodAutoscaler, instead of the replicas count.
+	// +kubebuilder:default:=<>
+	Autoscaling CBContainersAutoscalingSpec `json:"autoscaling,omitempty"`
+	// PodDisruptionBudget limits the evictions of the deployment pods. It is only created while the deployment may run
+	// more than a single replica, unless its min available or max unavailable pods are set.
+	// +kubebuilder:default:=<>
+	PodDisruptionBudget CBContainersPodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
 	// +kubebuilder:default:=<>
 	Env map[string]string `json:"env,omitempty"`
+	// ExtraEnv are environment vars of the pods that can also be sourced from the keys of Secrets and ConfigMaps.
+	// They override the env vars of the same name that the operator sets or that are set in Env.
+	// +optional
+	ExtraEnv []coreV1.EnvVar `json:"extraEnv,omitempty"`
 	// +kubebuilder:default:={repository:"cbartifactory/image-scanning-reporter"}
 	Image CBContainersImageSpec `json:"image,omitempty"`
 	// +kubebuilder:default:={requests: {memory: "64Mi", cpu: "200m"}, limits: {memory: "1024Mi", cpu: "900m"}}
@@ -25,6 +39,23 @@ type CBContainersImageScanningReporterSpec struct {
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 	// +kubebuilder:default:=<>
 	Affinity *coreV1.Affinity `json:"affinity,omitempty"`
+	// TopologySpreadConstraints spread the deployment pods across the cluster. When not set, the pods of a deployment
+	// that may run more than a single replica are spread across the zones and the nodes, unless the affinity is set.
+	// +optional
+	TopologySpreadConstraints []coreV1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// Paused stops the operator from changing the image scanning reporter deployment, e.g. while it is edited by hand.
+	// Its state is still reported in the agent status.
+	// +kubebuilder:default:=false
+	Paused *bool `json:"paused,omitempty"`
+	// Architectures are the node architectures that the image scanning reporter is scheduled on. When not set, the architectures of the components settings are used.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+	// PodTemplatePatch is a strategic merge patch of the pod template of the image scanning reporter deployment that is
+	// applied after the operator builds it, e.g. to add volumes or a sidecar container. It can't change the service
+	// account, the host namespaces or the selector labels of the pods, or grant the containers more privileges.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	PodTemplatePatch *runtime.RawExtension `json:"podTemplatePatch,omitempty"`
 }
 
 type CBContainersClusterScannerAgentSpec struct {
@@ -36,6 +67,10 @@ type CBContainersClusterScannerAgentSpec struct {
 	PodTemplateAnnotations map[string]string `json:"podTemplateAnnotations,omitempty"`
 	// +kubebuilder:default:=<>
 	Env map[string]string `json:"env,omitempty"`
+	// ExtraEnv are environment vars of the pods that can also be sourced from the keys of Secrets and ConfigMaps.
+	// They override the env vars of the same name that the operator sets or that are set in Env.
+	// +optional
+	ExtraEnv []coreV1.EnvVar `json:"extraEnv,omitempty"`
 	// +kubebuilder:default:={repository:"cbartifactory/cluster-scanner"}
 	Image CBContainersImageSpec `json:"image,omitempty"`
 	// +kubebuilder:default:={requests: {memory: "64Mi", cpu: "30m"}, limits: {memory: "6Gi", cpu: "2000m"}}
@@ -48,6 +83,14 @@ type CBContainersClusterScannerAgentSpec struct {
 	K8sContainerEngine K8sContainerEngineSpec `json:"k8sContainerEngine,omitempty"`
 	// +kubebuilder:default:=<>
 	CLIFlags CLIFlags `json:"cliFlags,omitempty"`
+	// Paused stops the operator from changing the components daemon set that runs the cluster scanner, e.g. while it is edited by hand.
+	// Its state is still reported in the agent status. As the daemon set is shared, it is paused when any of its components is paused.
+	// +kubebuilder:default:=false
+	Paused *bool `json:"paused,omitempty"`
+	// Architectures are the node architectures that the cluster scanner runs on. When not set, the architectures of the components settings are used.
+	// As the daemon set is shared, it is scheduled on the architectures that all its components run on.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
 }
 
 type CLIFlags struct {
@@ -13,6 +13,10 @@ type CBContainersCndrSensorSpec struct {
 	PodTemplateAnnotations map[string]string `json:"podTemplateAnnotations,omitempty"`
 	// +kubebuilder:default:=<>
 	Env map[string]string `json:"env,omitempty"`
+	// ExtraEnv are environment vars of the pods that can also be sourced from the keys of Secrets and ConfigMaps.
+	// They override the env vars of the same name that the operator sets or that are set in Env.
+	// +optional
+	ExtraEnv []coreV1.EnvVar `json:"extraEnv,omitempty"`
 	// +kubebuilder:default:={repository:"cbartifactory/cndr"}
 	Image CBContainersImageSpec `json:"image,omitempty"`
 	// +kubebuilder:default:={requests: {memory: "64Mi", cpu: "30m"}, limits: {memory: "1024Mi", cpu: "500m"}}
@@ -25,6 +29,14 @@ type CBContainersCndrSensorSpec struct {
 	VerbosityLevel *int `json:"verbosity_level,omitempty"`
 	// +kubebuilder:default:="info"
 	LogLevel string `json:"logLevel,omitempty"`
+	// Paused stops the operator from changing the components daemon set that runs the CNDR sensor, e.g. while it is edited by hand.
+	// Its state is still reported in the agent status. As the daemon set is shared, it is paused when any of its components is paused.
+	// +kubebuilder:default:=false
+	Paused *bool `json:"paused,omitempty"`
+	// Architectures are the node architectures that the CNDR sensor runs on. When not set, the architectures of the components settings are used.
+	// As the daemon set is shared, it is scheduled on the architectures that all its components run on.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
 }
 
 // CBContainersCndrSpec defines the desired state of CBContainersCndr
@@ -1,6 +1,9 @@
 package v1
 
-import coreV1 "k8s.io/api/core/v1"
+import (
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
 
 type CBContainersBasicSpec struct {
 	// +kubebuilder:default:=<>
@@ -20,6 +23,10 @@ type CBContainersMonitorSpec struct {
 	PodTemplateAnnotations map[string]string `json:"podTemplateAnnotations,omitempty"`
 	// +kubebuilder:default:=<>
 	Env map[string]string `json:"env,omitempty"`
+	// ExtraEnv are environment vars of the pods that can also be sourced from the keys of Secrets and ConfigMaps.
+	// They override the env vars of the same name that the operator sets or that are set in Env.
+	// +optional
+	ExtraEnv []coreV1.EnvVar `json:"extraEnv,omitempty"`
 	// +kubebuilder:default:={repository:"cbartifactory/monitor"}
 	Image CBContainersImageSpec `json:"image,omitempty"`
 	// +kubebuilder:default:={requests: {memory: "64Mi", cpu: "30m"}, limits: {memory: "256Mi", cpu: "200m"}}
@@ -30,4 +37,17 @@ type CBContainersMonitorSpec struct {
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 	// +kubebuilder:default:=<>
 	Affinity *coreV1.Affinity `json:"affinity,omitempty"`
+	// Paused stops the operator from changing the monitor deployment, e.g. while it is edited by hand.
+	// Its state is still reported in the agent status.
+	// +kubebuilder:default:=false
+	Paused *bool `json:"paused,omitempty"`
+	// Architectures are the node architectures that the monitor is scheduled on. When not set, the architectures of the components settings are used.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+	// PodTemplatePatch is a strategic merge patch of the pod template of the monitor deployment that is applied after the
+	// operator builds it, e.g. to add volumes or a sidecar container. It can't change the service account, the host
+	// namespaces or the selector labels of the pods, or grant the containers more privileges.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	PodTemplatePatch *runtime.RawExtension `json:"podTemplatePatch,omitempty"`
 }
@@ -0,0 +1,19 @@
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// CBContainersPodDisruptionBudgetSpec makes the operator create a PodDisruptionBudget that limits how many pods of a
+// deployment of the agent are evicted at once, e.g. while nodes are drained.
+type CBContainersPodDisruptionBudgetSpec struct {
+	// +kubebuilder:default:=true
+	Enabled *bool `json:"enabled,omitempty"`
+	// MinAvailable is the number or the percentage of the pods that must stay available. It can't be set with MaxUnavailable.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// MaxUnavailable is the number or the percentage of the pods that may be unavailable. It can't be set with MinAvailable.
+	// When neither is set, a single pod may be unavailable.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
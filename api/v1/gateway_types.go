@@ -1,5 +1,7 @@
 package v1
 
+import coreV1 "k8s.io/api/core/v1"
+
 type CBContainersGatewaysSpec struct {
 	// +kubebuilder:default:=<>
 	GatewayTLS             CBContainersGatewayTLS        `json:"gatewayTLS,omitempty"`
@@ -13,6 +15,19 @@ type CBContainersGatewayTLS struct {
 	// +kubebuilder:default:=false
 	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
 	RootCAsBundle      []byte `json:"rootCAsBundle,omitempty"`
+	// RootCAsBundleRef refers to a key of a ConfigMap or a Secret in the agent namespace that holds a PEM bundle of root
+	// CAs, e.g. a bundle that trust-manager distributes. It is appended to RootCAsBundle.
+	// +optional
+	RootCAsBundleRef *CBContainersRootCAsBundleRef `json:"rootCAsBundleRef,omitempty"`
+}
+
+// CBContainersRootCAsBundleRef refers to the key of a ConfigMap or of a Secret that holds a PEM bundle of root CAs.
+// When both are set, the bundle of the Secret is appended to the bundle of the ConfigMap.
+type CBContainersRootCAsBundleRef struct {
+	// +optional
+	ConfigMapKeyRef *coreV1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// +optional
+	SecretKeyRef *coreV1.SecretKeySelector `json:"secretKeyRef,omitempty"`
 }
 
 type CBContainersEventsGatewaySpec struct {
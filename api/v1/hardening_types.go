@@ -1,7 +1,10 @@
 package v1
 
 import (
+	admissionsV1 "k8s.io/api/admissionregistration/v1"
 	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 type CBContainersStateReporterSpec struct {
@@ -13,6 +16,10 @@ type CBContainersStateReporterSpec struct {
 	PodTemplateAnnotations map[string]string `json:"podTemplateAnnotations,omitempty"`
 	// +kubebuilder:default:=<>
 	Env map[string]string `json:"env,omitempty"`
+	// ExtraEnv are environment vars of the pods that can also be sourced from the keys of Secrets and ConfigMaps.
+	// They override the env vars of the same name that the operator sets or that are set in Env.
+	// +optional
+	ExtraEnv []coreV1.EnvVar `json:"extraEnv,omitempty"`
 	// +kubebuilder:default:={repository:"cbartifactory/guardrails-state-reporter"}
 	Image CBContainersImageSpec `json:"image,omitempty"`
 	// +kubebuilder:default:={requests: {memory: "256Mi", cpu: "200m"}, limits: {memory: "512Mi", cpu: "400m"}}
@@ -23,6 +30,19 @@ type CBContainersStateReporterSpec struct {
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 	// +kubebuilder:default:=<>
 	Affinity *coreV1.Affinity `json:"affinity,omitempty"`
+	// Paused stops the operator from changing the state reporter deployment, e.g. while it is edited by hand.
+	// Its state is still reported in the agent status.
+	// +kubebuilder:default:=false
+	Paused *bool `json:"paused,omitempty"`
+	// Architectures are the node architectures that the state reporter is scheduled on. When not set, the architectures of the components settings are used.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+	// PodTemplatePatch is a strategic merge patch of the pod template of the state reporter deployment that is applied
+	// after the operator builds it, e.g. to add volumes or a sidecar container. It can't change the service account, the
+	// host namespaces or the selector labels of the pods, or grant the containers more privileges.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	PodTemplatePatch *runtime.RawExtension `json:"podTemplatePatch,omitempty"`
 }
 
 type CBContainersEnforcerSpec struct {
@@ -34,8 +54,19 @@ type CBContainersEnforcerSpec struct {
 	PodTemplateAnnotations map[string]string `json:"podTemplateAnnotations,omitempty"`
 	// +kubebuilder:default:=<>
 	Env map[string]string `json:"env,omitempty"`
+	// ExtraEnv are environment vars of the pods that can also be sourced from the keys of Secrets and ConfigMaps.
+	// They override the env vars of the same name that the operator sets or that are set in Env.
+	// +optional
+	ExtraEnv []coreV1.EnvVar `json:"extraEnv,omitempty"`
 	// +kubebuilder:default:=1
 	ReplicasCount *int32 `json:"replicasCount,omitempty"`
+	// Autoscaling scales the deployment with a HorizontalPodAutoscaler, instead of the replicas count.
+	// +kubebuilder:default:=<>
+	Autoscaling CBContainersAutoscalingSpec `json:"autoscaling,omitempty"`
+	// PodDisruptionBudget limits the evictions of the deployment pods. It is only created while the deployment may run
+	// more than a single replica, unless its min available or max unavailable pods are set.
+	// +kubebuilder:default:=<>
+	PodDisruptionBudget CBContainersPodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
 	// +kubebuilder:default:={port: 7071}
 	Prometheus CBContainersPrometheusSpec `json:"prometheus,omitempty"`
 	// +kubebuilder:default:={repository:"cbartifactory/guardrails-enforcer"}
@@ -48,6 +79,10 @@ type CBContainersEnforcerSpec struct {
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 	// +kubebuilder:default:=<>
 	Affinity *coreV1.Affinity `json:"affinity,omitempty"`
+	// TopologySpreadConstraints spread the deployment pods across the cluster. When not set, the pods of a deployment
+	// that may run more than a single replica are spread across the zones and the nodes, unless the affinity is set.
+	// +optional
+	TopologySpreadConstraints []coreV1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
 	// +kubebuilder:default:=5
 	WebhookTimeoutSeconds int32 `json:"webhookTimeoutSeconds,omitempty"`
 	// +kubebuilder:default:=true
@@ -55,4 +90,82 @@ type CBContainersEnforcerSpec struct {
 	// +kubebuilder:validation:Enum=Ignore;Fail
 	// +kubebuilder:default:=Ignore
 	FailurePolicy string `json:"failurePolicy,omitempty"`
+	// Paused stops the operator from changing the enforcer deployment, e.g. while it is edited by hand.
+	// Its state is still reported in the agent status.
+	// +kubebuilder:default:=false
+	Paused *bool `json:"paused,omitempty"`
+	// Architectures are the node architectures that the enforcer is scheduled on. When not set, the architectures of the components settings are used.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+	// TlsRenewBefore is how long before the enforcer TLS certificates expire they are renewed.
+	// +kubebuilder:default:="720h"
+	TlsRenewBefore *metav1.Duration `json:"tlsRenewBefore,omitempty"`
+	// CertManager makes cert-manager issue the enforcer webhook certificates, instead of the operator.
+	// +optional
+	CertManager *CBContainersCertManagerSpec `json:"certManager,omitempty"`
+	// Webhooks configures which admission requests are sent to the enforcer webhooks.
+	// +kubebuilder:default:=<>
+	Webhooks CBContainersEnforcerWebhooksSpec `json:"webhooks,omitempty"`
+	// PodTemplatePatch is a strategic merge patch of the pod template of the enforcer deployment that is applied after the
+	// operator builds it, e.g. to add volumes or a sidecar container. It can't change the service account, the host
+	// namespaces or the selector labels of the pods, or grant the containers more privileges.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	PodTemplatePatch *runtime.RawExtension `json:"podTemplatePatch,omitempty"`
+}
+
+// CBContainersNamespaceLabelExclusion excludes the namespaces that have a label from the enforcer webhooks.
+type CBContainersNamespaceLabelExclusion struct {
+	// Key is the key of the label.
+	Key string `json:"key"`
+	// Values are the values of the label that exclude a namespace.
+	// When not set, a namespace that has the label is excluded, whatever its value is.
+	// +optional
+	Values []string `json:"values,omitempty"`
+}
+
+// CBContainersEnforcerWebhooksSpec configures which admission requests are sent to the enforcer webhooks.
+type CBContainersEnforcerWebhooksSpec struct {
+	// AdditionalResources are resources that are sent to the enforcer webhooks, on top of the default ones.
+	// +optional
+	AdditionalResources []string `json:"additionalResources,omitempty"`
+	// ExcludedResources are default resources that are not sent to the enforcer webhooks, e.g. "customresourcedefinitions".
+	// +optional
+	ExcludedResources []string `json:"excludedResources,omitempty"`
+	// ExcludedNamespaces are namespaces whose resources are not sent to the enforcer webhooks,
+	// on top of the agent namespace and the namespaces labeled with octarine=ignore.
+	// +optional
+	ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+	// ExcludedNamespaceLabels are labels of namespaces whose resources are not sent to the enforcer webhooks.
+	// A namespace is excluded when it has any of them, e.g. a team=platform label or a crossplane.io/managed label.
+	// +optional
+	ExcludedNamespaceLabels []CBContainersNamespaceLabelExclusion `json:"excludedNamespaceLabels,omitempty"`
+	// ObjectSelector limits the objects that are sent to the enforcer webhooks by their labels.
+	// +optional
+	ObjectSelector *metav1.LabelSelector `json:"objectSelector,omitempty"`
+	// MatchConditions are CEL expressions that the admission requests must match to be sent to the enforcer webhooks.
+	// They are evaluated by the API server, and are only set on clusters of Kubernetes v1.28 or later.
+	// +optional
+	MatchConditions []admissionsV1.MatchCondition `json:"matchConditions,omitempty"`
+	// AdmissionReviewVersions are the AdmissionReview versions ("v1" or "v1beta1") that the enforcer webhooks accept, by order of preference.
+	// When not set, the webhooks accept only v1beta1. v1 is dropped on API servers that don't support it.
+	// +optional
+	AdmissionReviewVersions []string `json:"admissionReviewVersions,omitempty"`
+}
+
+// CBContainersCertManagerSpec configures how cert-manager issues certificates.
+type CBContainersCertManagerSpec struct {
+	// IssuerRef references the cert-manager Issuer or ClusterIssuer that issues the certificates.
+	// An Issuer must be in the namespace of the agent.
+	IssuerRef CBContainersIssuerReference `json:"issuerRef"`
+}
+
+// CBContainersIssuerReference references a cert-manager issuer.
+type CBContainersIssuerReference struct {
+	Name string `json:"name"`
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +kubebuilder:default:=Issuer
+	Kind string `json:"kind,omitempty"`
+	// +kubebuilder:default:="cert-manager.io"
+	Group string `json:"group,omitempty"`
 }
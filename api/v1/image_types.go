@@ -14,4 +14,8 @@ type CBContainersImageSpec struct {
 	//
 	// The secrets must already exist.
 	PullSecrets []string `json:"pullSecrets,omitempty"`
+	// MultiArch marks the image as a multi-architecture image, which runs on all the architectures of its component.
+	// Otherwise, the image only runs on the 386, amd64 and amd64p32 architectures.
+	// +optional
+	MultiArch bool `json:"multiArch,omitempty"`
 }
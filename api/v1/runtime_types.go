@@ -2,6 +2,8 @@ package v1
 
 import (
 	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 type CBContainersRuntimeResolverSpec struct {
@@ -12,8 +14,19 @@ type CBContainersRuntimeResolverSpec struct {
 	// +kubebuilder:default:=<>
 	PodTemplateAnnotations map[string]string `json:"podTemplateAnnotations,omitempty"`
 	ReplicasCount          *int32            `json:"replicasCount,omitempty"`
+	// Autoscaling scales the deployment with a HorizontalPodAutoscaler, instead of the replicas count or the number of nodes.
+	// +kubebuilder:default:=<>
+	Autoscaling CBContainersAutoscalingSpec `json:"autoscaling,omitempty"`
+	// PodDisruptionBudget limits the evictions of the deployment pods. It is only created while the deployment may run
+	// more than a single replica, unless its min available or max unavailable pods are set.
+	// +kubebuilder:default:=<>
+	PodDisruptionBudget CBContainersPodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
 	// +kubebuilder:default:=<>
 	Env map[string]string `json:"env,omitempty"`
+	// ExtraEnv are environment vars of the pods that can also be sourced from the keys of Secrets and ConfigMaps.
+	// They override the env vars of the same name that the operator sets or that are set in Env.
+	// +optional
+	ExtraEnv []coreV1.EnvVar `json:"extraEnv,omitempty"`
 	// +kubebuilder:default:={repository:"cbartifactory/runtime-kubernetes-resolver"}
 	Image CBContainersImageSpec `json:"image,omitempty"`
 	// +kubebuilder:default:={requests: {memory: "512Mi", cpu: "200m"}, limits: {memory: "2Gi", cpu: "900m"}}
@@ -26,10 +39,49 @@ type CBContainersRuntimeResolverSpec struct {
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 	// +kubebuilder:default:=<>
 	Affinity *coreV1.Affinity `json:"affinity,omitempty"`
+	// TopologySpreadConstraints spread the deployment pods across the cluster. When not set, the pods of a deployment
+	// that may run more than a single replica are spread across the zones and the nodes, unless the affinity is set.
+	// +optional
+	TopologySpreadConstraints []coreV1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
 	// +kubebuilder:default:="info"
 	LogLevel string `json:"logLevel,omitempty"`
+	// NodesToReplicasRatio is the number of nodes per resolver replica, when the replicas count is not set.
+	// The replicas count is derived again whenever nodes are added to or removed from the cluster.
 	// +kubebuilder:default:=5
 	NodesToReplicasRatio int32 `json:"nodesToReplicasRatio,omitempty"`
+	// MinReplicas is the minimum replicas count that is derived from the number of nodes.
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// MaxReplicas is the maximum replicas count that is derived from the number of nodes. When not set, it is not bounded.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+	// ScaleNodesThreshold is how many nodes must be added or removed since the replicas count was last derived before
+	// it is derived again, so the resolver isn't scaled whenever a few nodes are added or removed.
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=1
+	ScaleNodesThreshold int32 `json:"scaleNodesThreshold,omitempty"`
+	// ScaleUpCooldown is how long after the replicas count was last changed it may be increased.
+	// +kubebuilder:default:="0s"
+	ScaleUpCooldown *metav1.Duration `json:"scaleUpCooldown,omitempty"`
+	// ScaleDownCooldown is how long after the replicas count was last changed it may be decreased, so the resolver
+	// isn't scaled down and up again while nodes are replaced.
+	// +kubebuilder:default:="5m"
+	ScaleDownCooldown *metav1.Duration `json:"scaleDownCooldown,omitempty"`
+	// Paused stops the operator from changing the resolver deployment, e.g. while it is edited by hand.
+	// Its state is still reported in the agent status.
+	// +kubebuilder:default:=false
+	Paused *bool `json:"paused,omitempty"`
+	// Architectures are the node architectures that the runtime resolver is scheduled on. When not set, the architectures of the components settings are used.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+	// PodTemplatePatch is a strategic merge patch of the pod template of the runtime resolver deployment that is applied
+	// after the operator builds it, e.g. to add volumes or a sidecar container. It can't change the service account, the
+	// host namespaces or the selector labels of the pods, or grant the containers more privileges.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	PodTemplatePatch *runtime.RawExtension `json:"podTemplatePatch,omitempty"`
 }
 
 type CBContainersRuntimeSensorSpec struct {
@@ -41,6 +93,10 @@ type CBContainersRuntimeSensorSpec struct {
 	PodTemplateAnnotations map[string]string `json:"podTemplateAnnotations,omitempty"`
 	// +kubebuilder:default:=<>
 	Env map[string]string `json:"env,omitempty"`
+	// ExtraEnv are environment vars of the pods that can also be sourced from the keys of Secrets and ConfigMaps.
+	// They override the env vars of the same name that the operator sets or that are set in Env.
+	// +optional
+	ExtraEnv []coreV1.EnvVar `json:"extraEnv,omitempty"`
 	// +kubebuilder:default:={repository:"cbartifactory/runtime-kubernetes-sensor"}
 	Image CBContainersImageSpec `json:"image,omitempty"`
 	// +kubebuilder:default:={requests: {memory: "64Mi", cpu: "30m"}, limits: {memory: "1024Mi", cpu: "500m"}}
@@ -53,6 +109,21 @@ type CBContainersRuntimeSensorSpec struct {
 	VerbosityLevel *int `json:"verbosity_level,omitempty"`
 	// +kubebuilder:default:="info"
 	LogLevel string `json:"logLevel,omitempty"`
+	// Paused stops the operator from changing the components daemon set that runs the runtime sensor, e.g. while it is edited by hand.
+	// Its state is still reported in the agent status. As the daemon set is shared, it is paused when any of its components is paused.
+	// +kubebuilder:default:=false
+	Paused *bool `json:"paused,omitempty"`
+	// Architectures are the node architectures that the runtime sensor runs on. When not set, the architectures of the components settings are used.
+	// As the daemon set is shared, it is scheduled on the architectures that all its components run on.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+	// PodTemplatePatch is a strategic merge patch of the pod template of the components daemon set that is applied after
+	// the operator builds it, e.g. to add volumes or a sidecar container. It can't change the service account, the host
+	// namespaces or the selector labels of the pods, or grant the containers more privileges. As the daemon set is shared,
+	// the patch applies to the pods of all its components.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	PodTemplatePatch *runtime.RawExtension `json:"podTemplatePatch,omitempty"`
 }
 
 // CBContainersRuntimeProtectionSpec defines the desired state of CBContainersRuntime
@@ -22,8 +22,12 @@ limitations under the License.
 package v1
 
 import (
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -32,7 +36,7 @@ func (in *CBContainersAgent) DeepCopyInto(out *CBContainersAgent) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersAgent.
@@ -90,6 +94,11 @@ func (in *CBContainersAgentSpec) DeepCopyInto(out *CBContainersAgentSpec) {
 	*out = *in
 	in.Gateways.DeepCopyInto(&out.Gateways)
 	in.Components.DeepCopyInto(&out.Components)
+	if in.Paused != nil {
+		in, out := &in.Paused, &out.Paused
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersAgentSpec.
@@ -105,6 +114,52 @@ func (in *CBContainersAgentSpec) DeepCopy() *CBContainersAgentSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CBContainersAgentStatus) DeepCopyInto(out *CBContainersAgentStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]CBContainersComponentStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeCleanup != nil {
+		in, out := &in.NodeCleanup, &out.NodeCleanup
+		*out = new(CBContainersNodeCleanupStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EnforcerCertificates != nil {
+		in, out := &in.EnforcerCertificates, &out.EnforcerCertificates
+		*out = new(CBContainersCertificatesStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodesExcludedByArchitecture != nil {
+		in, out := &in.NodesExcludedByArchitecture, &out.NodesExcludedByArchitecture
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NodeCoverage != nil {
+		in, out := &in.NodeCoverage, &out.NodeCoverage
+		*out = new(CBContainersNodeCoverageStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DaemonSetsTolerations != nil {
+		in, out := &in.DaemonSetsTolerations, &out.DaemonSetsTolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RuntimeResolverScaling != nil {
+		in, out := &in.RuntimeResolverScaling, &out.RuntimeResolverScaling
+		*out = new(CBContainersReplicasScalingStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersAgentStatus.
@@ -132,6 +187,53 @@ func (in *CBContainersApiGatewaySpec) DeepCopy() *CBContainersApiGatewaySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CBContainersAutoscalingSpec) DeepCopyInto(out *CBContainersAutoscalingSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetCPUUtilizationPercentage != nil {
+		in, out := &in.TargetCPUUtilizationPercentage, &out.TargetCPUUtilizationPercentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetMemoryUtilizationPercentage != nil {
+		in, out := &in.TargetMemoryUtilizationPercentage, &out.TargetMemoryUtilizationPercentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = make([]v2.MetricSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Behavior != nil {
+		in, out := &in.Behavior, &out.Behavior
+		*out = new(v2.HorizontalPodAutoscalerBehavior)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersAutoscalingSpec.
+func (in *CBContainersAutoscalingSpec) DeepCopy() *CBContainersAutoscalingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CBContainersAutoscalingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CBContainersBasicSpec) DeepCopyInto(out *CBContainersBasicSpec) {
 	*out = *in
@@ -150,6 +252,43 @@ func (in *CBContainersBasicSpec) DeepCopy() *CBContainersBasicSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CBContainersCertManagerSpec) DeepCopyInto(out *CBContainersCertManagerSpec) {
+	*out = *in
+	out.IssuerRef = in.IssuerRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersCertManagerSpec.
+func (in *CBContainersCertManagerSpec) DeepCopy() *CBContainersCertManagerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CBContainersCertManagerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CBContainersCertificatesStatus) DeepCopyInto(out *CBContainersCertificatesStatus) {
+	*out = *in
+	in.NotAfter.DeepCopyInto(&out.NotAfter)
+	in.RenewalTime.DeepCopyInto(&out.RenewalTime)
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersCertificatesStatus.
+func (in *CBContainersCertificatesStatus) DeepCopy() *CBContainersCertificatesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CBContainersCertificatesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CBContainersClusterScannerAgentSpec) DeepCopyInto(out *CBContainersClusterScannerAgentSpec) {
 	*out = *in
@@ -181,12 +320,29 @@ func (in *CBContainersClusterScannerAgentSpec) DeepCopyInto(out *CBContainersClu
 			(*out)[key] = val
 		}
 	}
+	if in.ExtraEnv != nil {
+		in, out := &in.ExtraEnv, &out.ExtraEnv
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Image.DeepCopyInto(&out.Image)
 	in.Resources.DeepCopyInto(&out.Resources)
 	out.Probes = in.Probes
 	in.Prometheus.DeepCopyInto(&out.Prometheus)
 	out.K8sContainerEngine = in.K8sContainerEngine
 	in.CLIFlags.DeepCopyInto(&out.CLIFlags)
+	if in.Paused != nil {
+		in, out := &in.Paused, &out.Paused
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersClusterScannerAgentSpec.
@@ -252,6 +408,13 @@ func (in *CBContainersCndrSensorSpec) DeepCopyInto(out *CBContainersCndrSensorSp
 			(*out)[key] = val
 		}
 	}
+	if in.ExtraEnv != nil {
+		in, out := &in.ExtraEnv, &out.ExtraEnv
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Image.DeepCopyInto(&out.Image)
 	in.Resources.DeepCopyInto(&out.Resources)
 	out.Probes = in.Probes
@@ -261,6 +424,16 @@ func (in *CBContainersCndrSensorSpec) DeepCopyInto(out *CBContainersCndrSensorSp
 		*out = new(int)
 		**out = **in
 	}
+	if in.Paused != nil {
+		in, out := &in.Paused, &out.Paused
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersCndrSensorSpec.
@@ -309,6 +482,28 @@ func (in *CBContainersCommonProbesSpec) DeepCopy() *CBContainersCommonProbesSpec
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CBContainersComponentStatus) DeepCopyInto(out *CBContainersComponentStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersComponentStatus.
+func (in *CBContainersComponentStatus) DeepCopy() *CBContainersComponentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CBContainersComponentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CBContainersComponentsSettings) DeepCopyInto(out *CBContainersComponentsSettings) {
 	*out = *in
@@ -319,6 +514,12 @@ func (in *CBContainersComponentsSettings) DeepCopyInto(out *CBContainersComponen
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	in.DaemonSetsTolerationsDiscovery.DeepCopyInto(&out.DaemonSetsTolerationsDiscovery)
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.CreateDefaultImagePullSecrets != nil {
 		in, out := &in.CreateDefaultImagePullSecrets, &out.CreateDefaultImagePullSecrets
 		*out = new(bool)
@@ -329,6 +530,7 @@ func (in *CBContainersComponentsSettings) DeepCopyInto(out *CBContainersComponen
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	in.NodeCleanupImage.DeepCopyInto(&out.NodeCleanupImage)
 	if in.Proxy != nil {
 		in, out := &in.Proxy, &out.Proxy
 		*out = new(CBContainersProxySettings)
@@ -406,11 +608,20 @@ func (in *CBContainersEnforcerSpec) DeepCopyInto(out *CBContainersEnforcerSpec)
 			(*out)[key] = val
 		}
 	}
+	if in.ExtraEnv != nil {
+		in, out := &in.ExtraEnv, &out.ExtraEnv
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.ReplicasCount != nil {
 		in, out := &in.ReplicasCount, &out.ReplicasCount
 		*out = new(int32)
 		**out = **in
 	}
+	in.Autoscaling.DeepCopyInto(&out.Autoscaling)
+	in.PodDisruptionBudget.DeepCopyInto(&out.PodDisruptionBudget)
 	in.Prometheus.DeepCopyInto(&out.Prometheus)
 	in.Image.DeepCopyInto(&out.Image)
 	in.Resources.DeepCopyInto(&out.Resources)
@@ -427,11 +638,44 @@ func (in *CBContainersEnforcerSpec) DeepCopyInto(out *CBContainersEnforcerSpec)
 		*out = new(corev1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]corev1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.EnableEnforcementFeature != nil {
 		in, out := &in.EnableEnforcementFeature, &out.EnableEnforcementFeature
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Paused != nil {
+		in, out := &in.Paused, &out.Paused
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TlsRenewBefore != nil {
+		in, out := &in.TlsRenewBefore, &out.TlsRenewBefore
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.CertManager != nil {
+		in, out := &in.CertManager, &out.CertManager
+		*out = new(CBContainersCertManagerSpec)
+		**out = **in
+	}
+	in.Webhooks.DeepCopyInto(&out.Webhooks)
+	if in.PodTemplatePatch != nil {
+		in, out := &in.PodTemplatePatch, &out.PodTemplatePatch
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersEnforcerSpec.
@@ -444,6 +688,58 @@ func (in *CBContainersEnforcerSpec) DeepCopy() *CBContainersEnforcerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CBContainersEnforcerWebhooksSpec) DeepCopyInto(out *CBContainersEnforcerWebhooksSpec) {
+	*out = *in
+	if in.AdditionalResources != nil {
+		in, out := &in.AdditionalResources, &out.AdditionalResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludedResources != nil {
+		in, out := &in.ExcludedResources, &out.ExcludedResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludedNamespaces != nil {
+		in, out := &in.ExcludedNamespaces, &out.ExcludedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludedNamespaceLabels != nil {
+		in, out := &in.ExcludedNamespaceLabels, &out.ExcludedNamespaceLabels
+		*out = make([]CBContainersNamespaceLabelExclusion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ObjectSelector != nil {
+		in, out := &in.ObjectSelector, &out.ObjectSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MatchConditions != nil {
+		in, out := &in.MatchConditions, &out.MatchConditions
+		*out = make([]admissionregistrationv1.MatchCondition, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdmissionReviewVersions != nil {
+		in, out := &in.AdmissionReviewVersions, &out.AdmissionReviewVersions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersEnforcerWebhooksSpec.
+func (in *CBContainersEnforcerWebhooksSpec) DeepCopy() *CBContainersEnforcerWebhooksSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CBContainersEnforcerWebhooksSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CBContainersEventsGatewaySpec) DeepCopyInto(out *CBContainersEventsGatewaySpec) {
 	*out = *in
@@ -483,6 +779,11 @@ func (in *CBContainersGatewayTLS) DeepCopyInto(out *CBContainersGatewayTLS) {
 		*out = make([]byte, len(*in))
 		copy(*out, *in)
 	}
+	if in.RootCAsBundleRef != nil {
+		in, out := &in.RootCAsBundleRef, &out.RootCAsBundleRef
+		*out = new(CBContainersRootCAsBundleRef)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersGatewayTLS.
@@ -560,6 +861,8 @@ func (in *CBContainersImageScanningReporterSpec) DeepCopyInto(out *CBContainersI
 		*out = new(int32)
 		**out = **in
 	}
+	in.Autoscaling.DeepCopyInto(&out.Autoscaling)
+	in.PodDisruptionBudget.DeepCopyInto(&out.PodDisruptionBudget)
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
 		*out = make(map[string]string, len(*in))
@@ -567,6 +870,13 @@ func (in *CBContainersImageScanningReporterSpec) DeepCopyInto(out *CBContainersI
 			(*out)[key] = val
 		}
 	}
+	if in.ExtraEnv != nil {
+		in, out := &in.ExtraEnv, &out.ExtraEnv
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Image.DeepCopyInto(&out.Image)
 	in.Resources.DeepCopyInto(&out.Resources)
 	out.Probes = in.Probes
@@ -583,6 +893,28 @@ func (in *CBContainersImageScanningReporterSpec) DeepCopyInto(out *CBContainersI
 		*out = new(corev1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]corev1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Paused != nil {
+		in, out := &in.Paused, &out.Paused
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodTemplatePatch != nil {
+		in, out := &in.PodTemplatePatch, &out.PodTemplatePatch
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersImageScanningReporterSpec.
@@ -620,6 +952,21 @@ func (in *CBContainersImageSpec) DeepCopy() *CBContainersImageSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CBContainersIssuerReference) DeepCopyInto(out *CBContainersIssuerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersIssuerReference.
+func (in *CBContainersIssuerReference) DeepCopy() *CBContainersIssuerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(CBContainersIssuerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CBContainersMonitorSpec) DeepCopyInto(out *CBContainersMonitorSpec) {
 	*out = *in
@@ -651,6 +998,13 @@ func (in *CBContainersMonitorSpec) DeepCopyInto(out *CBContainersMonitorSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.ExtraEnv != nil {
+		in, out := &in.ExtraEnv, &out.ExtraEnv
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Image.DeepCopyInto(&out.Image)
 	in.Resources.DeepCopyInto(&out.Resources)
 	out.Probes = in.Probes
@@ -666,6 +1020,21 @@ func (in *CBContainersMonitorSpec) DeepCopyInto(out *CBContainersMonitorSpec) {
 		*out = new(corev1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Paused != nil {
+		in, out := &in.Paused, &out.Paused
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodTemplatePatch != nil {
+		in, out := &in.PodTemplatePatch, &out.PodTemplatePatch
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersMonitorSpec.
@@ -678,6 +1047,107 @@ func (in *CBContainersMonitorSpec) DeepCopy() *CBContainersMonitorSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CBContainersNamespaceLabelExclusion) DeepCopyInto(out *CBContainersNamespaceLabelExclusion) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersNamespaceLabelExclusion.
+func (in *CBContainersNamespaceLabelExclusion) DeepCopy() *CBContainersNamespaceLabelExclusion {
+	if in == nil {
+		return nil
+	}
+	out := new(CBContainersNamespaceLabelExclusion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CBContainersNodeCleanupStatus) DeepCopyInto(out *CBContainersNodeCleanupStatus) {
+	*out = *in
+	if in.PendingNodes != nil {
+		in, out := &in.PendingNodes, &out.PendingNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailedNodes != nil {
+		in, out := &in.FailedNodes, &out.FailedNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersNodeCleanupStatus.
+func (in *CBContainersNodeCleanupStatus) DeepCopy() *CBContainersNodeCleanupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CBContainersNodeCleanupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CBContainersNodeCoverageStatus) DeepCopyInto(out *CBContainersNodeCoverageStatus) {
+	*out = *in
+	if in.UncoveredNodes != nil {
+		in, out := &in.UncoveredNodes, &out.UncoveredNodes
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersNodeCoverageStatus.
+func (in *CBContainersNodeCoverageStatus) DeepCopy() *CBContainersNodeCoverageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CBContainersNodeCoverageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CBContainersPodDisruptionBudgetSpec) DeepCopyInto(out *CBContainersPodDisruptionBudgetSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MinAvailable != nil {
+		in, out := &in.MinAvailable, &out.MinAvailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersPodDisruptionBudgetSpec.
+func (in *CBContainersPodDisruptionBudgetSpec) DeepCopy() *CBContainersPodDisruptionBudgetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CBContainersPodDisruptionBudgetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CBContainersPrometheusSpec) DeepCopyInto(out *CBContainersPrometheusSpec) {
 	*out = *in
@@ -758,6 +1228,47 @@ func (in *CBContainersRemoteConfigurationSettings) DeepCopy() *CBContainersRemot
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CBContainersReplicasScalingStatus) DeepCopyInto(out *CBContainersReplicasScalingStatus) {
+	*out = *in
+	in.LastScaleTime.DeepCopyInto(&out.LastScaleTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersReplicasScalingStatus.
+func (in *CBContainersReplicasScalingStatus) DeepCopy() *CBContainersReplicasScalingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CBContainersReplicasScalingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CBContainersRootCAsBundleRef) DeepCopyInto(out *CBContainersRootCAsBundleRef) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersRootCAsBundleRef.
+func (in *CBContainersRootCAsBundleRef) DeepCopy() *CBContainersRootCAsBundleRef {
+	if in == nil {
+		return nil
+	}
+	out := new(CBContainersRootCAsBundleRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CBContainersRuntimeProtectionSpec) DeepCopyInto(out *CBContainersRuntimeProtectionSpec) {
 	*out = *in
@@ -809,6 +1320,8 @@ func (in *CBContainersRuntimeResolverSpec) DeepCopyInto(out *CBContainersRuntime
 		*out = new(int32)
 		**out = **in
 	}
+	in.Autoscaling.DeepCopyInto(&out.Autoscaling)
+	in.PodDisruptionBudget.DeepCopyInto(&out.PodDisruptionBudget)
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
 		*out = make(map[string]string, len(*in))
@@ -816,6 +1329,13 @@ func (in *CBContainersRuntimeResolverSpec) DeepCopyInto(out *CBContainersRuntime
 			(*out)[key] = val
 		}
 	}
+	if in.ExtraEnv != nil {
+		in, out := &in.ExtraEnv, &out.ExtraEnv
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Image.DeepCopyInto(&out.Image)
 	in.Resources.DeepCopyInto(&out.Resources)
 	out.Probes = in.Probes
@@ -832,6 +1352,48 @@ func (in *CBContainersRuntimeResolverSpec) DeepCopyInto(out *CBContainersRuntime
 		*out = new(corev1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]corev1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ScaleUpCooldown != nil {
+		in, out := &in.ScaleUpCooldown, &out.ScaleUpCooldown
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ScaleDownCooldown != nil {
+		in, out := &in.ScaleDownCooldown, &out.ScaleDownCooldown
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Paused != nil {
+		in, out := &in.Paused, &out.Paused
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodTemplatePatch != nil {
+		in, out := &in.PodTemplatePatch, &out.PodTemplatePatch
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersRuntimeResolverSpec.
@@ -875,6 +1437,13 @@ func (in *CBContainersRuntimeSensorSpec) DeepCopyInto(out *CBContainersRuntimeSe
 			(*out)[key] = val
 		}
 	}
+	if in.ExtraEnv != nil {
+		in, out := &in.ExtraEnv, &out.ExtraEnv
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Image.DeepCopyInto(&out.Image)
 	in.Resources.DeepCopyInto(&out.Resources)
 	out.Probes = in.Probes
@@ -884,6 +1453,21 @@ func (in *CBContainersRuntimeSensorSpec) DeepCopyInto(out *CBContainersRuntimeSe
 		*out = new(int)
 		**out = **in
 	}
+	if in.Paused != nil {
+		in, out := &in.Paused, &out.Paused
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodTemplatePatch != nil {
+		in, out := &in.PodTemplatePatch, &out.PodTemplatePatch
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersRuntimeSensorSpec.
@@ -927,6 +1511,13 @@ func (in *CBContainersStateReporterSpec) DeepCopyInto(out *CBContainersStateRepo
 			(*out)[key] = val
 		}
 	}
+	if in.ExtraEnv != nil {
+		in, out := &in.ExtraEnv, &out.ExtraEnv
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Image.DeepCopyInto(&out.Image)
 	in.Resources.DeepCopyInto(&out.Resources)
 	out.Probes = in.Probes
@@ -942,6 +1533,21 @@ func (in *CBContainersStateReporterSpec) DeepCopyInto(out *CBContainersStateRepo
 		*out = new(corev1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Paused != nil {
+		in, out := &in.Paused, &out.Paused
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodTemplatePatch != nil {
+		in, out := &in.PodTemplatePatch, &out.PodTemplatePatch
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersStateReporterSpec.
@@ -954,6 +1560,36 @@ func (in *CBContainersStateReporterSpec) DeepCopy() *CBContainersStateReporterSp
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CBContainersTolerationsDiscoverySpec) DeepCopyInto(out *CBContainersTolerationsDiscoverySpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AllowedTaintKeys != nil {
+		in, out := &in.AllowedTaintKeys, &out.AllowedTaintKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeniedTaintKeys != nil {
+		in, out := &in.DeniedTaintKeys, &out.DeniedTaintKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CBContainersTolerationsDiscoverySpec.
+func (in *CBContainersTolerationsDiscoverySpec) DeepCopy() *CBContainersTolerationsDiscoverySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CBContainersTolerationsDiscoverySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CLIFlags) DeepCopyInto(out *CLIFlags) {
 	*out = *in
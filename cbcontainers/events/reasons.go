@@ -0,0 +1,45 @@
+package events
+
+// Reasons of the Kubernetes events that are recorded on the CBContainersAgent resource.
+// They should be kept stable, as users may filter events by them.
+const (
+	// ReasonComponentCreated is used when an agent component k8s object was created.
+	ReasonComponentCreated = "ComponentCreated"
+	// ReasonComponentUpdated is used when an agent component k8s object was changed to match the desired state.
+	ReasonComponentUpdated = "ComponentUpdated"
+	// ReasonComponentDeleted is used when an agent component k8s object was deleted.
+	ReasonComponentDeleted = "ComponentDeleted"
+	// ReasonWebhooksRemoved is used when the enforcer webhooks were removed because the enforcer is not ready.
+	ReasonWebhooksRemoved = "WebhooksRemoved"
+	// ReasonApplyFailed is used when the desired state of the agent could not be applied.
+	ReasonApplyFailed = "ApplyFailed"
+	// ReasonAccessTokenInvalid is used when the access token secret is missing or doesn't hold a valid token.
+	ReasonAccessTokenInvalid = "AccessTokenInvalid"
+	// ReasonRootCAsBundleInvalid is used when the root CAs bundle that the agent refers to is missing.
+	ReasonRootCAsBundleInvalid = "RootCAsBundleInvalid"
+	// ReasonCompatibilityCheckFailed is used when the desired agent version is not compatible with the operator.
+	ReasonCompatibilityCheckFailed = "CompatibilityCheckFailed"
+	// ReasonProcessingFailed is used when the agent could not be registered with the backend.
+	ReasonProcessingFailed = "ProcessingFailed"
+	// ReasonRemoteConfigurationApplied is used when a remote configuration change was applied to the agent.
+	ReasonRemoteConfigurationApplied = "RemoteConfigurationApplied"
+	// ReasonRemoteConfigurationFailed is used when a remote configuration change could not be applied to the agent.
+	ReasonRemoteConfigurationFailed = "RemoteConfigurationFailed"
+	// ReasonPlanPublished is used when the plan of the changes to the agent components was published.
+	ReasonPlanPublished = "PlanPublished"
+	// ReasonPlanFailed is used when the changes to the agent components could not be planned.
+	ReasonPlanFailed = "PlanFailed"
+	// ReasonTeardownFailed is used when the agent components could not be torn down after the agent was deleted.
+	ReasonTeardownFailed = "TeardownFailed"
+	// ReasonForegroundDeletion is used when the agent is deleted with the foreground propagation policy, so its components
+	// are deleted by the garbage collector in parallel with the teardown.
+	ReasonForegroundDeletion = "ForegroundDeletion"
+	// ReasonNodeCleanupCompleted is used when the data that the CNDR sensor left on the nodes was removed.
+	ReasonNodeCleanupCompleted = "NodeCleanupCompleted"
+	// ReasonNodeCleanupFailed is used when the data that the CNDR sensor left on some nodes could not be removed.
+	ReasonNodeCleanupFailed = "NodeCleanupFailed"
+	// ReasonCertificatesRenewed is used when the enforcer TLS certificates were renewed before they expired.
+	ReasonCertificatesRenewed = "CertificatesRenewed"
+	// ReasonCertManagerNotInstalled is used when the enforcer certificates should be issued by cert-manager, but it is not installed.
+	ReasonCertManagerNotInstalled = "CertManagerNotInstalled"
+)
@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// EnforcerCertificatesExpiry is the time the enforcer TLS certificates expire.
+	EnforcerCertificatesExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cbcontainers_operator_enforcer_certificates_expiry_timestamp_seconds",
+		Help: "The time the enforcer TLS certificates expire, in seconds since the epoch.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(EnforcerCertificatesExpiry)
+}
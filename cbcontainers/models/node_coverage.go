@@ -0,0 +1,32 @@
+package models
+
+// NodeCoverageReason is the reason that a node is or isn't covered by the components daemon set.
+type NodeCoverageReason string
+
+const (
+	// NodeCoverageReasonCovered is the reason of the nodes that a ready pod of the daemon set runs on.
+	NodeCoverageReasonCovered NodeCoverageReason = "Covered"
+	// NodeCoverageReasonUnsupportedOperatingSystem is the reason of the nodes that the daemon set isn't scheduled on because of their OS.
+	NodeCoverageReasonUnsupportedOperatingSystem NodeCoverageReason = "UnsupportedOperatingSystem"
+	// NodeCoverageReasonUnsupportedArchitecture is the reason of the nodes that the daemon set isn't scheduled on because of their architecture.
+	NodeCoverageReasonUnsupportedArchitecture NodeCoverageReason = "UnsupportedArchitecture"
+	// NodeCoverageReasonNodeAffinityMismatch is the reason of the nodes that don't match the rest of the node affinity or the node selector of the daemon set.
+	NodeCoverageReasonNodeAffinityMismatch NodeCoverageReason = "NodeAffinityMismatch"
+	// NodeCoverageReasonUntoleratedTaint is the reason of the nodes that have a taint which the daemon set doesn't tolerate.
+	NodeCoverageReasonUntoleratedTaint NodeCoverageReason = "UntoleratedTaint"
+	// NodeCoverageReasonPodMissing is the reason of the nodes that the daemon set should run on, but none of its pods does.
+	NodeCoverageReasonPodMissing NodeCoverageReason = "PodMissing"
+	// NodeCoverageReasonImagePullFailed is the reason of the nodes that the images of the daemon set pod can't be pulled on.
+	NodeCoverageReasonImagePullFailed NodeCoverageReason = "ImagePullFailed"
+	// NodeCoverageReasonCrashLoopBackOff is the reason of the nodes that a container of the daemon set pod keeps crashing on.
+	NodeCoverageReasonCrashLoopBackOff NodeCoverageReason = "CrashLoopBackOff"
+	// NodeCoverageReasonPodNotReady is the reason of the nodes that the daemon set pod isn't ready on for any other reason.
+	NodeCoverageReasonPodNotReady NodeCoverageReason = "PodNotReady"
+)
+
+// NodeCoverage describes whether a node is covered by the components daemon set, and why it isn't.
+type NodeCoverage struct {
+	Node    string             `json:"node"`
+	Reason  NodeCoverageReason `json:"reason"`
+	Message string             `json:"message,omitempty"`
+}
@@ -2,6 +2,15 @@ package models
 
 import "fmt"
 
+// IncompatibleVersionsError is returned when the desired agent version is not supported by the running operator.
+type IncompatibleVersionsError struct {
+	msg string
+}
+
+func (e IncompatibleVersionsError) Error() string {
+	return e.msg
+}
+
 // OperatorCompatibility shows the min and max supported agent versions.
 type OperatorCompatibility struct {
 	MinAgent Version `json:"min_agent"`
@@ -15,10 +24,10 @@ type OperatorCompatibility struct {
 // skip the check and return true.
 func (c OperatorCompatibility) CheckCompatibility(agentVersion Version) error {
 	if c.MaxAgent.IsLessThan(agentVersion) {
-		return fmt.Errorf("agent version too high, upgrade the operator to use that agent version: max is [%s], desired is [%s]", c.MaxAgent, agentVersion)
+		return IncompatibleVersionsError{msg: fmt.Sprintf("agent version too high, upgrade the operator to use that agent version: max is [%s], desired is [%s]", c.MaxAgent, agentVersion)}
 	}
 	if c.MinAgent.IsLargerThan(agentVersion) {
-		return fmt.Errorf("agent version too low, downgrade the operator to use that agent version: min is [%s], desired is [%s]", c.MinAgent, agentVersion)
+		return IncompatibleVersionsError{msg: fmt.Sprintf("agent version too low, downgrade the operator to use that agent version: min is [%s], desired is [%s]", c.MinAgent, agentVersion)}
 	}
 
 	// if we are here it means the operator and the agent version are compatible
@@ -63,6 +63,7 @@ func testCheckCompatibility(t *testing.T, testCases []testCase, areCompatible bo
 				require.NoError(t, err, "CheckCompatibility should not return error when versions are compatible - min (%s), max (%s), agent (%s)", testCase.min, testCase.max, testCase.agent)
 			} else {
 				require.Error(t, err, "CheckCompatibility should return error when versions are not compatible - min (%s), max (%s), agent (%s)", testCase.min, testCase.max, testCase.agent)
+				require.ErrorAs(t, err, &models.IncompatibleVersionsError{})
 			}
 		})
 	}
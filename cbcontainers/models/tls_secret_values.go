@@ -1,10 +1,15 @@
 package models
 
 const (
-	CaCertKey     = "ca.crt"
-	CaKeyKey      = "ca.key"
-	SignedCertKey = "signed_cert"
-	KeyKey        = "key"
+	CaCertKey         = "ca.crt"
+	CaKeyKey          = "ca.key"
+	SignedCertKey     = "signed_cert"
+	KeyKey            = "key"
+	PreviousCaCertKey = "previous_ca.crt"
+
+	// The keys of the secrets that cert-manager issues certificates to.
+	CertManagerSignedCertKey = "tls.crt"
+	CertManagerKeyKey        = "tls.key"
 )
 
 type TlsSecretValues struct {
@@ -12,6 +17,9 @@ type TlsSecretValues struct {
 	CaKey      []byte `json:"caKey"`
 	SignedCert []byte `json:"signedCert"`
 	Key        []byte `json:"key"`
+	// PreviousCaCert is the CA that was replaced by the last certificates rotation.
+	// It is kept until the enforcer pods use the new certificates.
+	PreviousCaCert []byte `json:"previousCaCert,omitempty"`
 }
 
 func NewTlsSecretValues(caCert, caKey, signedCert, key []byte) TlsSecretValues {
@@ -24,14 +32,41 @@ func NewTlsSecretValues(caCert, caKey, signedCert, key []byte) TlsSecretValues {
 }
 
 func TlsSecretValuesFromSecretData(data map[string][]byte) TlsSecretValues {
-	return NewTlsSecretValues(data[CaCertKey], data[CaKeyKey], data[SignedCertKey], data[KeyKey])
+	tlsSecretValues := NewTlsSecretValues(data[CaCertKey], data[CaKeyKey], data[SignedCertKey], data[KeyKey])
+	tlsSecretValues.PreviousCaCert = data[PreviousCaCertKey]
+	return tlsSecretValues
+}
+
+// TlsSecretValuesFromCertManagerSecretData reads the values of a secret that cert-manager issued certificates to.
+// The CA key is never part of such a secret.
+func TlsSecretValuesFromCertManagerSecretData(data map[string][]byte) TlsSecretValues {
+	return NewTlsSecretValues(data[CaCertKey], nil, data[CertManagerSignedCertKey], data[CertManagerKeyKey])
 }
 
 func (tlsSecretValues TlsSecretValues) ToDataMap() map[string][]byte {
-	return map[string][]byte{
+	data := map[string][]byte{
 		CaCertKey:     tlsSecretValues.CaCert,
 		CaKeyKey:      tlsSecretValues.CaKey,
 		SignedCertKey: tlsSecretValues.SignedCert,
 		KeyKey:        tlsSecretValues.Key,
 	}
+	if len(tlsSecretValues.PreviousCaCert) > 0 {
+		data[PreviousCaCertKey] = tlsSecretValues.PreviousCaCert
+	}
+
+	return data
+}
+
+// CaBundle returns the CA certificates the webhooks should trust.
+// During a certificates rotation it holds both the new and the previous CA, so the enforcer pods are trusted while they roll.
+func (tlsSecretValues TlsSecretValues) CaBundle() []byte {
+	if len(tlsSecretValues.PreviousCaCert) == 0 {
+		return tlsSecretValues.CaCert
+	}
+
+	caBundle := append([]byte{}, tlsSecretValues.CaCert...)
+	if len(caBundle) > 0 && caBundle[len(caBundle)-1] != '\n' {
+		caBundle = append(caBundle, '\n')
+	}
+	return append(caBundle, tlsSecretValues.PreviousCaCert...)
 }
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"github.com/go-logr/logr"
 	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/events"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sort"
 	"sync"
@@ -46,6 +49,7 @@ type Configurator struct {
 	clusterIdentifier   string
 
 	validatorCreator ValidatorCreator
+	eventRecorder    record.EventRecorder
 
 	mux sync.Mutex
 }
@@ -56,6 +60,7 @@ func NewConfigurator(
 	logger logr.Logger,
 	accessTokenProvider AccessTokenProvider,
 	validatorCreator ValidatorCreator,
+	eventRecorder record.EventRecorder,
 	deployedNamespace string,
 	clusterIdentifier string,
 ) *Configurator {
@@ -65,6 +70,7 @@ func NewConfigurator(
 		apiCreator:          gatewayCreator,
 		accessTokenProvider: accessTokenProvider,
 		validatorCreator:    validatorCreator,
+		eventRecorder:       eventRecorder,
 		deployedNamespace:   deployedNamespace,
 		clusterIdentifier:   clusterIdentifier,
 		mux:                 sync.Mutex{},
@@ -95,6 +101,12 @@ func (configurator *Configurator) RunIteration(ctx context.Context) error {
 		return nil
 
 	}
+
+	if cr.Spec.Paused != nil && *cr.Spec.Paused {
+		configurator.logger.Info("Reconciliation of the agent is paused, no remote configuration changes will be made")
+		return nil
+	}
+
 	apiGateway, err := configurator.createAPIGateway(ctx, cr)
 	if err != nil {
 		configurator.logger.Error(err, "Failed to create a valid CB API Gateway, cannot continue")
@@ -117,9 +129,15 @@ func (configurator *Configurator) RunIteration(ctx context.Context) error {
 	errApplyingCR := configurator.applyChangeToCR(ctx, apiGateway, *change, cr)
 	if errApplyingCR != nil {
 		configurator.logger.Error(errApplyingCR, "Failed to apply configuration changes to CBContainerAGent resource")
+		if errors.As(errApplyingCR, &invalidChangeError{}) {
+			configurator.eventRecorder.Eventf(cr, coreV1.EventTypeWarning, events.ReasonCompatibilityCheckFailed, "Remote configuration change %v is not compatible with the operator: %v", change.ID, errApplyingCR)
+		} else {
+			configurator.eventRecorder.Eventf(cr, coreV1.EventTypeWarning, events.ReasonRemoteConfigurationFailed, "Failed to apply remote configuration change %v: %v", change.ID, errApplyingCR)
+		}
 		// Intentional fallthrough as we want to report the change application as failed to the backend
 	} else {
 		configurator.logger.Info("Successfully applied configuration changes to CBContainerAgent resource")
+		configurator.eventRecorder.Eventf(cr, coreV1.EventTypeNormal, events.ReasonRemoteConfigurationApplied, "Applied remote configuration change %v", change.ID)
 	}
 
 	if err := configurator.updateChangeStatus(ctx, apiGateway, *change, cr, errApplyingCR); err != nil {
@@ -217,6 +235,7 @@ func (configurator *Configurator) updateChangeStatus(
 func (configurator *Configurator) createAPIGateway(ctx context.Context, cr *cbcontainersv1.CBContainersAgent) (ApiGateway, error) {
 	accessToken, err := configurator.accessTokenProvider.GetCBAccessToken(ctx, cr, configurator.deployedNamespace)
 	if err != nil {
+		configurator.eventRecorder.Event(cr, coreV1.EventTypeWarning, events.ReasonAccessTokenInvalid, err.Error())
 		return nil, err
 	}
 	return configurator.apiCreator(cr, accessToken)
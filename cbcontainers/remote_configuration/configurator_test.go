@@ -8,11 +8,13 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/events"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/remote_configuration"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/remote_configuration/mocks"
 	k8sMocks "github.com/vmware/cbcontainers-operator/cbcontainers/test_utils/mocks"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"testing"
 	"time"
 )
@@ -22,6 +24,7 @@ type configuratorMocks struct {
 	apiGateway          *mocks.MockApiGateway
 	accessTokenProvider *mocks.MockAccessTokenProvider
 	validator           *mocks.MockChangeValidator
+	eventRecorder       *record.FakeRecorder
 
 	stubAccessToken string
 	stubNamespace   string
@@ -34,6 +37,7 @@ func setupConfigurator(ctrl *gomock.Controller) (*remote_configuration.Configura
 	apiGateway := mocks.NewMockApiGateway(ctrl)
 	accessTokenProvider := mocks.NewMockAccessTokenProvider(ctrl)
 	validator := mocks.NewMockChangeValidator(ctrl)
+	eventRecorder := record.NewFakeRecorder(10)
 
 	var mockAPIProvider remote_configuration.ApiCreator = func(
 		cbContainersCluster *cbcontainersv1.CBContainersAgent,
@@ -56,6 +60,7 @@ func setupConfigurator(ctrl *gomock.Controller) (*remote_configuration.Configura
 		logr.Discard(),
 		accessTokenProvider,
 		mockValidatorProvider,
+		eventRecorder,
 		namespace,
 		clusterID,
 	)
@@ -65,6 +70,7 @@ func setupConfigurator(ctrl *gomock.Controller) (*remote_configuration.Configura
 		apiGateway:          apiGateway,
 		accessTokenProvider: accessTokenProvider,
 		validator:           validator,
+		eventRecorder:       eventRecorder,
 		stubAccessToken:     accessToken,
 		stubNamespace:       namespace,
 		stubClusterID:       clusterID,
@@ -112,6 +118,7 @@ func TestConfigChangeIsAppliedAndAcknowledgedCorrectly(t *testing.T) {
 
 	err := configurator.RunIteration(context.Background())
 	assert.NoError(t, err)
+	assert.Contains(t, <-mocks.eventRecorder.Events, events.ReasonRemoteConfigurationApplied)
 }
 
 func TestWhenSensorMetadataIsAvailableItIsUsed(t *testing.T) {
@@ -186,6 +193,7 @@ func TestWhenChangeIsNotApplicableShouldReturnError(t *testing.T) {
 
 	err := configurator.RunIteration(context.Background())
 	assert.Error(t, err)
+	assert.Contains(t, <-mocks.eventRecorder.Events, events.ReasonCompatibilityCheckFailed)
 }
 
 func TestWhenThereAreNoPendingChangesNothingHappens(t *testing.T) {
@@ -323,6 +331,7 @@ func TestWhenUpdatingCRFailsChangeIsUpdatedAsFailed(t *testing.T) {
 	returnedErr := configurator.RunIteration(context.Background())
 	assert.Error(t, returnedErr)
 	assert.ErrorIs(t, returnedErr, errFromService, "expected returned error to match or wrap error from service")
+	assert.Contains(t, <-mocks.eventRecorder.Events, events.ReasonRemoteConfigurationFailed)
 }
 
 func TestWhenUpdatingStatusToBackendFailsShouldReturnError(t *testing.T) {
@@ -385,6 +394,19 @@ func TestWhenFeatureIsDisabledInCRNothingHappens(t *testing.T) {
 	assert.NoError(t, configurator.RunIteration(context.Background()))
 }
 
+func TestWhenAgentIsPausedNothingHappens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	configurator, mocks := setupConfigurator(ctrl)
+
+	paused := true
+	setupCRInK8S(mocks.k8sClient, &cbcontainersv1.CBContainersAgent{Spec: cbcontainersv1.CBContainersAgentSpec{Paused: &paused}})
+
+	// No other mock calls should happen while the agent is paused
+	assert.NoError(t, configurator.RunIteration(context.Background()))
+}
+
 // setupCRInK8S ensures the mock client will return 1 agent item for List calls - either the provided one or an empty CR otherwise
 func setupCRInK8S(mock *k8sMocks.MockClient, item *cbcontainersv1.CBContainersAgent) *cbcontainersv1.CBContainersAgent {
 	if item == nil {
@@ -88,6 +88,10 @@ func (w *mutatingWebhookV1) SetNamespaceSelector(selector *metav1.LabelSelector)
 	w.NamespaceSelector = selector
 }
 
+func (w *mutatingWebhookV1) SetObjectSelector(selector *metav1.LabelSelector) {
+	w.ObjectSelector = selector
+}
+
 func (w *mutatingWebhookV1) SetTimeoutSeconds(timeoutSeconds int32) {
 	w.TimeoutSeconds = &timeoutSeconds
 }
@@ -130,6 +134,22 @@ func (w *mutatingWebhookV1) SetAdmissionRules(rules []AdmissionRuleAdapter) {
 	w.Rules = newRules
 }
 
+func (w *mutatingWebhookV1) SetMatchConditions(conditions []MatchConditionAdapter) {
+	if len(conditions) == 0 {
+		w.MatchConditions = nil
+		return
+	}
+
+	newConditions := make([]admissionsV1.MatchCondition, 0, len(conditions))
+	for _, c := range conditions {
+		newConditions = append(newConditions, admissionsV1.MatchCondition{
+			Name:       c.Name,
+			Expression: c.Expression,
+		})
+	}
+	w.MatchConditions = newConditions
+}
+
 func (w *mutatingWebhookV1) InitializeServiceReference() {
 	if w.ClientConfig.Service == nil {
 		w.ClientConfig.Service = &admissionsV1.ServiceReference{}
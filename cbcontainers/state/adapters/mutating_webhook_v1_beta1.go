@@ -71,6 +71,10 @@ func (w *mutatingWebhookV1Beta1) SetNamespaceSelector(selector *metav1.LabelSele
 	w.NamespaceSelector = selector
 }
 
+func (w *mutatingWebhookV1Beta1) SetObjectSelector(selector *metav1.LabelSelector) {
+	w.ObjectSelector = selector
+}
+
 func (w *mutatingWebhookV1Beta1) SetTimeoutSeconds(timeoutSeconds int32) {
 	w.TimeoutSeconds = &timeoutSeconds
 }
@@ -131,6 +135,22 @@ func (w *mutatingWebhookV1Beta1) SetAdmissionRules(rules []AdmissionRuleAdapter)
 	}
 	w.Rules = newRules
 }
+func (w *mutatingWebhookV1Beta1) SetMatchConditions(conditions []MatchConditionAdapter) {
+	if len(conditions) == 0 {
+		w.MatchConditions = nil
+		return
+	}
+
+	newConditions := make([]admissionsV1Beta1.MatchCondition, 0, len(conditions))
+	for _, c := range conditions {
+		newConditions = append(newConditions, admissionsV1Beta1.MatchCondition{
+			Name:       c.Name,
+			Expression: c.Expression,
+		})
+	}
+	w.MatchConditions = newConditions
+}
+
 func (w *mutatingWebhookV1Beta1) InitializeServiceReference() {
 	if w.ClientConfig.Service == nil {
 		w.ClientConfig.Service = &admissionsV1Beta1.ServiceReference{}
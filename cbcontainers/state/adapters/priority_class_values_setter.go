@@ -1,16 +1,18 @@
 package adapters
 
 import (
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/capabilities"
 	schedulingV1 "k8s.io/api/scheduling/v1"
 	schedulingV1alpha1 "k8s.io/api/scheduling/v1alpha1"
 	schedulingV1beta1 "k8s.io/api/scheduling/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func EmptyPriorityClassForVersion(k8sVersion string) client.Object {
-	if k8sVersion == "" || k8sVersion >= "v1.14" {
+// EmptyPriorityClassForCapabilities returns an empty PriorityClass instance of the newest version that the API server serves
+func EmptyPriorityClassForCapabilities(c *capabilities.Capabilities) client.Object {
+	if c.HasGroupVersion(capabilities.SchedulingV1) {
 		return &schedulingV1.PriorityClass{}
-	} else if k8sVersion >= "v1.11" {
+	} else if c.HasGroupVersion(capabilities.SchedulingV1Beta1) {
 		return &schedulingV1beta1.PriorityClass{}
 	}
 
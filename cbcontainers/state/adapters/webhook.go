@@ -1,6 +1,7 @@
 package adapters
 
 import (
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/capabilities"
 	admissionsV1 "k8s.io/api/admissionregistration/v1"
 	admissionsV1Beta1 "k8s.io/api/admissionregistration/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -34,6 +35,7 @@ type WebhookAdapter interface {
 	SetMatchPolicy(policy string)
 	GetNamespaceSelector() *metav1.LabelSelector
 	SetNamespaceSelector(selector *metav1.LabelSelector)
+	SetObjectSelector(selector *metav1.LabelSelector)
 	SetTimeoutSeconds(timeoutSeconds int32)
 	SetCABundle(bundle []byte)
 	SetServiceNamespace(namespace string)
@@ -41,6 +43,7 @@ type WebhookAdapter interface {
 	SetServicePath(path *string)
 	GetAdmissionRules() []AdmissionRuleAdapter
 	SetAdmissionRules([]AdmissionRuleAdapter)
+	SetMatchConditions([]MatchConditionAdapter)
 }
 
 // These are the same between v1 and v1beta1 - if they diverge; the adapters should handle internal conversion.
@@ -61,18 +64,18 @@ const (
 	SideEffectClassNoneOnDryRun = string(admissionsV1.SideEffectClassNoneOnDryRun)
 )
 
-// EmptyValidatingWebhookConfigForVersion returns an empty ValidatingWebhookConfiguration instance that is suitable for the provided k8s version
-func EmptyValidatingWebhookConfigForVersion(k8sVersion string) client.Object {
-	if k8sVersion < "v1.16" {
+// EmptyValidatingWebhookConfigForCapabilities returns an empty ValidatingWebhookConfiguration instance that is suitable for the provided API server capabilities
+func EmptyValidatingWebhookConfigForCapabilities(c *capabilities.Capabilities) client.Object {
+	if !c.HasGroupVersion(capabilities.AdmissionRegistrationV1) {
 		return &admissionsV1Beta1.ValidatingWebhookConfiguration{}
 	}
 
 	return &admissionsV1.ValidatingWebhookConfiguration{}
 }
 
-// EmptyValidatingWebhookAdapterForVersion creates an empty ValidatingWebhook instance for the given k8s version and returns an adapter that wraps it
-func EmptyValidatingWebhookAdapterForVersion(k8sVersion string) WebhookAdapter {
-	if k8sVersion < "v1.16" {
+// EmptyValidatingWebhookAdapterForCapabilities creates an empty ValidatingWebhook instance for the given API server capabilities and returns an adapter that wraps it
+func EmptyValidatingWebhookAdapterForCapabilities(c *capabilities.Capabilities) WebhookAdapter {
+	if !c.HasGroupVersion(capabilities.AdmissionRegistrationV1) {
 		return (*validatingWebhookV1Beta1)(&admissionsV1Beta1.ValidatingWebhook{})
 	} else {
 		return (*validatingWebhookV1)(&admissionsV1.ValidatingWebhook{})
@@ -90,18 +93,18 @@ func TryGetValidatingWebhookConfigurationAdapter(k8sObject client.Object) (Webho
 	return nil, false
 }
 
-// EmptyMutatingWebhookConfigForVersion returns an empty MutatingWebhookConfiguration instance that is suitable for the provided k8s version
-func EmptyMutatingWebhookConfigForVersion(k8sVersion string) client.Object {
-	if k8sVersion < "v1.16" {
+// EmptyMutatingWebhookConfigForCapabilities returns an empty MutatingWebhookConfiguration instance that is suitable for the provided API server capabilities
+func EmptyMutatingWebhookConfigForCapabilities(c *capabilities.Capabilities) client.Object {
+	if !c.HasGroupVersion(capabilities.AdmissionRegistrationV1) {
 		return &admissionsV1Beta1.MutatingWebhookConfiguration{}
 	}
 
 	return &admissionsV1.MutatingWebhookConfiguration{}
 }
 
-// EmptyMutatingWebhookAdapterForVersion creates an empty MutatingWebhook instance for the given k8s version and returns an adapter that wraps it
-func EmptyMutatingWebhookAdapterForVersion(k8sVersion string) WebhookAdapter {
-	if k8sVersion < "v1.16" {
+// EmptyMutatingWebhookAdapterForCapabilities creates an empty MutatingWebhook instance for the given API server capabilities and returns an adapter that wraps it
+func EmptyMutatingWebhookAdapterForCapabilities(c *capabilities.Capabilities) WebhookAdapter {
+	if !c.HasGroupVersion(capabilities.AdmissionRegistrationV1) {
 		return (*mutatingWebhookV1Beta1)(&admissionsV1Beta1.MutatingWebhook{})
 	} else {
 		return (*mutatingWebhookV1)(&admissionsV1.MutatingWebhook{})
@@ -127,3 +130,9 @@ type AdmissionRuleAdapter struct {
 	Resources   []string
 	Scope       *string
 }
+
+// MatchConditionAdapter is a simple struct that mimics the admission.MatchCondition struct
+type MatchConditionAdapter struct {
+	Name       string
+	Expression string
+}
@@ -10,7 +10,7 @@ import (
 
 type componentApplier interface {
 	Apply(ctx context.Context, desiredK8sObject applyment.DesiredK8sObject, applyOptionsList ...*applymentOptions.ApplyOptions) (bool, client.Object, error)
-	Delete(ctx context.Context, desiredK8sObject applyment.DesiredK8sObject) (bool, error)
+	Delete(ctx context.Context, desiredK8sObject applyment.DesiredK8sObject, deleteOptions ...client.DeleteOption) (bool, error)
 }
 
 type AgentComponentApplier struct {
@@ -28,7 +28,7 @@ func (agentComponentApplier *AgentComponentApplier) Apply(ctx context.Context, b
 	return agentComponentApplier.applier.Apply(ctx, wrapper, applyOptionsList...)
 }
 
-func (agentComponentApplier *AgentComponentApplier) Delete(ctx context.Context, builder AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec) (bool, error) {
+func (agentComponentApplier *AgentComponentApplier) Delete(ctx context.Context, builder AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec, deleteOptions ...client.DeleteOption) (bool, error) {
 	wrapper := NewDesiredAgentComponentWrapper(builder, agentSpec)
-	return agentComponentApplier.applier.Delete(ctx, wrapper)
+	return agentComponentApplier.applier.Delete(ctx, wrapper, deleteOptions...)
 }
@@ -6,21 +6,46 @@ import (
 	"fmt"
 	applymentOptions "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	DefaultFieldManager = "cbcontainers-operator"
+
+	// LegacyFieldManager is the field manager under which the operator updated the k8s objects before it used
+	// server-side apply. The API server defaults the field manager of an update to the command name in the user agent
+	// of the k8s client, and the operator binary is built as `manager` (see the Dockerfile and the Makefile).
+	// Only this field manager is migrated and not every manager whose operation is Update, as those are other clients,
+	// e.g. kubectl or the HPA controller, and the operator would take over, and then remove, the fields they set.
+	LegacyFieldManager = "manager"
+)
+
 type ComponentApplier struct {
 	client client.Client
+
+	// fieldManager is the field manager under which the k8s objects are applied using server-side apply.
+	// When empty, the k8s objects are mutated in-place and updated as a whole.
+	fieldManager string
 }
 
 func NewComponentApplier(client client.Client) *ComponentApplier {
 	return &ComponentApplier{client: client}
 }
 
-func (applier *ComponentApplier) Delete(ctx context.Context, desiredK8sObject DesiredK8sObject) (bool, error) {
-	k8sObject, objectExists, err := applier.getK8sObject(ctx, desiredK8sObject, desiredK8sObject.NamespacedName())
+// NewServerSideComponentApplier creates an applier that uses server-side apply, so the operator owns only the fields it sets
+// and leaves the fields set by other controllers (e.g. labels and annotations added by service meshes) untouched.
+func NewServerSideComponentApplier(client client.Client, fieldManager string) *ComponentApplier {
+	return &ComponentApplier{client: client, fieldManager: fieldManager}
+}
+
+func (applier *ComponentApplier) Delete(ctx context.Context, desiredK8sObject DesiredK8sObject, deleteOptions ...client.DeleteOption) (bool, error) {
+	k8sObject, objectExists, err := getK8sObject(ctx, applier.client, desiredK8sObject, desiredK8sObject.NamespacedName())
 	if err != nil {
 		return false, err
 	}
@@ -29,14 +54,14 @@ func (applier *ComponentApplier) Delete(ctx context.Context, desiredK8sObject De
 		return false, nil
 	}
 
-	return true, applier.client.Delete(ctx, k8sObject)
+	return true, applier.client.Delete(ctx, k8sObject, deleteOptions...)
 }
 
 func (applier *ComponentApplier) Apply(ctx context.Context, desiredK8sObject DesiredK8sObject, applyOptionsList ...*applymentOptions.ApplyOptions) (bool, client.Object, error) {
 	applyOptions := applymentOptions.MergeApplyOptions(applyOptionsList...)
 	namespacedName := desiredK8sObject.NamespacedName()
 
-	k8sObject, objectExists, err := applier.getK8sObject(ctx, desiredK8sObject, namespacedName)
+	k8sObject, objectExists, err := getK8sObject(ctx, applier.client, desiredK8sObject, namespacedName)
 	if err != nil {
 		return false, nil, err
 	}
@@ -45,6 +70,10 @@ func (applier *ComponentApplier) Apply(ctx context.Context, desiredK8sObject Des
 		return false, k8sObject, nil
 	}
 
+	if applier.fieldManager != "" {
+		return applier.serverSideApplyK8sObject(ctx, desiredK8sObject, k8sObject, objectExists, namespacedName, applyOptions)
+	}
+
 	beforeMutationRaw, _ := json.Marshal(k8sObject)
 	if err := desiredK8sObject.MutateK8sObject(k8sObject); err != nil {
 		return false, nil, fmt.Errorf("failed mutating K8s object `%v`: %v", namespacedName, err)
@@ -55,6 +84,7 @@ func (applier *ComponentApplier) Apply(ctx context.Context, desiredK8sObject Des
 			return false, nil, err
 		}
 
+		recordChange(applyOptions, k8sObject, true)
 		return true, k8sObject, nil
 	}
 
@@ -66,12 +96,12 @@ func (applier *ComponentApplier) Apply(ctx context.Context, desiredK8sObject Des
 	return k8sObjectWasChanged, k8sObject, nil
 }
 
-func (applier *ComponentApplier) getK8sObject(ctx context.Context, desiredK8sObject DesiredK8sObject, namespacedName types.NamespacedName) (client.Object, bool, error) {
+func getK8sObject(ctx context.Context, reader client.Reader, desiredK8sObject DesiredK8sObjectInitializer, namespacedName types.NamespacedName) (client.Object, bool, error) {
 	k8sObject := desiredK8sObject.EmptyK8sObject()
 
-	err := applier.client.Get(ctx, namespacedName, k8sObject)
+	err := reader.Get(ctx, namespacedName, k8sObject)
 	if err != nil && !errors.IsNotFound(err) {
-		return nil, false, fmt.Errorf("failed getting K8s object: %v", err)
+		return nil, false, fmt.Errorf("failed getting K8s object: %w", err)
 	}
 
 	objectsExists := err == nil || !errors.IsNotFound(err)
@@ -109,9 +139,87 @@ func (applier *ComponentApplier) updateK8sObject(ctx context.Context, applyOptio
 		return false, fmt.Errorf("failed updating exsiting K8s object `%v`: %v", namespacedName, updateErr)
 	}
 
+	recordChange(applyOptions, k8sObject, false)
 	return true, nil
 }
 
+// serverSideApplyK8sObject builds the desired k8s object from scratch and applies only the fields set by the operator, so
+// the fields it stops setting are removed. The fields the operator owned while it updated the existing k8s object as a
+// whole are migrated to its apply field manager first, otherwise they would be kept forever.
+// The k8s object was changed only if it didn't exist before or its resource version was bumped by the apply.
+func (applier *ComponentApplier) serverSideApplyK8sObject(ctx context.Context, desiredK8sObject DesiredK8sObject, existingK8sObject client.Object, objectExists bool, namespacedName types.NamespacedName, applyOptions *applymentOptions.ApplyOptions) (bool, client.Object, error) {
+	k8sObject, applyConfiguration, err := buildApplyConfiguration(applier.client, desiredK8sObject, namespacedName, applyOptions)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if objectExists {
+		if err := applier.migrateLegacyManagedFields(ctx, existingK8sObject, namespacedName); err != nil {
+			return false, nil, err
+		}
+	}
+
+	if err := applier.client.Patch(ctx, applyConfiguration, client.Apply, client.FieldOwner(applier.fieldManager), client.ForceOwnership); err != nil {
+		return false, nil, fmt.Errorf("failed applying K8s object `%v`: %v", namespacedName, err)
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(applyConfiguration.Object, k8sObject); err != nil {
+		return false, nil, fmt.Errorf("failed converting the applied K8s object `%v`: %v", namespacedName, err)
+	}
+
+	if objectExists && k8sObject.GetResourceVersion() == existingK8sObject.GetResourceVersion() {
+		return false, k8sObject, nil
+	}
+
+	recordChange(applyOptions, k8sObject, !objectExists)
+	return true, k8sObject, nil
+}
+
+// buildApplyConfiguration builds the desired k8s object from scratch, and returns it along with its apply configuration.
+func buildApplyConfiguration(k8sClient client.Client, desiredK8sObject DesiredK8sObject, namespacedName types.NamespacedName, applyOptions *applymentOptions.ApplyOptions) (client.Object, *unstructured.Unstructured, error) {
+	k8sObject := desiredK8sObject.EmptyK8sObject()
+	if err := desiredK8sObject.MutateK8sObject(k8sObject); err != nil {
+		return nil, nil, fmt.Errorf("failed mutating K8s object `%v`: %v", namespacedName, err)
+	}
+
+	k8sObject.SetNamespace(namespacedName.Namespace)
+	k8sObject.SetName(namespacedName.Name)
+	if err := setOwner(applyOptions, k8sObject, namespacedName); err != nil {
+		return nil, nil, err
+	}
+
+	gvk, err := k8sClient.GroupVersionKindFor(k8sObject)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed getting the kind of K8s object `%v`: %v", namespacedName, err)
+	}
+	k8sObject.GetObjectKind().SetGroupVersionKind(gvk)
+
+	applyConfiguration, err := toApplyConfiguration(k8sObject)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed building the apply configuration of K8s object `%v`: %v", namespacedName, err)
+	}
+
+	return k8sObject, applyConfiguration, nil
+}
+
+// migrateLegacyManagedFields moves the fields that the legacy field manager owns to the apply field manager, and
+// updates the existing k8s object with the migrated managed fields and resource version.
+func (applier *ComponentApplier) migrateLegacyManagedFields(ctx context.Context, existingK8sObject client.Object, namespacedName types.NamespacedName) error {
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(existingK8sObject, sets.New(LegacyFieldManager), applier.fieldManager)
+	if err != nil {
+		return fmt.Errorf("failed migrating the managed fields of K8s object `%v`: %v", namespacedName, err)
+	}
+	if patch == nil {
+		return nil
+	}
+
+	if err := applier.client.Patch(ctx, existingK8sObject, client.RawPatch(types.JSONPatchType, patch)); err != nil {
+		return fmt.Errorf("failed migrating the managed fields of K8s object `%v`: %v", namespacedName, err)
+	}
+
+	return nil
+}
+
 func setOwner(applyOptions *applymentOptions.ApplyOptions, k8sObject client.Object, namespacedName types.NamespacedName) error {
 	setOwner := applyOptions.OwnerSetter()
 	if setOwner == nil {
@@ -124,3 +232,9 @@ func setOwner(applyOptions *applymentOptions.ApplyOptions, k8sObject client.Obje
 
 	return nil
 }
+
+func recordChange(applyOptions *applymentOptions.ApplyOptions, k8sObject client.Object, created bool) {
+	if recordChange := applyOptions.ChangeRecorder(); recordChange != nil {
+		recordChange(k8sObject, created)
+	}
+}
@@ -0,0 +1,97 @@
+package applyment
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+type desiredConfigMap struct {
+	namespacedName types.NamespacedName
+	data           map[string]string
+}
+
+func (desired *desiredConfigMap) EmptyK8sObject() client.Object { return &coreV1.ConfigMap{} }
+
+func (desired *desiredConfigMap) NamespacedName() types.NamespacedName { return desired.namespacedName }
+
+func (desired *desiredConfigMap) MutateK8sObject(k8sObject client.Object) error {
+	k8sObject.(*coreV1.ConfigMap).Data = desired.data
+	return nil
+}
+
+// startEnvtestClient starts a k8s API server using the assets that `make test` downloads, and skips the test when
+// they are missing.
+func startEnvtestClient(t *testing.T) client.Client {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set, run the test using `make test`")
+	}
+
+	testEnv := &envtest.Environment{}
+	config, err := testEnv.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, testEnv.Stop()) })
+
+	k8sClient, err := client.New(config, client.Options{Scheme: clientgoscheme.Scheme})
+	require.NoError(t, err)
+
+	return k8sClient
+}
+
+func TestServerSideApplyWithAPIServer(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := startEnvtestClient(t)
+	namespacedName := types.NamespacedName{Name: "config", Namespace: metav1.NamespaceDefault}
+	getConfigMap := func() *coreV1.ConfigMap {
+		configMap := &coreV1.ConfigMap{}
+		require.NoError(t, k8sClient.Get(ctx, namespacedName, configMap))
+		return configMap
+	}
+
+	legacyConfigMap := &coreV1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: namespacedName.Name, Namespace: namespacedName.Namespace},
+		Data:       map[string]string{"kept": "value", "removed": "value"},
+	}
+	require.NoError(t, k8sClient.Create(ctx, legacyConfigMap, client.FieldOwner(LegacyFieldManager)))
+
+	otherControllerPatch := []byte(`{"metadata":{"annotations":{"other-controller":"value"}}}`)
+	require.NoError(t, k8sClient.Patch(ctx, getConfigMap(), client.RawPatch(types.MergePatchType, otherControllerPatch), client.FieldOwner("other-controller")))
+
+	applier := NewServerSideComponentApplier(k8sClient, DefaultFieldManager)
+
+	t.Run("Should migrate the fields of the legacy field manager and remove the fields that are not desired anymore", func(t *testing.T) {
+		changed, _, err := applier.Apply(ctx, &desiredConfigMap{namespacedName: namespacedName, data: map[string]string{"kept": "value"}})
+
+		require.NoError(t, err)
+		require.True(t, changed)
+		configMap := getConfigMap()
+		require.Equal(t, map[string]string{"kept": "value"}, configMap.Data)
+		require.Equal(t, "value", configMap.Annotations["other-controller"])
+		for _, managedFields := range configMap.ManagedFields {
+			require.NotEqual(t, LegacyFieldManager, managedFields.Manager)
+		}
+	})
+
+	t.Run("When applying the same desired fields again, should report no change", func(t *testing.T) {
+		changed, _, err := applier.Apply(ctx, &desiredConfigMap{namespacedName: namespacedName, data: map[string]string{"kept": "value"}})
+
+		require.NoError(t, err)
+		require.False(t, changed)
+	})
+
+	t.Run("Should remove the fields that the operator stops setting", func(t *testing.T) {
+		changed, _, err := applier.Apply(ctx, &desiredConfigMap{namespacedName: namespacedName, data: map[string]string{"added": "value"}})
+
+		require.NoError(t, err)
+		require.True(t, changed)
+		require.Equal(t, map[string]string{"added": "value"}, getConfigMap().Data)
+	})
+}
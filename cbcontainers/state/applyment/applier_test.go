@@ -9,8 +9,10 @@ import (
 	applymentOptions "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/test_utils"
 	testUtilsMocks "github.com/vmware/cbcontainers-operator/cbcontainers/test_utils/mocks"
+	coreV1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -121,6 +123,43 @@ func TestObjectIsUpdatedWhenExistingAndChanged(t *testing.T) {
 	require.True(t, changed)
 }
 
+func TestChangeIsRecordedWhenObjectIsCreatedOrUpdated(t *testing.T) {
+	type recordedChange struct {
+		object  metav1.Object
+		created bool
+	}
+
+	var changes []recordedChange
+	changeRecorderOption := applymentOptions.NewApplyOptions().SetChangeRecorder(func(changedResource metav1.Object, created bool) {
+		changes = append(changes, recordedChange{object: changedResource, created: created})
+	})
+
+	_, _, err := testApplyDesiredK8sObject(t, func(mocks *ApplierTestMocks) {
+		notFoundError := errors.NewNotFound(schema.GroupResource{}, "")
+		mocks.client.EXPECT().Get(gomock.Any(), NamespacedName, K8sObject).Return(notFoundError)
+		mocks.desiredK8sObject.EXPECT().MutateK8sObject(K8sObject).Return(nil)
+		mocks.client.EXPECT().Create(gomock.Any(), K8sObject).Return(nil)
+	}, changeRecorderOption)
+	require.NoError(t, err)
+
+	_, _, err = testApplyDesiredK8sObject(t, func(mocks *ApplierTestMocks) {
+		mocks.client.EXPECT().Get(gomock.Any(), NamespacedName, K8sObject).Return(nil)
+		mocks.desiredK8sObject.EXPECT().MutateK8sObject(K8sObject).Do(func(object *FakeTypeK8sObject) {
+			object.Foo += object.Foo
+		}).Return(nil)
+		mocks.client.EXPECT().Update(gomock.Any(), K8sObject).Return(nil)
+	}, changeRecorderOption)
+	require.NoError(t, err)
+
+	_, _, err = testApplyDesiredK8sObject(t, func(mocks *ApplierTestMocks) {
+		mocks.client.EXPECT().Get(gomock.Any(), NamespacedName, K8sObject).Return(nil)
+		mocks.desiredK8sObject.EXPECT().MutateK8sObject(K8sObject).Return(nil)
+	}, changeRecorderOption)
+	require.NoError(t, err)
+
+	require.Equal(t, []recordedChange{{object: K8sObject, created: true}, {object: K8sObject, created: false}}, changes)
+}
+
 func TestObjectIsNotUpdatedWhenExistingButNotChanged(t *testing.T) {
 	changed, _, err := testApplyDesiredK8sObject(t, func(mocks *ApplierTestMocks) {
 		mocks.client.EXPECT().Get(gomock.Any(), NamespacedName, K8sObject).Return(nil)
@@ -188,3 +227,189 @@ func TestObjectIsNotDeletedWhenNotExisting(t *testing.T) {
 	require.NoError(t, err)
 	require.False(t, deleted)
 }
+
+type ServerSideApplierTestSetup func(mocks *ApplierTestMocks, existingConfigMap *coreV1.ConfigMap)
+
+var configMapGVK = coreV1.SchemeGroupVersion.WithKind("ConfigMap")
+
+func testServerSideApplyDesiredK8sObject(t *testing.T, setup ServerSideApplierTestSetup, applyOptionsList ...*applymentOptions.ApplyOptions) (bool, client.Object, error) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockObjects := &ApplierTestMocks{
+		client:           testUtilsMocks.NewMockClient(ctrl),
+		desiredK8sObject: mocks.NewMockDesiredK8sObject(ctrl),
+	}
+
+	existingConfigMap := &coreV1.ConfigMap{}
+	mockObjects.desiredK8sObject.EXPECT().NamespacedName().Return(NamespacedName)
+	mockObjects.desiredK8sObject.EXPECT().EmptyK8sObject().Return(existingConfigMap)
+	mockObjects.desiredK8sObject.EXPECT().EmptyK8sObject().Return(&coreV1.ConfigMap{}).MaxTimes(1)
+	setup(mockObjects, existingConfigMap)
+
+	return NewServerSideComponentApplier(mockObjects.client, DefaultFieldManager).Apply(context.Background(), mockObjects.desiredK8sObject, applyOptionsList...)
+}
+
+func expectServerSideApply(mocks *ApplierTestMocks, resourceVersionAfterApply string) {
+	mocks.desiredK8sObject.EXPECT().MutateK8sObject(gomock.Any()).Do(func(configMap *coreV1.ConfigMap) {
+		configMap.Data = map[string]string{"foo": "bar"}
+	}).Return(nil)
+	mocks.client.EXPECT().GroupVersionKindFor(gomock.Any()).Return(configMapGVK, nil)
+	mocks.client.EXPECT().Patch(gomock.Any(), gomock.Any(), client.Apply, client.FieldOwner(DefaultFieldManager), client.ForceOwnership).
+		DoAndReturn(func(_ context.Context, applyConfiguration *unstructured.Unstructured, _ client.Patch, _ ...client.PatchOption) error {
+			applyConfiguration.SetResourceVersion(resourceVersionAfterApply)
+			return nil
+		})
+}
+
+func TestServerSideApply(t *testing.T) {
+	t.Run("When not existing, should apply only the desired fields and report a creation", func(t *testing.T) {
+		var created []bool
+		var controlledResourceToSetOwner metav1.Object
+		changed, appliedObject, err := testServerSideApplyDesiredK8sObject(t, func(mocks *ApplierTestMocks, _ *coreV1.ConfigMap) {
+			mocks.client.EXPECT().Get(gomock.Any(), NamespacedName, gomock.Any()).Return(errors.NewNotFound(schema.GroupResource{}, ""))
+			expectServerSideApply(mocks, "1")
+		}, applymentOptions.NewApplyOptions().SetOwnerSetter(func(controlledResource metav1.Object) error {
+			controlledResourceToSetOwner = controlledResource
+			return nil
+		}).SetChangeRecorder(func(_ metav1.Object, wasCreated bool) {
+			created = append(created, wasCreated)
+		}))
+
+		require.NoError(t, err)
+		require.True(t, changed)
+		require.Equal(t, []bool{true}, created)
+		require.Equal(t, controlledResourceToSetOwner, appliedObject)
+		require.Equal(t, NamespacedName.Namespace, appliedObject.GetNamespace())
+		require.Equal(t, NamespacedName.Name, appliedObject.GetName())
+		require.Equal(t, configMapGVK, appliedObject.GetObjectKind().GroupVersionKind())
+	})
+
+	t.Run("Should apply only the fields set by the operator", func(t *testing.T) {
+		var appliedFields map[string]interface{}
+		_, _, err := testServerSideApplyDesiredK8sObject(t, func(mocks *ApplierTestMocks, _ *coreV1.ConfigMap) {
+			mocks.client.EXPECT().Get(gomock.Any(), NamespacedName, gomock.Any()).Return(errors.NewNotFound(schema.GroupResource{}, ""))
+			mocks.desiredK8sObject.EXPECT().MutateK8sObject(gomock.Any()).Do(func(configMap *coreV1.ConfigMap) {
+				configMap.Data = map[string]string{"foo": "bar"}
+			}).Return(nil)
+			mocks.client.EXPECT().GroupVersionKindFor(gomock.Any()).Return(configMapGVK, nil)
+			mocks.client.EXPECT().Patch(gomock.Any(), gomock.Any(), client.Apply, gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, applyConfiguration *unstructured.Unstructured, _ client.Patch, _ ...client.PatchOption) error {
+					appliedFields = applyConfiguration.DeepCopy().Object
+					return nil
+				})
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": NamespacedName.Name, "namespace": NamespacedName.Namespace},
+			"data":       map[string]interface{}{"foo": "bar"},
+		}, appliedFields)
+	})
+
+	t.Run("When existing with fields of the legacy field manager, should migrate them before applying", func(t *testing.T) {
+		changed, _, err := testServerSideApplyDesiredK8sObject(t, func(mocks *ApplierTestMocks, existingConfigMap *coreV1.ConfigMap) {
+			mocks.client.EXPECT().Get(gomock.Any(), NamespacedName, existingConfigMap).Do(func(_ context.Context, _ types.NamespacedName, configMap *coreV1.ConfigMap, _ ...client.GetOption) {
+				configMap.ResourceVersion = "1"
+				configMap.ManagedFields = []metav1.ManagedFieldsEntry{{
+					Manager:    LegacyFieldManager,
+					Operation:  metav1.ManagedFieldsOperationUpdate,
+					APIVersion: "v1",
+					FieldsType: "FieldsV1",
+					FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:foo":{}}}`)},
+				}}
+			}).Return(nil)
+			migration := mocks.client.EXPECT().Patch(gomock.Any(), existingConfigMap, gomock.Any()).
+				DoAndReturn(func(_ context.Context, configMap *coreV1.ConfigMap, patch client.Patch, _ ...client.PatchOption) error {
+					require.Equal(t, types.JSONPatchType, patch.Type())
+					data, err := patch.Data(configMap)
+					require.NoError(t, err)
+					require.Contains(t, string(data), `"manager":"`+DefaultFieldManager+`","operation":"Apply"`)
+					require.NotContains(t, string(data), `"manager":"`+LegacyFieldManager+`"`)
+					configMap.ResourceVersion = "2"
+					return nil
+				})
+			mocks.desiredK8sObject.EXPECT().MutateK8sObject(gomock.Any()).Return(nil)
+			mocks.client.EXPECT().GroupVersionKindFor(gomock.Any()).Return(configMapGVK, nil)
+			mocks.client.EXPECT().Patch(gomock.Any(), gomock.Any(), client.Apply, gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, applyConfiguration *unstructured.Unstructured, _ client.Patch, _ ...client.PatchOption) error {
+					applyConfiguration.SetResourceVersion("2")
+					return nil
+				}).After(migration)
+		})
+
+		require.NoError(t, err)
+		require.False(t, changed)
+	})
+
+	t.Run("When migrating the fields of the legacy field manager fails, should return error", func(t *testing.T) {
+		_, _, err := testServerSideApplyDesiredK8sObject(t, func(mocks *ApplierTestMocks, existingConfigMap *coreV1.ConfigMap) {
+			mocks.client.EXPECT().Get(gomock.Any(), NamespacedName, existingConfigMap).Do(func(_ context.Context, _ types.NamespacedName, configMap *coreV1.ConfigMap, _ ...client.GetOption) {
+				configMap.ManagedFields = []metav1.ManagedFieldsEntry{{
+					Manager:    LegacyFieldManager,
+					Operation:  metav1.ManagedFieldsOperationUpdate,
+					APIVersion: "v1",
+					FieldsType: "FieldsV1",
+					FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:foo":{}}}`)},
+				}}
+			}).Return(nil)
+			mocks.desiredK8sObject.EXPECT().MutateK8sObject(gomock.Any()).Return(nil)
+			mocks.client.EXPECT().GroupVersionKindFor(gomock.Any()).Return(configMapGVK, nil)
+			mocks.client.EXPECT().Patch(gomock.Any(), existingConfigMap, gomock.Any()).Return(fmt.Errorf(""))
+		})
+
+		require.Error(t, err)
+	})
+
+	t.Run("When existing and the resource version was not bumped, should report no change", func(t *testing.T) {
+		changed, _, err := testServerSideApplyDesiredK8sObject(t, func(mocks *ApplierTestMocks, existingConfigMap *coreV1.ConfigMap) {
+			mocks.client.EXPECT().Get(gomock.Any(), NamespacedName, existingConfigMap).Do(func(_ context.Context, _ types.NamespacedName, configMap *coreV1.ConfigMap, _ ...client.GetOption) {
+				configMap.ResourceVersion = "1"
+			}).Return(nil)
+			expectServerSideApply(mocks, "1")
+		}, applymentOptions.NewApplyOptions().SetChangeRecorder(func(_ metav1.Object, _ bool) {
+			require.Fail(t, "no change should be recorded")
+		}))
+
+		require.NoError(t, err)
+		require.False(t, changed)
+	})
+
+	t.Run("When existing and the resource version was bumped, should report an update", func(t *testing.T) {
+		var created []bool
+		changed, _, err := testServerSideApplyDesiredK8sObject(t, func(mocks *ApplierTestMocks, existingConfigMap *coreV1.ConfigMap) {
+			mocks.client.EXPECT().Get(gomock.Any(), NamespacedName, existingConfigMap).Do(func(_ context.Context, _ types.NamespacedName, configMap *coreV1.ConfigMap, _ ...client.GetOption) {
+				configMap.ResourceVersion = "1"
+			}).Return(nil)
+			expectServerSideApply(mocks, "2")
+		}, applymentOptions.NewApplyOptions().SetChangeRecorder(func(_ metav1.Object, wasCreated bool) {
+			created = append(created, wasCreated)
+		}))
+
+		require.NoError(t, err)
+		require.True(t, changed)
+		require.Equal(t, []bool{false}, created)
+	})
+
+	t.Run("When existing and with creation only flag, should not apply", func(t *testing.T) {
+		changed, _, err := testServerSideApplyDesiredK8sObject(t, func(mocks *ApplierTestMocks, existingConfigMap *coreV1.ConfigMap) {
+			mocks.client.EXPECT().Get(gomock.Any(), NamespacedName, existingConfigMap).Return(nil)
+		}, applymentOptions.NewApplyOptions().SetCreateOnly(true))
+
+		require.NoError(t, err)
+		require.False(t, changed)
+	})
+
+	t.Run("When apply fails, should return error", func(t *testing.T) {
+		_, _, err := testServerSideApplyDesiredK8sObject(t, func(mocks *ApplierTestMocks, existingConfigMap *coreV1.ConfigMap) {
+			mocks.client.EXPECT().Get(gomock.Any(), NamespacedName, existingConfigMap).Return(nil)
+			mocks.desiredK8sObject.EXPECT().MutateK8sObject(gomock.Any()).Return(nil)
+			mocks.client.EXPECT().GroupVersionKindFor(gomock.Any()).Return(configMapGVK, nil)
+			mocks.client.EXPECT().Patch(gomock.Any(), gomock.Any(), client.Apply, gomock.Any(), gomock.Any()).Return(fmt.Errorf(""))
+		})
+
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,119 @@
+package applyment
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// toApplyConfiguration converts the typed k8s object to the unstructured apply configuration of the fields the
+// operator sets. The typed k8s objects are serialized with the zero values of their fields that are not omitted when
+// empty, e.g. `metadata.creationTimestamp: null`, `status: {}` or `resources: {}`, so these are pruned, otherwise the
+// operator would own them and reset the values other controllers set.
+// The status is never applied, as it's written by the controllers of the k8s objects. Some of its fields are not
+// omitted when empty, e.g. the scheduled pods counters of a DaemonSet, so it's removed as a whole.
+func toApplyConfiguration(k8sObject client.Object) (*unstructured.Unstructured, error) {
+	fields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(k8sObject)
+	if err != nil {
+		return nil, err
+	}
+	delete(fields, "status")
+
+	objectType := reflect.TypeOf(k8sObject)
+	if objectType.Kind() == reflect.Ptr {
+		objectType = objectType.Elem()
+	}
+	if objectType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unsupported k8s object type %v", objectType)
+	}
+	pruneZeroFields(fields, objectType)
+
+	return &unstructured.Unstructured{Object: fields}, nil
+}
+
+// pruneZeroFields removes from the unstructured fields of the provided struct type the null values, and the empty
+// objects of the non-pointer struct fields. Empty objects of pointer fields, e.g. `emptyDir: {}`, are meaningful and kept.
+func pruneZeroFields(fields map[string]interface{}, structType reflect.Type) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name, inline := jsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		fieldType := field.Type
+		isPointer := fieldType.Kind() == reflect.Ptr
+		if isPointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if inline {
+			if fieldType.Kind() == reflect.Struct {
+				pruneZeroFields(fields, fieldType)
+			}
+			continue
+		}
+
+		value, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if value == nil {
+			delete(fields, name)
+			continue
+		}
+
+		switch fieldType.Kind() {
+		case reflect.Struct:
+			nestedFields, ok := value.(map[string]interface{})
+			if !ok {
+				// The struct is serialized as a scalar, e.g. a quantity or an int-or-string.
+				continue
+			}
+			pruneZeroFields(nestedFields, fieldType)
+			if len(nestedFields) == 0 && !isPointer {
+				delete(fields, name)
+			}
+		case reflect.Slice:
+			pruneZeroItems(value, fieldType.Elem())
+		}
+	}
+}
+
+func pruneZeroItems(value interface{}, itemType reflect.Type) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return
+	}
+	if itemType.Kind() == reflect.Ptr {
+		itemType = itemType.Elem()
+	}
+	if itemType.Kind() != reflect.Struct {
+		return
+	}
+
+	for _, item := range items {
+		if itemFields, ok := item.(map[string]interface{}); ok {
+			pruneZeroFields(itemFields, itemType)
+		}
+	}
+}
+
+// jsonFieldName returns the name of the field in its json serialization, and whether its fields are inlined in the
+// fields of the struct that embeds it.
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	name, options, _ := strings.Cut(tag, ",")
+	if strings.Contains(","+options+",", ",inline,") || (name == "" && field.Anonymous) {
+		return "", true
+	}
+	if name == "" {
+		return field.Name, false
+	}
+
+	return name, false
+}
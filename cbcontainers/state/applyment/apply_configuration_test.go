@@ -0,0 +1,276 @@
+package applyment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	admissionsV1 "k8s.io/api/admissionregistration/v1"
+	admissionsV1Beta1 "k8s.io/api/admissionregistration/v1beta1"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestToApplyConfiguration(t *testing.T) {
+	sizeLimit := resource.MustParse("1Gi")
+	maxUnavailable := intstr.FromString("25%")
+	webhookPath := "/validate"
+	var webhookPort int32 = 443
+	var timeoutSeconds int32 = 5
+	failurePolicyV1 := admissionsV1.Ignore
+	sideEffectsV1 := admissionsV1.SideEffectClassNone
+	scopeV1 := admissionsV1.AllScopes
+	failurePolicyV1Beta1 := admissionsV1Beta1.Ignore
+	sideEffectsV1Beta1 := admissionsV1Beta1.SideEffectClassNone
+	reinvocationPolicyV1Beta1 := admissionsV1Beta1.IfNeededReinvocationPolicy
+
+	tests := map[string]struct {
+		k8sObject client.Object
+		expected  map[string]interface{}
+	}{
+		"Deployment, should keep the quantities, the int-or-strings and the empty objects of pointer fields": {
+			k8sObject: &appsV1.Deployment{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+				ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "namespace"},
+				Spec: appsV1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "name"}},
+					Template: coreV1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "name"}},
+						Spec: coreV1.PodSpec{
+							Containers: []coreV1.Container{{
+								Name:  "container",
+								Image: "image",
+								Resources: coreV1.ResourceRequirements{
+									Limits: coreV1.ResourceList{coreV1.ResourceMemory: resource.MustParse("64Mi")},
+								},
+								ReadinessProbe: &coreV1.Probe{ProbeHandler: coreV1.ProbeHandler{
+									HTTPGet: &coreV1.HTTPGetAction{Port: intstr.FromInt32(8080)},
+								}},
+							}, {
+								Name:  "sidecar",
+								Image: "image",
+							}},
+							Volumes: []coreV1.Volume{{
+								Name:         "volume",
+								VolumeSource: coreV1.VolumeSource{EmptyDir: &coreV1.EmptyDirVolumeSource{}},
+							}},
+						},
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "name", "namespace": "namespace"},
+				"spec": map[string]interface{}{
+					"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "name"}},
+					"template": map[string]interface{}{
+						"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "name"}},
+						"spec": map[string]interface{}{
+							"containers": []interface{}{
+								map[string]interface{}{
+									"name":      "container",
+									"image":     "image",
+									"resources": map[string]interface{}{"limits": map[string]interface{}{"memory": "64Mi"}},
+									"readinessProbe": map[string]interface{}{
+										"httpGet": map[string]interface{}{"port": int64(8080)},
+									},
+								},
+								map[string]interface{}{
+									"name":  "sidecar",
+									"image": "image",
+								},
+							},
+							"volumes": []interface{}{
+								map[string]interface{}{"name": "volume", "emptyDir": map[string]interface{}{}},
+							},
+						},
+					},
+				},
+			},
+		},
+		"DaemonSet, should prune the empty resources and the status": {
+			k8sObject: &appsV1.DaemonSet{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "DaemonSet"},
+				ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "namespace"},
+				Spec: appsV1.DaemonSetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "name"}},
+					UpdateStrategy: appsV1.DaemonSetUpdateStrategy{
+						Type:          appsV1.RollingUpdateDaemonSetStrategyType,
+						RollingUpdate: &appsV1.RollingUpdateDaemonSet{MaxUnavailable: &maxUnavailable},
+					},
+					Template: coreV1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "name"}},
+						Spec: coreV1.PodSpec{
+							Containers: []coreV1.Container{{
+								Name:  "container",
+								Image: "image",
+								Resources: coreV1.ResourceRequirements{
+									Requests: coreV1.ResourceList{coreV1.ResourceCPU: resource.MustParse("100m")},
+								},
+								Ports: []coreV1.ContainerPort{{Name: "https", ContainerPort: 8443, Protocol: coreV1.ProtocolTCP}},
+								LivenessProbe: &coreV1.Probe{ProbeHandler: coreV1.ProbeHandler{
+									TCPSocket: &coreV1.TCPSocketAction{Port: intstr.FromString("https")},
+								}},
+							}, {
+								Name:      "sidecar",
+								Image:     "image",
+								Resources: coreV1.ResourceRequirements{},
+							}},
+							Volumes: []coreV1.Volume{{
+								Name:         "volume",
+								VolumeSource: coreV1.VolumeSource{EmptyDir: &coreV1.EmptyDirVolumeSource{SizeLimit: &sizeLimit}},
+							}},
+						},
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "DaemonSet",
+				"metadata":   map[string]interface{}{"name": "name", "namespace": "namespace"},
+				"spec": map[string]interface{}{
+					"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "name"}},
+					"updateStrategy": map[string]interface{}{
+						"type":          "RollingUpdate",
+						"rollingUpdate": map[string]interface{}{"maxUnavailable": "25%"},
+					},
+					"template": map[string]interface{}{
+						"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "name"}},
+						"spec": map[string]interface{}{
+							"containers": []interface{}{
+								map[string]interface{}{
+									"name":      "container",
+									"image":     "image",
+									"resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": "100m"}},
+									"ports": []interface{}{
+										map[string]interface{}{"name": "https", "containerPort": int64(8443), "protocol": "TCP"},
+									},
+									"livenessProbe": map[string]interface{}{
+										"tcpSocket": map[string]interface{}{"port": "https"},
+									},
+								},
+								map[string]interface{}{
+									"name":  "sidecar",
+									"image": "image",
+								},
+							},
+							"volumes": []interface{}{
+								map[string]interface{}{"name": "volume", "emptyDir": map[string]interface{}{"sizeLimit": "1Gi"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		"ValidatingWebhookConfiguration v1, should keep the inlined rule fields and the empty selectors": {
+			k8sObject: &admissionsV1.ValidatingWebhookConfiguration{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "admissionregistration.k8s.io/v1", Kind: "ValidatingWebhookConfiguration"},
+				ObjectMeta: metav1.ObjectMeta{Name: "name"},
+				Webhooks: []admissionsV1.ValidatingWebhook{{
+					Name: "webhook.name",
+					ClientConfig: admissionsV1.WebhookClientConfig{
+						Service:  &admissionsV1.ServiceReference{Namespace: "namespace", Name: "name", Path: &webhookPath, Port: &webhookPort},
+						CABundle: []byte("ca"),
+					},
+					Rules: []admissionsV1.RuleWithOperations{{
+						Operations: []admissionsV1.OperationType{admissionsV1.Create},
+						Rule:       admissionsV1.Rule{APIGroups: []string{"*"}, APIVersions: []string{"*"}, Resources: []string{"pods"}, Scope: &scopeV1},
+					}},
+					FailurePolicy:           &failurePolicyV1,
+					SideEffects:             &sideEffectsV1,
+					TimeoutSeconds:          &timeoutSeconds,
+					AdmissionReviewVersions: []string{"v1beta1"},
+					NamespaceSelector:       &metav1.LabelSelector{},
+					ObjectSelector:          &metav1.LabelSelector{},
+				}},
+			},
+			expected: map[string]interface{}{
+				"apiVersion": "admissionregistration.k8s.io/v1",
+				"kind":       "ValidatingWebhookConfiguration",
+				"metadata":   map[string]interface{}{"name": "name"},
+				"webhooks": []interface{}{
+					map[string]interface{}{
+						"name": "webhook.name",
+						"clientConfig": map[string]interface{}{
+							"service":  map[string]interface{}{"namespace": "namespace", "name": "name", "path": "/validate", "port": int64(443)},
+							"caBundle": "Y2E=",
+						},
+						"rules": []interface{}{
+							map[string]interface{}{
+								"operations":  []interface{}{"CREATE"},
+								"apiGroups":   []interface{}{"*"},
+								"apiVersions": []interface{}{"*"},
+								"resources":   []interface{}{"pods"},
+								"scope":       "*",
+							},
+						},
+						"failurePolicy":           "Ignore",
+						"sideEffects":             "None",
+						"timeoutSeconds":          int64(5),
+						"admissionReviewVersions": []interface{}{"v1beta1"},
+						"namespaceSelector":       map[string]interface{}{},
+						"objectSelector":          map[string]interface{}{},
+					},
+				},
+			},
+		},
+		"MutatingWebhookConfiguration v1beta1, should keep the empty selectors": {
+			k8sObject: &admissionsV1Beta1.MutatingWebhookConfiguration{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "admissionregistration.k8s.io/v1beta1", Kind: "MutatingWebhookConfiguration"},
+				ObjectMeta: metav1.ObjectMeta{Name: "name"},
+				Webhooks: []admissionsV1Beta1.MutatingWebhook{{
+					Name: "webhook.name",
+					ClientConfig: admissionsV1Beta1.WebhookClientConfig{
+						Service: &admissionsV1Beta1.ServiceReference{Namespace: "namespace", Name: "name", Port: &webhookPort},
+					},
+					Rules: []admissionsV1Beta1.RuleWithOperations{{
+						Operations: []admissionsV1Beta1.OperationType{admissionsV1Beta1.OperationAll},
+						Rule:       admissionsV1Beta1.Rule{Resources: []string{"pods"}},
+					}},
+					FailurePolicy:           &failurePolicyV1Beta1,
+					SideEffects:             &sideEffectsV1Beta1,
+					ReinvocationPolicy:      &reinvocationPolicyV1Beta1,
+					AdmissionReviewVersions: []string{"v1beta1"},
+					NamespaceSelector:       &metav1.LabelSelector{},
+				}},
+			},
+			expected: map[string]interface{}{
+				"apiVersion": "admissionregistration.k8s.io/v1beta1",
+				"kind":       "MutatingWebhookConfiguration",
+				"metadata":   map[string]interface{}{"name": "name"},
+				"webhooks": []interface{}{
+					map[string]interface{}{
+						"name": "webhook.name",
+						"clientConfig": map[string]interface{}{
+							"service": map[string]interface{}{"namespace": "namespace", "name": "name", "port": int64(443)},
+						},
+						"rules": []interface{}{
+							map[string]interface{}{
+								"operations": []interface{}{"*"},
+								"resources":  []interface{}{"pods"},
+							},
+						},
+						"failurePolicy":           "Ignore",
+						"sideEffects":             "None",
+						"reinvocationPolicy":      "IfNeeded",
+						"admissionReviewVersions": []interface{}{"v1beta1"},
+						"namespaceSelector":       map[string]interface{}{},
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			applyConfiguration, err := toApplyConfiguration(test.k8sObject)
+
+			require.NoError(t, err)
+			require.Equal(t, test.expected, applyConfiguration.Object)
+		})
+	}
+}
@@ -8,6 +8,9 @@ const (
 
 type OwnerSetter func(controlledResource metav1.Object) error
 
+// ChangeRecorder is notified after a k8s object was created (created set to true) or updated
+type ChangeRecorder func(changedResource metav1.Object, created bool)
+
 type ApplyOptions struct {
 	//When set to true, The k8s object will not be modified if it already exists
 	//Default set to false
@@ -16,6 +19,10 @@ type ApplyOptions struct {
 	//The callback that sets the owner of the k8s object
 	//Default set to nil
 	setOwner OwnerSetter
+
+	//The callback that is notified when the k8s object was created or updated
+	//Default set to nil
+	recordChange ChangeRecorder
 }
 
 func MergeApplyOptions(options ...*ApplyOptions) *ApplyOptions {
@@ -30,6 +37,10 @@ func MergeApplyOptions(options ...*ApplyOptions) *ApplyOptions {
 		if singleApplyOptions.setOwner != nil {
 			mergedApplyOptions.setOwner = singleApplyOptions.setOwner
 		}
+
+		if singleApplyOptions.recordChange != nil {
+			mergedApplyOptions.recordChange = singleApplyOptions.recordChange
+		}
 	}
 
 	return mergedApplyOptions
@@ -37,8 +48,9 @@ func MergeApplyOptions(options ...*ApplyOptions) *ApplyOptions {
 
 func NewApplyOptions() *ApplyOptions {
 	return &ApplyOptions{
-		createOnly: nil,
-		setOwner:   nil,
+		createOnly:   nil,
+		setOwner:     nil,
+		recordChange: nil,
 	}
 }
 
@@ -63,3 +75,12 @@ func (options *ApplyOptions) SetOwnerSetter(setOwner OwnerSetter) *ApplyOptions
 	options.setOwner = setOwner
 	return options
 }
+
+func (options *ApplyOptions) ChangeRecorder() ChangeRecorder {
+	return options.recordChange
+}
+
+func (options *ApplyOptions) SetChangeRecorder(recordChange ChangeRecorder) *ApplyOptions {
+	options.recordChange = recordChange
+	return options
+}
@@ -0,0 +1,192 @@
+package applyment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	applymentOptions "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type PlannedAction string
+
+const (
+	PlannedActionCreate PlannedAction = "Create"
+	PlannedActionUpdate PlannedAction = "Update"
+	PlannedActionDelete PlannedAction = "Delete"
+)
+
+// PlannedChange is a change that applying the desired state would make to a k8s object.
+type PlannedChange struct {
+	Action    PlannedAction `json:"action"`
+	Kind      string        `json:"kind"`
+	Namespace string        `json:"namespace,omitempty"`
+	Name      string        `json:"name"`
+
+	// Diff is the strategic merge patch from the live k8s object to the desired one.
+	// It is never set for secrets, so their data isn't exposed in the plan.
+	Diff json.RawMessage `json:"diff,omitempty"`
+}
+
+// ComponentPlanner has the same API as the ComponentApplier, but instead of writing the changes it records them.
+// It runs the same mutation code as the ComponentApplier against the live k8s objects, so the plan can't drift from
+// what applying really does.
+type ComponentPlanner struct {
+	reader  client.Reader
+	changes []PlannedChange
+
+	// dryRunClient and fieldManager are set when the ComponentApplier uses server-side apply. The desired k8s objects
+	// are then applied with a server-side dry run, so the plan shows what the API server would merge.
+	dryRunClient client.Client
+	fieldManager string
+}
+
+func NewComponentPlanner(reader client.Reader) *ComponentPlanner {
+	return &ComponentPlanner{reader: reader}
+}
+
+// NewServerSideDryRunComponentPlanner creates a planner for the ComponentApplier that uses server-side apply with the
+// given field manager.
+func NewServerSideDryRunComponentPlanner(client client.Client, fieldManager string) *ComponentPlanner {
+	return &ComponentPlanner{reader: client, dryRunClient: client, fieldManager: fieldManager}
+}
+
+// Changes returns the changes that were planned so far, in the order they were planned.
+func (planner *ComponentPlanner) Changes() []PlannedChange {
+	return planner.changes
+}
+
+func (planner *ComponentPlanner) Delete(ctx context.Context, desiredK8sObject DesiredK8sObject, _ ...client.DeleteOption) (bool, error) {
+	k8sObject, objectExists, err := getK8sObject(ctx, planner.reader, desiredK8sObject, desiredK8sObject.NamespacedName())
+	if err != nil {
+		return false, err
+	}
+
+	if !objectExists {
+		return false, nil
+	}
+
+	planner.recordChange(PlannedActionDelete, k8sObject, nil)
+	return true, nil
+}
+
+func (planner *ComponentPlanner) Apply(ctx context.Context, desiredK8sObject DesiredK8sObject, applyOptionsList ...*applymentOptions.ApplyOptions) (bool, client.Object, error) {
+	applyOptions := applymentOptions.MergeApplyOptions(applyOptionsList...)
+	namespacedName := desiredK8sObject.NamespacedName()
+
+	k8sObject, objectExists, err := getK8sObject(ctx, planner.reader, desiredK8sObject, namespacedName)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if objectExists && applyOptions.CreateOnly() {
+		return false, k8sObject, nil
+	}
+
+	plan := planner.planMutation
+	if planner.dryRunClient != nil {
+		plan = planner.planServerSideDryRun
+	}
+	k8sObject, beforeMutationRaw, afterMutationRaw, err := plan(ctx, desiredK8sObject, k8sObject, objectExists, namespacedName, applyOptions)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if objectExists && reflect.DeepEqual(beforeMutationRaw, afterMutationRaw) {
+		return false, k8sObject, nil
+	}
+
+	diff, err := strategicpatch.CreateTwoWayMergePatch(beforeMutationRaw, afterMutationRaw, desiredK8sObject.EmptyK8sObject())
+	if err != nil {
+		return false, nil, fmt.Errorf("failed computing the diff of K8s object `%v`: %v", namespacedName, err)
+	}
+
+	action := PlannedActionUpdate
+	if !objectExists {
+		action = PlannedActionCreate
+	}
+	planner.recordChange(action, k8sObject, diff)
+
+	return true, k8sObject, nil
+}
+
+// planMutation mutates the live k8s object as the ComponentApplier does, and returns it along with its serialization
+// before and after the mutation.
+func (planner *ComponentPlanner) planMutation(_ context.Context, desiredK8sObject DesiredK8sObject, k8sObject client.Object, objectExists bool, namespacedName types.NamespacedName, applyOptions *applymentOptions.ApplyOptions) (client.Object, []byte, []byte, error) {
+	beforeMutationRaw := []byte("{}")
+	if objectExists {
+		beforeMutationRaw, _ = json.Marshal(k8sObject)
+	}
+
+	if err := desiredK8sObject.MutateK8sObject(k8sObject); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed mutating K8s object `%v`: %v", namespacedName, err)
+	}
+
+	k8sObject.SetNamespace(namespacedName.Namespace)
+	k8sObject.SetName(namespacedName.Name)
+	if err := setOwner(applyOptions, k8sObject, namespacedName); err != nil {
+		return nil, nil, nil, err
+	}
+
+	afterMutationRaw, _ := json.Marshal(k8sObject)
+	return k8sObject, beforeMutationRaw, afterMutationRaw, nil
+}
+
+// planServerSideDryRun applies the desired k8s object with a server-side dry run, and returns the k8s object the API
+// server would store along with the serialization of the live and of the stored k8s object. The metadata that the API
+// server maintains, e.g. the managed fields and the resource version, is left out of the serializations.
+func (planner *ComponentPlanner) planServerSideDryRun(ctx context.Context, desiredK8sObject DesiredK8sObject, liveK8sObject client.Object, objectExists bool, namespacedName types.NamespacedName, applyOptions *applymentOptions.ApplyOptions) (client.Object, []byte, []byte, error) {
+	beforeApplyRaw := []byte("{}")
+	if objectExists {
+		beforeApplyRaw, _ = json.Marshal(withoutServerMetadata(liveK8sObject))
+	}
+
+	k8sObject, applyConfiguration, err := buildApplyConfiguration(planner.dryRunClient, desiredK8sObject, namespacedName, applyOptions)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := planner.dryRunClient.Patch(ctx, applyConfiguration, client.Apply, client.FieldOwner(planner.fieldManager), client.ForceOwnership, client.DryRunAll); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed applying K8s object `%v` with a dry run: %v", namespacedName, err)
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(applyConfiguration.Object, k8sObject); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed converting the dry run applied K8s object `%v`: %v", namespacedName, err)
+	}
+
+	afterApplyRaw, _ := json.Marshal(withoutServerMetadata(k8sObject))
+	return k8sObject, beforeApplyRaw, afterApplyRaw, nil
+}
+
+func withoutServerMetadata(k8sObject client.Object) client.Object {
+	k8sObject = k8sObject.DeepCopyObject().(client.Object)
+	k8sObject.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{})
+	k8sObject.SetManagedFields(nil)
+	k8sObject.SetResourceVersion("")
+	k8sObject.SetGeneration(0)
+	k8sObject.SetUID("")
+	k8sObject.SetCreationTimestamp(metav1.Time{})
+	return k8sObject
+}
+
+func (planner *ComponentPlanner) recordChange(action PlannedAction, k8sObject client.Object, diff json.RawMessage) {
+	if _, isSecret := k8sObject.(*coreV1.Secret); isSecret {
+		diff = nil
+	}
+
+	planner.changes = append(planner.changes, PlannedChange{
+		Action:    action,
+		Kind:      reflect.Indirect(reflect.ValueOf(k8sObject)).Type().Name(),
+		Namespace: k8sObject.GetNamespace(),
+		Name:      k8sObject.GetName(),
+		Diff:      diff,
+	})
+}
@@ -0,0 +1,209 @@
+package applyment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/mocks"
+	applymentOptions "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
+	testUtilsMocks "github.com/vmware/cbcontainers-operator/cbcontainers/test_utils/mocks"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type PlannerTestSetup func(reader *testUtilsMocks.MockClient, desiredK8sObject *mocks.MockDesiredK8sObject)
+
+func testPlanner(t *testing.T, emptyK8sObject func() client.Object, setup PlannerTestSetup, plan func(*ComponentPlanner, DesiredK8sObject) (bool, error)) (bool, []PlannedChange) {
+	return testPlannerCreatedBy(t, func(reader *testUtilsMocks.MockClient) *ComponentPlanner { return NewComponentPlanner(reader) }, emptyK8sObject, setup, plan)
+}
+
+func testServerSideDryRunPlanner(t *testing.T, setup PlannerTestSetup) (bool, []PlannedChange) {
+	return testPlannerCreatedBy(t, func(k8sClient *testUtilsMocks.MockClient) *ComponentPlanner {
+		return NewServerSideDryRunComponentPlanner(k8sClient, DefaultFieldManager)
+	}, emptyConfigMap, setup, planApply())
+}
+
+func testPlannerCreatedBy(t *testing.T, newPlanner func(*testUtilsMocks.MockClient) *ComponentPlanner, emptyK8sObject func() client.Object, setup PlannerTestSetup, plan func(*ComponentPlanner, DesiredK8sObject) (bool, error)) (bool, []PlannedChange) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	reader := testUtilsMocks.NewMockClient(ctrl)
+	desiredK8sObject := mocks.NewMockDesiredK8sObject(ctrl)
+	desiredK8sObject.EXPECT().NamespacedName().Return(NamespacedName).AnyTimes()
+	desiredK8sObject.EXPECT().EmptyK8sObject().DoAndReturn(emptyK8sObject).AnyTimes()
+	setup(reader, desiredK8sObject)
+
+	planner := newPlanner(reader)
+	changed, err := plan(planner, desiredK8sObject)
+	require.NoError(t, err)
+
+	return changed, planner.Changes()
+}
+
+func planApply(applyOptionsList ...*applymentOptions.ApplyOptions) func(*ComponentPlanner, DesiredK8sObject) (bool, error) {
+	return func(planner *ComponentPlanner, desiredK8sObject DesiredK8sObject) (bool, error) {
+		changed, _, err := planner.Apply(context.Background(), desiredK8sObject, applyOptionsList...)
+		return changed, err
+	}
+}
+
+func planDelete(planner *ComponentPlanner, desiredK8sObject DesiredK8sObject) (bool, error) {
+	return planner.Delete(context.Background(), desiredK8sObject)
+}
+
+func emptyConfigMap() client.Object { return &coreV1.ConfigMap{} }
+
+func existingConfigMap(data map[string]string) func(context.Context, types.NamespacedName, *coreV1.ConfigMap, ...client.GetOption) {
+	return func(_ context.Context, _ types.NamespacedName, configMap *coreV1.ConfigMap, _ ...client.GetOption) {
+		configMap.Namespace = NamespacedName.Namespace
+		configMap.Name = NamespacedName.Name
+		configMap.Data = data
+	}
+}
+
+func mutateConfigMapData(data map[string]string) func(*coreV1.ConfigMap) {
+	return func(configMap *coreV1.ConfigMap) {
+		configMap.Data = data
+	}
+}
+
+func TestPlannerApply(t *testing.T) {
+	t.Run("When not existing, should plan a creation", func(t *testing.T) {
+		changed, changes := testPlanner(t, emptyConfigMap, func(reader *testUtilsMocks.MockClient, desiredK8sObject *mocks.MockDesiredK8sObject) {
+			reader.EXPECT().Get(gomock.Any(), NamespacedName, gomock.Any()).Return(errors.NewNotFound(schema.GroupResource{}, ""))
+			desiredK8sObject.EXPECT().MutateK8sObject(gomock.Any()).Do(mutateConfigMapData(map[string]string{"foo": "bar"})).Return(nil)
+		}, planApply())
+
+		require.True(t, changed)
+		require.Len(t, changes, 1)
+		require.Equal(t, PlannedActionCreate, changes[0].Action)
+		require.Equal(t, "ConfigMap", changes[0].Kind)
+		require.Equal(t, NamespacedName.Namespace, changes[0].Namespace)
+		require.Equal(t, NamespacedName.Name, changes[0].Name)
+		require.Contains(t, string(changes[0].Diff), `"data":{"foo":"bar"}`)
+	})
+
+	t.Run("When existing and changed, should plan an update with only the changed fields", func(t *testing.T) {
+		changed, changes := testPlanner(t, emptyConfigMap, func(reader *testUtilsMocks.MockClient, desiredK8sObject *mocks.MockDesiredK8sObject) {
+			reader.EXPECT().Get(gomock.Any(), NamespacedName, gomock.Any()).Do(existingConfigMap(map[string]string{"foo": "bar", "same": "value"})).Return(nil)
+			desiredK8sObject.EXPECT().MutateK8sObject(gomock.Any()).Do(mutateConfigMapData(map[string]string{"foo": "baz", "same": "value"})).Return(nil)
+		}, planApply())
+
+		require.True(t, changed)
+		require.Len(t, changes, 1)
+		require.Equal(t, PlannedActionUpdate, changes[0].Action)
+		require.JSONEq(t, `{"data":{"foo":"baz"}}`, string(changes[0].Diff))
+	})
+
+	t.Run("When existing and not changed, should not plan anything", func(t *testing.T) {
+		changed, changes := testPlanner(t, emptyConfigMap, func(reader *testUtilsMocks.MockClient, desiredK8sObject *mocks.MockDesiredK8sObject) {
+			reader.EXPECT().Get(gomock.Any(), NamespacedName, gomock.Any()).Do(existingConfigMap(map[string]string{"foo": "bar"})).Return(nil)
+			desiredK8sObject.EXPECT().MutateK8sObject(gomock.Any()).Return(nil)
+		}, planApply())
+
+		require.False(t, changed)
+		require.Empty(t, changes)
+	})
+
+	t.Run("When existing and with creation only flag, should not plan anything", func(t *testing.T) {
+		changed, changes := testPlanner(t, emptyConfigMap, func(reader *testUtilsMocks.MockClient, desiredK8sObject *mocks.MockDesiredK8sObject) {
+			reader.EXPECT().Get(gomock.Any(), NamespacedName, gomock.Any()).Return(nil)
+		}, planApply(applymentOptions.NewApplyOptions().SetCreateOnly(true)))
+
+		require.False(t, changed)
+		require.Empty(t, changes)
+	})
+
+	t.Run("With a secret, should not expose its data", func(t *testing.T) {
+		changed, changes := testPlanner(t, func() client.Object { return &coreV1.Secret{} }, func(reader *testUtilsMocks.MockClient, desiredK8sObject *mocks.MockDesiredK8sObject) {
+			reader.EXPECT().Get(gomock.Any(), NamespacedName, gomock.Any()).Return(errors.NewNotFound(schema.GroupResource{}, ""))
+			desiredK8sObject.EXPECT().MutateK8sObject(gomock.Any()).Do(func(secret *coreV1.Secret) {
+				secret.Data = map[string][]byte{"key": []byte("private")}
+			}).Return(nil)
+		}, planApply())
+
+		require.True(t, changed)
+		require.Len(t, changes, 1)
+		require.Equal(t, PlannedActionCreate, changes[0].Action)
+		require.Nil(t, changes[0].Diff)
+	})
+}
+
+func expectDryRunApply(k8sClient *testUtilsMocks.MockClient, data map[string]string) {
+	k8sClient.EXPECT().GroupVersionKindFor(gomock.Any()).Return(configMapGVK, nil)
+	k8sClient.EXPECT().Patch(gomock.Any(), gomock.Any(), client.Apply, client.FieldOwner(DefaultFieldManager), client.ForceOwnership, client.DryRunAll).
+		DoAndReturn(func(_ context.Context, applyConfiguration *unstructured.Unstructured, _ client.Patch, _ ...client.PatchOption) error {
+			// The API server merges the applied fields into the live k8s object, and maintains its metadata.
+			applyConfiguration.SetResourceVersion("2")
+			applyConfiguration.SetGeneration(2)
+			applyConfiguration.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: DefaultFieldManager, Operation: metav1.ManagedFieldsOperationApply}})
+			return unstructured.SetNestedStringMap(applyConfiguration.Object, data, "data")
+		})
+}
+
+func TestServerSideDryRunPlannerApply(t *testing.T) {
+	t.Run("When not existing, should plan a creation", func(t *testing.T) {
+		changed, changes := testServerSideDryRunPlanner(t, func(k8sClient *testUtilsMocks.MockClient, desiredK8sObject *mocks.MockDesiredK8sObject) {
+			k8sClient.EXPECT().Get(gomock.Any(), NamespacedName, gomock.Any()).Return(errors.NewNotFound(schema.GroupResource{}, ""))
+			desiredK8sObject.EXPECT().MutateK8sObject(gomock.Any()).Do(mutateConfigMapData(map[string]string{"foo": "bar"})).Return(nil)
+			expectDryRunApply(k8sClient, map[string]string{"foo": "bar"})
+		})
+
+		require.True(t, changed)
+		require.Len(t, changes, 1)
+		require.Equal(t, PlannedActionCreate, changes[0].Action)
+		require.Contains(t, string(changes[0].Diff), `"data":{"foo":"bar"}`)
+		require.NotContains(t, string(changes[0].Diff), "managedFields")
+	})
+
+	t.Run("When existing and the API server would change it, should plan an update with the merged fields", func(t *testing.T) {
+		changed, changes := testServerSideDryRunPlanner(t, func(k8sClient *testUtilsMocks.MockClient, desiredK8sObject *mocks.MockDesiredK8sObject) {
+			k8sClient.EXPECT().Get(gomock.Any(), NamespacedName, gomock.Any()).Do(existingConfigMap(map[string]string{"foo": "bar", "other": "value"})).Return(nil)
+			desiredK8sObject.EXPECT().MutateK8sObject(gomock.Any()).Do(mutateConfigMapData(map[string]string{"foo": "baz"})).Return(nil)
+			expectDryRunApply(k8sClient, map[string]string{"foo": "baz", "other": "value"})
+		})
+
+		require.True(t, changed)
+		require.Len(t, changes, 1)
+		require.Equal(t, PlannedActionUpdate, changes[0].Action)
+		require.JSONEq(t, `{"data":{"foo":"baz"}}`, string(changes[0].Diff))
+	})
+
+	t.Run("When existing and the API server would change only its metadata, should not plan anything", func(t *testing.T) {
+		changed, changes := testServerSideDryRunPlanner(t, func(k8sClient *testUtilsMocks.MockClient, desiredK8sObject *mocks.MockDesiredK8sObject) {
+			k8sClient.EXPECT().Get(gomock.Any(), NamespacedName, gomock.Any()).Do(existingConfigMap(map[string]string{"foo": "bar"})).Return(nil)
+			desiredK8sObject.EXPECT().MutateK8sObject(gomock.Any()).Do(mutateConfigMapData(map[string]string{"foo": "bar"})).Return(nil)
+			expectDryRunApply(k8sClient, map[string]string{"foo": "bar"})
+		})
+
+		require.False(t, changed)
+		require.Empty(t, changes)
+	})
+}
+
+func TestPlannerDelete(t *testing.T) {
+	t.Run("When existing, should plan a deletion", func(t *testing.T) {
+		changed, changes := testPlanner(t, emptyConfigMap, func(reader *testUtilsMocks.MockClient, _ *mocks.MockDesiredK8sObject) {
+			reader.EXPECT().Get(gomock.Any(), NamespacedName, gomock.Any()).Do(existingConfigMap(nil)).Return(nil)
+		}, planDelete)
+
+		require.True(t, changed)
+		require.Equal(t, []PlannedChange{{Action: PlannedActionDelete, Kind: "ConfigMap", Namespace: NamespacedName.Namespace, Name: NamespacedName.Name}}, changes)
+	})
+
+	t.Run("When not existing, should not plan anything", func(t *testing.T) {
+		changed, changes := testPlanner(t, emptyConfigMap, func(reader *testUtilsMocks.MockClient, _ *mocks.MockDesiredK8sObject) {
+			reader.EXPECT().Get(gomock.Any(), NamespacedName, gomock.Any()).Return(errors.NewNotFound(schema.GroupResource{}, ""))
+		}, planDelete)
+
+		require.False(t, changed)
+		require.Empty(t, changes)
+	})
+}
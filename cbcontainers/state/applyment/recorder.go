@@ -0,0 +1,109 @@
+package applyment
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	applymentOptions "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ComponentRecorder has the same API as the ComponentApplier, but instead of writing the changes to a cluster it records
+// the k8s objects, as if they were applied to an empty cluster. It's also the reader of that cluster, so the code that
+// reads the applied k8s objects back reads the recorded ones, and lists nothing, e.g. there are no nodes and no pods.
+type ComponentRecorder struct {
+	k8sObjects []client.Object
+}
+
+func NewComponentRecorder() *ComponentRecorder {
+	return &ComponentRecorder{}
+}
+
+// K8sObjects returns the recorded k8s objects, in the order they were first applied.
+func (recorder *ComponentRecorder) K8sObjects() []client.Object {
+	return recorder.k8sObjects
+}
+
+func (recorder *ComponentRecorder) Delete(_ context.Context, desiredK8sObject DesiredK8sObject, _ ...client.DeleteOption) (bool, error) {
+	index := recorder.find(desiredK8sObject.EmptyK8sObject(), desiredK8sObject.NamespacedName())
+	if index < 0 {
+		return false, nil
+	}
+
+	recorder.k8sObjects = append(recorder.k8sObjects[:index], recorder.k8sObjects[index+1:]...)
+	return true, nil
+}
+
+func (recorder *ComponentRecorder) Apply(_ context.Context, desiredK8sObject DesiredK8sObject, applyOptionsList ...*applymentOptions.ApplyOptions) (bool, client.Object, error) {
+	applyOptions := applymentOptions.MergeApplyOptions(applyOptionsList...)
+	namespacedName := desiredK8sObject.NamespacedName()
+
+	k8sObject := desiredK8sObject.EmptyK8sObject()
+	index := recorder.find(k8sObject, namespacedName)
+	if index >= 0 {
+		copyK8sObject(recorder.k8sObjects[index], k8sObject)
+		if applyOptions.CreateOnly() {
+			return false, k8sObject, nil
+		}
+	}
+
+	if err := desiredK8sObject.MutateK8sObject(k8sObject); err != nil {
+		return false, nil, fmt.Errorf("failed mutating K8s object `%v`: %v", namespacedName, err)
+	}
+
+	k8sObject.SetNamespace(namespacedName.Namespace)
+	k8sObject.SetName(namespacedName.Name)
+	if err := setOwner(applyOptions, k8sObject, namespacedName); err != nil {
+		return false, nil, err
+	}
+
+	recordedK8sObject := k8sObject.DeepCopyObject().(client.Object)
+	if index >= 0 {
+		recorder.k8sObjects[index] = recordedK8sObject
+	} else {
+		recorder.k8sObjects = append(recorder.k8sObjects, recordedK8sObject)
+	}
+
+	return true, k8sObject, nil
+}
+
+func (recorder *ComponentRecorder) Get(_ context.Context, key client.ObjectKey, k8sObject client.Object, _ ...client.GetOption) error {
+	index := recorder.find(k8sObject, key)
+	if index < 0 {
+		return errors.NewNotFound(schema.GroupResource{Resource: reflect.Indirect(reflect.ValueOf(k8sObject)).Type().Name()}, key.Name)
+	}
+
+	copyK8sObject(recorder.k8sObjects[index], k8sObject)
+	return nil
+}
+
+func (recorder *ComponentRecorder) List(context.Context, client.ObjectList, ...client.ListOption) error {
+	return nil
+}
+
+// find returns the index of the recorded k8s object of the same type and name, or -1 when there is none. The kinds are
+// compared when both are set, as the type of all the unstructured k8s objects is the same.
+func (recorder *ComponentRecorder) find(k8sObject client.Object, namespacedName types.NamespacedName) int {
+	kind := k8sObject.GetObjectKind().GroupVersionKind().Kind
+	for i, recordedK8sObject := range recorder.k8sObjects {
+		if reflect.TypeOf(recordedK8sObject) != reflect.TypeOf(k8sObject) {
+			continue
+		}
+		if recordedKind := recordedK8sObject.GetObjectKind().GroupVersionKind().Kind; kind != "" && recordedKind != "" && kind != recordedKind {
+			continue
+		}
+		if recordedK8sObject.GetNamespace() == namespacedName.Namespace && recordedK8sObject.GetName() == namespacedName.Name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func copyK8sObject(from, to client.Object) {
+	reflect.ValueOf(to).Elem().Set(reflect.ValueOf(from.DeepCopyObject()).Elem())
+}
@@ -0,0 +1,78 @@
+package applyment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	applymentOptions "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestComponentRecorder(t *testing.T) {
+	ctx := context.Background()
+	namespacedName := types.NamespacedName{Name: "config", Namespace: "namespace"}
+
+	t.Run("Should record the applied k8s object and read it back", func(t *testing.T) {
+		recorder := NewComponentRecorder()
+
+		changed, k8sObject, err := recorder.Apply(ctx, &desiredConfigMap{namespacedName: namespacedName, data: map[string]string{"key": "value"}})
+
+		require.NoError(t, err)
+		require.True(t, changed)
+		require.Equal(t, namespacedName.Name, k8sObject.GetName())
+		require.Len(t, recorder.K8sObjects(), 1)
+		configMap := &coreV1.ConfigMap{}
+		require.NoError(t, recorder.Get(ctx, namespacedName, configMap))
+		require.Equal(t, map[string]string{"key": "value"}, configMap.Data)
+	})
+
+	t.Run("When applying an already recorded k8s object, should replace it", func(t *testing.T) {
+		recorder := NewComponentRecorder()
+		_, _, err := recorder.Apply(ctx, &desiredConfigMap{namespacedName: namespacedName, data: map[string]string{"key": "value"}})
+		require.NoError(t, err)
+
+		_, _, err = recorder.Apply(ctx, &desiredConfigMap{namespacedName: namespacedName, data: map[string]string{"key": "changed"}})
+
+		require.NoError(t, err)
+		require.Len(t, recorder.K8sObjects(), 1)
+		require.Equal(t, map[string]string{"key": "changed"}, recorder.K8sObjects()[0].(*coreV1.ConfigMap).Data)
+	})
+
+	t.Run("When applying an already recorded k8s object with create only, should keep it", func(t *testing.T) {
+		recorder := NewComponentRecorder()
+		_, _, err := recorder.Apply(ctx, &desiredConfigMap{namespacedName: namespacedName, data: map[string]string{"key": "value"}})
+		require.NoError(t, err)
+
+		changed, _, err := recorder.Apply(ctx, &desiredConfigMap{namespacedName: namespacedName, data: map[string]string{"key": "changed"}}, applymentOptions.NewApplyOptions().SetCreateOnly(true))
+
+		require.NoError(t, err)
+		require.False(t, changed)
+		require.Equal(t, map[string]string{"key": "value"}, recorder.K8sObjects()[0].(*coreV1.ConfigMap).Data)
+	})
+
+	t.Run("When deleting a recorded k8s object, should not read it anymore", func(t *testing.T) {
+		recorder := NewComponentRecorder()
+		_, _, err := recorder.Apply(ctx, &desiredConfigMap{namespacedName: namespacedName})
+		require.NoError(t, err)
+
+		deleted, err := recorder.Delete(ctx, &desiredConfigMap{namespacedName: namespacedName})
+
+		require.NoError(t, err)
+		require.True(t, deleted)
+		require.Empty(t, recorder.K8sObjects())
+		require.True(t, k8sErrors.IsNotFound(recorder.Get(ctx, namespacedName, &coreV1.ConfigMap{})))
+	})
+
+	t.Run("Should list no k8s objects", func(t *testing.T) {
+		recorder := NewComponentRecorder()
+		_, _, err := recorder.Apply(ctx, &desiredConfigMap{namespacedName: namespacedName})
+		require.NoError(t, err)
+
+		configMaps := &coreV1.ConfigMapList{}
+		require.NoError(t, recorder.List(ctx, configMaps))
+		require.Empty(t, configMaps.Items)
+	})
+}
@@ -0,0 +1,110 @@
+package capabilities
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+const (
+	AdmissionRegistrationV1      = "admissionregistration.k8s.io/v1"
+	AdmissionRegistrationV1Beta1 = "admissionregistration.k8s.io/v1beta1"
+	AutoscalingV2                = "autoscaling/v2"
+	PolicyV1                     = "policy/v1"
+	SchedulingV1                 = "scheduling.k8s.io/v1"
+	SchedulingV1Beta1            = "scheduling.k8s.io/v1beta1"
+)
+
+var (
+	// groupVersionsIntroducedIn are the Kubernetes versions that introduced the group versions the operator uses.
+	// They are used when the group versions that the API server serves are not known.
+	groupVersionsIntroducedIn = map[string]*version.Version{
+		AdmissionRegistrationV1:      version.MajorMinor(1, 16),
+		AdmissionRegistrationV1Beta1: version.MajorMinor(1, 9),
+		AutoscalingV2:                version.MajorMinor(1, 23),
+		PolicyV1:                     version.MajorMinor(1, 21),
+		SchedulingV1:                 version.MajorMinor(1, 14),
+		SchedulingV1Beta1:            version.MajorMinor(1, 11),
+	}
+
+	webhookTimeoutsIntroducedIn        = version.MajorMinor(1, 14)
+	webhookMatchPolicyIntroducedIn     = version.MajorMinor(1, 15)
+	webhookMatchConditionsIntroducedIn = version.MajorMinor(1, 28)
+)
+
+// Provider provides the capabilities of the Kubernetes API server.
+type Provider interface {
+	Capabilities() *Capabilities
+}
+
+// Capabilities are the API group versions and the fields that the Kubernetes API server supports.
+//
+// A nil version is treated as the latest Kubernetes version, and nil group versions are implied by the version.
+type Capabilities struct {
+	// Version is the version of the API server.
+	Version *version.Version
+	// GroupVersions are the API group versions that the API server serves, e.g. "scheduling.k8s.io/v1".
+	GroupVersions map[string]struct{}
+}
+
+// ForVersion returns the capabilities of a Kubernetes version, e.g. "v1.29.1".
+// An empty version is treated as the latest Kubernetes version.
+func ForVersion(kubernetesVersion string) (*Capabilities, error) {
+	if kubernetesVersion == "" {
+		return &Capabilities{}, nil
+	}
+
+	parsedVersion, err := version.ParseGeneric(kubernetesVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing the Kubernetes version: %w", err)
+	}
+
+	return &Capabilities{Version: parsedVersion}, nil
+}
+
+func (c *Capabilities) HasGroupVersion(groupVersion string) bool {
+	if c.GroupVersions != nil {
+		_, ok := c.GroupVersions[groupVersion]
+		return ok
+	}
+
+	introducedIn, ok := groupVersionsIntroducedIn[groupVersion]
+	return ok && c.isAtLeast(introducedIn)
+}
+
+// HasAdmissionReviewV1 returns whether the API server sends v1 AdmissionReview objects to webhooks.
+func (c *Capabilities) HasAdmissionReviewV1() bool {
+	return c.HasGroupVersion(AdmissionRegistrationV1)
+}
+
+// HasWebhookTimeouts returns whether webhooks have the timeoutSeconds and the admissionReviewVersions fields.
+func (c *Capabilities) HasWebhookTimeouts() bool {
+	return c.isAtLeast(webhookTimeoutsIntroducedIn)
+}
+
+// HasWebhookMatchPolicy returns whether webhooks have the matchPolicy and the objectSelector fields.
+func (c *Capabilities) HasWebhookMatchPolicy() bool {
+	return c.isAtLeast(webhookMatchPolicyIntroducedIn)
+}
+
+// HasWebhookMatchConditions returns whether webhooks have the matchConditions field, which is enabled by default since 1.28.
+func (c *Capabilities) HasWebhookMatchConditions() bool {
+	return c.isAtLeast(webhookMatchConditionsIntroducedIn)
+}
+
+func (c *Capabilities) isAtLeast(minVersion *version.Version) bool {
+	return c.Version == nil || c.Version.AtLeast(minVersion)
+}
+
+type staticProvider struct {
+	capabilities *Capabilities
+}
+
+// NewStaticProvider returns a provider of capabilities that don't change, e.g. when the cluster isn't reachable.
+func NewStaticProvider(capabilities *Capabilities) Provider {
+	return &staticProvider{capabilities: capabilities}
+}
+
+func (p *staticProvider) Capabilities() *Capabilities {
+	return p.capabilities
+}
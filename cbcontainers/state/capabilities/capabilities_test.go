@@ -0,0 +1,116 @@
+package capabilities_test
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/capabilities"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakeDiscovery "k8s.io/client-go/discovery/fake"
+	k8sTesting "k8s.io/client-go/testing"
+)
+
+func TestForVersion(t *testing.T) {
+	testCases := map[string]struct {
+		version                   string
+		expectedGroupVersions     []string
+		expectedMissingGroups     []string
+		expectedWebhookTimeouts   bool
+		expectedMatchPolicy       bool
+		expectedMatchConditions   bool
+		expectedAdmissionReviewV1 bool
+	}{
+		"latest version": {
+			version:                   "",
+			expectedGroupVersions:     []string{capabilities.SchedulingV1, capabilities.AdmissionRegistrationV1, capabilities.AutoscalingV2, capabilities.PolicyV1},
+			expectedWebhookTimeouts:   true,
+			expectedMatchPolicy:       true,
+			expectedMatchConditions:   true,
+			expectedAdmissionReviewV1: true,
+		},
+		"v1.9 is older than v1.14": {
+			version:               "v1.9.11",
+			expectedGroupVersions: []string{capabilities.AdmissionRegistrationV1Beta1},
+			expectedMissingGroups: []string{capabilities.SchedulingV1, capabilities.SchedulingV1Beta1, capabilities.AdmissionRegistrationV1},
+		},
+		"v1.15": {
+			version:                 "v1.15.0",
+			expectedGroupVersions:   []string{capabilities.SchedulingV1, capabilities.AdmissionRegistrationV1Beta1},
+			expectedMissingGroups:   []string{capabilities.AdmissionRegistrationV1, capabilities.AutoscalingV2, capabilities.PolicyV1},
+			expectedWebhookTimeouts: true,
+			expectedMatchPolicy:     true,
+		},
+		"managed cluster version": {
+			version:                   "v1.28.3-eks-4f4795d",
+			expectedGroupVersions:     []string{capabilities.SchedulingV1, capabilities.AdmissionRegistrationV1, capabilities.AutoscalingV2, capabilities.PolicyV1},
+			expectedWebhookTimeouts:   true,
+			expectedMatchPolicy:       true,
+			expectedMatchConditions:   true,
+			expectedAdmissionReviewV1: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c, err := capabilities.ForVersion(testCase.version)
+			require.NoError(t, err)
+
+			for _, groupVersion := range testCase.expectedGroupVersions {
+				require.True(t, c.HasGroupVersion(groupVersion), groupVersion)
+			}
+			for _, groupVersion := range testCase.expectedMissingGroups {
+				require.False(t, c.HasGroupVersion(groupVersion), groupVersion)
+			}
+			require.Equal(t, testCase.expectedWebhookTimeouts, c.HasWebhookTimeouts())
+			require.Equal(t, testCase.expectedMatchPolicy, c.HasWebhookMatchPolicy())
+			require.Equal(t, testCase.expectedMatchConditions, c.HasWebhookMatchConditions())
+			require.Equal(t, testCase.expectedAdmissionReviewV1, c.HasAdmissionReviewV1())
+		})
+	}
+}
+
+func TestForVersionWithInvalidVersionReturnsError(t *testing.T) {
+	_, err := capabilities.ForVersion("latest")
+	require.Error(t, err)
+}
+
+func TestDiscoveryProviderUsesTheDiscoveredGroupVersions(t *testing.T) {
+	discoveryClient := &fakeDiscovery.FakeDiscovery{
+		Fake: &k8sTesting.Fake{
+			Resources: []*metav1.APIResourceList{
+				{GroupVersion: capabilities.SchedulingV1},
+				{GroupVersion: capabilities.AdmissionRegistrationV1},
+			},
+		},
+		FakedServerVersion: &version.Info{GitVersion: "v1.29.1"},
+	}
+	provider := capabilities.NewDiscoveryProvider(discoveryClient, capabilities.DefaultRefreshInterval, logr.Discard())
+
+	require.NoError(t, provider.Refresh())
+	c := provider.Capabilities()
+	require.True(t, c.HasGroupVersion(capabilities.SchedulingV1))
+	require.True(t, c.HasGroupVersion(capabilities.AdmissionRegistrationV1))
+	// The version would imply it, but the API server doesn't serve it
+	require.False(t, c.HasGroupVersion(capabilities.AdmissionRegistrationV1Beta1))
+	require.True(t, c.HasWebhookMatchConditions())
+
+	// The control plane was upgraded
+	discoveryClient.FakedServerVersion = &version.Info{GitVersion: "v1.30.0"}
+	require.NoError(t, provider.Refresh())
+	require.Equal(t, "1.30.0", provider.Capabilities().Version.String())
+}
+
+func TestDiscoveryProviderKeepsTheCapabilitiesWhenTheVersionIsInvalid(t *testing.T) {
+	discoveryClient := &fakeDiscovery.FakeDiscovery{
+		Fake:               &k8sTesting.Fake{},
+		FakedServerVersion: &version.Info{GitVersion: "v1.29.1"},
+	}
+	provider := capabilities.NewDiscoveryProvider(discoveryClient, capabilities.DefaultRefreshInterval, logr.Discard())
+	require.NoError(t, provider.Refresh())
+
+	discoveryClient.FakedServerVersion = &version.Info{GitVersion: "unknown"}
+	require.Error(t, provider.Refresh())
+	require.Equal(t, "1.29.1", provider.Capabilities().Version.String())
+}
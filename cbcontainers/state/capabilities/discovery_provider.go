@@ -0,0 +1,94 @@
+package capabilities
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+)
+
+// DefaultRefreshInterval is how often the capabilities are discovered again, so upgrades of the control plane are noticed.
+const DefaultRefreshInterval = 10 * time.Minute
+
+// DiscoveryProvider provides the capabilities of the Kubernetes API server, using the discovery API.
+type DiscoveryProvider struct {
+	discoveryClient discovery.DiscoveryInterface
+	refreshInterval time.Duration
+	log             logr.Logger
+
+	lock         sync.RWMutex
+	capabilities *Capabilities
+}
+
+func NewDiscoveryProvider(discoveryClient discovery.DiscoveryInterface, refreshInterval time.Duration, log logr.Logger) *DiscoveryProvider {
+	return &DiscoveryProvider{
+		discoveryClient: discoveryClient,
+		refreshInterval: refreshInterval,
+		log:             log,
+		capabilities:    &Capabilities{},
+	}
+}
+
+// Capabilities returns the capabilities that were last discovered.
+func (p *DiscoveryProvider) Capabilities() *Capabilities {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.capabilities
+}
+
+// Refresh discovers the version and the group versions of the API server.
+func (p *DiscoveryProvider) Refresh() error {
+	serverVersion, err := p.discoveryClient.ServerVersion()
+	if err != nil {
+		return fmt.Errorf("failed getting the API server version: %w", err)
+	}
+
+	parsedVersion, err := version.ParseGeneric(serverVersion.GitVersion)
+	if err != nil {
+		return fmt.Errorf("failed parsing the API server version: %w", err)
+	}
+
+	serverGroups, err := p.discoveryClient.ServerGroups()
+	if err != nil {
+		return fmt.Errorf("failed getting the API server groups: %w", err)
+	}
+
+	groupVersions := make(map[string]struct{})
+	for _, group := range serverGroups.Groups {
+		for _, groupVersion := range group.Versions {
+			groupVersions[groupVersion.GroupVersion] = struct{}{}
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.capabilities.Version == nil || p.capabilities.Version.String() != parsedVersion.String() {
+		p.log.Info("Discovered the API server version", "version", serverVersion.GitVersion)
+	}
+	p.capabilities = &Capabilities{Version: parsedVersion, GroupVersions: groupVersions}
+
+	return nil
+}
+
+// Start refreshes the capabilities periodically until the context is done.
+// The last discovered capabilities are kept when a refresh fails.
+func (p *DiscoveryProvider) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := p.Refresh(); err != nil {
+			p.log.Error(err, "Failed refreshing the API server capabilities")
+		}
+	}, p.refreshInterval)
+
+	return nil
+}
+
+// NeedLeaderElection returns false, so the capabilities are refreshed on all the operator replicas.
+func (p *DiscoveryProvider) NeedLeaderElection() bool {
+	return false
+}
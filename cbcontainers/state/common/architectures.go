@@ -0,0 +1,51 @@
+package common
+
+import (
+	"fmt"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"k8s.io/utils/strings/slices"
+)
+
+var (
+	// DefaultArchitectures are the node architectures that images which are not multi-architecture images run on.
+	DefaultArchitectures = []string{"386", "amd64", "amd64p32"}
+)
+
+// GetArchitectures returns the node architectures that a component is scheduled on: the architectures of the component,
+// or the architectures of the components settings when it doesn't set them. Images that are not multi-architecture
+// images only run on the default architectures.
+//
+// An error is returned when the image doesn't run on any of the architectures.
+func GetArchitectures(settings *cbcontainersv1.CBContainersComponentsSettings, componentArchitectures []string, image *cbcontainersv1.CBContainersImageSpec) ([]string, error) {
+	architectures := componentArchitectures
+	if len(architectures) == 0 {
+		architectures = settings.Architectures
+	}
+	if len(architectures) == 0 {
+		architectures = DefaultArchitectures
+	}
+
+	if image.MultiArch {
+		return architectures, nil
+	}
+
+	supportedArchitectures := IntersectArchitectures(architectures, DefaultArchitectures)
+	if len(supportedArchitectures) == 0 {
+		return nil, fmt.Errorf("the image %v only runs on the architectures %v, set the image as a multi-architecture image to run it on %v", image.Repository, DefaultArchitectures, architectures)
+	}
+
+	return supportedArchitectures, nil
+}
+
+// IntersectArchitectures returns the architectures that are in both lists, in the order of the first list.
+func IntersectArchitectures(architectures, otherArchitectures []string) []string {
+	intersection := make([]string, 0, len(architectures))
+	for _, architecture := range architectures {
+		if slices.Contains(otherArchitectures, architecture) && !slices.Contains(intersection, architecture) {
+			intersection = append(intersection, architecture)
+		}
+	}
+
+	return intersection
+}
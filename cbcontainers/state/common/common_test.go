@@ -348,6 +348,158 @@ func TestMutationTrueDifferentSize(t *testing.T) {
 	compareEnvVars(t, expected, container.Env)
 }
 
+func TestWithExtraEnvOverridesSpec(t *testing.T) {
+	envSpec := map[string]string{
+		testName1: testValue1,
+		testName2: testValue2,
+	}
+	extraEnv := []coreV1.EnvVar{
+		{
+			Name: testName2,
+			ValueFrom: &coreV1.EnvVarSource{
+				SecretKeyRef: &coreV1.SecretKeySelector{
+					LocalObjectReference: coreV1.LocalObjectReference{Name: "test-secret"},
+					Key:                  "test-key",
+				},
+			},
+		},
+	}
+	expected := map[string]coreV1.EnvVar{
+		testName1: {
+			Name:  testName1,
+			Value: testValue1,
+		},
+		testName2: extraEnv[0],
+	}
+
+	actual := NewEnvVarBuilder().
+		WithSpec(envSpec).
+		WithExtraEnv(extraEnv).
+		Build()
+
+	compareEnvVars(t, expected, actual)
+}
+
+func TestWithProxySettingsKeepsEnvVarsFromSecrets(t *testing.T) {
+	enabled, httpProxy, noProxy, noProxySuffix := true, "http://proxy:3128", "example.com", "cb.local"
+	proxySettings := &cbcontainersv1.CBContainersProxySettings{
+		Enabled:       &enabled,
+		HttpProxy:     &httpProxy,
+		HttpsProxy:    &httpProxy,
+		NoProxy:       &noProxy,
+		NoProxySuffix: &noProxySuffix,
+	}
+	extraEnv := []coreV1.EnvVar{
+		{
+			Name: proxyHttpsProxyVarName,
+			ValueFrom: &coreV1.EnvVarSource{
+				SecretKeyRef: &coreV1.SecretKeySelector{
+					LocalObjectReference: coreV1.LocalObjectReference{Name: "proxy-secret"},
+					Key:                  "https-proxy",
+				},
+			},
+		},
+		{
+			Name: proxyNoProxyVarName,
+			ValueFrom: &coreV1.EnvVarSource{
+				ConfigMapKeyRef: &coreV1.ConfigMapKeySelector{
+					LocalObjectReference: coreV1.LocalObjectReference{Name: "proxy-config"},
+					Key:                  "no-proxy",
+				},
+			},
+		},
+	}
+	expected := map[string]coreV1.EnvVar{
+		proxyHttpProxyVarName: {
+			Name:  proxyHttpProxyVarName,
+			Value: httpProxy,
+		},
+		proxyHttpsProxyVarName: extraEnv[0],
+		proxyNoProxyVarName:    extraEnv[1],
+	}
+
+	actual := NewEnvVarBuilder().
+		WithExtraEnv(extraEnv).
+		WithProxySettings(proxySettings).
+		Build()
+
+	compareEnvVars(t, expected, actual)
+}
+
+func TestMutationFalseWithEnvVarSources(t *testing.T) {
+	optional := false
+	desired := []coreV1.EnvVar{
+		{
+			Name: testName1,
+			ValueFrom: &coreV1.EnvVarSource{
+				SecretKeyRef: &coreV1.SecretKeySelector{
+					LocalObjectReference: coreV1.LocalObjectReference{Name: "test-secret"},
+					Key:                  "test-key",
+				},
+			},
+		},
+		{
+			Name: testName2,
+			ValueFrom: &coreV1.EnvVarSource{
+				ConfigMapKeyRef: &coreV1.ConfigMapKeySelector{
+					LocalObjectReference: coreV1.LocalObjectReference{Name: "test-config"},
+					Key:                  "test-key",
+					Optional:             &optional,
+				},
+			},
+		},
+		{
+			Name: "test_node_name",
+			ValueFrom: &coreV1.EnvVarSource{
+				FieldRef: &coreV1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+			},
+		},
+	}
+	// The api version of the field ref is defaulted by the API server
+	actual := []coreV1.EnvVar{*desired[0].DeepCopy(), *desired[1].DeepCopy(), *desired[2].DeepCopy()}
+	actual[1].ValueFrom.ConfigMapKeyRef.Optional = nil
+	actual[2].ValueFrom.FieldRef.APIVersion = "v1"
+
+	require.True(t, NewEnvVarBuilder().WithExtraEnv(desired).IsEqual(actual))
+}
+
+func TestMutationTrueWithEnvVarSources(t *testing.T) {
+	optional := true
+	secretEnvVar := coreV1.EnvVar{
+		Name: testName1,
+		ValueFrom: &coreV1.EnvVarSource{
+			SecretKeyRef: &coreV1.SecretKeySelector{
+				LocalObjectReference: coreV1.LocalObjectReference{Name: "test-secret"},
+				Key:                  "test-key",
+			},
+		},
+	}
+
+	for name, mutateActual := range map[string]func(envVar *coreV1.EnvVar){
+		"other secret": func(envVar *coreV1.EnvVar) { envVar.ValueFrom.SecretKeyRef.Name = "other-secret" },
+		"other key":    func(envVar *coreV1.EnvVar) { envVar.ValueFrom.SecretKeyRef.Key = "other-key" },
+		"optional":     func(envVar *coreV1.EnvVar) { envVar.ValueFrom.SecretKeyRef.Optional = &optional },
+		"configmap": func(envVar *coreV1.EnvVar) {
+			envVar.ValueFrom = &coreV1.EnvVarSource{
+				ConfigMapKeyRef: &coreV1.ConfigMapKeySelector{
+					LocalObjectReference: coreV1.LocalObjectReference{Name: "test-secret"},
+					Key:                  "test-key",
+				},
+			}
+		},
+		"value": func(envVar *coreV1.EnvVar) { envVar.ValueFrom, envVar.Value = nil, testValue1 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			actualEnvVar := *secretEnvVar.DeepCopy()
+			mutateActual(&actualEnvVar)
+			container := &coreV1.Container{Env: []coreV1.EnvVar{actualEnvVar}}
+
+			MutateEnvVars(container, NewEnvVarBuilder().WithExtraEnv([]coreV1.EnvVar{secretEnvVar}))
+			compareEnvVars(t, map[string]coreV1.EnvVar{testName1: secretEnvVar}, container.Env)
+		})
+	}
+}
+
 func TestMutateImageWithTag(t *testing.T) {
 	expectedImage := "cbartifactory/test:1.0.0"
 	expectedPullPolicy := coreV1.PullPolicy("IfNotPresent")
@@ -569,3 +721,61 @@ func TestComponentsEnableCheck(t *testing.T) {
 	require.True(t, reflect.DeepEqual(IsEnabled(clusterScanningSpecEnabled.Enabled), true))
 	require.True(t, reflect.DeepEqual(IsDisabled(clusterScanningSpecEnabled.Enabled), false))
 }
+
+func TestGetArchitectures(t *testing.T) {
+	settings := &cbcontainersv1.CBContainersComponentsSettings{Architectures: []string{"amd64", "arm64"}}
+
+	testCases := map[string]struct {
+		settings               *cbcontainersv1.CBContainersComponentsSettings
+		componentArchitectures []string
+		image                  *cbcontainersv1.CBContainersImageSpec
+		expectedArchitectures  []string
+		expectedError          bool
+	}{
+		"default architectures": {
+			settings:              &cbcontainersv1.CBContainersComponentsSettings{},
+			image:                 &cbcontainersv1.CBContainersImageSpec{},
+			expectedArchitectures: DefaultArchitectures,
+		},
+		"settings architectures of a multi-architecture image": {
+			settings:              settings,
+			image:                 &cbcontainersv1.CBContainersImageSpec{MultiArch: true},
+			expectedArchitectures: []string{"amd64", "arm64"},
+		},
+		"settings architectures of an image that isn't a multi-architecture image": {
+			settings:              settings,
+			image:                 &cbcontainersv1.CBContainersImageSpec{},
+			expectedArchitectures: []string{"amd64"},
+		},
+		"component architectures override the settings architectures": {
+			settings:               settings,
+			componentArchitectures: []string{"arm64"},
+			image:                  &cbcontainersv1.CBContainersImageSpec{MultiArch: true},
+			expectedArchitectures:  []string{"arm64"},
+		},
+		"image that doesn't run on any of the architectures": {
+			settings:               settings,
+			componentArchitectures: []string{"arm64"},
+			image:                  &cbcontainersv1.CBContainersImageSpec{},
+			expectedError:          true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			architectures, err := GetArchitectures(testCase.settings, testCase.componentArchitectures, testCase.image)
+			if testCase.expectedError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testCase.expectedArchitectures, architectures)
+		})
+	}
+}
+
+func TestIntersectArchitectures(t *testing.T) {
+	require.Equal(t, []string{"amd64"}, IntersectArchitectures([]string{"arm64", "amd64", "amd64"}, DefaultArchitectures))
+	require.Empty(t, IntersectArchitectures([]string{"arm64"}, DefaultArchitectures))
+}
@@ -8,6 +8,10 @@ const (
 	KubeSystemNamespaceName = "kube-system"
 
 	DataPlaneConfigmapName = "cbcontainers-dataplane-config"
+	// PlanConfigMapName is the name of the configmap to which the plan of the changes is published while the agent is in plan mode.
+	PlanConfigMapName = "cbcontainers-agent-plan"
+	// NodeCoverageConfigMapName is the name of the configmap to which the reason that each node isn't covered by the components daemon set is published.
+	NodeCoverageConfigMapName = "cbcontainers-node-coverage"
 	// RegistrySecretName is the name of the secret that contains the image pull secret for the default registry of the agent images.
 	//
 	// The creation of this secret is optional, as the users may override the agent images and not use the registry we provide.
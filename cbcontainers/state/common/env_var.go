@@ -46,6 +46,16 @@ func (b *EnvVarBuilder) WithSpec(desiredEnvsValues map[string]string) *EnvVarBui
 	return b
 }
 
+// WithExtraEnv overrides the env vars of the same name, including the ones of WithSpec, so it must follow it.
+// The env vars may be sourced from the keys of Secrets and ConfigMaps.
+func (b *EnvVarBuilder) WithExtraEnv(extraEnv []coreV1.EnvVar) *EnvVarBuilder {
+	for _, extraEnvVar := range extraEnv {
+		b.envVars[extraEnvVar.Name] = *extraEnvVar.DeepCopy()
+	}
+
+	return b
+}
+
 func (b *EnvVarBuilder) WithEventsGateway(eventsGatewaySpec *cbcontainersv1.CBContainersEventsGatewaySpec) *EnvVarBuilder {
 	b.envVars[eventGatewayHostVarName] = coreV1.EnvVar{Name: eventGatewayHostVarName, Value: eventsGatewaySpec.Host}
 	b.envVars[eventGatewayPortVarName] = coreV1.EnvVar{Name: eventGatewayPortVarName, Value: strconv.Itoa(eventsGatewaySpec.Port)}
@@ -71,6 +81,18 @@ func (b *EnvVarBuilder) WithProxySettings(proxySettings *cbcontainersv1.CBContai
 		return b
 	}
 
+	// The value of an env var that is sourced from a Secret or a ConfigMap isn't known, so it's used as is
+	if noProxyVar, ok := b.envVars[proxyNoProxyVarName]; !ok || noProxyVar.ValueFrom == nil {
+		b.withNoProxy(proxySettings)
+	}
+
+	b.withDefaultValueOrNothing(proxyHttpProxyVarName, proxySettings.HttpProxy).
+		withDefaultValueOrNothing(proxyHttpsProxyVarName, proxySettings.HttpsProxy)
+
+	return b
+}
+
+func (b *EnvVarBuilder) withNoProxy(proxySettings *cbcontainersv1.CBContainersProxySettings) *EnvVarBuilder {
 	var userNoProxy string
 	if noProxyVar, ok := b.envVars[proxyNoProxyVarName]; ok {
 		userNoProxy = noProxyVar.Value
@@ -86,15 +108,15 @@ func (b *EnvVarBuilder) WithProxySettings(proxySettings *cbcontainersv1.CBContai
 	noProxyVal = strings.Trim(noProxyVal, ",") // thus, we strip it here
 	b.envVars[proxyNoProxyVarName] = coreV1.EnvVar{Name: proxyNoProxyVarName, Value: noProxyVal}
 
-	b.withDefaultValueOrNothing(proxyHttpProxyVarName, proxySettings.HttpProxy).
-		withDefaultValueOrNothing(proxyHttpsProxyVarName, proxySettings.HttpsProxy)
-
 	return b
 }
 
 func (b *EnvVarBuilder) withDefaultValueOrNothing(envName string, defValue *string) *EnvVarBuilder {
 	var value string
-	if envVar, ok := b.envVars[envName]; ok {
+	if envVar, ok := b.envVars[envName]; ok && envVar.ValueFrom != nil {
+		// The env var is sourced from a Secret or a ConfigMap
+		return b
+	} else if ok {
 		value = envVar.Value
 	} else if defValue != nil {
 		value = *defValue
@@ -189,11 +211,11 @@ func (b *EnvVarBuilder) IsEqual(actualEnv []coreV1.EnvVar) bool {
 			return false
 		}
 
-		if desiredEnvVar.ValueFrom != nil && desiredEnvVar.ValueFrom.ResourceFieldRef != nil {
-			if !b.isResourceFieldRefEquals(desiredEnvVar.ValueFrom.ResourceFieldRef, actualEnvVar.ValueFrom.ResourceFieldRef) {
+		if desiredEnvVar.ValueFrom == nil || actualEnvVar.ValueFrom == nil {
+			if !reflect.DeepEqual(actualEnvVar, desiredEnvVar) {
 				return false
 			}
-		} else if !reflect.DeepEqual(actualEnvVar, desiredEnvVar) {
+		} else if desiredEnvVar.Value != actualEnvVar.Value || !b.isEnvVarSourceEquals(desiredEnvVar.ValueFrom, actualEnvVar.ValueFrom) {
 			return false
 		}
 	}
@@ -201,6 +223,62 @@ func (b *EnvVarBuilder) IsEqual(actualEnv []coreV1.EnvVar) bool {
 	return true
 }
 
+// isEnvVarSourceEquals compares the env var sources while ignoring the fields that the API server defaults
+func (b *EnvVarBuilder) isEnvVarSourceEquals(desiredSource, actualSource *coreV1.EnvVarSource) bool {
+	if (desiredSource.ResourceFieldRef == nil) != (actualSource.ResourceFieldRef == nil) ||
+		(desiredSource.FieldRef == nil) != (actualSource.FieldRef == nil) ||
+		(desiredSource.SecretKeyRef == nil) != (actualSource.SecretKeyRef == nil) ||
+		(desiredSource.ConfigMapKeyRef == nil) != (actualSource.ConfigMapKeyRef == nil) {
+		return false
+	}
+
+	if desiredSource.ResourceFieldRef != nil && !b.isResourceFieldRefEquals(desiredSource.ResourceFieldRef, actualSource.ResourceFieldRef) {
+		return false
+	}
+	if desiredSource.FieldRef != nil && !b.isFieldRefEquals(desiredSource.FieldRef, actualSource.FieldRef) {
+		return false
+	}
+	if desiredSource.SecretKeyRef != nil && !b.isSecretKeyRefEquals(desiredSource.SecretKeyRef, actualSource.SecretKeyRef) {
+		return false
+	}
+	if desiredSource.ConfigMapKeyRef != nil && !b.isConfigMapKeyRefEquals(desiredSource.ConfigMapKeyRef, actualSource.ConfigMapKeyRef) {
+		return false
+	}
+
+	return true
+}
+
+func (b *EnvVarBuilder) isFieldRefEquals(desiredFieldRef, actualFieldRef *coreV1.ObjectFieldSelector) bool {
+	if desiredFieldRef.FieldPath != actualFieldRef.FieldPath {
+		return false
+	}
+
+	// The API server defaults the api version to v1
+	desiredAPIVersion, actualAPIVersion := desiredFieldRef.APIVersion, actualFieldRef.APIVersion
+	if desiredAPIVersion == "" {
+		desiredAPIVersion = "v1"
+	}
+	if actualAPIVersion == "" {
+		actualAPIVersion = "v1"
+	}
+
+	return desiredAPIVersion == actualAPIVersion
+}
+
+// isSecretKeyRefEquals compares the Secret keys. A key that isn't marked optional is required.
+func (b *EnvVarBuilder) isSecretKeyRefEquals(desiredSecretKeyRef, actualSecretKeyRef *coreV1.SecretKeySelector) bool {
+	return desiredSecretKeyRef.Name == actualSecretKeyRef.Name &&
+		desiredSecretKeyRef.Key == actualSecretKeyRef.Key &&
+		IsEnabled(desiredSecretKeyRef.Optional) == IsEnabled(actualSecretKeyRef.Optional)
+}
+
+// isConfigMapKeyRefEquals compares the ConfigMap keys. A key that isn't marked optional is required.
+func (b *EnvVarBuilder) isConfigMapKeyRefEquals(desiredConfigMapKeyRef, actualConfigMapKeyRef *coreV1.ConfigMapKeySelector) bool {
+	return desiredConfigMapKeyRef.Name == actualConfigMapKeyRef.Name &&
+		desiredConfigMapKeyRef.Key == actualConfigMapKeyRef.Key &&
+		IsEnabled(desiredConfigMapKeyRef.Optional) == IsEnabled(actualConfigMapKeyRef.Optional)
+}
+
 func (b *EnvVarBuilder) isResourceFieldRefEquals(desiredResourceFieldRef, actualResourceFieldRef *coreV1.ResourceFieldSelector) bool {
 	if desiredResourceFieldRef.ContainerName != actualResourceFieldRef.ContainerName {
 		return false
@@ -10,13 +10,14 @@ type NodeTermsBuilder struct {
 	requirements []v1.NodeSelectorRequirement
 }
 
-func NewNodeTermsBuilder(podSpec *v1.PodSpec) *NodeTermsBuilder {
+// NewNodeTermsBuilder returns a builder of the node affinity of a pod, which requires Linux nodes of one of the architectures.
+func NewNodeTermsBuilder(podSpec *v1.PodSpec, architectures []string) *NodeTermsBuilder {
 	builder := &NodeTermsBuilder{
 		podSpec:      podSpec,
 		requirements: make([]v1.NodeSelectorRequirement, 0),
 	}
 
-	return builder.withOSRequirement().withArchRequirement()
+	return builder.withOSRequirement().withArchRequirement(architectures)
 }
 
 func (builder *NodeTermsBuilder) withOSRequirement() *NodeTermsBuilder {
@@ -27,11 +28,11 @@ func (builder *NodeTermsBuilder) withOSRequirement() *NodeTermsBuilder {
 	})
 }
 
-func (builder *NodeTermsBuilder) withArchRequirement() *NodeTermsBuilder {
+func (builder *NodeTermsBuilder) withArchRequirement(architectures []string) *NodeTermsBuilder {
 	return builder.WithRequirement(v1.NodeSelectorRequirement{
 		Key:      v1.LabelArchStable,
 		Operator: v1.NodeSelectorOpIn,
-		Values:   []string{"386", "amd64", "amd64p32"},
+		Values:   architectures,
 	})
 }
 
@@ -4,6 +4,7 @@ import (
 	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
 	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
 	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // getImagePullSecrets returns a list of shared pull secrets, defined in the agent spec.
@@ -40,3 +41,43 @@ func objectsDiffer(actual, desired []coreV1.LocalObjectReference) bool {
 	}
 	return false
 }
+
+// DeploymentMaxReplicas returns the most replicas that a deployment may run: the max replicas of its autoscaling while
+// it is autoscaled, and otherwise its replicas count, or the given replicas count when it isn't set.
+func DeploymentMaxReplicas(replicasCount *int32, autoscaling *cbcontainersv1.CBContainersAutoscalingSpec, defaultReplicasCount int32) int32 {
+	if commonState.IsEnabled(autoscaling.Enabled) {
+		return autoscaling.MaxReplicas
+	}
+	if replicasCount != nil {
+		return *replicasCount
+	}
+
+	return defaultReplicasCount
+}
+
+// mutateTopologySpreadConstraints sets the topology spread constraints of the deployment pods. When none are set, the
+// pods of a deployment that may run more than a single replica are spread across the zones and the nodes, unless the
+// affinity of the deployment is set, which decides where the pods are scheduled instead.
+func mutateTopologySpreadConstraints(templatePodSpec *coreV1.PodSpec, topologySpreadConstraints []coreV1.TopologySpreadConstraint, affinity *coreV1.Affinity, maxReplicasCount int32, labelKey, name string) {
+	if len(topologySpreadConstraints) > 0 {
+		templatePodSpec.TopologySpreadConstraints = topologySpreadConstraints
+		return
+	}
+
+	if maxReplicasCount < 2 || (affinity != nil && *affinity != (coreV1.Affinity{})) {
+		templatePodSpec.TopologySpreadConstraints = nil
+		return
+	}
+
+	templatePodSpec.TopologySpreadConstraints = make([]coreV1.TopologySpreadConstraint, 0, 2)
+	for _, topologyKey := range []string{coreV1.LabelTopologyZone, coreV1.LabelHostname} {
+		templatePodSpec.TopologySpreadConstraints = append(templatePodSpec.TopologySpreadConstraints, coreV1.TopologySpreadConstraint{
+			MaxSkew:           1,
+			TopologyKey:       topologyKey,
+			WhenUnsatisfiable: coreV1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{labelKey: name},
+			},
+		})
+	}
+}
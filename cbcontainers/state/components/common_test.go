@@ -0,0 +1,77 @@
+package components_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/agent_applyment"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/capabilities"
+	"github.com/vmware/cbcontainers-operator/controllers"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	testNamespace         = "dataplane"
+	testKubernetesVersion = "v1.29.1"
+)
+
+// testAgentSpec returns the agent spec with its defaults, after it is changed by the test.
+func testAgentSpec(t *testing.T, changeSpec func(agentSpec *cbcontainersv1.CBContainersAgentSpec)) *cbcontainersv1.CBContainersAgentSpec {
+	agentSpec := &cbcontainersv1.CBContainersAgentSpec{
+		Account:     "account",
+		ClusterName: "cluster",
+		Version:     "3.0.0",
+		Gateways: cbcontainersv1.CBContainersGatewaysSpec{
+			ApiGateway:             cbcontainersv1.CBContainersApiGatewaySpec{Host: "api.example.com"},
+			CoreEventsGateway:      cbcontainersv1.CBContainersEventsGatewaySpec{Host: "core.example.com"},
+			HardeningEventsGateway: cbcontainersv1.CBContainersEventsGatewaySpec{Host: "hardening.example.com"},
+			RuntimeEventsGateway:   cbcontainersv1.CBContainersEventsGatewaySpec{Host: "runtime.example.com"},
+		},
+	}
+	if changeSpec != nil {
+		changeSpec(agentSpec)
+	}
+	require.NoError(t, controllers.SetAgentDefaults(agentSpec))
+
+	return agentSpec
+}
+
+func testCapabilitiesProvider(t *testing.T, kubernetesVersion string) capabilities.Provider {
+	apiCapabilities, err := capabilities.ForVersion(kubernetesVersion)
+	require.NoError(t, err)
+	return capabilities.NewStaticProvider(apiCapabilities)
+}
+
+// mutatedK8sObject returns the k8s object that the builder builds from scratch for the agent spec.
+func mutatedK8sObject(builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec) (client.Object, error) {
+	k8sObject := builder.EmptyK8sObject()
+	return k8sObject, builder.MutateK8sObject(k8sObject, agentSpec)
+}
+
+// podTemplate returns the pod template of the Deployment or the DaemonSet.
+func podTemplate(t *testing.T, workload client.Object) *coreV1.PodTemplateSpec {
+	switch workload := workload.(type) {
+	case *appsV1.Deployment:
+		return &workload.Spec.Template
+	case *appsV1.DaemonSet:
+		return &workload.Spec.Template
+	default:
+		require.Failf(t, "unexpected workload", "%T", workload)
+		return nil
+	}
+}
+
+// nodeArchitectures returns the node architectures that the pods are scheduled on by their required node affinity.
+func nodeArchitectures(podSpec *coreV1.PodSpec) []string {
+	for _, term := range podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, requirement := range term.MatchExpressions {
+			if requirement.Key == coreV1.LabelArchStable && requirement.Operator == coreV1.NodeSelectorOpIn {
+				return requirement.Values
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package components
+
+import (
+	"sort"
+
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ConfigChecksumAnnotation holds a checksum of the ConfigMaps and Secrets keys that the pods of a workload consume
+	// through env vars and volumes, so the pods are rolled when any of them changes.
+	ConfigChecksumAnnotation = "operator.containers.carbonblack.io/config-checksum"
+
+	ConfigMapKind = "ConfigMap"
+	SecretKind    = "Secret"
+)
+
+// ConfigReference is a key of a ConfigMap or a Secret that the pods of a workload consume. A reference without a key
+// is to all the keys of the ConfigMap or the Secret.
+type ConfigReference struct {
+	Kind string
+	Name string
+	Key  string
+}
+
+// ConsumedConfigReferences returns the ConfigMaps and Secrets keys that the pod template of the workload consumes,
+// sorted and without duplicates.
+// The enforcer TLS secret isn't returned, as the enforcer pods are rolled by EnforcerTlsChecksumAnnotation when its
+// certificate is renewed, and neither are the image pull secrets, which are only used when the images are pulled.
+func ConsumedConfigReferences(k8sObject client.Object) []ConfigReference {
+	var podSpec *coreV1.PodSpec
+	switch workload := k8sObject.(type) {
+	case *appsV1.Deployment:
+		podSpec = &workload.Spec.Template.Spec
+	case *appsV1.DaemonSet:
+		podSpec = &workload.Spec.Template.Spec
+	default:
+		return nil
+	}
+
+	references := make(map[ConfigReference]struct{})
+	for _, containers := range [][]coreV1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for _, container := range containers {
+			for _, envVar := range container.Env {
+				if envVar.ValueFrom == nil {
+					continue
+				}
+				if keyRef := envVar.ValueFrom.ConfigMapKeyRef; keyRef != nil {
+					references[ConfigReference{Kind: ConfigMapKind, Name: keyRef.Name, Key: keyRef.Key}] = struct{}{}
+				}
+				if keyRef := envVar.ValueFrom.SecretKeyRef; keyRef != nil {
+					references[ConfigReference{Kind: SecretKind, Name: keyRef.Name, Key: keyRef.Key}] = struct{}{}
+				}
+			}
+			for _, envFrom := range container.EnvFrom {
+				if envFrom.ConfigMapRef != nil {
+					references[ConfigReference{Kind: ConfigMapKind, Name: envFrom.ConfigMapRef.Name}] = struct{}{}
+				}
+				if envFrom.SecretRef != nil {
+					references[ConfigReference{Kind: SecretKind, Name: envFrom.SecretRef.Name}] = struct{}{}
+				}
+			}
+		}
+	}
+
+	for _, volume := range podSpec.Volumes {
+		if volume.ConfigMap != nil {
+			addVolumeConfigReferences(references, ConfigMapKind, volume.ConfigMap.Name, volume.ConfigMap.Items)
+		}
+		if volume.Secret != nil && volume.Secret.SecretName != EnforcerTlsName {
+			addVolumeConfigReferences(references, SecretKind, volume.Secret.SecretName, volume.Secret.Items)
+		}
+	}
+
+	sortedReferences := make([]ConfigReference, 0, len(references))
+	for reference := range references {
+		sortedReferences = append(sortedReferences, reference)
+	}
+	sort.Slice(sortedReferences, func(i, j int) bool {
+		a, b := sortedReferences[i], sortedReferences[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Key < b.Key
+	})
+
+	return sortedReferences
+}
+
+// addVolumeConfigReferences adds the keys that a volume projects, or all the keys when it projects all of them.
+func addVolumeConfigReferences(references map[ConfigReference]struct{}, kind, name string, items []coreV1.KeyToPath) {
+	if len(items) == 0 {
+		references[ConfigReference{Kind: kind, Name: name}] = struct{}{}
+		return
+	}
+
+	for _, item := range items {
+		references[ConfigReference{Kind: kind, Name: name, Key: item.Key}] = struct{}{}
+	}
+}
+
+// mutateConfigChecksum sets the checksum of the consumed ConfigMaps and Secrets keys on the pod template, or removes
+// it when there is no checksum, as the pods don't consume any of them.
+func mutateConfigChecksum(templateMeta *metav1.ObjectMeta, configChecksum string) {
+	if configChecksum == "" {
+		delete(templateMeta.Annotations, ConfigChecksumAnnotation)
+		return
+	}
+
+	if templateMeta.Annotations == nil {
+		templateMeta.Annotations = make(map[string]string)
+	}
+	templateMeta.Annotations[ConfigChecksumAnnotation] = configChecksum
+}
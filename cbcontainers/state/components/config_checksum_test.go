@@ -0,0 +1,74 @@
+package components_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/agent_applyment"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// configChecksumBuilder is a workload builder whose pods are rolled when the config they consume changes.
+type configChecksumBuilder interface {
+	agent_applyment.AgentComponentBuilder
+	UpdateConfigChecksum(configChecksum string)
+}
+
+func TestWorkloadsSetTheConfigChecksumOnThePodTemplates(t *testing.T) {
+	builders := map[string]func() configChecksumBuilder{
+		components.MonitorName:       func() configChecksumBuilder { return components.NewMonitorDeploymentK8sObject(testNamespace) },
+		components.StateReporterName: func() configChecksumBuilder { return components.NewStateReporterDeploymentK8sObject(testNamespace) },
+		components.EnforcerName:      func() configChecksumBuilder { return components.NewEnforcerDeploymentK8sObject(testNamespace) },
+		components.ResolverName:      func() configChecksumBuilder { return components.NewResolverDeploymentK8sObject(testNamespace) },
+		components.ImageScanningReporterName: func() configChecksumBuilder {
+			return components.NewImageScanningReporterDeploymentK8sObject(testNamespace)
+		},
+		components.DaemonSetName: func() configChecksumBuilder { return components.NewSensorDaemonSetK8sObject(testNamespace) },
+	}
+
+	for name, newBuilder := range builders {
+		t.Run(name, func(t *testing.T) {
+			agentSpec := testAgentSpec(t, nil)
+			builder := newBuilder()
+			builder.UpdateConfigChecksum("checksum")
+
+			workload, err := mutatedK8sObject(builder, agentSpec)
+			require.NoError(t, err)
+			require.Equal(t, "checksum", podTemplate(t, workload).Annotations[components.ConfigChecksumAnnotation])
+
+			// Without a checksum, as the pods don't consume any ConfigMaps or Secrets keys, it is removed
+			builder.UpdateConfigChecksum("")
+			require.NoError(t, builder.MutateK8sObject(workload, agentSpec))
+			require.NotContains(t, podTemplate(t, workload).Annotations, components.ConfigChecksumAnnotation)
+		})
+	}
+}
+
+func TestConsumedConfigReferences(t *testing.T) {
+	t.Run("Should return the keys of the Secrets and ConfigMaps that the extra env vars are sourced from", func(t *testing.T) {
+		agentSpec := testAgentSpec(t, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+			agentSpec.Components.Basic.StateReporter.ExtraEnv = []coreV1.EnvVar{
+				{Name: "CNDR_TOKEN", ValueFrom: &coreV1.EnvVarSource{SecretKeyRef: &coreV1.SecretKeySelector{LocalObjectReference: coreV1.LocalObjectReference{Name: "cndr-secret"}, Key: "token"}}},
+				{Name: "CNDR_URL", ValueFrom: &coreV1.EnvVarSource{ConfigMapKeyRef: &coreV1.ConfigMapKeySelector{LocalObjectReference: coreV1.LocalObjectReference{Name: "cndr-config"}, Key: "url"}}},
+			}
+		})
+
+		deployment, err := mutatedK8sObject(components.NewStateReporterDeploymentK8sObject(testNamespace), agentSpec)
+
+		require.NoError(t, err)
+		references := components.ConsumedConfigReferences(deployment)
+		require.Contains(t, references, components.ConfigReference{Kind: components.SecretKind, Name: "cndr-secret", Key: "token"})
+		require.Contains(t, references, components.ConfigReference{Kind: components.ConfigMapKind, Name: "cndr-config", Key: "url"})
+	})
+
+	t.Run("Should not return the enforcer TLS secret, as the enforcer pods are rolled by its own checksum", func(t *testing.T) {
+		deployment, err := mutatedK8sObject(components.NewEnforcerDeploymentK8sObject(testNamespace), testAgentSpec(t, nil))
+
+		require.NoError(t, err)
+		for _, reference := range components.ConsumedConfigReferences(deployment) {
+			require.NotEqual(t, components.EnforcerTlsName, reference.Name)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package components
+
+import (
+	"fmt"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// EnforcerCertificateName is the name of the cert-manager Certificate that the enforcer webhook certificates are issued for.
+	EnforcerCertificateName = EnforcerName
+
+	// CertManagerInjectCAFromAnnotation makes the cert-manager CA injector set the caBundle of the webhooks from a Certificate.
+	CertManagerInjectCAFromAnnotation = "cert-manager.io/inject-ca-from"
+	// CertManagerCertificateNameAnnotation is set by cert-manager on the secrets it issues certificates to.
+	CertManagerCertificateNameAnnotation = "cert-manager.io/certificate-name"
+)
+
+var (
+	// CertificateGroupVersionKind is the kind of the cert-manager Certificate.
+	// It is used through unstructured objects, so the operator doesn't depend on the cert-manager API being installed.
+	CertificateGroupVersionKind = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+)
+
+type EnforcerCertificateK8sObject struct {
+	// Namespace is the Namespace in which the Certificate will be created.
+	Namespace string
+}
+
+func NewEnforcerCertificateK8sObject(namespace string) *EnforcerCertificateK8sObject {
+	return &EnforcerCertificateK8sObject{
+		Namespace: namespace,
+	}
+}
+
+func (obj *EnforcerCertificateK8sObject) EmptyK8sObject() client.Object {
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(CertificateGroupVersionKind)
+	return certificate
+}
+
+func (obj *EnforcerCertificateK8sObject) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: EnforcerCertificateName, Namespace: obj.Namespace}
+}
+
+func (obj *EnforcerCertificateK8sObject) MutateK8sObject(k8sObject client.Object, agentSpec *cbcontainersv1.CBContainersAgentSpec) error {
+	certificate, ok := k8sObject.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("expected Certificate K8s object")
+	}
+
+	certManager := agentSpec.Components.Basic.Enforcer.CertManager
+	if certManager == nil {
+		return fmt.Errorf("cert-manager is not configured for the enforcer")
+	}
+
+	// The fields are set one by one, so fields that cert-manager defaults are kept
+	serviceName := fmt.Sprintf("%s.%s.svc", EnforcerName, obj.Namespace)
+	fields := []struct {
+		value interface{}
+		path  []string
+	}{
+		{EnforcerTlsName, []string{"spec", "secretName"}},
+		{serviceName, []string{"spec", "commonName"}},
+		{[]interface{}{serviceName}, []string{"spec", "dnsNames"}},
+		{certManager.IssuerRef.Name, []string{"spec", "issuerRef", "name"}},
+		{certManager.IssuerRef.Kind, []string{"spec", "issuerRef", "kind"}},
+		{certManager.IssuerRef.Group, []string{"spec", "issuerRef", "group"}},
+	}
+	for _, field := range fields {
+		if err := unstructured.SetNestedField(certificate.Object, field.value, field.path...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mutateCAInjection makes the cert-manager CA injector set the caBundle of the webhooks when the enforcer certificates
+// are issued by cert-manager.
+func mutateCAInjection(webhookConfiguration client.Object, enforcer *cbcontainersv1.CBContainersEnforcerSpec, namespace string) {
+	annotations := webhookConfiguration.GetAnnotations()
+	if enforcer.CertManager == nil {
+		if _, ok := annotations[CertManagerInjectCAFromAnnotation]; ok {
+			delete(annotations, CertManagerInjectCAFromAnnotation)
+			webhookConfiguration.SetAnnotations(annotations)
+		}
+		return
+	}
+
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[CertManagerInjectCAFromAnnotation] = fmt.Sprintf("%s/%s", namespace, EnforcerCertificateName)
+	webhookConfiguration.SetAnnotations(annotations)
+}
@@ -1,9 +1,12 @@
 package components
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 
 	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment"
 	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
 	appsV1 "k8s.io/api/apps/v1"
@@ -24,6 +27,9 @@ const (
 	DesiredTlsSecretVolumeMountReadOnly = true
 
 	EnforcerLabelKey = "app.kubernetes.io/name"
+
+	// EnforcerTlsChecksumAnnotation holds a checksum of the enforcer certificate, so the enforcer pods are rolled when it is renewed.
+	EnforcerTlsChecksumAnnotation = "operator.containers.carbonblack.io/tls-checksum"
 )
 
 var (
@@ -38,8 +44,13 @@ var (
 )
 
 type EnforcerDeploymentK8sObject struct {
+	tlsSecretValues *models.TlsSecretValues
+
 	// Namespace is the Namespace in which the Deployment will be created.
 	Namespace string
+
+	// configChecksum is the checksum of the ConfigMaps and Secrets keys that the pods consume.
+	configChecksum string
 }
 
 func NewEnforcerDeploymentK8sObject(namespace string) *EnforcerDeploymentK8sObject {
@@ -48,6 +59,16 @@ func NewEnforcerDeploymentK8sObject(namespace string) *EnforcerDeploymentK8sObje
 	}
 }
 
+// UpdateConfigChecksum sets the checksum of the ConfigMaps and Secrets keys that the pods consume, which rolls the
+// pods when it changes.
+func (obj *EnforcerDeploymentK8sObject) UpdateConfigChecksum(configChecksum string) {
+	obj.configChecksum = configChecksum
+}
+
+func (obj *EnforcerDeploymentK8sObject) UpdateTlsSecretValues(tlsSecretValues models.TlsSecretValues) {
+	obj.tlsSecretValues = &tlsSecretValues
+}
+
 func (obj *EnforcerDeploymentK8sObject) EmptyK8sObject() client.Object {
 	return &appsV1.Deployment{}
 }
@@ -74,7 +95,10 @@ func (obj *EnforcerDeploymentK8sObject) MutateK8sObject(k8sObject client.Object,
 		deployment.Spec.Selector = &metav1.LabelSelector{}
 	}
 
-	deployment.Spec.Replicas = enforcer.ReplicasCount
+	// While autoscaling, the replicas are set by the HorizontalPodAutoscaler
+	if commonState.IsDisabled(enforcer.Autoscaling.Enabled) {
+		deployment.Spec.Replicas = enforcer.ReplicasCount
+	}
 	deployment.ObjectMeta.Labels = desiredLabels
 	deployment.Spec.Selector.MatchLabels = desiredLabels
 	deployment.Spec.Template.ObjectMeta.Labels = desiredLabels
@@ -85,12 +109,19 @@ func (obj *EnforcerDeploymentK8sObject) MutateK8sObject(k8sObject client.Object,
 		deployment.Spec.Template.Spec.ImagePullSecrets = desiredImagePullSecrets
 	}
 	obj.mutateAnnotations(deployment, enforcer)
-	obj.mutateVolumes(&deployment.Spec.Template.Spec)
+	obj.mutateVolumes(&deployment.Spec.Template.Spec, enforcer)
 	obj.mutateAffinityAndNodeSelector(&deployment.Spec.Template.Spec, enforcer)
+	mutateTopologySpreadConstraints(&deployment.Spec.Template.Spec, enforcer.TopologySpreadConstraints, enforcer.Affinity, DeploymentMaxReplicas(enforcer.ReplicasCount, &enforcer.Autoscaling, 1), EnforcerLabelKey, EnforcerName)
 	obj.mutateContainersList(&deployment.Spec.Template.Spec, agentSpec)
-	commonState.NewNodeTermsBuilder(&deployment.Spec.Template.Spec).Build()
+	architectures, err := commonState.GetArchitectures(&agentSpec.Components.Settings, enforcer.Architectures, &enforcer.Image)
+	if err != nil {
+		return err
+	}
+	commonState.NewNodeTermsBuilder(&deployment.Spec.Template.Spec, architectures).Build()
+
+	mutateConfigChecksum(&deployment.Spec.Template.ObjectMeta, obj.configChecksum)
 
-	return nil
+	return mutatePodTemplatePatch(&deployment.ObjectMeta, &deployment.Spec.Template, enforcer.PodTemplatePatch, EnforcerName)
 }
 
 func (obj *EnforcerDeploymentK8sObject) mutateAnnotations(deployment *appsV1.Deployment, enforcerSpec *cbcontainersv1.CBContainersEnforcerSpec) {
@@ -109,9 +140,19 @@ func (obj *EnforcerDeploymentK8sObject) mutateAnnotations(deployment *appsV1.Dep
 		"prometheus.io/port":   fmt.Sprint(enforcerSpec.Prometheus.Port),
 	})
 	applyment.EnforceMapContains(deployment.Spec.Template.ObjectMeta.Annotations, enforcerSpec.PodTemplateAnnotations)
+
+	if obj.tlsSecretValues != nil && len(obj.tlsSecretValues.SignedCert) > 0 {
+		deployment.Spec.Template.ObjectMeta.Annotations[EnforcerTlsChecksumAnnotation] = EnforcerTlsChecksum(*obj.tlsSecretValues)
+	}
 }
 
-func (obj *EnforcerDeploymentK8sObject) mutateVolumes(templatePodSpec *coreV1.PodSpec) {
+// EnforcerTlsChecksum returns the checksum of the enforcer certificate that is set on the enforcer pods.
+func EnforcerTlsChecksum(tlsSecretValues models.TlsSecretValues) string {
+	checksum := sha256.Sum256(tlsSecretValues.SignedCert)
+	return hex.EncodeToString(checksum[:])
+}
+
+func (obj *EnforcerDeploymentK8sObject) mutateVolumes(templatePodSpec *coreV1.PodSpec, enforcerSpec *cbcontainersv1.CBContainersEnforcerSpec) {
 	if templatePodSpec.Volumes == nil || len(templatePodSpec.Volumes) != 2 {
 		templatePodSpec.Volumes = make([]coreV1.Volume, 0)
 	}
@@ -123,12 +164,22 @@ func (obj *EnforcerDeploymentK8sObject) mutateVolumes(templatePodSpec *coreV1.Po
 	templatePodSpec.Volumes[tlsSecretVolumeIndex].Secret.SecretName = EnforcerTlsName
 	templatePodSpec.Volumes[tlsSecretVolumeIndex].Secret.DefaultMode = &DesiredTlsSecretVolumeDecimalDefaultMode
 	templatePodSpec.Volumes[tlsSecretVolumeIndex].Secret.Optional = &DesiredTlsSecretVolumeOptionalValue
+	templatePodSpec.Volumes[tlsSecretVolumeIndex].Secret.Items = nil
+	if enforcerSpec.CertManager != nil {
+		// The enforcer reads the certificates from the file names of the secret that the operator creates
+		templatePodSpec.Volumes[tlsSecretVolumeIndex].Secret.Items = []coreV1.KeyToPath{
+			{Key: models.CaCertKey, Path: models.CaCertKey},
+			{Key: models.CertManagerSignedCertKey, Path: models.SignedCertKey},
+			{Key: models.CertManagerKeyKey, Path: models.KeyKey},
+		}
+	}
 
 	commonState.MutateVolumesToIncludeRootCAsVolume(templatePodSpec)
 }
 
 func (obj *EnforcerDeploymentK8sObject) mutateAffinityAndNodeSelector(templatePodSpec *coreV1.PodSpec, enforcerSpec *cbcontainersv1.CBContainersEnforcerSpec) {
-	templatePodSpec.Affinity = enforcerSpec.Affinity
+	// The node terms are added to a copy of the affinity, so the affinity of the spec is kept as it was set
+	templatePodSpec.Affinity = enforcerSpec.Affinity.DeepCopy()
 	templatePodSpec.NodeSelector = enforcerSpec.NodeSelector
 }
 
@@ -170,6 +221,7 @@ func (obj *EnforcerDeploymentK8sObject) mutateEnforcerEnvVars(container *coreV1.
 		WithEventsGateway(&agentSpec.Gateways.HardeningEventsGateway).
 		WithCustom(customEnvs...).
 		WithSpec(enforcerSpec.Env).
+		WithExtraEnv(enforcerSpec.ExtraEnv).
 		WithProxySettings(agentSpec.Components.Settings.Proxy)
 	commonState.MutateEnvVars(container, envVarBuilder)
 }
@@ -0,0 +1,204 @@
+package components_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestEnforcerDeploymentArchitectures(t *testing.T) {
+	tests := map[string]struct {
+		changeSpec            func(agentSpec *cbcontainersv1.CBContainersAgentSpec)
+		expectedArchitectures []string
+		expectedError         string
+	}{
+		"With default spec, should schedule the pods on the default architectures": {
+			expectedArchitectures: commonState.DefaultArchitectures,
+		},
+		"When the settings have architectures that the image doesn't run on, should schedule the pods on the ones it runs on": {
+			changeSpec: func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.Settings.Architectures = []string{"amd64", "arm64"}
+			},
+			expectedArchitectures: []string{"amd64"},
+		},
+		"When the image is a multi-architecture image, should schedule the pods on the architectures of the settings": {
+			changeSpec: func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.Settings.Architectures = []string{"amd64", "arm64"}
+				agentSpec.Components.Basic.Enforcer.Image.MultiArch = true
+			},
+			expectedArchitectures: []string{"amd64", "arm64"},
+		},
+		"When the enforcer has architectures of its own, should schedule the pods on them instead of the settings ones": {
+			changeSpec: func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.Settings.Architectures = []string{"amd64", "arm64"}
+				agentSpec.Components.Basic.Enforcer.Architectures = []string{"arm64"}
+				agentSpec.Components.Basic.Enforcer.Image.MultiArch = true
+			},
+			expectedArchitectures: []string{"arm64"},
+		},
+		"When the image doesn't run on any of the architectures, should return an error": {
+			changeSpec: func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.Basic.Enforcer.Architectures = []string{"arm64"}
+			},
+			expectedError: "set the image as a multi-architecture image",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			deployment, err := mutatedK8sObject(components.NewEnforcerDeploymentK8sObject(testNamespace), testAgentSpec(t, test.changeSpec))
+
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expectedArchitectures, nodeArchitectures(&podTemplate(t, deployment).Spec))
+		})
+	}
+}
+
+func TestEnforcerDeploymentReplicas(t *testing.T) {
+	replicasCount := int32(3)
+
+	tests := map[string]struct {
+		autoscalingEnabled bool
+		expectedReplicas   *int32
+	}{
+		"When the autoscaling is disabled, should set the replicas count of the spec": {
+			expectedReplicas: &replicasCount,
+		},
+		"When the autoscaling is enabled, should leave the replicas to the HorizontalPodAutoscaler": {
+			autoscalingEnabled: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			agentSpec := testAgentSpec(t, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.Basic.Enforcer.ReplicasCount = &replicasCount
+				agentSpec.Components.Basic.Enforcer.Autoscaling.Enabled = &test.autoscalingEnabled
+				agentSpec.Components.Basic.Enforcer.Autoscaling.MaxReplicas = 10
+			})
+
+			deployment, err := mutatedK8sObject(components.NewEnforcerDeploymentK8sObject(testNamespace), agentSpec)
+
+			require.NoError(t, err)
+			require.Equal(t, test.expectedReplicas, deployment.(*appsV1.Deployment).Spec.Replicas)
+		})
+	}
+}
+
+func TestEnforcerDeploymentTopologySpreadConstraints(t *testing.T) {
+	singleReplica, multipleReplicas := int32(1), int32(3)
+
+	tests := map[string]struct {
+		changeEnforcer            func(enforcer *cbcontainersv1.CBContainersEnforcerSpec)
+		expectedTopologyKeys      []string
+		expectedWhenUnsatisfiable coreV1.UnsatisfiableConstraintAction
+	}{
+		"When the enforcer runs a single replica, should not spread it": {
+			changeEnforcer: func(enforcer *cbcontainersv1.CBContainersEnforcerSpec) {
+				enforcer.ReplicasCount = &singleReplica
+			},
+		},
+		"When the enforcer runs multiple replicas, should spread them across zones and nodes": {
+			changeEnforcer: func(enforcer *cbcontainersv1.CBContainersEnforcerSpec) {
+				enforcer.ReplicasCount = &multipleReplicas
+			},
+			expectedTopologyKeys:      []string{coreV1.LabelTopologyZone, coreV1.LabelHostname},
+			expectedWhenUnsatisfiable: coreV1.ScheduleAnyway,
+		},
+		"When the enforcer runs multiple replicas with an affinity, should leave the scheduling to the affinity": {
+			changeEnforcer: func(enforcer *cbcontainersv1.CBContainersEnforcerSpec) {
+				enforcer.ReplicasCount = &multipleReplicas
+				enforcer.Affinity = &coreV1.Affinity{PodAntiAffinity: &coreV1.PodAntiAffinity{
+					PreferredDuringSchedulingIgnoredDuringExecution: []coreV1.WeightedPodAffinityTerm{
+						{Weight: 1, PodAffinityTerm: coreV1.PodAffinityTerm{TopologyKey: coreV1.LabelHostname}},
+					},
+				}}
+			},
+		},
+		"When the topology spread constraints are set in the spec, should use them": {
+			changeEnforcer: func(enforcer *cbcontainersv1.CBContainersEnforcerSpec) {
+				enforcer.TopologySpreadConstraints = []coreV1.TopologySpreadConstraint{
+					{MaxSkew: 1, TopologyKey: coreV1.LabelTopologyRegion, WhenUnsatisfiable: coreV1.DoNotSchedule},
+				}
+			},
+			expectedTopologyKeys:      []string{coreV1.LabelTopologyRegion},
+			expectedWhenUnsatisfiable: coreV1.DoNotSchedule,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			agentSpec := testAgentSpec(t, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				test.changeEnforcer(&agentSpec.Components.Basic.Enforcer)
+			})
+
+			deployment, err := mutatedK8sObject(components.NewEnforcerDeploymentK8sObject(testNamespace), agentSpec)
+
+			require.NoError(t, err)
+			var topologyKeys []string
+			for _, topologySpreadConstraint := range podTemplate(t, deployment).Spec.TopologySpreadConstraints {
+				topologyKeys = append(topologyKeys, topologySpreadConstraint.TopologyKey)
+				require.Equal(t, test.expectedWhenUnsatisfiable, topologySpreadConstraint.WhenUnsatisfiable)
+			}
+			require.Equal(t, test.expectedTopologyKeys, topologyKeys)
+		})
+	}
+}
+
+func TestEnforcerDeploymentPodTemplatePatch(t *testing.T) {
+	tests := map[string]struct {
+		podTemplatePatch       string
+		expectedContainerNames []string
+		expectedError          string
+	}{
+		"When the patch adds host aliases and a sidecar container, should patch the pod template": {
+			podTemplatePatch:       `{"spec":{"hostAliases":[{"ip":"10.0.0.1","hostnames":["proxy.internal"]}],"containers":[{"name":"log-shipper","image":"fluent/fluent-bit:3.0"}]}}`,
+			expectedContainerNames: []string{components.EnforcerName, "log-shipper"},
+		},
+		"When the patch adds a privileged container, should return an error": {
+			podTemplatePatch: `{"spec":{"containers":[{"name":"debug","image":"busybox","securityContext":{"privileged":true}}]}}`,
+			expectedError:    "the debug container can't be privileged",
+		},
+		"When the patch changes the service account, should return an error": {
+			podTemplatePatch: `{"spec":{"serviceAccountName":"default"}}`,
+			expectedError:    "the service account can't be changed",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			agentSpec := testAgentSpec(t, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.Basic.Enforcer.PodTemplatePatch = &runtime.RawExtension{Raw: []byte(test.podTemplatePatch)}
+			})
+
+			k8sObject, err := mutatedK8sObject(components.NewEnforcerDeploymentK8sObject(testNamespace), agentSpec)
+
+			if test.expectedError != "" {
+				var patchErr *components.PodTemplatePatchError
+				require.ErrorAs(t, err, &patchErr)
+				require.Equal(t, components.EnforcerName, patchErr.WorkloadName)
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			deployment := k8sObject.(*appsV1.Deployment)
+			require.Equal(t, []string{"proxy.internal"}, deployment.Spec.Template.Spec.HostAliases[0].Hostnames)
+			var containerNames []string
+			for _, container := range deployment.Spec.Template.Spec.Containers {
+				containerNames = append(containerNames, container.Name)
+			}
+			require.ElementsMatch(t, test.expectedContainerNames, containerNames)
+			require.NotEmpty(t, deployment.Annotations[components.PodTemplateChecksumAnnotation])
+		})
+	}
+}
@@ -2,14 +2,13 @@ package components
 
 import (
 	"fmt"
-	"reflect"
 
 	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/adapters"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/capabilities"
 	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/utils"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -27,17 +26,17 @@ var (
 )
 
 type EnforcerMutatingWebhookK8sObject struct {
-	tlsSecretValues *models.TlsSecretValues
-	kubeletVersion  string
+	tlsSecretValues      *models.TlsSecretValues
+	capabilitiesProvider capabilities.Provider
 
 	// ServiceNamespace is the namespace of the Service that serves the validating webhook.
 	ServiceNamespace string
 }
 
-func NewEnforcerMutatingWebhookK8sObject(serviceNamespace, kubeletVersion string) *EnforcerMutatingWebhookK8sObject {
+func NewEnforcerMutatingWebhookK8sObject(serviceNamespace string, capabilitiesProvider capabilities.Provider) *EnforcerMutatingWebhookK8sObject {
 	return &EnforcerMutatingWebhookK8sObject{
-		kubeletVersion:   kubeletVersion,
-		ServiceNamespace: serviceNamespace,
+		capabilitiesProvider: capabilitiesProvider,
+		ServiceNamespace:     serviceNamespace,
 	}
 }
 
@@ -46,7 +45,7 @@ func (obj *EnforcerMutatingWebhookK8sObject) UpdateTlsSecretValues(tlsSecretValu
 }
 
 func (obj *EnforcerMutatingWebhookK8sObject) EmptyK8sObject() client.Object {
-	return adapters.EmptyMutatingWebhookConfigForVersion(obj.kubeletVersion)
+	return adapters.EmptyMutatingWebhookConfigForCapabilities(obj.capabilitiesProvider.Capabilities())
 }
 
 func (obj *EnforcerMutatingWebhookK8sObject) NamespacedName() types.NamespacedName {
@@ -59,16 +58,19 @@ func (obj *EnforcerMutatingWebhookK8sObject) MutateK8sObject(k8sObject client.Ob
 		return fmt.Errorf("expected a valid instance of ValidatingWebhookConfiguration")
 	}
 
-	if obj.tlsSecretValues == nil {
+	enforcer := &agentSpec.Components.Basic.Enforcer
+	// With cert-manager, the caBundle is set by the cert-manager CA injector
+	if enforcer.CertManager == nil && obj.tlsSecretValues == nil {
 		return fmt.Errorf("tls secret values weren't provided")
 	}
 
-	enforcer := &agentSpec.Components.Basic.Enforcer
 	obj.mutateWebhookConfigurationLabels(webhookConfiguration, enforcer)
-	return obj.mutateWebhooks(webhookConfiguration, enforcer)
+	mutateCAInjection(k8sObject, enforcer, obj.ServiceNamespace)
+	apiCapabilities := obj.capabilitiesProvider.Capabilities()
+	return obj.mutateWebhooks(webhookConfiguration, enforcer, apiCapabilities, getAdmissionReviewVersions(agentSpec, apiCapabilities))
 }
 
-func (obj *EnforcerMutatingWebhookK8sObject) mutateWebhooks(webhookConfiguration adapters.WebhookConfigurationAdapter, enforcer *cbcontainersv1.CBContainersEnforcerSpec) error {
+func (obj *EnforcerMutatingWebhookK8sObject) mutateWebhooks(webhookConfiguration adapters.WebhookConfigurationAdapter, enforcer *cbcontainersv1.CBContainersEnforcerSpec, apiCapabilities *capabilities.Capabilities, admissionReviewVersions []string) error {
 	var resourcesWebhookObj adapters.WebhookAdapter
 
 	initializeWebhooks := false
@@ -83,7 +85,7 @@ func (obj *EnforcerMutatingWebhookK8sObject) mutateWebhooks(webhookConfiguration
 
 	if initializeWebhooks {
 		webhooks := []adapters.WebhookAdapter{
-			adapters.EmptyMutatingWebhookAdapterForVersion(obj.kubeletVersion),
+			adapters.EmptyMutatingWebhookAdapterForCapabilities(apiCapabilities),
 		}
 		updatedWebhooks, err := webhookConfiguration.SetWebhooks(webhooks)
 		if err != nil {
@@ -93,7 +95,10 @@ func (obj *EnforcerMutatingWebhookK8sObject) mutateWebhooks(webhookConfiguration
 		resourcesWebhookObj = updatedWebhooks[0]
 	}
 
-	obj.mutateResourcesWebhook(resourcesWebhookObj, enforcer.WebhookTimeoutSeconds, enforcer.FailurePolicy)
+	obj.mutateResourcesWebhook(resourcesWebhookObj, enforcer, apiCapabilities, admissionReviewVersions)
+	if enforcer.CertManager == nil {
+		resourcesWebhookObj.SetCABundle(obj.tlsSecretValues.CaBundle())
+	}
 	return nil
 }
 
@@ -107,70 +112,30 @@ func (obj *EnforcerMutatingWebhookK8sObject) findWebhookByName(webhooks []adapte
 	return nil, false
 }
 
-func (obj *EnforcerMutatingWebhookK8sObject) mutateResourcesWebhook(resourcesWebhook adapters.WebhookAdapter, timeoutSeconds int32, failurePolicy string) {
+func (obj *EnforcerMutatingWebhookK8sObject) mutateResourcesWebhook(resourcesWebhook adapters.WebhookAdapter, enforcer *cbcontainersv1.CBContainersEnforcerSpec, apiCapabilities *capabilities.Capabilities, admissionReviewVersions []string) {
+	webhooksSpec := &enforcer.Webhooks
 	resourcesWebhook.SetName(MutatingWebhookName)
-	resourcesWebhook.SetFailurePolicy(failurePolicy)
+	resourcesWebhook.SetFailurePolicy(enforcer.FailurePolicy)
 	resourcesWebhook.SetSideEffects(MutatingWebhookSideEffect)
-	namespaceSelector := obj.getResourcesNamespaceSelector(resourcesWebhook.GetNamespaceSelector())
-	resourcesWebhook.SetNamespaceSelector(namespaceSelector)
-	obj.mutateMutatingWebhooksRules(resourcesWebhook)
-	if obj.kubeletVersion == "" || obj.kubeletVersion >= "v1.14" {
-		resourcesWebhook.SetTimeoutSeconds(timeoutSeconds)
-		resourcesWebhook.SetAdmissionReviewVersions([]string{"v1beta1"})
+	resourcesWebhook.SetNamespaceSelector(getWebhookNamespaceSelector([]string{obj.ServiceNamespace, commonState.KubeSystemNamespaceName}, webhooksSpec))
+	obj.mutateMutatingWebhooksRules(resourcesWebhook, webhooksSpec)
+	if apiCapabilities.HasWebhookTimeouts() {
+		resourcesWebhook.SetTimeoutSeconds(enforcer.WebhookTimeoutSeconds)
+		resourcesWebhook.SetAdmissionReviewVersions(admissionReviewVersions)
 	}
-	if obj.kubeletVersion == "" || obj.kubeletVersion >= "v1.15" {
+	if apiCapabilities.HasWebhookMatchPolicy() {
 		resourcesWebhook.SetMatchPolicy(WebhookMatchPolicy)
+		resourcesWebhook.SetObjectSelector(getWebhookObjectSelector(webhooksSpec))
+	}
+	if apiCapabilities.HasWebhookMatchConditions() {
+		resourcesWebhook.SetMatchConditions(getWebhookMatchConditions(webhooksSpec))
 	}
-	resourcesWebhook.SetCABundle(obj.tlsSecretValues.CaCert)
 	resourcesWebhook.SetServiceName(EnforcerName)
 	resourcesWebhook.SetServiceNamespace(obj.ServiceNamespace)
 	resourcesWebhook.SetServicePath(&MutatingWebhookPath)
 }
 
-func (obj *EnforcerMutatingWebhookK8sObject) getResourcesNamespaceSelector(selector *metav1.LabelSelector) *metav1.LabelSelector {
-	octarineIgnore := metav1.LabelSelectorRequirement{
-		Key:      "octarine",
-		Operator: metav1.LabelSelectorOpNotIn,
-		Values:   []string{"ignore"},
-	}
-
-	ignoredNamespaces := metav1.LabelSelectorRequirement{
-		// See https://kubernetes.io/docs/reference/labels-annotations-taints/#kubernetes-io-metadata-name
-		// This is the label that always matches the namespace name
-		// We can't filter directly by namespace otherwise
-		Key:      "kubernetes.io/metadata.name",
-		Operator: metav1.LabelSelectorOpNotIn,
-		Values:   []string{obj.ServiceNamespace, commonState.KubeSystemNamespaceName},
-	}
-
-	initializeLabelSelector := false
-	if selector == nil || selector.MatchExpressions == nil || len(selector.MatchExpressions) != 2 {
-		initializeLabelSelector = true
-	} else {
-		octarineIgnoreFound := false
-		cbContainersNamespaceFound := false
-		for _, requirement := range selector.MatchExpressions {
-			if reflect.DeepEqual(requirement, octarineIgnore) {
-				octarineIgnoreFound = true
-			}
-			if reflect.DeepEqual(requirement, ignoredNamespaces) {
-				cbContainersNamespaceFound = true
-			}
-		}
-		initializeLabelSelector = !octarineIgnoreFound || !cbContainersNamespaceFound
-	}
-
-	if initializeLabelSelector {
-		return &metav1.LabelSelector{
-			MatchExpressions: []metav1.LabelSelectorRequirement{octarineIgnore, ignoredNamespaces},
-		}
-	}
-
-	return selector
-
-}
-
-func (obj *EnforcerMutatingWebhookK8sObject) mutateMutatingWebhooksRules(webhook adapters.WebhookAdapter) {
+func (obj *EnforcerMutatingWebhookK8sObject) mutateMutatingWebhooksRules(webhook adapters.WebhookAdapter, webhooksSpec *cbcontainersv1.CBContainersEnforcerWebhooksSpec) {
 	rules := webhook.GetAdmissionRules()
 	if rules == nil || len(rules) != 1 {
 		rules = make([]adapters.AdmissionRuleAdapter, 1)
@@ -180,14 +145,14 @@ func (obj *EnforcerMutatingWebhookK8sObject) mutateMutatingWebhooksRules(webhook
 	rules[0].APIVersions = []string{"*"}
 	rules[0].APIGroups = []string{"*"}
 
-	expectedResourcesList := obj.getResourcesList()
+	expectedResourcesList := getWebhookResources(obj.getDefaultResourcesList(), webhooksSpec)
 	if !utils.StringsSlicesHaveSameItems(rules[0].Resources, expectedResourcesList) {
 		rules[0].Resources = expectedResourcesList
 	}
 	webhook.SetAdmissionRules(rules)
 }
 
-func (obj *EnforcerMutatingWebhookK8sObject) getResourcesList() []string {
+func (obj *EnforcerMutatingWebhookK8sObject) getDefaultResourcesList() []string {
 	return []string{
 		"namespaces",
 		"pods",
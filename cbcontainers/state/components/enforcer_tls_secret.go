@@ -20,6 +20,7 @@ type TlsSecretsValuesCreator interface {
 
 type EnforcerTlsK8sObject struct {
 	tlsSecretsValuesCreator TlsSecretsValuesCreator
+	tlsSecretValues         *models.TlsSecretValues
 
 	// Namespace is the Namespace in which the Enforcer TLS secret will be created.
 	Namespace string
@@ -32,6 +33,12 @@ func NewEnforcerTlsK8sObject(namespace string, tlsSecretsValuesCreator TlsSecret
 	}
 }
 
+// UpdateTlsSecretValues sets the values that are written to the secret, e.g. when the certificates are rotated.
+// When nil, new certificates are created.
+func (obj *EnforcerTlsK8sObject) UpdateTlsSecretValues(tlsSecretValues *models.TlsSecretValues) {
+	obj.tlsSecretValues = tlsSecretValues
+}
+
 func (obj *EnforcerTlsK8sObject) EmptyK8sObject() client.Object {
 	return &coreV1.Secret{}
 }
@@ -46,7 +53,12 @@ func (obj *EnforcerTlsK8sObject) MutateK8sObject(k8sObject client.Object, spec *
 		return fmt.Errorf("expected Secret K8s object")
 	}
 
-	tlsSecretValues, err := obj.tlsSecretsValuesCreator.CreateTlsSecretsValues(types.NamespacedName{Name: EnforcerName, Namespace: obj.Namespace})
+	if obj.tlsSecretValues != nil {
+		secret.Data = obj.tlsSecretValues.ToDataMap()
+		return nil
+	}
+
+	tlsSecretValues, err := obj.CreateTlsSecretValues()
 	if err != nil {
 		return err
 	}
@@ -55,3 +67,8 @@ func (obj *EnforcerTlsK8sObject) MutateK8sObject(k8sObject client.Object, spec *
 
 	return nil
 }
+
+// CreateTlsSecretValues creates new certificates for the enforcer service.
+func (obj *EnforcerTlsK8sObject) CreateTlsSecretValues() (models.TlsSecretValues, error) {
+	return obj.tlsSecretsValuesCreator.CreateTlsSecretsValues(types.NamespacedName{Name: EnforcerName, Namespace: obj.Namespace})
+}
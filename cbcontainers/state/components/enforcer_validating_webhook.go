@@ -2,11 +2,11 @@ package components
 
 import (
 	"fmt"
-	"reflect"
 
 	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/adapters"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/capabilities"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/utils"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -28,17 +28,17 @@ var (
 )
 
 type EnforcerValidatingWebhookK8sObject struct {
-	tlsSecretValues *models.TlsSecretValues
-	kubeletVersion  string
+	tlsSecretValues      *models.TlsSecretValues
+	capabilitiesProvider capabilities.Provider
 
 	// ServiceNamespace is the namespace of the Service that serves the validating webhook.
 	ServiceNamespace string
 }
 
-func NewEnforcerValidatingWebhookK8sObject(serviceNamespace, kubeletVersion string) *EnforcerValidatingWebhookK8sObject {
+func NewEnforcerValidatingWebhookK8sObject(serviceNamespace string, capabilitiesProvider capabilities.Provider) *EnforcerValidatingWebhookK8sObject {
 	return &EnforcerValidatingWebhookK8sObject{
-		kubeletVersion:   kubeletVersion,
-		ServiceNamespace: serviceNamespace,
+		capabilitiesProvider: capabilitiesProvider,
+		ServiceNamespace:     serviceNamespace,
 	}
 }
 
@@ -47,7 +47,7 @@ func (obj *EnforcerValidatingWebhookK8sObject) UpdateTlsSecretValues(tlsSecretVa
 }
 
 func (obj *EnforcerValidatingWebhookK8sObject) EmptyK8sObject() client.Object {
-	return adapters.EmptyValidatingWebhookConfigForVersion(obj.kubeletVersion)
+	return adapters.EmptyValidatingWebhookConfigForCapabilities(obj.capabilitiesProvider.Capabilities())
 }
 
 func (obj *EnforcerValidatingWebhookK8sObject) NamespacedName() types.NamespacedName {
@@ -60,16 +60,19 @@ func (obj *EnforcerValidatingWebhookK8sObject) MutateK8sObject(k8sObject client.
 		return fmt.Errorf("expected a valid instance of ValidatingWebhookConfiguration")
 	}
 
-	if obj.tlsSecretValues == nil {
+	enforcer := &agentSpec.Components.Basic.Enforcer
+	// With cert-manager, the caBundle is set by the cert-manager CA injector
+	if enforcer.CertManager == nil && obj.tlsSecretValues == nil {
 		return fmt.Errorf("tls secret values weren't provided")
 	}
 
-	enforcer := &agentSpec.Components.Basic.Enforcer
 	obj.mutateWebhookConfigurationLabels(webhookConfiguration, enforcer)
-	return obj.mutateWebhooks(webhookConfiguration, enforcer)
+	mutateCAInjection(k8sObject, enforcer, obj.ServiceNamespace)
+	apiCapabilities := obj.capabilitiesProvider.Capabilities()
+	return obj.mutateWebhooks(webhookConfiguration, enforcer, apiCapabilities, getAdmissionReviewVersions(agentSpec, apiCapabilities))
 }
 
-func (obj *EnforcerValidatingWebhookK8sObject) mutateWebhooks(webhookConfiguration adapters.WebhookConfigurationAdapter, enforcer *cbcontainersv1.CBContainersEnforcerSpec) error {
+func (obj *EnforcerValidatingWebhookK8sObject) mutateWebhooks(webhookConfiguration adapters.WebhookConfigurationAdapter, enforcer *cbcontainersv1.CBContainersEnforcerSpec, apiCapabilities *capabilities.Capabilities, admissionReviewVersions []string) error {
 	var resourcesWebhookObj adapters.WebhookAdapter
 	var namespacesWebhookObj adapters.WebhookAdapter
 
@@ -87,8 +90,8 @@ func (obj *EnforcerValidatingWebhookK8sObject) mutateWebhooks(webhookConfigurati
 
 	if initializeWebhooks {
 		webhooks := []adapters.WebhookAdapter{
-			adapters.EmptyValidatingWebhookAdapterForVersion(obj.kubeletVersion),
-			adapters.EmptyValidatingWebhookAdapterForVersion(obj.kubeletVersion),
+			adapters.EmptyValidatingWebhookAdapterForCapabilities(apiCapabilities),
+			adapters.EmptyValidatingWebhookAdapterForCapabilities(apiCapabilities),
 		}
 		updatedWebhooks, err := webhookConfiguration.SetWebhooks(webhooks)
 		if err != nil {
@@ -99,8 +102,12 @@ func (obj *EnforcerValidatingWebhookK8sObject) mutateWebhooks(webhookConfigurati
 		namespacesWebhookObj = updatedWebhooks[1]
 	}
 
-	obj.mutateResourcesWebhook(resourcesWebhookObj, enforcer.WebhookTimeoutSeconds, enforcer.FailurePolicy)
-	obj.mutateNamespacesWebhook(namespacesWebhookObj, enforcer.WebhookTimeoutSeconds, enforcer.FailurePolicy)
+	obj.mutateResourcesWebhook(resourcesWebhookObj, enforcer, apiCapabilities, admissionReviewVersions)
+	obj.mutateNamespacesWebhook(namespacesWebhookObj, enforcer, apiCapabilities, admissionReviewVersions)
+	if enforcer.CertManager == nil {
+		resourcesWebhookObj.SetCABundle(obj.tlsSecretValues.CaBundle())
+		namespacesWebhookObj.SetCABundle(obj.tlsSecretValues.CaBundle())
+	}
 	return nil
 }
 
@@ -114,70 +121,30 @@ func (obj *EnforcerValidatingWebhookK8sObject) findWebhookByName(webhooks []adap
 	return nil, false
 }
 
-func (obj *EnforcerValidatingWebhookK8sObject) mutateResourcesWebhook(resourcesWebhook adapters.WebhookAdapter, timeoutSeconds int32, failurePolicy string) {
+func (obj *EnforcerValidatingWebhookK8sObject) mutateResourcesWebhook(resourcesWebhook adapters.WebhookAdapter, enforcer *cbcontainersv1.CBContainersEnforcerSpec, apiCapabilities *capabilities.Capabilities, admissionReviewVersions []string) {
+	webhooksSpec := &enforcer.Webhooks
 	resourcesWebhook.SetName(ValidatingResourcesWebhookName)
-	resourcesWebhook.SetFailurePolicy(failurePolicy)
+	resourcesWebhook.SetFailurePolicy(enforcer.FailurePolicy)
 	resourcesWebhook.SetSideEffects(ResourcesWebhookSideEffect)
-	namespaceSelector := obj.getResourcesNamespaceSelector(resourcesWebhook.GetNamespaceSelector())
-	resourcesWebhook.SetNamespaceSelector(namespaceSelector)
-	obj.mutateResourcesWebhooksRules(resourcesWebhook)
-	if obj.kubeletVersion == "" || obj.kubeletVersion >= "v1.14" {
-		resourcesWebhook.SetTimeoutSeconds(timeoutSeconds)
-		resourcesWebhook.SetAdmissionReviewVersions([]string{"v1beta1"})
+	resourcesWebhook.SetNamespaceSelector(getWebhookNamespaceSelector([]string{obj.ServiceNamespace}, webhooksSpec))
+	obj.mutateResourcesWebhooksRules(resourcesWebhook, webhooksSpec)
+	if apiCapabilities.HasWebhookTimeouts() {
+		resourcesWebhook.SetTimeoutSeconds(enforcer.WebhookTimeoutSeconds)
+		resourcesWebhook.SetAdmissionReviewVersions(admissionReviewVersions)
 	}
-	if obj.kubeletVersion == "" || obj.kubeletVersion >= "v1.15" {
+	if apiCapabilities.HasWebhookMatchPolicy() {
 		resourcesWebhook.SetMatchPolicy(WebhookMatchPolicy)
+		resourcesWebhook.SetObjectSelector(getWebhookObjectSelector(webhooksSpec))
+	}
+	if apiCapabilities.HasWebhookMatchConditions() {
+		resourcesWebhook.SetMatchConditions(getWebhookMatchConditions(webhooksSpec))
 	}
-	resourcesWebhook.SetCABundle(obj.tlsSecretValues.CaCert)
 	resourcesWebhook.SetServiceName(EnforcerName)
 	resourcesWebhook.SetServiceNamespace(obj.ServiceNamespace)
 	resourcesWebhook.SetServicePath(&WebhookPath)
 }
 
-func (obj *EnforcerValidatingWebhookK8sObject) getResourcesNamespaceSelector(selector *metav1.LabelSelector) *metav1.LabelSelector {
-	octarineIgnore := metav1.LabelSelectorRequirement{
-		Key:      "octarine",
-		Operator: metav1.LabelSelectorOpNotIn,
-		Values:   []string{"ignore"},
-	}
-
-	agentNamespace := metav1.LabelSelectorRequirement{
-		// See https://kubernetes.io/docs/reference/labels-annotations-taints/#kubernetes-io-metadata-name
-		// This is the label that always matches the namespace name
-		// We can't filter directly by namespace otherwise
-		Key:      "kubernetes.io/metadata.name",
-		Operator: metav1.LabelSelectorOpNotIn,
-		Values:   []string{obj.ServiceNamespace},
-	}
-
-	initializeLabelSelector := false
-	if selector == nil || selector.MatchExpressions == nil || len(selector.MatchExpressions) != 2 {
-		initializeLabelSelector = true
-	} else {
-		octarineIgnoreFound := false
-		cbContainersNamespaceFound := false
-		for _, requirement := range selector.MatchExpressions {
-			if reflect.DeepEqual(requirement, octarineIgnore) {
-				octarineIgnoreFound = true
-			}
-			if reflect.DeepEqual(requirement, agentNamespace) {
-				cbContainersNamespaceFound = true
-			}
-		}
-		initializeLabelSelector = !octarineIgnoreFound || !cbContainersNamespaceFound
-	}
-
-	if initializeLabelSelector {
-		return &metav1.LabelSelector{
-			MatchExpressions: []metav1.LabelSelectorRequirement{octarineIgnore, agentNamespace},
-		}
-	}
-
-	return selector
-
-}
-
-func (obj *EnforcerValidatingWebhookK8sObject) mutateResourcesWebhooksRules(webhook adapters.WebhookAdapter) {
+func (obj *EnforcerValidatingWebhookK8sObject) mutateResourcesWebhooksRules(webhook adapters.WebhookAdapter, webhooksSpec *cbcontainersv1.CBContainersEnforcerWebhooksSpec) {
 	rules := webhook.GetAdmissionRules()
 	if rules == nil || len(rules) != 1 {
 		rules = make([]adapters.AdmissionRuleAdapter, 1)
@@ -187,14 +154,14 @@ func (obj *EnforcerValidatingWebhookK8sObject) mutateResourcesWebhooksRules(webh
 	rules[0].APIVersions = []string{"*"}
 	rules[0].APIGroups = []string{"*"}
 
-	expectedResourcesList := obj.getResourcesList()
+	expectedResourcesList := getWebhookResources(obj.getDefaultResourcesList(), webhooksSpec)
 	if !utils.StringsSlicesHaveSameItems(rules[0].Resources, expectedResourcesList) {
 		rules[0].Resources = expectedResourcesList
 	}
 	webhook.SetAdmissionRules(rules)
 }
 
-func (obj *EnforcerValidatingWebhookK8sObject) getResourcesList() []string {
+func (obj *EnforcerValidatingWebhookK8sObject) getDefaultResourcesList() []string {
 	return []string{
 		"pods/portforward",
 		"pods/exec",
@@ -220,21 +187,20 @@ func (obj *EnforcerValidatingWebhookK8sObject) getResourcesList() []string {
 	}
 }
 
-func (obj *EnforcerValidatingWebhookK8sObject) mutateNamespacesWebhook(namespacesWebhook adapters.WebhookAdapter, timeoutSeconds int32, failurePolicy string) {
+func (obj *EnforcerValidatingWebhookK8sObject) mutateNamespacesWebhook(namespacesWebhook adapters.WebhookAdapter, enforcer *cbcontainersv1.CBContainersEnforcerSpec, apiCapabilities *capabilities.Capabilities, admissionReviewVersions []string) {
 	namespacesWebhook.SetName(ValidatingNamespacesWebhookName)
-	namespacesWebhook.SetFailurePolicy(failurePolicy)
+	namespacesWebhook.SetFailurePolicy(enforcer.FailurePolicy)
 	namespacesWebhook.SetSideEffects(NamespacesWebhookSideEffect)
 	namespacesWebhook.SetNamespaceSelector(&metav1.LabelSelector{})
-	if obj.kubeletVersion == "" || obj.kubeletVersion >= "v1.14" {
+	if apiCapabilities.HasWebhookTimeouts() {
 		// Fields introduced to v1beta1 in 1.14
-		namespacesWebhook.SetTimeoutSeconds(timeoutSeconds)
-		namespacesWebhook.SetAdmissionReviewVersions([]string{"v1beta1"})
+		namespacesWebhook.SetTimeoutSeconds(enforcer.WebhookTimeoutSeconds)
+		namespacesWebhook.SetAdmissionReviewVersions(admissionReviewVersions)
 	}
-	if obj.kubeletVersion == "" || obj.kubeletVersion >= "v1.15" {
+	if apiCapabilities.HasWebhookMatchPolicy() {
 		// Fields introduced to v1beta1 in 1.15
 		namespacesWebhook.SetMatchPolicy(WebhookMatchPolicy)
 	}
-	namespacesWebhook.SetCABundle(obj.tlsSecretValues.CaCert)
 	namespacesWebhook.SetServiceNamespace(obj.ServiceNamespace)
 	namespacesWebhook.SetServiceName(EnforcerName)
 	namespacesWebhook.SetServicePath(&WebhookPath)
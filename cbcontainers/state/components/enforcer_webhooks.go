@@ -0,0 +1,118 @@
+package components
+
+import (
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/adapters"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/capabilities"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	AdmissionReviewV1      = "v1"
+	AdmissionReviewV1Beta1 = "v1beta1"
+)
+
+// getAdmissionReviewVersions returns the AdmissionReview versions that the enforcer webhooks accept, by order of
+// preference. The enforcer accepts v1beta1 AdmissionReview objects, so v1 is sent only when the webhooks spec opts in
+// to it, and only by API servers that support it.
+func getAdmissionReviewVersions(agentSpec *cbcontainersv1.CBContainersAgentSpec, apiCapabilities *capabilities.Capabilities) []string {
+	var versions []string
+	for _, version := range agentSpec.Components.Basic.Enforcer.Webhooks.AdmissionReviewVersions {
+		if version != AdmissionReviewV1 || apiCapabilities.HasAdmissionReviewV1() {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		return []string{AdmissionReviewV1Beta1}
+	}
+
+	return versions
+}
+
+// getWebhookResources returns the resources that are sent to an enforcer webhook: its default resources without the
+// excluded ones, and the additional resources of the webhooks spec.
+func getWebhookResources(defaultResources []string, webhooksSpec *cbcontainersv1.CBContainersEnforcerWebhooksSpec) []string {
+	excludedResources := make(map[string]struct{})
+	for _, resource := range webhooksSpec.ExcludedResources {
+		excludedResources[resource] = struct{}{}
+	}
+
+	resources := make([]string, 0, len(defaultResources)+len(webhooksSpec.AdditionalResources))
+	addedResources := make(map[string]struct{})
+	for _, resource := range defaultResources {
+		if _, ok := excludedResources[resource]; !ok {
+			resources = append(resources, resource)
+			addedResources[resource] = struct{}{}
+		}
+	}
+
+	// The exclusions only apply to the default resources, so an additional resource is sent even when it is excluded
+	for _, resource := range webhooksSpec.AdditionalResources {
+		if _, ok := addedResources[resource]; !ok {
+			resources = append(resources, resource)
+			addedResources[resource] = struct{}{}
+		}
+	}
+
+	return resources
+}
+
+// getWebhookNamespaceSelector returns the namespace selector of an enforcer webhook. It excludes the namespaces labeled
+// with octarine=ignore, the ignored namespaces, and the excluded namespaces and namespace labels of the webhooks spec.
+// The requirements of a label selector must all match, so every excluded label is a requirement of its own that the
+// namespaces without the label match: NotIn its values, or DoesNotExist when it has none.
+func getWebhookNamespaceSelector(ignoredNamespaces []string, webhooksSpec *cbcontainersv1.CBContainersEnforcerWebhooksSpec) *metav1.LabelSelector {
+	octarineIgnore := metav1.LabelSelectorRequirement{
+		Key:      "octarine",
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   []string{"ignore"},
+	}
+
+	namespaces := make([]string, 0, len(ignoredNamespaces)+len(webhooksSpec.ExcludedNamespaces))
+	namespaces = append(namespaces, ignoredNamespaces...)
+	namespaces = append(namespaces, webhooksSpec.ExcludedNamespaces...)
+	excludedNamespaces := metav1.LabelSelectorRequirement{
+		// See https://kubernetes.io/docs/reference/labels-annotations-taints/#kubernetes-io-metadata-name
+		// This is the label that always matches the namespace name
+		// We can't filter directly by namespace otherwise
+		Key:      "kubernetes.io/metadata.name",
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   namespaces,
+	}
+
+	selector := &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{octarineIgnore, excludedNamespaces},
+	}
+	for _, excludedLabel := range webhooksSpec.ExcludedNamespaceLabels {
+		requirement := metav1.LabelSelectorRequirement{Key: excludedLabel.Key, Operator: metav1.LabelSelectorOpDoesNotExist}
+		if len(excludedLabel.Values) > 0 {
+			requirement.Operator = metav1.LabelSelectorOpNotIn
+			requirement.Values = append([]string{}, excludedLabel.Values...)
+		}
+		selector.MatchExpressions = append(selector.MatchExpressions, requirement)
+	}
+
+	return selector
+}
+
+// getWebhookObjectSelector returns the object selector of an enforcer webhook.
+// It is empty rather than nil when it isn't set, the same as the API server defaults it.
+func getWebhookObjectSelector(webhooksSpec *cbcontainersv1.CBContainersEnforcerWebhooksSpec) *metav1.LabelSelector {
+	if webhooksSpec.ObjectSelector == nil {
+		return &metav1.LabelSelector{}
+	}
+
+	return webhooksSpec.ObjectSelector.DeepCopy()
+}
+
+// getWebhookMatchConditions returns the match conditions of an enforcer webhook.
+func getWebhookMatchConditions(webhooksSpec *cbcontainersv1.CBContainersEnforcerWebhooksSpec) []adapters.MatchConditionAdapter {
+	conditions := make([]adapters.MatchConditionAdapter, 0, len(webhooksSpec.MatchConditions))
+	for _, condition := range webhooksSpec.MatchConditions {
+		conditions = append(conditions, adapters.MatchConditionAdapter{
+			Name:       condition.Name,
+			Expression: condition.Expression,
+		})
+	}
+	return conditions
+}
@@ -0,0 +1,212 @@
+package components_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/agent_applyment"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	admissionsV1 "k8s.io/api/admissionregistration/v1"
+	admissionsV1Beta1 "k8s.io/api/admissionregistration/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// testEnforcerWebhooks returns the webhooks of the validating and the mutating webhook configurations that the enforcer
+// webhooks builders build for the agent spec, by their names.
+func testEnforcerWebhooks(t *testing.T, kubernetesVersion string, agentSpec *cbcontainersv1.CBContainersAgentSpec) map[string]admissionsV1.ValidatingWebhook {
+	tlsSecretValues := models.TlsSecretValues{CaCert: []byte("ca")}
+	capabilitiesProvider := testCapabilitiesProvider(t, kubernetesVersion)
+
+	validatingWebhookBuilder := components.NewEnforcerValidatingWebhookK8sObject(testNamespace, capabilitiesProvider)
+	validatingWebhookBuilder.UpdateTlsSecretValues(tlsSecretValues)
+	validatingWebhookConfiguration, err := mutatedK8sObject(validatingWebhookBuilder, agentSpec)
+	require.NoError(t, err)
+
+	mutatingWebhookBuilder := components.NewEnforcerMutatingWebhookK8sObject(testNamespace, capabilitiesProvider)
+	mutatingWebhookBuilder.UpdateTlsSecretValues(tlsSecretValues)
+	mutatingWebhookConfiguration, err := mutatedK8sObject(mutatingWebhookBuilder, agentSpec)
+	require.NoError(t, err)
+
+	webhooks := make(map[string]admissionsV1.ValidatingWebhook)
+	for _, webhook := range validatingWebhookConfiguration.(*admissionsV1.ValidatingWebhookConfiguration).Webhooks {
+		webhooks[webhook.Name] = webhook
+	}
+	// The mutating webhook is converted to a validating one, as the tests only check the fields they have in common
+	for _, webhook := range mutatingWebhookConfiguration.(*admissionsV1.MutatingWebhookConfiguration).Webhooks {
+		webhooks[webhook.Name] = admissionsV1.ValidatingWebhook{
+			Name:                    webhook.Name,
+			Rules:                   webhook.Rules,
+			NamespaceSelector:       webhook.NamespaceSelector,
+			ObjectSelector:          webhook.ObjectSelector,
+			AdmissionReviewVersions: webhook.AdmissionReviewVersions,
+			MatchConditions:         webhook.MatchConditions,
+		}
+	}
+
+	return webhooks
+}
+
+func TestEnforcerWebhooksFilterTheAdmissionRequests(t *testing.T) {
+	agentSpec := testAgentSpec(t, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+		agentSpec.Components.Basic.Enforcer.Webhooks = cbcontainersv1.CBContainersEnforcerWebhooksSpec{
+			AdditionalResources: []string{"compositions"},
+			ExcludedResources:   []string{"customresourcedefinitions", "namespaces"},
+			ExcludedNamespaces:  []string{"crossplane-system"},
+			ExcludedNamespaceLabels: []cbcontainersv1.CBContainersNamespaceLabelExclusion{
+				{Key: "team", Values: []string{"platform"}},
+				{Key: "crossplane.io/managed"},
+			},
+			ObjectSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"enforced": "true"}},
+		}
+	})
+	webhooks := testEnforcerWebhooks(t, testKubernetesVersion, agentSpec)
+
+	tests := map[string]struct {
+		expectedExcludedNamespaces []string
+	}{
+		components.ValidatingResourcesWebhookName: {expectedExcludedNamespaces: []string{testNamespace, "crossplane-system"}},
+		components.MutatingWebhookName:            {expectedExcludedNamespaces: []string{testNamespace, "kube-system", "crossplane-system"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			webhook := webhooks[name]
+			require.Contains(t, webhook.Rules[0].Resources, "pods")
+			require.Contains(t, webhook.Rules[0].Resources, "compositions")
+			require.NotContains(t, webhook.Rules[0].Resources, "customresourcedefinitions")
+			require.NotContains(t, webhook.Rules[0].Resources, "namespaces")
+			require.Equal(t, []metav1.LabelSelectorRequirement{
+				{Key: "octarine", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"ignore"}},
+				{Key: "kubernetes.io/metadata.name", Operator: metav1.LabelSelectorOpNotIn, Values: test.expectedExcludedNamespaces},
+				{Key: "team", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"platform"}},
+				{Key: "crossplane.io/managed", Operator: metav1.LabelSelectorOpDoesNotExist},
+			}, webhook.NamespaceSelector.MatchExpressions)
+			require.Equal(t, map[string]string{"enforced": "true"}, webhook.ObjectSelector.MatchLabels)
+		})
+	}
+
+	t.Run("The namespaces webhook guards the octarine=ignore label of all the namespaces, so it isn't filtered", func(t *testing.T) {
+		namespacesWebhook := webhooks[components.ValidatingNamespacesWebhookName]
+		require.Equal(t, []string{"namespaces"}, namespacesWebhook.Rules[0].Resources)
+		require.Empty(t, namespacesWebhook.NamespaceSelector.MatchExpressions)
+	})
+}
+
+// webhooksAdmissionReviewVersions returns the AdmissionReview versions of the webhooks of the v1 or v1beta1 webhook
+// configuration.
+func webhooksAdmissionReviewVersions(t *testing.T, webhookConfiguration client.Object) [][]string {
+	var admissionReviewVersions [][]string
+	switch webhookConfiguration := webhookConfiguration.(type) {
+	case *admissionsV1.ValidatingWebhookConfiguration:
+		for _, webhook := range webhookConfiguration.Webhooks {
+			admissionReviewVersions = append(admissionReviewVersions, webhook.AdmissionReviewVersions)
+		}
+	case *admissionsV1.MutatingWebhookConfiguration:
+		for _, webhook := range webhookConfiguration.Webhooks {
+			admissionReviewVersions = append(admissionReviewVersions, webhook.AdmissionReviewVersions)
+		}
+	case *admissionsV1Beta1.ValidatingWebhookConfiguration:
+		for _, webhook := range webhookConfiguration.Webhooks {
+			admissionReviewVersions = append(admissionReviewVersions, webhook.AdmissionReviewVersions)
+		}
+	case *admissionsV1Beta1.MutatingWebhookConfiguration:
+		for _, webhook := range webhookConfiguration.Webhooks {
+			admissionReviewVersions = append(admissionReviewVersions, webhook.AdmissionReviewVersions)
+		}
+	default:
+		require.Failf(t, "unexpected webhook configuration", "%T", webhookConfiguration)
+	}
+
+	return admissionReviewVersions
+}
+
+func TestEnforcerWebhooksAdmissionReviewVersions(t *testing.T) {
+	optInToV1 := func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+		agentSpec.Components.Basic.Enforcer.Webhooks.AdmissionReviewVersions = []string{components.AdmissionReviewV1, components.AdmissionReviewV1Beta1}
+	}
+
+	tests := map[string]struct {
+		kubernetesVersion string
+		changeSpec        func(agentSpec *cbcontainersv1.CBContainersAgentSpec)
+		expected          []string
+	}{
+		"With default spec, should accept only v1beta1": {
+			kubernetesVersion: testKubernetesVersion,
+			expected:          []string{components.AdmissionReviewV1Beta1},
+		},
+		"When the enforcer image is pinned by a digest, should accept only v1beta1": {
+			kubernetesVersion: testKubernetesVersion,
+			changeSpec: func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.Basic.Enforcer.Image.Tag = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+			},
+			expected: []string{components.AdmissionReviewV1Beta1},
+		},
+		"When the spec opts in to v1, should prefer v1": {
+			kubernetesVersion: testKubernetesVersion,
+			changeSpec:        optInToV1,
+			expected:          []string{components.AdmissionReviewV1, components.AdmissionReviewV1Beta1},
+		},
+		"When the spec opts in to v1 and the API server doesn't support it, should accept only v1beta1": {
+			kubernetesVersion: "v1.15.0",
+			changeSpec:        optInToV1,
+			expected:          []string{components.AdmissionReviewV1Beta1},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			agentSpec := testAgentSpec(t, test.changeSpec)
+			capabilitiesProvider := testCapabilitiesProvider(t, test.kubernetesVersion)
+			validatingWebhookBuilder := components.NewEnforcerValidatingWebhookK8sObject(testNamespace, capabilitiesProvider)
+			validatingWebhookBuilder.UpdateTlsSecretValues(models.TlsSecretValues{CaCert: []byte("ca")})
+			mutatingWebhookBuilder := components.NewEnforcerMutatingWebhookK8sObject(testNamespace, capabilitiesProvider)
+			mutatingWebhookBuilder.UpdateTlsSecretValues(models.TlsSecretValues{CaCert: []byte("ca")})
+
+			for _, builder := range []agent_applyment.AgentComponentBuilder{validatingWebhookBuilder, mutatingWebhookBuilder} {
+				webhookConfiguration, err := mutatedK8sObject(builder, agentSpec)
+				require.NoError(t, err)
+				webhooksVersions := webhooksAdmissionReviewVersions(t, webhookConfiguration)
+				require.NotEmpty(t, webhooksVersions)
+				for _, admissionReviewVersions := range webhooksVersions {
+					require.Equal(t, test.expected, admissionReviewVersions)
+				}
+			}
+		})
+	}
+}
+
+func TestEnforcerWebhooksMatchConditions(t *testing.T) {
+	matchConditions := []admissionsV1.MatchCondition{
+		{Name: "skip-cd-updates", Expression: "request.userInfo.username != 'system:serviceaccount:cd:deployer'"},
+	}
+
+	tests := map[string]struct {
+		kubernetesVersion       string
+		expectedMatchConditions []admissionsV1.MatchCondition
+	}{
+		"When the API server supports match conditions, should set them on the resources webhooks": {
+			kubernetesVersion:       testKubernetesVersion,
+			expectedMatchConditions: matchConditions,
+		},
+		"When the API server doesn't support match conditions, should not set them": {
+			kubernetesVersion: "v1.27.0",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			webhooks := testEnforcerWebhooks(t, test.kubernetesVersion, testAgentSpec(t, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.Basic.Enforcer.Webhooks.MatchConditions = matchConditions
+			}))
+
+			require.Len(t, webhooks, 3)
+			require.Equal(t, test.expectedMatchConditions, webhooks[components.ValidatingResourcesWebhookName].MatchConditions)
+			require.Equal(t, test.expectedMatchConditions, webhooks[components.MutatingWebhookName].MatchConditions)
+			// The namespaces webhook guards the octarine=ignore label of all the namespaces, so it isn't filtered
+			require.Empty(t, webhooks[components.ValidatingNamespacesWebhookName].MatchConditions)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package components
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	autoscalingV2 "k8s.io/api/autoscaling/v2"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// HorizontalPodAutoscalerBehaviorChecksumAnnotation is the checksum of the behavior that the HorizontalPodAutoscaler
+	// was applied with. The API server fills the behavior fields that aren't set, so the applied behavior can't be
+	// compared with the desired one.
+	HorizontalPodAutoscalerBehaviorChecksumAnnotation = "operator.containers.carbonblack.io/behavior-checksum"
+
+	defaultTargetCPUUtilizationPercentage int32 = 80
+)
+
+// AutoscalingSpecGetter returns the autoscaling spec of the deployment that a HorizontalPodAutoscaler scales.
+type AutoscalingSpecGetter func(agentSpec *cbcontainersv1.CBContainersAgentSpec) *cbcontainersv1.CBContainersAutoscalingSpec
+
+// HorizontalPodAutoscalerK8sObject is an autoscaling/v2 HorizontalPodAutoscaler that scales a deployment of the agent.
+// It has the name of the deployment that it scales.
+type HorizontalPodAutoscalerK8sObject struct {
+	// Namespace is the Namespace in which the HorizontalPodAutoscaler will be created.
+	Namespace string
+
+	// DeploymentName is the name of the deployment that the HorizontalPodAutoscaler scales.
+	DeploymentName string
+
+	autoscalingSpec AutoscalingSpecGetter
+}
+
+func NewHorizontalPodAutoscalerK8sObject(namespace, deploymentName string, autoscalingSpec AutoscalingSpecGetter) *HorizontalPodAutoscalerK8sObject {
+	return &HorizontalPodAutoscalerK8sObject{
+		Namespace:       namespace,
+		DeploymentName:  deploymentName,
+		autoscalingSpec: autoscalingSpec,
+	}
+}
+
+func NewEnforcerHorizontalPodAutoscalerK8sObject(namespace string) *HorizontalPodAutoscalerK8sObject {
+	return NewHorizontalPodAutoscalerK8sObject(namespace, EnforcerName, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) *cbcontainersv1.CBContainersAutoscalingSpec {
+		return &agentSpec.Components.Basic.Enforcer.Autoscaling
+	})
+}
+
+func NewResolverHorizontalPodAutoscalerK8sObject(namespace string) *HorizontalPodAutoscalerK8sObject {
+	return NewHorizontalPodAutoscalerK8sObject(namespace, ResolverName, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) *cbcontainersv1.CBContainersAutoscalingSpec {
+		return &agentSpec.Components.RuntimeProtection.Resolver.Autoscaling
+	})
+}
+
+func NewImageScanningReporterHorizontalPodAutoscalerK8sObject(namespace string) *HorizontalPodAutoscalerK8sObject {
+	return NewHorizontalPodAutoscalerK8sObject(namespace, ImageScanningReporterName, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) *cbcontainersv1.CBContainersAutoscalingSpec {
+		return &agentSpec.Components.ClusterScanning.ImageScanningReporter.Autoscaling
+	})
+}
+
+// AutoscalingSpec returns the autoscaling spec of the deployment that the HorizontalPodAutoscaler scales.
+func (obj *HorizontalPodAutoscalerK8sObject) AutoscalingSpec(agentSpec *cbcontainersv1.CBContainersAgentSpec) *cbcontainersv1.CBContainersAutoscalingSpec {
+	return obj.autoscalingSpec(agentSpec)
+}
+
+func (obj *HorizontalPodAutoscalerK8sObject) EmptyK8sObject() client.Object {
+	return &autoscalingV2.HorizontalPodAutoscaler{}
+}
+
+func (obj *HorizontalPodAutoscalerK8sObject) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: obj.DeploymentName, Namespace: obj.Namespace}
+}
+
+func (obj *HorizontalPodAutoscalerK8sObject) MutateK8sObject(k8sObject client.Object, agentSpec *cbcontainersv1.CBContainersAgentSpec) error {
+	horizontalPodAutoscaler, ok := k8sObject.(*autoscalingV2.HorizontalPodAutoscaler)
+	if !ok {
+		return fmt.Errorf("expected HorizontalPodAutoscaler K8s object")
+	}
+
+	autoscaling := obj.autoscalingSpec(agentSpec)
+	if autoscaling.MinReplicas != nil && autoscaling.MaxReplicas < *autoscaling.MinReplicas {
+		return fmt.Errorf("the max replicas %d of the %v autoscaling are less than its min replicas %d", autoscaling.MaxReplicas, obj.DeploymentName, *autoscaling.MinReplicas)
+	}
+
+	horizontalPodAutoscaler.Spec.ScaleTargetRef = autoscalingV2.CrossVersionObjectReference{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Name:       obj.DeploymentName,
+	}
+	horizontalPodAutoscaler.Spec.MinReplicas = autoscaling.MinReplicas
+	horizontalPodAutoscaler.Spec.MaxReplicas = autoscaling.MaxReplicas
+
+	desiredMetrics := autoscalingMetrics(autoscaling)
+	if !equality.Semantic.DeepEqual(desiredMetrics, horizontalPodAutoscaler.Spec.Metrics) {
+		horizontalPodAutoscaler.Spec.Metrics = desiredMetrics
+	}
+
+	return obj.mutateBehavior(horizontalPodAutoscaler, autoscaling.Behavior)
+}
+
+// mutateBehavior changes the behavior of the HorizontalPodAutoscaler only when the desired behavior was changed since
+// it was applied. When the behavior isn't set, the API server fills the default behavior.
+func (obj *HorizontalPodAutoscalerK8sObject) mutateBehavior(horizontalPodAutoscaler *autoscalingV2.HorizontalPodAutoscaler, desiredBehavior *autoscalingV2.HorizontalPodAutoscalerBehavior) error {
+	desiredChecksum := ""
+	if desiredBehavior != nil {
+		rawBehavior, err := json.Marshal(desiredBehavior)
+		if err != nil {
+			return fmt.Errorf("failed marshaling the %v autoscaling behavior: %w", obj.DeploymentName, err)
+		}
+		checksum := sha256.Sum256(rawBehavior)
+		desiredChecksum = hex.EncodeToString(checksum[:])
+	}
+
+	if horizontalPodAutoscaler.Annotations[HorizontalPodAutoscalerBehaviorChecksumAnnotation] == desiredChecksum {
+		return nil
+	}
+
+	horizontalPodAutoscaler.Spec.Behavior = desiredBehavior.DeepCopy()
+	if desiredChecksum == "" {
+		delete(horizontalPodAutoscaler.Annotations, HorizontalPodAutoscalerBehaviorChecksumAnnotation)
+		return nil
+	}
+
+	if horizontalPodAutoscaler.Annotations == nil {
+		horizontalPodAutoscaler.Annotations = make(map[string]string)
+	}
+	horizontalPodAutoscaler.Annotations[HorizontalPodAutoscalerBehaviorChecksumAnnotation] = desiredChecksum
+
+	return nil
+}
+
+// autoscalingMetrics returns the resource utilization metrics of the autoscaling spec, followed by its other metrics.
+// Without any metrics, the replicas are scaled by the CPU utilization, as the API server would default them to.
+func autoscalingMetrics(autoscaling *cbcontainersv1.CBContainersAutoscalingSpec) []autoscalingV2.MetricSpec {
+	var metrics []autoscalingV2.MetricSpec
+	if autoscaling.TargetCPUUtilizationPercentage != nil {
+		metrics = append(metrics, resourceUtilizationMetric(coreV1.ResourceCPU, *autoscaling.TargetCPUUtilizationPercentage))
+	}
+	if autoscaling.TargetMemoryUtilizationPercentage != nil {
+		metrics = append(metrics, resourceUtilizationMetric(coreV1.ResourceMemory, *autoscaling.TargetMemoryUtilizationPercentage))
+	}
+	for i := range autoscaling.Metrics {
+		metrics = append(metrics, *autoscaling.Metrics[i].DeepCopy())
+	}
+	if len(metrics) == 0 {
+		metrics = append(metrics, resourceUtilizationMetric(coreV1.ResourceCPU, defaultTargetCPUUtilizationPercentage))
+	}
+
+	return metrics
+}
+
+func resourceUtilizationMetric(resourceName coreV1.ResourceName, averageUtilization int32) autoscalingV2.MetricSpec {
+	return autoscalingV2.MetricSpec{
+		Type: autoscalingV2.ResourceMetricSourceType,
+		Resource: &autoscalingV2.ResourceMetricSource{
+			Name: resourceName,
+			Target: autoscalingV2.MetricTarget{
+				Type:               autoscalingV2.UtilizationMetricType,
+				AverageUtilization: &averageUtilization,
+			},
+		},
+	}
+}
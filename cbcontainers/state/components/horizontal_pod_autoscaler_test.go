@@ -0,0 +1,87 @@
+package components_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	autoscalingV2 "k8s.io/api/autoscaling/v2"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+func utilizationMetric(resourceName coreV1.ResourceName, averageUtilization int32) autoscalingV2.MetricSpec {
+	return autoscalingV2.MetricSpec{
+		Type: autoscalingV2.ResourceMetricSourceType,
+		Resource: &autoscalingV2.ResourceMetricSource{
+			Name:   resourceName,
+			Target: autoscalingV2.MetricTarget{Type: autoscalingV2.UtilizationMetricType, AverageUtilization: &averageUtilization},
+		},
+	}
+}
+
+func TestEnforcerHorizontalPodAutoscaler(t *testing.T) {
+	var minReplicas, targetCPUUtilizationPercentage, targetMemoryUtilizationPercentage int32 = 2, 60, 70
+	var stabilizationWindowSeconds int32 = 600
+	behavior := &autoscalingV2.HorizontalPodAutoscalerBehavior{
+		ScaleDown: &autoscalingV2.HPAScalingRules{StabilizationWindowSeconds: &stabilizationWindowSeconds},
+	}
+
+	tests := map[string]struct {
+		autoscaling     cbcontainersv1.CBContainersAutoscalingSpec
+		expectedMetrics []autoscalingV2.MetricSpec
+		expectedError   string
+	}{
+		"When a target CPU utilization is set, should scale the enforcer by it": {
+			autoscaling:     cbcontainersv1.CBContainersAutoscalingSpec{MaxReplicas: 10, TargetCPUUtilizationPercentage: &targetCPUUtilizationPercentage},
+			expectedMetrics: []autoscalingV2.MetricSpec{utilizationMetric(coreV1.ResourceCPU, 60)},
+		},
+		"When no metrics are set, should scale the enforcer by the default CPU utilization": {
+			autoscaling:     cbcontainersv1.CBContainersAutoscalingSpec{MaxReplicas: 10},
+			expectedMetrics: []autoscalingV2.MetricSpec{utilizationMetric(coreV1.ResourceCPU, 80)},
+		},
+		"When the target CPU and memory utilizations are set, should scale the enforcer by both": {
+			autoscaling: cbcontainersv1.CBContainersAutoscalingSpec{
+				MinReplicas:                       &minReplicas,
+				MaxReplicas:                       10,
+				TargetCPUUtilizationPercentage:    &targetCPUUtilizationPercentage,
+				TargetMemoryUtilizationPercentage: &targetMemoryUtilizationPercentage,
+				Behavior:                          behavior,
+			},
+			expectedMetrics: []autoscalingV2.MetricSpec{utilizationMetric(coreV1.ResourceCPU, 60), utilizationMetric(coreV1.ResourceMemory, 70)},
+		},
+		"When the max replicas are less than the min replicas, should return an error": {
+			autoscaling:   cbcontainersv1.CBContainersAutoscalingSpec{MinReplicas: &minReplicas, MaxReplicas: 1},
+			expectedError: "the max replicas 1 of the cbcontainers-hardening-enforcer autoscaling are less than its min replicas 2",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			agentSpec := testAgentSpec(t, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				enabled := true
+				agentSpec.Components.Basic.Enforcer.Autoscaling = test.autoscaling
+				agentSpec.Components.Basic.Enforcer.Autoscaling.Enabled = &enabled
+			})
+
+			k8sObject, err := mutatedK8sObject(components.NewEnforcerHorizontalPodAutoscalerK8sObject(testNamespace), agentSpec)
+
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			horizontalPodAutoscaler := k8sObject.(*autoscalingV2.HorizontalPodAutoscaler)
+			require.Equal(t, autoscalingV2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: components.EnforcerName}, horizontalPodAutoscaler.Spec.ScaleTargetRef)
+			require.Equal(t, agentSpec.Components.Basic.Enforcer.Autoscaling.MinReplicas, horizontalPodAutoscaler.Spec.MinReplicas)
+			require.Equal(t, test.autoscaling.MaxReplicas, horizontalPodAutoscaler.Spec.MaxReplicas)
+			require.Equal(t, test.expectedMetrics, horizontalPodAutoscaler.Spec.Metrics)
+			require.Equal(t, test.autoscaling.Behavior, horizontalPodAutoscaler.Spec.Behavior)
+			if test.autoscaling.Behavior == nil {
+				require.NotContains(t, horizontalPodAutoscaler.Annotations, components.HorizontalPodAutoscalerBehaviorChecksumAnnotation)
+			} else {
+				require.NotEmpty(t, horizontalPodAutoscaler.Annotations[components.HorizontalPodAutoscalerBehaviorChecksumAnnotation])
+			}
+		})
+	}
+}
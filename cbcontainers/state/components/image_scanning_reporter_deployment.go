@@ -32,6 +32,9 @@ var (
 type ImageScanningReporterDeploymentK8sObject struct {
 	// Namespace is the Namespace in which the Deployment will be created.
 	Namespace string
+
+	// configChecksum is the checksum of the ConfigMaps and Secrets keys that the pods consume.
+	configChecksum string
 }
 
 func NewImageScanningReporterDeploymentK8sObject(namespace string) *ImageScanningReporterDeploymentK8sObject {
@@ -40,6 +43,12 @@ func NewImageScanningReporterDeploymentK8sObject(namespace string) *ImageScannin
 	}
 }
 
+// UpdateConfigChecksum sets the checksum of the ConfigMaps and Secrets keys that the pods consume, which rolls the
+// pods when it changes.
+func (obj *ImageScanningReporterDeploymentK8sObject) UpdateConfigChecksum(configChecksum string) {
+	obj.configChecksum = configChecksum
+}
+
 func (obj *ImageScanningReporterDeploymentK8sObject) EmptyK8sObject() client.Object {
 	return &appsV1.Deployment{}
 }
@@ -61,10 +70,17 @@ func (obj *ImageScanningReporterDeploymentK8sObject) MutateK8sObject(k8sObject c
 	obj.mutateAnnotations(deployment, imageScanningReporter)
 	obj.mutateVolumes(&deployment.Spec.Template.Spec)
 	obj.mutateAffinityAndNodeSelector(&deployment.Spec.Template.Spec, imageScanningReporter)
+	mutateTopologySpreadConstraints(&deployment.Spec.Template.Spec, imageScanningReporter.TopologySpreadConstraints, imageScanningReporter.Affinity, DeploymentMaxReplicas(imageScanningReporter.ReplicasCount, &imageScanningReporter.Autoscaling, 1), ImageScanningReporterLabelKey, ImageScanningReporterName)
 	obj.mutateContainersList(&deployment.Spec.Template.Spec, agentSpec)
-	commonState.NewNodeTermsBuilder(&deployment.Spec.Template.Spec).Build()
+	architectures, err := commonState.GetArchitectures(&agentSpec.Components.Settings, imageScanningReporter.Architectures, &imageScanningReporter.Image)
+	if err != nil {
+		return err
+	}
+	commonState.NewNodeTermsBuilder(&deployment.Spec.Template.Spec, architectures).Build()
+
+	mutateConfigChecksum(&deployment.Spec.Template.ObjectMeta, obj.configChecksum)
 
-	return nil
+	return mutatePodTemplatePatch(&deployment.ObjectMeta, &deployment.Spec.Template, imageScanningReporter.PodTemplatePatch, ImageScanningReporterName)
 }
 
 // initiateDeployment initiate the deployment attributes with empty or default values.
@@ -82,7 +98,10 @@ func (obj *ImageScanningReporterDeploymentK8sObject) initiateDeployment(deployme
 	}
 
 	imageScanningReporter := &agentSpec.Components.ClusterScanning.ImageScanningReporter
-	deployment.Spec.Replicas = imageScanningReporter.ReplicasCount
+	// While autoscaling, the replicas are set by the HorizontalPodAutoscaler
+	if commonState.IsDisabled(imageScanningReporter.Autoscaling.Enabled) {
+		deployment.Spec.Replicas = imageScanningReporter.ReplicasCount
+	}
 	deployment.Spec.Template.Spec.ServiceAccountName = commonState.ImageScanningServiceAccountName
 	deployment.Spec.Template.Spec.PriorityClassName = commonState.DataPlanePriorityClassName
 	desiredImagePullSecrets := getImagePullSecrets(agentSpec, agentSpec.Components.ClusterScanning.ImageScanningReporter.Image.PullSecrets...)
@@ -123,7 +142,8 @@ func (obj *ImageScanningReporterDeploymentK8sObject) mutateVolumes(templatePodSp
 }
 
 func (obj *ImageScanningReporterDeploymentK8sObject) mutateAffinityAndNodeSelector(templatePodSpec *coreV1.PodSpec, imageScanningReporterSpec *cbcontainersv1.CBContainersImageScanningReporterSpec) {
-	templatePodSpec.Affinity = imageScanningReporterSpec.Affinity
+	// The node terms are added to a copy of the affinity, so the affinity of the spec is kept as it was set
+	templatePodSpec.Affinity = imageScanningReporterSpec.Affinity.DeepCopy()
 	templatePodSpec.NodeSelector = imageScanningReporterSpec.NodeSelector
 }
 
@@ -162,6 +182,7 @@ func (obj *ImageScanningReporterDeploymentK8sObject) mutateImageScanningReporter
 		WithEventsGateway(&agentSpec.Gateways.HardeningEventsGateway).
 		WithCustom(customEnvs...).
 		WithSpec(imageScanningReporterSpec.Env).
+		WithExtraEnv(imageScanningReporterSpec.ExtraEnv).
 		WithProxySettings(agentSpec.Components.Settings.Proxy)
 	commonState.MutateEnvVars(container, envVarBuilder)
 }
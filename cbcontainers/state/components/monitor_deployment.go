@@ -35,6 +35,9 @@ var (
 type MonitorDeploymentK8sObject struct {
 	// Namespace is the Namespace in which the Deployment will be created.
 	Namespace string
+
+	// configChecksum is the checksum of the ConfigMaps and Secrets keys that the pods consume.
+	configChecksum string
 }
 
 func NewMonitorDeploymentK8sObject(namespace string) *MonitorDeploymentK8sObject {
@@ -43,6 +46,12 @@ func NewMonitorDeploymentK8sObject(namespace string) *MonitorDeploymentK8sObject
 	}
 }
 
+// UpdateConfigChecksum sets the checksum of the ConfigMaps and Secrets keys that the pods consume, which rolls the
+// pods when it changes.
+func (obj *MonitorDeploymentK8sObject) UpdateConfigChecksum(configChecksum string) {
+	obj.configChecksum = configChecksum
+}
+
 func (obj *MonitorDeploymentK8sObject) EmptyK8sObject() client.Object {
 	return &appsV1.Deployment{}
 }
@@ -91,9 +100,15 @@ func (obj *MonitorDeploymentK8sObject) MutateK8sObject(k8sObject client.Object,
 	obj.mutateVolumes(&deployment.Spec.Template.Spec)
 	obj.mutateAffinityAndNodeSelector(&deployment.Spec.Template.Spec, monitor)
 	obj.mutateContainersList(&deployment.Spec.Template.Spec, agentSpec)
-	commonState.NewNodeTermsBuilder(&deployment.Spec.Template.Spec).Build()
+	architectures, err := commonState.GetArchitectures(&agentSpec.Components.Settings, monitor.Architectures, &monitor.Image)
+	if err != nil {
+		return err
+	}
+	commonState.NewNodeTermsBuilder(&deployment.Spec.Template.Spec, architectures).Build()
+
+	mutateConfigChecksum(&deployment.Spec.Template.ObjectMeta, obj.configChecksum)
 
-	return nil
+	return mutatePodTemplatePatch(&deployment.ObjectMeta, &deployment.Spec.Template, monitor.PodTemplatePatch, MonitorName)
 }
 
 func (obj *MonitorDeploymentK8sObject) mutateVolumes(templatePodSpec *coreV1.PodSpec) {
@@ -130,6 +145,7 @@ func (obj *MonitorDeploymentK8sObject) mutateContainer(container *coreV1.Contain
 		WithEnvVarFromConfigmap(MonitorAgentVersionEnvVarKey, commonState.DataPlaneConfigmapAgentVersionKey).
 		WithEnvVarFromConfigmap(MonitorDataplaneNamespaceEnvVarKey, commonState.DataPlaneConfigmapDataplaneNamespaceKey).
 		WithSpec(monitorSpec.Env).
+		WithExtraEnv(monitorSpec.ExtraEnv).
 		WithProxySettings(agentSpec.Components.Settings.Proxy)
 	commonState.MutateEnvVars(container, envVarBuilder)
 
@@ -0,0 +1,120 @@
+package components
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	NodeCleanupName     = "cbcontainers-node-cleaner"
+	NodeCleanupLabelKey = "app.kubernetes.io/name"
+
+	nodeCleanupVarVolumeName = "var-dir"
+	nodeCleanupVarPath       = "/var"
+)
+
+var (
+	// NodeCleanupDataDirPath is the directory on the node in which the agent components store their data.
+	NodeCleanupDataDirPath = cndrHostPaths["cb-data-dir"].Path
+
+	nodeCleanupIsPrivileged                = true
+	nodeCleanupRunAsUser             int64 = 0
+	nodeCleanupBackoffLimit          int32 = 2
+	nodeCleanupActiveDeadlineSeconds int64 = 300
+)
+
+// NodeCleanupJobK8sObject is a Job that removes the data that the CNDR sensor left on a single node.
+// The Job runs on the node by its name, so it doesn't depend on the scheduler.
+type NodeCleanupJobK8sObject struct {
+	nodeName string
+
+	// Namespace is the Namespace in which the Job will be created.
+	Namespace string
+}
+
+func NewNodeCleanupJobK8sObject(namespace string) *NodeCleanupJobK8sObject {
+	return &NodeCleanupJobK8sObject{
+		Namespace: namespace,
+	}
+}
+
+func (obj *NodeCleanupJobK8sObject) UpdateNodeName(nodeName string) {
+	obj.nodeName = nodeName
+}
+
+func (obj *NodeCleanupJobK8sObject) EmptyK8sObject() client.Object { return &batchV1.Job{} }
+
+// NamespacedName returns a name that is unique per node. Node names may be longer than the allowed Job name,
+// so a hash of the node name is used.
+func (obj *NodeCleanupJobK8sObject) NamespacedName() types.NamespacedName {
+	nodeNameHash := fnv.New32a()
+	_, _ = nodeNameHash.Write([]byte(obj.nodeName))
+	return types.NamespacedName{Name: fmt.Sprintf("%v-%x", NodeCleanupName, nodeNameHash.Sum32()), Namespace: obj.Namespace}
+}
+
+func (obj *NodeCleanupJobK8sObject) MutateK8sObject(k8sObject client.Object, agentSpec *cbcontainersv1.CBContainersAgentSpec) error {
+	job, ok := k8sObject.(*batchV1.Job)
+	if !ok {
+		return fmt.Errorf("expected Job K8s object")
+	}
+
+	if obj.nodeName == "" {
+		return fmt.Errorf("wasn't given with the node to clean up")
+	}
+
+	desiredLabels := map[string]string{NodeCleanupLabelKey: NodeCleanupName}
+	job.ObjectMeta.Labels = desiredLabels
+	job.Spec.Template.ObjectMeta.Labels = desiredLabels
+	job.Spec.BackoffLimit = &nodeCleanupBackoffLimit
+	// A node that can't run the Job (e.g. a node that is not ready) must not block the cleanup forever
+	job.Spec.ActiveDeadlineSeconds = &nodeCleanupActiveDeadlineSeconds
+
+	templatePodSpec := &job.Spec.Template.Spec
+	templatePodSpec.NodeName = obj.nodeName
+	// The node agent service account is already allowed to run privileged pods with host paths (e.g. by an OpenShift SCC)
+	templatePodSpec.ServiceAccountName = commonState.AgentNodeServiceAccountName
+	templatePodSpec.RestartPolicy = coreV1.RestartPolicyNever
+	templatePodSpec.Tolerations = agentSpec.Components.Settings.DaemonSetsTolerations
+	templatePodSpec.ImagePullSecrets = getImagePullSecrets(agentSpec, agentSpec.Components.Settings.NodeCleanupImage.PullSecrets...)
+	templatePodSpec.Volumes = []coreV1.Volume{{
+		Name: nodeCleanupVarVolumeName,
+		VolumeSource: coreV1.VolumeSource{
+			HostPath: &coreV1.HostPathVolumeSource{Path: nodeCleanupVarPath, Type: &hostPathDirectory},
+		},
+	}}
+	container := coreV1.Container{
+		Name:    NodeCleanupName,
+		Command: []string{"/usr/bin/rm", "-rf", NodeCleanupDataDirPath},
+		SecurityContext: &coreV1.SecurityContext{
+			Privileged: &nodeCleanupIsPrivileged,
+			RunAsUser:  &nodeCleanupRunAsUser,
+		},
+		VolumeMounts: []coreV1.VolumeMount{{Name: nodeCleanupVarVolumeName, MountPath: nodeCleanupVarPath}},
+	}
+	commonState.MutateImage(&container, agentSpec.Components.Settings.NodeCleanupImage, agentSpec.Version, agentSpec.Components.Settings.DefaultImagesRegistry)
+	templatePodSpec.Containers = []coreV1.Container{container}
+
+	return nil
+}
+
+// IsNodeCleanupJobFinished returns true when the Job either completed or failed.
+func IsNodeCleanupJobFinished(job *batchV1.Job) bool {
+	return job.Status.Succeeded > 0 || IsNodeCleanupJobFailed(job)
+}
+
+// IsNodeCleanupJobFailed returns true when the Job has failed, e.g. because it didn't complete in time.
+func IsNodeCleanupJobFailed(job *batchV1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchV1.JobFailed && condition.Status == coreV1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
+	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	NodeCoverageConfigMapKey = "coverage.json"
+)
+
+// NodeCoverageConfigMapK8sObject is a ConfigMap that reports the reason that each of the nodes which the components
+// daemon set doesn't cover is not covered. The covered nodes are not listed, so the report stays small on large clusters.
+type NodeCoverageConfigMapK8sObject struct {
+	uncoveredNodes []models.NodeCoverage
+
+	// Namespace is the Namespace in which the ConfigMap will be created.
+	Namespace string
+}
+
+func NewNodeCoverageConfigMapK8sObject(namespace string) *NodeCoverageConfigMapK8sObject {
+	return &NodeCoverageConfigMapK8sObject{
+		Namespace: namespace,
+	}
+}
+
+func (obj *NodeCoverageConfigMapK8sObject) UpdateUncoveredNodes(uncoveredNodes []models.NodeCoverage) {
+	obj.uncoveredNodes = uncoveredNodes
+}
+
+func (obj *NodeCoverageConfigMapK8sObject) EmptyK8sObject() client.Object { return &coreV1.ConfigMap{} }
+
+func (obj *NodeCoverageConfigMapK8sObject) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: commonState.NodeCoverageConfigMapName, Namespace: obj.Namespace}
+}
+
+func (obj *NodeCoverageConfigMapK8sObject) MutateK8sObject(k8sObject client.Object, agentSpec *cbcontainersv1.CBContainersAgentSpec) error {
+	configMap, ok := k8sObject.(*coreV1.ConfigMap)
+	if !ok {
+		return fmt.Errorf("expected ConfigMap K8s object")
+	}
+
+	uncoveredNodes := obj.uncoveredNodes
+	if uncoveredNodes == nil {
+		uncoveredNodes = []models.NodeCoverage{}
+	}
+	rawCoverage, err := json.MarshalIndent(uncoveredNodes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed marshaling the node coverage: %w", err)
+	}
+
+	configMap.Data = map[string]string{NodeCoverageConfigMapKey: string(rawCoverage)}
+
+	return nil
+}
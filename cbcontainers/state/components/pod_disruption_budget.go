@@ -0,0 +1,111 @@
+package components
+
+import (
+	"fmt"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
+	policyV1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PodDisruptionBudgetSpecGetter returns the pod disruption budget spec of the deployment that a PodDisruptionBudget protects.
+type PodDisruptionBudgetSpecGetter func(agentSpec *cbcontainersv1.CBContainersAgentSpec) *cbcontainersv1.CBContainersPodDisruptionBudgetSpec
+
+// PodDisruptionBudgetK8sObject is a policy/v1 PodDisruptionBudget that limits the evictions of the pods of a deployment
+// of the agent. It has the name of the deployment that it protects.
+type PodDisruptionBudgetK8sObject struct {
+	// Namespace is the Namespace in which the PodDisruptionBudget will be created.
+	Namespace string
+
+	// DeploymentName is the name of the deployment that the PodDisruptionBudget protects.
+	DeploymentName string
+
+	// selectorLabelKey is the label key that the deployment selects its pods by, with the deployment name as its value.
+	selectorLabelKey string
+
+	podDisruptionBudgetSpec PodDisruptionBudgetSpecGetter
+
+	// maxReplicasCount is the most replicas that the deployment may run.
+	maxReplicasCount int32
+}
+
+func NewPodDisruptionBudgetK8sObject(namespace, deploymentName, selectorLabelKey string, podDisruptionBudgetSpec PodDisruptionBudgetSpecGetter) *PodDisruptionBudgetK8sObject {
+	return &PodDisruptionBudgetK8sObject{
+		Namespace:               namespace,
+		DeploymentName:          deploymentName,
+		selectorLabelKey:        selectorLabelKey,
+		podDisruptionBudgetSpec: podDisruptionBudgetSpec,
+	}
+}
+
+func NewEnforcerPodDisruptionBudgetK8sObject(namespace string) *PodDisruptionBudgetK8sObject {
+	return NewPodDisruptionBudgetK8sObject(namespace, EnforcerName, EnforcerLabelKey, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) *cbcontainersv1.CBContainersPodDisruptionBudgetSpec {
+		return &agentSpec.Components.Basic.Enforcer.PodDisruptionBudget
+	})
+}
+
+func NewResolverPodDisruptionBudgetK8sObject(namespace string) *PodDisruptionBudgetK8sObject {
+	return NewPodDisruptionBudgetK8sObject(namespace, ResolverName, resolverLabelKey, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) *cbcontainersv1.CBContainersPodDisruptionBudgetSpec {
+		return &agentSpec.Components.RuntimeProtection.Resolver.PodDisruptionBudget
+	})
+}
+
+func NewImageScanningReporterPodDisruptionBudgetK8sObject(namespace string) *PodDisruptionBudgetK8sObject {
+	return NewPodDisruptionBudgetK8sObject(namespace, ImageScanningReporterName, ImageScanningReporterLabelKey, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) *cbcontainersv1.CBContainersPodDisruptionBudgetSpec {
+		return &agentSpec.Components.ClusterScanning.ImageScanningReporter.PodDisruptionBudget
+	})
+}
+
+// UpdateMaxReplicasCount sets the most replicas that the deployment may run, by its replicas count or its autoscaling.
+func (obj *PodDisruptionBudgetK8sObject) UpdateMaxReplicasCount(maxReplicasCount int32) {
+	obj.maxReplicasCount = maxReplicasCount
+}
+
+// IsRequired returns whether the PodDisruptionBudget should exist. A single replica can't be evicted without an outage,
+// so the PodDisruptionBudget is only required by default while the deployment may run more replicas.
+func (obj *PodDisruptionBudgetK8sObject) IsRequired(agentSpec *cbcontainersv1.CBContainersAgentSpec) bool {
+	podDisruptionBudget := obj.podDisruptionBudgetSpec(agentSpec)
+	if common.IsDisabled(podDisruptionBudget.Enabled) {
+		return false
+	}
+
+	return obj.maxReplicasCount > 1 || podDisruptionBudget.MinAvailable != nil || podDisruptionBudget.MaxUnavailable != nil
+}
+
+func (obj *PodDisruptionBudgetK8sObject) EmptyK8sObject() client.Object {
+	return &policyV1.PodDisruptionBudget{}
+}
+
+func (obj *PodDisruptionBudgetK8sObject) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: obj.DeploymentName, Namespace: obj.Namespace}
+}
+
+func (obj *PodDisruptionBudgetK8sObject) MutateK8sObject(k8sObject client.Object, agentSpec *cbcontainersv1.CBContainersAgentSpec) error {
+	podDisruptionBudget, ok := k8sObject.(*policyV1.PodDisruptionBudget)
+	if !ok {
+		return fmt.Errorf("expected PodDisruptionBudget K8s object")
+	}
+
+	podDisruptionBudgetSpec := obj.podDisruptionBudgetSpec(agentSpec)
+	if podDisruptionBudgetSpec.MinAvailable != nil && podDisruptionBudgetSpec.MaxUnavailable != nil {
+		return fmt.Errorf("only one of the min available and the max unavailable pods of the %v pod disruption budget can be set", obj.DeploymentName)
+	}
+
+	podDisruptionBudget.Spec.Selector = &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			obj.selectorLabelKey: obj.DeploymentName,
+		},
+	}
+	podDisruptionBudget.Spec.MinAvailable = podDisruptionBudgetSpec.MinAvailable
+	podDisruptionBudget.Spec.MaxUnavailable = podDisruptionBudgetSpec.MaxUnavailable
+	if podDisruptionBudget.Spec.MinAvailable == nil && podDisruptionBudget.Spec.MaxUnavailable == nil {
+		defaultMaxUnavailable := intstr.FromInt32(1)
+		podDisruptionBudget.Spec.MaxUnavailable = &defaultMaxUnavailable
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package components_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	policyV1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestPodDisruptionBudget(t *testing.T) {
+	minAvailable, maxUnavailable, defaultMaxUnavailable := intstr.FromString("50%"), intstr.FromInt32(2), intstr.FromInt32(1)
+	disabled := false
+
+	tests := map[string]struct {
+		builder                *components.PodDisruptionBudgetK8sObject
+		maxReplicasCount       int32
+		podDisruptionBudget    cbcontainersv1.CBContainersPodDisruptionBudgetSpec
+		expectedRequired       bool
+		expectedSelectorLabels map[string]string
+		expectedMinAvailable   *intstr.IntOrString
+		expectedMaxUnavailable *intstr.IntOrString
+		expectedError          string
+	}{
+		"When the deployment runs a single replica, should not be required": {
+			builder:                components.NewEnforcerPodDisruptionBudgetK8sObject(testNamespace),
+			maxReplicasCount:       1,
+			expectedSelectorLabels: map[string]string{components.EnforcerLabelKey: components.EnforcerName},
+			expectedMaxUnavailable: &defaultMaxUnavailable,
+		},
+		"When the deployment runs multiple replicas, should allow one unavailable pod": {
+			builder:                components.NewEnforcerPodDisruptionBudgetK8sObject(testNamespace),
+			maxReplicasCount:       3,
+			expectedRequired:       true,
+			expectedSelectorLabels: map[string]string{components.EnforcerLabelKey: components.EnforcerName},
+			expectedMaxUnavailable: &defaultMaxUnavailable,
+		},
+		"When the min available pods are set, should be required even for a single replica": {
+			builder:                components.NewImageScanningReporterPodDisruptionBudgetK8sObject(testNamespace),
+			maxReplicasCount:       1,
+			podDisruptionBudget:    cbcontainersv1.CBContainersPodDisruptionBudgetSpec{MinAvailable: &minAvailable},
+			expectedRequired:       true,
+			expectedSelectorLabels: map[string]string{components.ImageScanningReporterLabelKey: components.ImageScanningReporterName},
+			expectedMinAvailable:   &minAvailable,
+		},
+		"When the max unavailable pods are set, should allow them to be unavailable": {
+			builder:                components.NewResolverPodDisruptionBudgetK8sObject(testNamespace),
+			maxReplicasCount:       3,
+			podDisruptionBudget:    cbcontainersv1.CBContainersPodDisruptionBudgetSpec{MaxUnavailable: &maxUnavailable},
+			expectedRequired:       true,
+			expectedSelectorLabels: map[string]string{"app.kubernetes.io/name": components.ResolverName},
+			expectedMaxUnavailable: &maxUnavailable,
+		},
+		"When it's disabled, should not be required": {
+			builder:                components.NewEnforcerPodDisruptionBudgetK8sObject(testNamespace),
+			maxReplicasCount:       3,
+			podDisruptionBudget:    cbcontainersv1.CBContainersPodDisruptionBudgetSpec{Enabled: &disabled},
+			expectedSelectorLabels: map[string]string{components.EnforcerLabelKey: components.EnforcerName},
+			expectedMaxUnavailable: &defaultMaxUnavailable,
+		},
+		"When both the min available and the max unavailable pods are set, should return an error": {
+			builder:             components.NewEnforcerPodDisruptionBudgetK8sObject(testNamespace),
+			maxReplicasCount:    3,
+			podDisruptionBudget: cbcontainersv1.CBContainersPodDisruptionBudgetSpec{MinAvailable: &minAvailable, MaxUnavailable: &maxUnavailable},
+			expectedRequired:    true,
+			expectedError:       "only one of the min available and the max unavailable pods",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			agentSpec := testAgentSpec(t, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.Basic.Enforcer.PodDisruptionBudget = test.podDisruptionBudget
+				agentSpec.Components.RuntimeProtection.Resolver.PodDisruptionBudget = test.podDisruptionBudget
+				agentSpec.Components.ClusterScanning.ImageScanningReporter.PodDisruptionBudget = test.podDisruptionBudget
+			})
+			test.builder.UpdateMaxReplicasCount(test.maxReplicasCount)
+
+			require.Equal(t, test.expectedRequired, test.builder.IsRequired(agentSpec))
+			k8sObject, err := mutatedK8sObject(test.builder, agentSpec)
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			podDisruptionBudget := k8sObject.(*policyV1.PodDisruptionBudget)
+			require.Equal(t, test.expectedSelectorLabels, podDisruptionBudget.Spec.Selector.MatchLabels)
+			require.Equal(t, test.expectedMinAvailable, podDisruptionBudget.Spec.MinAvailable)
+			require.Equal(t, test.expectedMaxUnavailable, podDisruptionBudget.Spec.MaxUnavailable)
+		})
+	}
+}
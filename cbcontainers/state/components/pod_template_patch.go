@@ -0,0 +1,210 @@
+package components
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+const (
+	// PodTemplateChecksumAnnotation is the checksum of the patched pod template that a workload was applied with, so
+	// the patched pod template is validated again only when it changes.
+	PodTemplateChecksumAnnotation = "operator.containers.carbonblack.io/pod-template-checksum"
+)
+
+// PodTemplatePatchError is returned when the pod template patch of a workload can't be applied.
+type PodTemplatePatchError struct {
+	WorkloadName string
+	Reason       string
+}
+
+func (err *PodTemplatePatchError) Error() string {
+	return fmt.Sprintf("invalid pod template patch of %v: %v", err.WorkloadName, err.Reason)
+}
+
+// mutatePodTemplatePatch applies the strategic merge patch to the pod template that the operator built, and rejects
+// patches that change the security-critical fields of the pod template.
+// The patch is merged into the built pod template on every reconcile, so the fields that the API server filled are
+// kept, and the patched pod template is validated whenever its checksum changes.
+func mutatePodTemplatePatch(workloadMeta *metav1.ObjectMeta, template *coreV1.PodTemplateSpec, patch *runtime.RawExtension, workloadName string) error {
+	if patch == nil || len(patch.Raw) == 0 {
+		delete(workloadMeta.Annotations, PodTemplateChecksumAnnotation)
+		return nil
+	}
+
+	patchedTemplate, err := patchPodTemplate(template, patch.Raw)
+	if err != nil {
+		return &PodTemplatePatchError{WorkloadName: workloadName, Reason: err.Error()}
+	}
+	rawPatchedTemplate, err := json.Marshal(patchedTemplate)
+	if err != nil {
+		return fmt.Errorf("failed marshaling the patched pod template of %v: %w", workloadName, err)
+	}
+	checksum := sha256.Sum256(rawPatchedTemplate)
+	desiredChecksum := hex.EncodeToString(checksum[:])
+
+	if workloadMeta.Annotations == nil {
+		workloadMeta.Annotations = make(map[string]string)
+	}
+	if workloadMeta.Annotations[PodTemplateChecksumAnnotation] != desiredChecksum {
+		if err := validatePatchedPodTemplate(template, patchedTemplate); err != nil {
+			return &PodTemplatePatchError{WorkloadName: workloadName, Reason: err.Error()}
+		}
+		workloadMeta.Annotations[PodTemplateChecksumAnnotation] = desiredChecksum
+	}
+	*template = *patchedTemplate
+
+	return nil
+}
+
+func patchPodTemplate(template *coreV1.PodTemplateSpec, patch []byte) (*coreV1.PodTemplateSpec, error) {
+	rawTemplate, err := json.Marshal(template)
+	if err != nil {
+		return nil, err
+	}
+
+	rawPatchedTemplate, err := strategicpatch.StrategicMergePatch(rawTemplate, patch, coreV1.PodTemplateSpec{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown fields are rejected, so a misspelled field isn't silently dropped
+	patchedTemplate := &coreV1.PodTemplateSpec{}
+	decoder := json.NewDecoder(bytes.NewReader(rawPatchedTemplate))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patchedTemplate); err != nil {
+		return nil, err
+	}
+
+	return patchedTemplate, nil
+}
+
+// validatePatchedPodTemplate returns an error when the patch changes the labels that the workload selects its pods
+// by, the identity or the security context of the pods or their containers or their access to the host, or adds
+// containers with privileges.
+func validatePatchedPodTemplate(template, patchedTemplate *coreV1.PodTemplateSpec) error {
+	for key, value := range template.Labels {
+		if patchedTemplate.Labels[key] != value {
+			return fmt.Errorf("the %v label can't be changed", key)
+		}
+	}
+
+	podSpec, patchedPodSpec := &template.Spec, &patchedTemplate.Spec
+	if patchedPodSpec.ServiceAccountName != podSpec.ServiceAccountName || patchedPodSpec.DeprecatedServiceAccount != podSpec.DeprecatedServiceAccount {
+		return fmt.Errorf("the service account can't be changed")
+	}
+	if !boolPointersEqual(patchedPodSpec.AutomountServiceAccountToken, podSpec.AutomountServiceAccountToken) {
+		return fmt.Errorf("the automount of the service account token can't be changed")
+	}
+	if patchedPodSpec.HostNetwork != podSpec.HostNetwork || patchedPodSpec.HostPID != podSpec.HostPID || patchedPodSpec.HostIPC != podSpec.HostIPC {
+		return fmt.Errorf("the host namespaces can't be changed")
+	}
+	if !equality.Semantic.DeepEqual(podSecurityContextOrEmpty(patchedPodSpec), podSecurityContextOrEmpty(podSpec)) {
+		return fmt.Errorf("the pod security context can't be changed")
+	}
+
+	hostPathVolumes := make(map[string]coreV1.HostPathVolumeSource)
+	for _, volume := range podSpec.Volumes {
+		if volume.HostPath != nil {
+			hostPathVolumes[volume.Name] = *volume.HostPath
+		}
+	}
+	for _, volume := range patchedPodSpec.Volumes {
+		if hostPath, ok := hostPathVolumes[volume.Name]; volume.HostPath != nil && (!ok || hostPath.Path != volume.HostPath.Path) {
+			return fmt.Errorf("the %v volume can't mount a host path", volume.Name)
+		}
+	}
+
+	containers := make(map[string]*coreV1.Container)
+	for _, containersList := range [][]coreV1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containersList {
+			containers[containersList[i].Name] = &containersList[i]
+		}
+	}
+	for _, containersList := range [][]coreV1.Container{patchedPodSpec.InitContainers, patchedPodSpec.Containers} {
+		for i := range containersList {
+			container := containers[containersList[i].Name]
+			if err := validatePatchedContainerSecurityContext(container, &containersList[i]); err != nil {
+				return err
+			}
+			if err := validatePatchedContainerHostPathMounts(container, &containersList[i], hostPathVolumes); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validatePatchedContainerSecurityContext returns an error when the patch changes the security context of a container
+// that the operator built, or adds a container with privileges.
+func validatePatchedContainerSecurityContext(container, patchedContainer *coreV1.Container) error {
+	if container != nil {
+		if !equality.Semantic.DeepEqual(containerSecurityContextOrEmpty(patchedContainer), containerSecurityContextOrEmpty(container)) {
+			return fmt.Errorf("the security context of the %v container can't be changed", patchedContainer.Name)
+		}
+		return nil
+	}
+
+	securityContext := containerSecurityContextOrEmpty(patchedContainer)
+	if commonState.IsEnabled(securityContext.Privileged) {
+		return fmt.Errorf("the %v container can't be privileged", patchedContainer.Name)
+	}
+	if securityContext.Capabilities != nil && len(securityContext.Capabilities.Add) > 0 {
+		return fmt.Errorf("the %v capability can't be added to the %v container", securityContext.Capabilities.Add[0], patchedContainer.Name)
+	}
+
+	return nil
+}
+
+// validatePatchedContainerHostPathMounts returns an error when the patched container mounts a host path volume that the
+// container that the operator built doesn't mount. Containers that the patch adds can't mount host path volumes.
+func validatePatchedContainerHostPathMounts(container, patchedContainer *coreV1.Container, hostPathVolumes map[string]coreV1.HostPathVolumeSource) error {
+	mountedVolumes := make(map[string]struct{})
+	if container != nil {
+		for _, volumeMount := range container.VolumeMounts {
+			mountedVolumes[volumeMount.Name] = struct{}{}
+		}
+	}
+
+	for _, volumeMount := range patchedContainer.VolumeMounts {
+		if _, ok := hostPathVolumes[volumeMount.Name]; !ok {
+			continue
+		}
+		if _, ok := mountedVolumes[volumeMount.Name]; !ok {
+			return fmt.Errorf("the %v host path volume can't be mounted by the %v container", volumeMount.Name, patchedContainer.Name)
+		}
+	}
+
+	return nil
+}
+
+func podSecurityContextOrEmpty(podSpec *coreV1.PodSpec) *coreV1.PodSecurityContext {
+	if podSpec.SecurityContext == nil {
+		return &coreV1.PodSecurityContext{}
+	}
+	return podSpec.SecurityContext
+}
+
+func containerSecurityContextOrEmpty(container *coreV1.Container) *coreV1.SecurityContext {
+	if container.SecurityContext == nil {
+		return &coreV1.SecurityContext{}
+	}
+	return container.SecurityContext
+}
+
+func boolPointersEqual(a, b *bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
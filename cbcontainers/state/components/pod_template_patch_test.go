@@ -0,0 +1,142 @@
+package components
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func testPodTemplate() *coreV1.PodTemplateSpec {
+	runAsUser, runAsNonRoot, readOnlyRootFilesystem, allowPrivilegeEscalation := int64(1500), true, true, false
+
+	return &coreV1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app.kubernetes.io/name": "workload"}},
+		Spec: coreV1.PodSpec{
+			ServiceAccountName: "service-account",
+			SecurityContext:    &coreV1.PodSecurityContext{RunAsUser: &runAsUser},
+			Containers: []coreV1.Container{{
+				Name:  "container",
+				Image: "image",
+				SecurityContext: &coreV1.SecurityContext{
+					RunAsUser:                &runAsUser,
+					RunAsNonRoot:             &runAsNonRoot,
+					ReadOnlyRootFilesystem:   &readOnlyRootFilesystem,
+					AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+					Capabilities:             &coreV1.Capabilities{Drop: []coreV1.Capability{"ALL"}},
+					SeccompProfile:           &coreV1.SeccompProfile{Type: coreV1.SeccompProfileTypeRuntimeDefault},
+				},
+				VolumeMounts: []coreV1.VolumeMount{{Name: "host-volume", MountPath: "/host"}},
+			}},
+			Volumes: []coreV1.Volume{{
+				Name:         "host-volume",
+				VolumeSource: coreV1.VolumeSource{HostPath: &coreV1.HostPathVolumeSource{Path: "/var/run"}},
+			}},
+		},
+	}
+}
+
+func TestMutatePodTemplatePatch(t *testing.T) {
+	tests := map[string]struct {
+		patch         string
+		expectedError string
+	}{
+		"When the patch adds a sidecar container and changes the resources, should patch the pod template": {
+			patch: `{"spec":{"containers":[{"name":"container","resources":{"limits":{"memory":"64Mi"}}},{"name":"sidecar","image":"image"}]}}`,
+		},
+		"When the patch changes the selector labels, should reject it": {
+			patch:         `{"metadata":{"labels":{"app.kubernetes.io/name":"other"}}}`,
+			expectedError: "the app.kubernetes.io/name label can't be changed",
+		},
+		"When the patch changes the service account, should reject it": {
+			patch:         `{"spec":{"serviceAccountName":"other"}}`,
+			expectedError: "the service account can't be changed",
+		},
+		"When the patch uses the host network, should reject it": {
+			patch:         `{"spec":{"hostNetwork":true}}`,
+			expectedError: "the host namespaces can't be changed",
+		},
+		"When the patch changes the pod security context, should reject it": {
+			patch:         `{"spec":{"securityContext":{"runAsUser":0}}}`,
+			expectedError: "the pod security context can't be changed",
+		},
+		"When the patch adds a host path volume, should reject it": {
+			patch:         `{"spec":{"volumes":[{"name":"other-host-volume","hostPath":{"path":"/"}}]}}`,
+			expectedError: "the other-host-volume volume can't mount a host path",
+		},
+		"When the patch mounts a host path volume in an added container, should reject it": {
+			patch:         `{"spec":{"containers":[{"name":"sidecar","image":"image","volumeMounts":[{"name":"host-volume","mountPath":"/host"}]}]}}`,
+			expectedError: "the host-volume host path volume can't be mounted by the sidecar container",
+		},
+		"When the patch changes the user of a container, should reject it": {
+			patch:         `{"spec":{"containers":[{"name":"container","securityContext":{"runAsUser":0}}]}}`,
+			expectedError: "the security context of the container container can't be changed",
+		},
+		"When the patch lets a container run as root, should reject it": {
+			patch:         `{"spec":{"containers":[{"name":"container","securityContext":{"runAsNonRoot":false}}]}}`,
+			expectedError: "the security context of the container container can't be changed",
+		},
+		"When the patch makes the root filesystem of a container writable, should reject it": {
+			patch:         `{"spec":{"containers":[{"name":"container","securityContext":{"readOnlyRootFilesystem":false}}]}}`,
+			expectedError: "the security context of the container container can't be changed",
+		},
+		"When the patch changes the dropped capabilities of a container, should reject it": {
+			patch:         `{"spec":{"containers":[{"name":"container","securityContext":{"capabilities":{"drop":["NET_RAW"]}}}]}}`,
+			expectedError: "the security context of the container container can't be changed",
+		},
+		"When the patch removes the seccomp profile of a container, should reject it": {
+			patch:         `{"spec":{"containers":[{"name":"container","securityContext":{"seccompProfile":null}}]}}`,
+			expectedError: "the security context of the container container can't be changed",
+		},
+		"When the patch removes the security context of a container, should reject it": {
+			patch:         `{"spec":{"containers":[{"name":"container","securityContext":null}]}}`,
+			expectedError: "the security context of the container container can't be changed",
+		},
+		"When the patch adds a privileged container, should reject it": {
+			patch:         `{"spec":{"containers":[{"name":"sidecar","image":"image","securityContext":{"privileged":true}}]}}`,
+			expectedError: "the sidecar container can't be privileged",
+		},
+		"When the patch adds a container with added capabilities, should reject it": {
+			patch:         `{"spec":{"initContainers":[{"name":"init","image":"image","securityContext":{"capabilities":{"add":["NET_ADMIN"]}}}]}}`,
+			expectedError: "the NET_ADMIN capability can't be added to the init container",
+		},
+		"When the patch has an unknown field, should reject it": {
+			patch:         `{"spec":{"hostAlias":[]}}`,
+			expectedError: "unknown field",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			workloadMeta := &metav1.ObjectMeta{}
+			template := testPodTemplate()
+
+			err := mutatePodTemplatePatch(workloadMeta, template, &runtime.RawExtension{Raw: []byte(test.patch)}, "workload")
+
+			if test.expectedError == "" {
+				require.NoError(t, err)
+				require.NotEmpty(t, workloadMeta.Annotations[PodTemplateChecksumAnnotation])
+				require.Len(t, template.Spec.Containers, 2)
+				require.Equal(t, testPodTemplate().Spec.Containers[0].SecurityContext, template.Spec.Containers[0].SecurityContext)
+				return
+			}
+			var patchErr *PodTemplatePatchError
+			require.True(t, errors.As(err, &patchErr))
+			require.Equal(t, "workload", patchErr.WorkloadName)
+			require.ErrorContains(t, err, test.expectedError)
+			require.Equal(t, testPodTemplate(), template)
+		})
+	}
+
+	t.Run("When the patch is removed, should remove the checksum of the patched pod template", func(t *testing.T) {
+		workloadMeta := &metav1.ObjectMeta{Annotations: map[string]string{PodTemplateChecksumAnnotation: "checksum"}}
+		template := testPodTemplate()
+
+		require.NoError(t, mutatePodTemplatePatch(workloadMeta, template, nil, "workload"))
+		require.NotContains(t, workloadMeta.Annotations, PodTemplateChecksumAnnotation)
+		require.Equal(t, testPodTemplate(), template)
+	})
+}
@@ -5,6 +5,7 @@ import (
 
 	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/adapters"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/capabilities"
 	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -17,17 +18,17 @@ const (
 )
 
 type PriorityClassK8sObject struct {
-	kubeletVersion string
+	capabilitiesProvider capabilities.Provider
 }
 
-func NewPriorityClassK8sObject(kubeletVersion string) *PriorityClassK8sObject {
+func NewPriorityClassK8sObject(capabilitiesProvider capabilities.Provider) *PriorityClassK8sObject {
 	return &PriorityClassK8sObject{
-		kubeletVersion: kubeletVersion,
+		capabilitiesProvider: capabilitiesProvider,
 	}
 }
 
 func (obj *PriorityClassK8sObject) EmptyK8sObject() client.Object {
-	return adapters.EmptyPriorityClassForVersion(obj.kubeletVersion)
+	return adapters.EmptyPriorityClassForCapabilities(obj.capabilitiesProvider.Capabilities())
 }
 
 func (obj *PriorityClassK8sObject) NamespacedName() types.NamespacedName {
@@ -1,7 +1,6 @@
 package components
 
 import (
-	"context"
 	"fmt"
 	cbContainersV1 "github.com/vmware/cbcontainers-operator/api/v1"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment"
@@ -12,6 +11,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"math"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
 )
 
 const (
@@ -33,16 +33,95 @@ var (
 type ResolverDeploymentK8sObject struct {
 	// Namespace is the Namespace in which the Deployment will be created.
 	Namespace string
-	APIReader client.Reader
+
+	// replicasCount is the replicas count that was derived from the number of nodes.
+	replicasCount *int32
+
+	// configChecksum is the checksum of the ConfigMaps and Secrets keys that the pods consume.
+	configChecksum string
 }
 
-func NewResolverDeploymentK8sObject(namespace string, apiReader client.Reader) *ResolverDeploymentK8sObject {
+func NewResolverDeploymentK8sObject(namespace string) *ResolverDeploymentK8sObject {
 	return &ResolverDeploymentK8sObject{
 		Namespace: namespace,
-		APIReader: apiReader,
 	}
 }
 
+// UpdateConfigChecksum sets the checksum of the ConfigMaps and Secrets keys that the pods consume, which rolls the
+// pods when it changes.
+func (obj *ResolverDeploymentK8sObject) UpdateConfigChecksum(configChecksum string) {
+	obj.configChecksum = configChecksum
+}
+
+func (obj *ResolverDeploymentK8sObject) UpdateReplicasCount(replicasCount int32) {
+	obj.replicasCount = &replicasCount
+}
+
+// ResolverReplicasForNodes derives the replicas count of the resolver from the number of nodes, by the nodes to replicas
+// ratio. The replicas count is bounded by the min and max replicas.
+func ResolverReplicasForNodes(resolver *cbContainersV1.CBContainersRuntimeResolverSpec, nodesCount int) int32 {
+	nodesToReplicasRatio := resolver.NodesToReplicasRatio
+	if nodesToReplicasRatio < 1 {
+		nodesToReplicasRatio = 1
+	}
+	replicasCount := int32(math.Ceil(float64(nodesCount) / float64(nodesToReplicasRatio)))
+
+	minReplicas := int32(1)
+	if resolver.MinReplicas != nil && *resolver.MinReplicas > minReplicas {
+		minReplicas = *resolver.MinReplicas
+	}
+	if replicasCount < minReplicas {
+		replicasCount = minReplicas
+	}
+	if resolver.MaxReplicas != nil && replicasCount > *resolver.MaxReplicas && *resolver.MaxReplicas >= minReplicas {
+		replicasCount = *resolver.MaxReplicas
+	}
+
+	return replicasCount
+}
+
+// ResolverNodesCountForScaling returns the number of nodes that the replicas count of the resolver is derived from.
+// While fewer nodes than the scale threshold were added or removed since the last scaling, it's the number of nodes
+// of the last scaling.
+func ResolverNodesCountForScaling(resolver *cbContainersV1.CBContainersRuntimeResolverSpec, lastScaling *cbContainersV1.CBContainersReplicasScalingStatus, nodesCount int) int {
+	if lastScaling == nil {
+		return nodesCount
+	}
+
+	threshold := int(resolver.ScaleNodesThreshold)
+	if threshold < 1 {
+		threshold = 1
+	}
+	if nodesChange := nodesCount - int(lastScaling.Nodes); nodesChange < threshold && -nodesChange < threshold {
+		return int(lastScaling.Nodes)
+	}
+	return nodesCount
+}
+
+// UntilResolverScaling returns how long until the resolver may be scaled to the desired replicas count by the scale up
+// or scale down cooldown, or 0 when it may be scaled already or there is no scaling to wait for.
+func UntilResolverScaling(agent *cbContainersV1.CBContainersAgent, desiredReplicas int32) time.Duration {
+	lastScaling := agent.Status.RuntimeResolverScaling
+	if lastScaling == nil || desiredReplicas == lastScaling.Replicas {
+		return 0
+	}
+
+	resolver := &agent.Spec.Components.RuntimeProtection.Resolver
+	cooldown := resolver.ScaleDownCooldown
+	if desiredReplicas > lastScaling.Replicas {
+		cooldown = resolver.ScaleUpCooldown
+	}
+	if cooldown == nil {
+		return 0
+	}
+
+	untilScaling := time.Until(lastScaling.LastScaleTime.Add(cooldown.Duration))
+	if untilScaling < 0 {
+		return 0
+	}
+	return untilScaling
+}
+
 func (obj *ResolverDeploymentK8sObject) EmptyK8sObject() client.Object {
 	return &appsV1.Deployment{}
 }
@@ -83,13 +162,14 @@ func (obj *ResolverDeploymentK8sObject) MutateK8sObject(k8sObject client.Object,
 
 	if resolver.ReplicasCount != nil {
 		replicasCount = resolver.ReplicasCount
-	} else {
-		if dynamicReplicasCount, err := obj.getDynamicReplicasCount(resolver.NodesToReplicasRatio); err == nil {
-			replicasCount = dynamicReplicasCount
-		}
+	} else if obj.replicasCount != nil {
+		replicasCount = obj.replicasCount
 	}
 
-	deployment.Spec.Replicas = replicasCount
+	// While autoscaling, the replicas are set by the HorizontalPodAutoscaler
+	if commonState.IsDisabled(resolver.Autoscaling.Enabled) {
+		deployment.Spec.Replicas = replicasCount
+	}
 	deployment.ObjectMeta.Labels = desiredLabels
 	deployment.Spec.Selector.MatchLabels = desiredLabels
 	deployment.Spec.Template.ObjectMeta.Labels = desiredLabels
@@ -102,10 +182,17 @@ func (obj *ResolverDeploymentK8sObject) MutateK8sObject(k8sObject client.Object,
 	obj.mutateAnnotations(deployment, agentSpec)
 	obj.mutateVolumes(deployment, agentSpec)
 	obj.mutateAffinityAndNodeSelector(deployment, agentSpec)
+	mutateTopologySpreadConstraints(&deployment.Spec.Template.Spec, resolver.TopologySpreadConstraints, resolver.Affinity, DeploymentMaxReplicas(replicasCount, &resolver.Autoscaling, 1), resolverLabelKey, ResolverName)
 	obj.mutateContainersList(deployment, agentSpec)
-	commonState.NewNodeTermsBuilder(&deployment.Spec.Template.Spec).Build()
+	architectures, err := commonState.GetArchitectures(&agentSpec.Components.Settings, resolver.Architectures, &resolver.Image)
+	if err != nil {
+		return err
+	}
+	commonState.NewNodeTermsBuilder(&deployment.Spec.Template.Spec, architectures).Build()
+
+	mutateConfigChecksum(&deployment.Spec.Template.ObjectMeta, obj.configChecksum)
 
-	return nil
+	return mutatePodTemplatePatch(&deployment.ObjectMeta, &deployment.Spec.Template, resolver.PodTemplatePatch, ResolverName)
 }
 
 func (obj *ResolverDeploymentK8sObject) mutateVolumes(deployment *appsV1.Deployment, agentSpec *cbContainersV1.CBContainersAgentSpec) {
@@ -121,7 +208,8 @@ func (obj *ResolverDeploymentK8sObject) mutateAffinityAndNodeSelector(deployment
 	resolverSpec := &agentSpec.Components.RuntimeProtection.Resolver
 
 	templatePodSpec := &deployment.Spec.Template.Spec
-	templatePodSpec.Affinity = resolverSpec.Affinity
+	// The node terms are added to a copy of the affinity, so the affinity of the spec is kept as it was set
+	templatePodSpec.Affinity = resolverSpec.Affinity.DeepCopy()
 	templatePodSpec.NodeSelector = resolverSpec.NodeSelector
 }
 
@@ -206,6 +294,7 @@ func (obj *ResolverDeploymentK8sObject) mutateEnvVars(
 		WithEventsGateway(eventsGatewaySpec).
 		WithCustom(customEnvs...).
 		WithSpec(resolverSpec.Env).
+		WithExtraEnv(resolverSpec.ExtraEnv).
 		WithProxySettings(agentSpec.Components.Settings.Proxy)
 	commonState.MutateEnvVars(container, envVarBuilder)
 }
@@ -231,13 +320,3 @@ func (obj *ResolverDeploymentK8sObject) mutateVolumesMounts(container *coreV1.Co
 
 	commonState.MutateVolumeMountToIncludeRootCAsVolumeMount(container)
 }
-
-func (obj *ResolverDeploymentK8sObject) getDynamicReplicasCount(nodesToReplicasRatio int32) (*int32, error) {
-	nodesList := &coreV1.NodeList{}
-	if err := obj.APIReader.List(context.Background(), nodesList); err != nil || nodesList.Items == nil || len(nodesList.Items) < 1 {
-		return nil, fmt.Errorf("error getting list of nodes: %v", err)
-	}
-	replicasCount := int32(math.Ceil(float64(len(nodesList.Items)) / float64(nodesToReplicasRatio)))
-
-	return &replicasCount, nil
-}
@@ -0,0 +1,38 @@
+package components_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+)
+
+func TestResolverDeploymentArchitectures(t *testing.T) {
+	tests := map[string]struct {
+		changeSpec            func(agentSpec *cbcontainersv1.CBContainersAgentSpec)
+		expectedArchitectures []string
+	}{
+		"With default spec, should schedule the pods on the default architectures": {
+			expectedArchitectures: commonState.DefaultArchitectures,
+		},
+		"When the resolver has architectures of its own, should schedule the pods on them instead of the settings ones": {
+			changeSpec: func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.Settings.Architectures = []string{"amd64", "arm64"}
+				agentSpec.Components.RuntimeProtection.Resolver.Architectures = []string{"arm64"}
+				agentSpec.Components.RuntimeProtection.Resolver.Image.MultiArch = true
+			},
+			expectedArchitectures: []string{"arm64"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			deployment, err := mutatedK8sObject(components.NewResolverDeploymentK8sObject(testNamespace), testAgentSpec(t, test.changeSpec))
+
+			require.NoError(t, err)
+			require.Equal(t, test.expectedArchitectures, nodeArchitectures(&podTemplate(t, deployment).Spec))
+		})
+	}
+}
@@ -20,7 +20,7 @@ const (
 	RuntimeContainerName         = "cbcontainers-runtime"
 	ClusterScanningContainerName = "cbcontainers-cluster-scanner"
 	CndrContainerName            = "cbcontainers-cndr"
-	daemonSetLabelKey            = "app.kubernetes.io/name"
+	DaemonSetLabelKey            = "app.kubernetes.io/name"
 
 	runtimeSensorRunCommand  = "/run_sensor.sh"
 	defaultDnsPolicy         = coreV1.DNSClusterFirst
@@ -97,6 +97,11 @@ var (
 type SensorDaemonSetK8sObject struct {
 	// Namespace is the Namespace in which the DaemonSet will be created.
 	Namespace string
+
+	discoveredTolerations []coreV1.Toleration
+
+	// configChecksum is the checksum of the ConfigMaps and Secrets keys that the pods consume.
+	configChecksum string
 }
 
 func NewSensorDaemonSetK8sObject(namespace string) *SensorDaemonSetK8sObject {
@@ -105,6 +110,12 @@ func NewSensorDaemonSetK8sObject(namespace string) *SensorDaemonSetK8sObject {
 	}
 }
 
+// UpdateConfigChecksum sets the checksum of the ConfigMaps and Secrets keys that the pods consume, which rolls the
+// pods when it changes.
+func (obj *SensorDaemonSetK8sObject) UpdateConfigChecksum(configChecksum string) {
+	obj.configChecksum = configChecksum
+}
+
 func (obj *SensorDaemonSetK8sObject) EmptyK8sObject() client.Object {
 	return &appsV1.DaemonSet{}
 }
@@ -140,9 +151,15 @@ func (obj *SensorDaemonSetK8sObject) MutateK8sObject(k8sObject client.Object, ag
 	obj.mutateVolumes(daemonSet, agentSpec)
 	obj.mutateTolerations(daemonSet, agentSpec)
 	obj.mutateContainersList(daemonSet, agentSpec)
-	commonState.NewNodeTermsBuilder(&daemonSet.Spec.Template.Spec).Build()
+	architectures, err := obj.Architectures(agentSpec)
+	if err != nil {
+		return err
+	}
+	commonState.NewNodeTermsBuilder(&daemonSet.Spec.Template.Spec, architectures).Build()
+
+	mutateConfigChecksum(&daemonSet.Spec.Template.ObjectMeta, obj.configChecksum)
 
-	return nil
+	return mutatePodTemplatePatch(&daemonSet.ObjectMeta, &daemonSet.Spec.Template, runtimeProtection.Sensor.PodTemplatePatch, DaemonSetName)
 }
 
 func (obj *SensorDaemonSetK8sObject) initiateDaemonSet(daemonSet *appsV1.DaemonSet, agentSpec *cbContainersV1.CBContainersAgentSpec) {
@@ -168,7 +185,7 @@ func (obj *SensorDaemonSetK8sObject) initiateDaemonSet(daemonSet *appsV1.DaemonS
 
 func (obj *SensorDaemonSetK8sObject) mutateLabels(daemonSet *appsV1.DaemonSet, agentSpec *cbContainersV1.CBContainersAgentSpec) {
 	desiredLabels := make(map[string]string)
-	desiredLabels[daemonSetLabelKey] = DaemonSetName
+	desiredLabels[DaemonSetLabelKey] = DaemonSetName
 	if commonState.IsEnabled(agentSpec.Components.RuntimeProtection.Enabled) {
 		applyment.EnforceMapContains(desiredLabels, agentSpec.Components.RuntimeProtection.Sensor.Labels)
 	}
@@ -211,6 +228,55 @@ func (obj *SensorDaemonSetK8sObject) mutateAnnotations(daemonSet *appsV1.DaemonS
 	}
 }
 
+// Architectures returns the node architectures that the daemon set is scheduled on,
+// which are the architectures that all of its enabled containers run on.
+func (obj *SensorDaemonSetK8sObject) Architectures(agentSpec *cbContainersV1.CBContainersAgentSpec) ([]string, error) {
+	settings := &agentSpec.Components.Settings
+	var architectures []string
+	intersect := func(componentArchitectures []string, image *cbContainersV1.CBContainersImageSpec) error {
+		containerArchitectures, err := commonState.GetArchitectures(settings, componentArchitectures, image)
+		if err != nil {
+			return err
+		}
+		if architectures == nil {
+			architectures = containerArchitectures
+		} else {
+			architectures = commonState.IntersectArchitectures(architectures, containerArchitectures)
+		}
+		return nil
+	}
+
+	if commonState.IsEnabled(agentSpec.Components.RuntimeProtection.Enabled) {
+		sensor := &agentSpec.Components.RuntimeProtection.Sensor
+		if err := intersect(sensor.Architectures, &sensor.Image); err != nil {
+			return nil, err
+		}
+	}
+
+	if commonState.IsEnabled(agentSpec.Components.ClusterScanning.Enabled) {
+		clusterScanner := &agentSpec.Components.ClusterScanning.ClusterScannerAgent
+		if err := intersect(clusterScanner.Architectures, &clusterScanner.Image); err != nil {
+			return nil, err
+		}
+	}
+
+	if isCndrEnbaled(agentSpec.Components.Cndr) {
+		cndrSensor := &agentSpec.Components.Cndr.Sensor
+		if err := intersect(cndrSensor.Architectures, &cndrSensor.Image); err != nil {
+			return nil, err
+		}
+	}
+
+	if architectures == nil {
+		return commonState.GetArchitectures(settings, nil, &cbContainersV1.CBContainersImageSpec{})
+	}
+	if len(architectures) == 0 {
+		return nil, fmt.Errorf("the containers of the daemon set don't run on any common architecture")
+	}
+
+	return architectures, nil
+}
+
 func isCndrEnbaled(cndrSpec *cbContainersV1.CBContainersCndrSpec) bool {
 	return cndrSpec != nil && commonState.IsEnabled(cndrSpec.Enabled)
 }
@@ -268,7 +334,7 @@ func (obj *SensorDaemonSetK8sObject) mutateVolumes(daemonSet *appsV1.DaemonSet,
 }
 
 func (obj *SensorDaemonSetK8sObject) mutateTolerations(daemonSet *appsV1.DaemonSet, agentSpec *cbContainersV1.CBContainersAgentSpec) {
-	daemonSet.Spec.Template.Spec.Tolerations = agentSpec.Components.Settings.DaemonSetsTolerations
+	daemonSet.Spec.Template.Spec.Tolerations = obj.Tolerations(agentSpec)
 }
 
 func (obj *SensorDaemonSetK8sObject) mutateContainersList(daemonSet *appsV1.DaemonSet, agentSpec *cbContainersV1.CBContainersAgentSpec) {
@@ -417,6 +483,7 @@ func (obj *SensorDaemonSetK8sObject) mutateRuntimeEnvVars(container *coreV1.Cont
 	envVarBuilder := commonState.NewEnvVarBuilder().
 		WithCustom(customEnvs...).
 		WithSpec(sensorSpec.Env).
+		WithExtraEnv(sensorSpec.ExtraEnv).
 		WithProxySettings(agentSpec.Components.Settings.Proxy)
 	commonState.MutateEnvVars(container, envVarBuilder)
 }
@@ -479,6 +546,7 @@ func (obj *SensorDaemonSetK8sObject) mutateCndrEnvVars(container *coreV1.Contain
 		WithCustom(customEnvs...).
 		WithEnvVarFromSecret(cndrCompanyCodeVarName, cndrSpec.CompanyCodeSecretName, cndrCompanyCodeKeyName).
 		WithSpec(cndrSpec.Sensor.Env).
+		WithExtraEnv(cndrSpec.Sensor.ExtraEnv).
 		WithProxySettings(agentSpec.Components.Settings.Proxy)
 
 	commonState.MutateEnvVars(container, envVarBuilder)
@@ -551,6 +619,7 @@ func (obj *SensorDaemonSetK8sObject) mutateClusterScannerEnvVars(container *core
 		WithEnvVarFromResource("CLUSTER_SCANNER_REQUESTS_MEMORY", ClusterScanningContainerName, "requests.memory").
 		WithEnvVarFromField("CLUSTER_SCANNER_NODE_NAME", "spec.nodeName", "v1").
 		WithSpec(clusterScannerSpec.Env).
+		WithExtraEnv(clusterScannerSpec.ExtraEnv).
 		WithGatewayTLS().
 		WithProxySettings(agentSpec.Components.Settings.Proxy)
 
@@ -0,0 +1,225 @@
+package components
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+var (
+	// daemonSetTolerations are the tolerations that the daemon set controller adds to the pods of every daemon set.
+	// See https://kubernetes.io/docs/concepts/workloads/controllers/daemonset/#taints-and-tolerations
+	daemonSetTolerations = []coreV1.Toleration{
+		{Key: coreV1.TaintNodeNotReady, Operator: coreV1.TolerationOpExists, Effect: coreV1.TaintEffectNoExecute},
+		{Key: coreV1.TaintNodeUnreachable, Operator: coreV1.TolerationOpExists, Effect: coreV1.TaintEffectNoExecute},
+		{Key: coreV1.TaintNodeDiskPressure, Operator: coreV1.TolerationOpExists, Effect: coreV1.TaintEffectNoSchedule},
+		{Key: coreV1.TaintNodeMemoryPressure, Operator: coreV1.TolerationOpExists, Effect: coreV1.TaintEffectNoSchedule},
+		{Key: coreV1.TaintNodePIDPressure, Operator: coreV1.TolerationOpExists, Effect: coreV1.TaintEffectNoSchedule},
+		{Key: coreV1.TaintNodeUnschedulable, Operator: coreV1.TolerationOpExists, Effect: coreV1.TaintEffectNoSchedule},
+	}
+
+	// hostNetworkDaemonSetToleration is added by the daemon set controller to the pods of daemon sets that use the host network.
+	hostNetworkDaemonSetToleration = coreV1.Toleration{Key: coreV1.TaintNodeNetworkUnavailable, Operator: coreV1.TolerationOpExists, Effect: coreV1.TaintEffectNoSchedule}
+
+	imagePullWaitingReasons = map[string]struct{}{
+		"ErrImagePull":     {},
+		"ImagePullBackOff": {},
+		"InvalidImageName": {},
+	}
+
+	nodeSelectorOperators = map[coreV1.NodeSelectorOperator]selection.Operator{
+		coreV1.NodeSelectorOpIn:           selection.In,
+		coreV1.NodeSelectorOpNotIn:        selection.NotIn,
+		coreV1.NodeSelectorOpExists:       selection.Exists,
+		coreV1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+		coreV1.NodeSelectorOpGt:           selection.GreaterThan,
+		coreV1.NodeSelectorOpLt:           selection.LessThan,
+	}
+)
+
+// NodeCoverage explains for each node whether the daemon set covers it, sorted by the node name.
+// The nodes that none of the daemon set pods runs on are explained by the node affinity, the node selector and the
+// tolerations that were applied to the daemon set, and the rest by the state of the pods.
+func (obj *SensorDaemonSetK8sObject) NodeCoverage(daemonSet *appsV1.DaemonSet, nodes []coreV1.Node, pods []coreV1.Pod) []models.NodeCoverage {
+	nodePods := make(map[string][]*coreV1.Pod)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName != "" && pod.DeletionTimestamp == nil {
+			nodePods[pod.Spec.NodeName] = append(nodePods[pod.Spec.NodeName], pod)
+		}
+	}
+
+	coverage := make([]models.NodeCoverage, 0, len(nodes))
+	for i := range nodes {
+		node := &nodes[i]
+		var reason models.NodeCoverageReason
+		var message string
+		if podsOnNode, ok := nodePods[node.Name]; ok {
+			reason, message = podsCoverage(podsOnNode)
+		} else {
+			reason, message = nodeSchedulingCoverage(&daemonSet.Spec.Template.Spec, node)
+		}
+
+		coverage = append(coverage, models.NodeCoverage{Node: node.Name, Reason: reason, Message: message})
+	}
+
+	sort.Slice(coverage, func(i, j int) bool {
+		return coverage[i].Node < coverage[j].Node
+	})
+
+	return coverage
+}
+
+// podsCoverage explains the coverage of a node by the daemon set pods that run on it.
+// There is more than a single pod on the node during a rollout, and the node is covered when any of them is ready.
+func podsCoverage(pods []*coreV1.Pod) (models.NodeCoverageReason, string) {
+	for _, pod := range pods {
+		if isPodReady(pod) {
+			return models.NodeCoverageReasonCovered, ""
+		}
+	}
+
+	pod := pods[0]
+	containerStatuses := append(append([]coreV1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, containerStatus := range containerStatuses {
+		waiting := containerStatus.State.Waiting
+		if waiting == nil {
+			continue
+		}
+
+		if _, ok := imagePullWaitingReasons[waiting.Reason]; ok {
+			return models.NodeCoverageReasonImagePullFailed, fmt.Sprintf("the container %v of the pod %v can't pull the image %v: %v", containerStatus.Name, pod.Name, containerStatus.Image, waiting.Message)
+		}
+		if waiting.Reason == "CrashLoopBackOff" {
+			return models.NodeCoverageReasonCrashLoopBackOff, fmt.Sprintf("the container %v of the pod %v restarted %d times", containerStatus.Name, pod.Name, containerStatus.RestartCount)
+		}
+	}
+
+	return models.NodeCoverageReasonPodNotReady, fmt.Sprintf("the pod %v is %v and not ready", pod.Name, pod.Status.Phase)
+}
+
+func isPodReady(pod *coreV1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == coreV1.PodReady {
+			return condition.Status == coreV1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// nodeSchedulingCoverage explains why none of the daemon set pods runs on a node.
+func nodeSchedulingCoverage(podSpec *coreV1.PodSpec, node *coreV1.Node) (models.NodeCoverageReason, string) {
+	for key, value := range podSpec.NodeSelector {
+		if nodeValue, ok := node.Labels[key]; !ok || nodeValue != value {
+			return models.NodeCoverageReasonNodeAffinityMismatch, fmt.Sprintf("the node doesn't have the label %v=%v of the node selector", key, value)
+		}
+	}
+
+	if podSpec.Affinity != nil && podSpec.Affinity.NodeAffinity != nil && podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		if requirement, matches := matchNodeSelectorTerms(node, podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms); !matches {
+			return nodeAffinityCoverage(node, requirement)
+		}
+	}
+
+	tolerations := append(append([]coreV1.Toleration{}, podSpec.Tolerations...), daemonSetTolerations...)
+	if podSpec.HostNetwork {
+		tolerations = append(tolerations, hostNetworkDaemonSetToleration)
+	}
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == coreV1.TaintEffectPreferNoSchedule || toleratesTaint(tolerations, taint) {
+			continue
+		}
+
+		return models.NodeCoverageReasonUntoleratedTaint, fmt.Sprintf("the daemon set doesn't tolerate the taint %v of the node", taint.ToString())
+	}
+
+	return models.NodeCoverageReasonPodMissing, "the node matches the daemon set, but none of its pods runs on it yet"
+}
+
+func nodeAffinityCoverage(node *coreV1.Node, requirement *coreV1.NodeSelectorRequirement) (models.NodeCoverageReason, string) {
+	if requirement == nil {
+		return models.NodeCoverageReasonNodeAffinityMismatch, "the node doesn't match any of the node selector terms of the daemon set"
+	}
+
+	nodeValue, ok := node.Labels[requirement.Key]
+	if !ok {
+		nodeValue = "not set"
+	}
+	message := fmt.Sprintf("the label %v of the node is %v, which doesn't match the requirement %v %v", requirement.Key, nodeValue, requirement.Operator, requirement.Values)
+
+	switch requirement.Key {
+	case coreV1.LabelOSStable:
+		return models.NodeCoverageReasonUnsupportedOperatingSystem, message
+	case coreV1.LabelArchStable:
+		return models.NodeCoverageReasonUnsupportedArchitecture, message
+	default:
+		return models.NodeCoverageReasonNodeAffinityMismatch, message
+	}
+}
+
+// matchNodeSelectorTerms returns whether the node matches any of the terms. When it doesn't, the first requirement of
+// the first term that the node doesn't match is returned.
+func matchNodeSelectorTerms(node *coreV1.Node, terms []coreV1.NodeSelectorTerm) (*coreV1.NodeSelectorRequirement, bool) {
+	var firstUnmatchedRequirement *coreV1.NodeSelectorRequirement
+	for i := range terms {
+		requirement := matchNodeSelectorTerm(node, &terms[i])
+		if requirement == nil {
+			return nil, true
+		}
+		if firstUnmatchedRequirement == nil {
+			firstUnmatchedRequirement = requirement
+		}
+	}
+
+	return firstUnmatchedRequirement, len(terms) == 0
+}
+
+// matchNodeSelectorTerm returns the first requirement of the term that the node doesn't match, or nil when it matches all of them.
+func matchNodeSelectorTerm(node *coreV1.Node, term *coreV1.NodeSelectorTerm) *coreV1.NodeSelectorRequirement {
+	for i := range term.MatchExpressions {
+		if !matchNodeSelectorRequirement(labels.Set(node.Labels), &term.MatchExpressions[i]) {
+			return &term.MatchExpressions[i]
+		}
+	}
+
+	// The node name is the only supported field
+	nodeFields := labels.Set{"metadata.name": node.Name}
+	for i := range term.MatchFields {
+		if !matchNodeSelectorRequirement(nodeFields, &term.MatchFields[i]) {
+			return &term.MatchFields[i]
+		}
+	}
+
+	return nil
+}
+
+func matchNodeSelectorRequirement(nodeLabels labels.Set, requirement *coreV1.NodeSelectorRequirement) bool {
+	operator, ok := nodeSelectorOperators[requirement.Operator]
+	if !ok {
+		return false
+	}
+
+	labelRequirement, err := labels.NewRequirement(requirement.Key, operator, requirement.Values)
+	if err != nil {
+		return false
+	}
+
+	return labelRequirement.Matches(nodeLabels)
+}
+
+func toleratesTaint(tolerations []coreV1.Toleration, taint *coreV1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+
+	return false
+}
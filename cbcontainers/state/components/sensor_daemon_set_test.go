@@ -0,0 +1,64 @@
+package components_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestSensorDaemonSetArchitectures(t *testing.T) {
+	tests := map[string]struct {
+		changeSpec            func(agentSpec *cbcontainersv1.CBContainersAgentSpec)
+		expectedArchitectures []string
+	}{
+		"When all the containers run on the architectures of the settings, should schedule the pods on them": {
+			changeSpec: func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.Settings.Architectures = []string{"amd64", "arm64"}
+				agentSpec.Components.RuntimeProtection.Sensor.Image.MultiArch = true
+				agentSpec.Components.ClusterScanning.ClusterScannerAgent.Image.MultiArch = true
+			},
+			expectedArchitectures: []string{"amd64", "arm64"},
+		},
+		"When a container doesn't run on all the architectures of the settings, should schedule the pods on the ones all the containers run on": {
+			changeSpec: func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.Settings.Architectures = []string{"amd64", "arm64"}
+				agentSpec.Components.RuntimeProtection.Sensor.Image.MultiArch = true
+			},
+			expectedArchitectures: []string{"amd64"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			daemonSet, err := mutatedK8sObject(components.NewSensorDaemonSetK8sObject(testNamespace), testAgentSpec(t, test.changeSpec))
+
+			require.NoError(t, err)
+			require.Equal(t, test.expectedArchitectures, nodeArchitectures(&podTemplate(t, daemonSet).Spec))
+		})
+	}
+}
+
+func TestSensorDaemonSetPodTemplatePatchMountingHostPathVolumes(t *testing.T) {
+	tests := map[string]string{
+		"When the patch mounts a host path volume in an added container, should return an error":      `{"spec":{"containers":[{"name":"debug","image":"busybox","volumeMounts":[{"name":"containerd","mountPath":"/host"}]}]}}`,
+		"When the patch mounts a host path volume in an added init container, should return an error": `{"spec":{"initContainers":[{"name":"debug","image":"busybox","volumeMounts":[{"name":"containerd","mountPath":"/host"}]}]}}`,
+	}
+
+	for name, podTemplatePatch := range tests {
+		t.Run(name, func(t *testing.T) {
+			agentSpec := testAgentSpec(t, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.RuntimeProtection.Sensor.PodTemplatePatch = &runtime.RawExtension{Raw: []byte(podTemplatePatch)}
+			})
+
+			_, err := mutatedK8sObject(components.NewSensorDaemonSetK8sObject(testNamespace), agentSpec)
+
+			var patchErr *components.PodTemplatePatchError
+			require.ErrorAs(t, err, &patchErr)
+			require.Equal(t, components.DaemonSetName, patchErr.WorkloadName)
+			require.ErrorContains(t, err, "the containerd host path volume can't be mounted by the debug container")
+		})
+	}
+}
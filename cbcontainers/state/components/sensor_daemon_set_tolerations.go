@@ -0,0 +1,89 @@
+package components
+
+import (
+	"sort"
+
+	cbContainersV1 "github.com/vmware/cbcontainers-operator/api/v1"
+	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/utils/strings/slices"
+)
+
+// DiscoverTolerations returns a toleration of every NoSchedule and NoExecute taint of the nodes, except for the taints
+// that the daemon set controller tolerates by itself and the taints that the discovery spec doesn't allow.
+// The tolerations are sorted, so they don't change as long as the taints don't.
+func DiscoverTolerations(nodes []coreV1.Node, discoverySpec *cbContainersV1.CBContainersTolerationsDiscoverySpec) []coreV1.Toleration {
+	tolerations := make(map[string]coreV1.Toleration)
+	for _, node := range nodes {
+		for i := range node.Spec.Taints {
+			taint := &node.Spec.Taints[i]
+			if taint.Effect == coreV1.TaintEffectPreferNoSchedule || toleratesTaint(daemonSetTolerations, taint) {
+				continue
+			}
+			if slices.Contains(discoverySpec.DeniedTaintKeys, taint.Key) {
+				continue
+			}
+			if len(discoverySpec.AllowedTaintKeys) != 0 && !slices.Contains(discoverySpec.AllowedTaintKeys, taint.Key) {
+				continue
+			}
+
+			toleration := coreV1.Toleration{Key: taint.Key, Operator: coreV1.TolerationOpEqual, Value: taint.Value, Effect: taint.Effect}
+			if taint.Value == "" {
+				toleration = coreV1.Toleration{Key: taint.Key, Operator: coreV1.TolerationOpExists, Effect: taint.Effect}
+			}
+			tolerations[taint.ToString()] = toleration
+		}
+	}
+
+	taints := make([]string, 0, len(tolerations))
+	for taint := range tolerations {
+		taints = append(taints, taint)
+	}
+	sort.Strings(taints)
+
+	discoveredTolerations := make([]coreV1.Toleration, 0, len(taints))
+	for _, taint := range taints {
+		discoveredTolerations = append(discoveredTolerations, tolerations[taint])
+	}
+
+	return discoveredTolerations
+}
+
+func (obj *SensorDaemonSetK8sObject) UpdateDiscoveredTolerations(discoveredTolerations []coreV1.Toleration) {
+	obj.discoveredTolerations = discoveredTolerations
+}
+
+// Tolerations returns the tolerations that the daemon set is applied with. While the discovery of the tolerations is
+// enabled, those are the tolerations of the settings that have a key, followed by the discovered tolerations.
+func (obj *SensorDaemonSetK8sObject) Tolerations(agentSpec *cbContainersV1.CBContainersAgentSpec) []coreV1.Toleration {
+	settings := &agentSpec.Components.Settings
+	if !commonState.IsEnabled(settings.DaemonSetsTolerationsDiscovery.Enabled) {
+		return settings.DaemonSetsTolerations
+	}
+
+	tolerations := make([]coreV1.Toleration, 0, len(settings.DaemonSetsTolerations)+len(obj.discoveredTolerations))
+	for _, toleration := range settings.DaemonSetsTolerations {
+		// A toleration without a key tolerates all the taints, including the denied ones
+		if toleration.Key != "" {
+			tolerations = append(tolerations, toleration)
+		}
+	}
+
+	for _, discoveredToleration := range obj.discoveredTolerations {
+		if !containsToleration(tolerations, &discoveredToleration) {
+			tolerations = append(tolerations, discoveredToleration)
+		}
+	}
+
+	return tolerations
+}
+
+func containsToleration(tolerations []coreV1.Toleration, toleration *coreV1.Toleration) bool {
+	for i := range tolerations {
+		if tolerations[i].MatchToleration(toleration) {
+			return true
+		}
+	}
+
+	return false
+}
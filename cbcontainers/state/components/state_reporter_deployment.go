@@ -30,6 +30,9 @@ var (
 type StateReporterDeploymentK8sObject struct {
 	// Namespace is the Namespace in which the Deployment will be created.
 	Namespace string
+
+	// configChecksum is the checksum of the ConfigMaps and Secrets keys that the pods consume.
+	configChecksum string
 }
 
 func NewStateReporterDeploymentK8sObject(namespace string) *StateReporterDeploymentK8sObject {
@@ -38,6 +41,12 @@ func NewStateReporterDeploymentK8sObject(namespace string) *StateReporterDeploym
 	}
 }
 
+// UpdateConfigChecksum sets the checksum of the ConfigMaps and Secrets keys that the pods consume, which rolls the
+// pods when it changes.
+func (obj *StateReporterDeploymentK8sObject) UpdateConfigChecksum(configChecksum string) {
+	obj.configChecksum = configChecksum
+}
+
 func (obj *StateReporterDeploymentK8sObject) EmptyK8sObject() client.Object {
 	return &appsV1.Deployment{}
 }
@@ -87,9 +96,15 @@ func (obj *StateReporterDeploymentK8sObject) MutateK8sObject(k8sObject client.Ob
 	obj.mutateVolumes(&deployment.Spec.Template.Spec)
 	obj.mutateAffinityAndNodeSelector(&deployment.Spec.Template.Spec, stateReporter)
 	obj.mutateContainersList(&deployment.Spec.Template.Spec, agentSpec)
-	commonState.NewNodeTermsBuilder(&deployment.Spec.Template.Spec).Build()
+	architectures, err := commonState.GetArchitectures(&agentSpec.Components.Settings, stateReporter.Architectures, &stateReporter.Image)
+	if err != nil {
+		return err
+	}
+	commonState.NewNodeTermsBuilder(&deployment.Spec.Template.Spec, architectures).Build()
+
+	mutateConfigChecksum(&deployment.Spec.Template.ObjectMeta, obj.configChecksum)
 
-	return nil
+	return mutatePodTemplatePatch(&deployment.ObjectMeta, &deployment.Spec.Template, stateReporter.PodTemplatePatch, StateReporterName)
 }
 
 func (obj *StateReporterDeploymentK8sObject) mutateVolumes(templatePodSpec *coreV1.PodSpec) {
@@ -124,6 +139,7 @@ func (obj *StateReporterDeploymentK8sObject) mutateContainer(container *coreV1.C
 		WithCommonDataPlane(agentSpec.AccessTokenSecretName).
 		WithEventsGateway(&agentSpec.Gateways.HardeningEventsGateway).
 		WithSpec(stateReporterSpec.Env).
+		WithExtraEnv(stateReporterSpec.ExtraEnv).
 		WithProxySettings(agentSpec.Components.Settings.Proxy)
 	commonState.MutateEnvVars(container, envVarBuilder)
 
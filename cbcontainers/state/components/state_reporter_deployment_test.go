@@ -0,0 +1,56 @@
+package components_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+func TestStateReporterDeploymentExtraEnv(t *testing.T) {
+	secretKeyRef := &coreV1.EnvVarSource{SecretKeyRef: &coreV1.SecretKeySelector{LocalObjectReference: coreV1.LocalObjectReference{Name: "cndr-secret"}, Key: "token"}}
+	configMapKeyRef := &coreV1.EnvVarSource{ConfigMapKeyRef: &coreV1.ConfigMapKeySelector{LocalObjectReference: coreV1.LocalObjectReference{Name: "cndr-config"}, Key: "token"}}
+
+	tests := map[string]struct {
+		extraEnv         []coreV1.EnvVar
+		expectedTokenEnv coreV1.EnvVar
+	}{
+		"When an extra env var is sourced from a Secret, should override the env var of the spec": {
+			extraEnv:         []coreV1.EnvVar{{Name: "CNDR_TOKEN", ValueFrom: secretKeyRef}},
+			expectedTokenEnv: coreV1.EnvVar{Name: "CNDR_TOKEN", ValueFrom: secretKeyRef},
+		},
+		"When an extra env var is sourced from a ConfigMap, should override the env var of the spec": {
+			extraEnv:         []coreV1.EnvVar{{Name: "CNDR_TOKEN", ValueFrom: configMapKeyRef}},
+			expectedTokenEnv: coreV1.EnvVar{Name: "CNDR_TOKEN", ValueFrom: configMapKeyRef},
+		},
+		"When an extra env var has a value, should override the env var of the spec": {
+			extraEnv:         []coreV1.EnvVar{{Name: "CNDR_TOKEN", Value: "extra"}},
+			expectedTokenEnv: coreV1.EnvVar{Name: "CNDR_TOKEN", Value: "extra"},
+		},
+		"Without extra env vars, should set the env var of the spec": {
+			expectedTokenEnv: coreV1.EnvVar{Name: "CNDR_TOKEN", Value: "plain"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			agentSpec := testAgentSpec(t, func(agentSpec *cbcontainersv1.CBContainersAgentSpec) {
+				agentSpec.Components.Basic.StateReporter.Env = map[string]string{"CNDR_TOKEN": "plain"}
+				agentSpec.Components.Basic.StateReporter.ExtraEnv = test.extraEnv
+			})
+
+			deployment, err := mutatedK8sObject(components.NewStateReporterDeploymentK8sObject(testNamespace), agentSpec)
+
+			require.NoError(t, err)
+			var tokenEnvVars []coreV1.EnvVar
+			for _, envVar := range podTemplate(t, deployment).Spec.Containers[0].Env {
+				if envVar.Name == "CNDR_TOKEN" {
+					tokenEnvVars = append(tokenEnvVars, envVar)
+				}
+			}
+			require.Equal(t, []coreV1.EnvVar{test.expectedTokenEnv}, tokenEnvVars)
+		})
+	}
+}
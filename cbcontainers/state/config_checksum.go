@@ -0,0 +1,134 @@
+package state
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/agent_applyment"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// configChecksumUpdater is implemented by the workloads whose pods are rolled when the ConfigMaps and Secrets keys that
+// they consume change.
+type configChecksumUpdater interface {
+	UpdateConfigChecksum(configChecksum string)
+}
+
+// configObjectKey identifies a ConfigMap or a Secret that the workloads consume.
+type configObjectKey struct {
+	kind           string
+	namespacedName types.NamespacedName
+}
+
+// updateConfigChecksum builds the workload from an empty object to find the ConfigMaps and Secrets keys that its pods
+// consume, and sets the checksum of their values on the workload. Only the workloads that consume a changed key are
+// rolled, e.g. a rotated access token rolls all the components, but a changed Secret of an extra env var of a component
+// rolls only that component.
+func (c *StateApplier) updateConfigChecksum(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, builder agent_applyment.AgentComponentBuilder) error {
+	updater, ok := builder.(configChecksumUpdater)
+	if !ok {
+		return nil
+	}
+
+	k8sObject := builder.EmptyK8sObject()
+	if err := builder.MutateK8sObject(k8sObject, &agent.Spec); err != nil {
+		// The error is returned when the workload is applied
+		return nil
+	}
+
+	configValues := make(map[string]json.RawMessage)
+	for _, reference := range components.ConsumedConfigReferences(k8sObject) {
+		value, err := c.readConfigValue(ctx, builder.NamespacedName().Namespace, reference)
+		if err != nil {
+			return err
+		}
+		configValues[fmt.Sprintf("%v/%v/%v", reference.Kind, reference.Name, reference.Key)] = value
+	}
+	if len(configValues) == 0 {
+		updater.UpdateConfigChecksum("")
+		return nil
+	}
+
+	// The keys of the map are sorted when it's marshaled
+	rawConfigValues, err := json.Marshal(configValues)
+	if err != nil {
+		return fmt.Errorf("failed marshaling the configuration of `%v`: %w", builder.NamespacedName(), err)
+	}
+	checksum := sha256.Sum256(rawConfigValues)
+	updater.UpdateConfigChecksum(hex.EncodeToString(checksum[:]))
+
+	return nil
+}
+
+// readConfigValue returns the value of a ConfigMap or a Secret key, or all its keys when the reference has no key.
+// A missing ConfigMap, Secret or key has a null value, so the pods are rolled once it's created.
+func (c *StateApplier) readConfigValue(ctx context.Context, namespace string, reference components.ConfigReference) (json.RawMessage, error) {
+	namespacedName := types.NamespacedName{Name: reference.Name, Namespace: namespace}
+	k8sObject, err := c.readConfigObject(ctx, reference.Kind, namespacedName)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch configObject := k8sObject.(type) {
+	case *coreV1.ConfigMap:
+		value = configValue(reference.Key, configObject.Data, configObject.BinaryData)
+	case *coreV1.Secret:
+		value = configValue(reference.Key, nil, configObject.Data)
+	}
+
+	rawValue, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshaling %v `%v`: %w", reference.Kind, namespacedName, err)
+	}
+
+	return rawValue, nil
+}
+
+// readConfigObject returns a ConfigMap or a Secret, or nil when it doesn't exist. Each of them is read once while the
+// desired state is applied, as several workloads usually consume the same ConfigMaps and Secrets.
+func (c *StateApplier) readConfigObject(ctx context.Context, kind string, namespacedName types.NamespacedName) (client.Object, error) {
+	key := configObjectKey{kind: kind, namespacedName: namespacedName}
+	if k8sObject, ok := c.configObjects[key]; ok {
+		return k8sObject, nil
+	}
+
+	var k8sObject client.Object = &coreV1.ConfigMap{}
+	if kind == components.SecretKind {
+		k8sObject = &coreV1.Secret{}
+	}
+	if err := c.apiReader.Get(ctx, namespacedName, k8sObject); err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed reading %v `%v`: %w", kind, namespacedName, err)
+		}
+		k8sObject = nil
+	}
+
+	if c.configObjects != nil {
+		c.configObjects[key] = k8sObject
+	}
+	return k8sObject, nil
+}
+
+func configValue(key string, data map[string]string, binaryData map[string][]byte) interface{} {
+	if key == "" {
+		return map[string]interface{}{"data": data, "binaryData": binaryData}
+	}
+
+	if value, ok := data[key]; ok {
+		return value
+	}
+	if value, ok := binaryData[key]; ok {
+		return value
+	}
+
+	return nil
+}
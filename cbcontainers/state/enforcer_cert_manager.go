@@ -0,0 +1,126 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/events"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
+	applymentOptions "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/status"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/utils/certificates"
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// applyEnforcerCertificate applies the cert-manager Certificate of the enforcer webhooks, and returns the secret that
+// cert-manager issued for it. The secret is nil while it isn't issued, or when the cert-manager CRDs are not installed.
+// Waiting for cert-manager is reported by the EnforcerCertificatesPending condition rather than as a changed state, so
+// the agent is reconciled again when the secret is issued or after a while, instead of right away.
+func (c *StateApplier) applyEnforcerCertificate(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, applyOptions *applymentOptions.ApplyOptions) (bool, *coreV1.Secret, error) {
+	mutatedCertificate, k8sObject, err := c.applier.Apply(ctx, c.enforcerCertificate, &agent.Spec, applyOptions)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			message := "The enforcer certificates should be issued by cert-manager, but the cert-manager CRDs are not installed"
+			if status.SetAgentEnforcerCertificatesPendingCondition(&agent.Status, status.ReasonCertManagerNotInstalled, message, agent.Generation) {
+				c.eventRecorder.Event(agent, coreV1.EventTypeWarning, events.ReasonCertManagerNotInstalled, message)
+			}
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+	c.log.Info("Applied enforcer certificate", "Mutated", mutatedCertificate)
+
+	certificate, ok := k8sObject.(*unstructured.Unstructured)
+	if !ok {
+		return false, nil, fmt.Errorf("expected Certificate K8s object")
+	}
+
+	tlsSecret := &coreV1.Secret{}
+	if err := c.apiReader.Get(ctx, c.enforcerTlsSecret.NamespacedName(), tlsSecret); err != nil && !k8sErrors.IsNotFound(err) {
+		return false, nil, fmt.Errorf("failed reading the enforcer tls secret: %w", err)
+	} else if err != nil || !isIssuedByCertManager(tlsSecret) {
+		c.log.Info("Waiting for cert-manager to issue the enforcer certificates")
+		status.SetAgentEnforcerCertificatesPendingCondition(&agent.Status, status.ReasonCertificatesNotIssued, "Waiting for cert-manager to issue the enforcer certificates", agent.Generation)
+		return mutatedCertificate, nil, nil
+	}
+	status.SetAgentEnforcerCertificatesPendingCondition(&agent.Status, status.ReasonCertificatesIssued, "cert-manager issued the enforcer certificates", agent.Generation)
+
+	tlsSecretValues := models.TlsSecretValuesFromCertManagerSecretData(tlsSecret.Data)
+	if notAfter, err := certificates.GetSignedCertificateExpiry(tlsSecretValues); err != nil {
+		c.log.Error(err, "Failed reading the enforcer certificates that cert-manager issued")
+	} else {
+		c.reportEnforcerCertificates(agent, notAfter, certificateRenewalTime(certificate, notAfter))
+	}
+
+	return mutatedCertificate, tlsSecret, nil
+}
+
+// stopUsingCertManager deletes the Certificate and the secret that cert-manager issued for it, after cert-manager was
+// disabled, so the operator creates the secret again.
+func (c *StateApplier) stopUsingCertManager(ctx context.Context, agent *cbcontainersv1.CBContainersAgent) (bool, *coreV1.Secret, error) {
+	if _, err := c.deleteEnforcerCertificate(ctx, agent); err != nil {
+		return false, nil, err
+	}
+
+	if _, err := c.deleteComponent(ctx, agent, c.enforcerTlsSecret); err != nil {
+		return false, nil, err
+	}
+
+	return true, nil, nil
+}
+
+// deleteEnforcerCertificate deletes the cert-manager Certificate of the enforcer webhooks.
+// There is nothing to delete when the cert-manager CRDs are not installed.
+func (c *StateApplier) deleteEnforcerCertificate(ctx context.Context, agent *cbcontainersv1.CBContainersAgent) (bool, error) {
+	deleted, err := c.deleteComponent(ctx, agent, c.enforcerCertificate)
+	if err != nil && !meta.IsNoMatchError(err) {
+		return false, err
+	}
+
+	return deleted, nil
+}
+
+// certificateRenewalTime returns the time cert-manager will renew a Certificate, as reported in its status.
+// Until it is reported, the certificate is expected to be renewed when it expires.
+func certificateRenewalTime(certificate *unstructured.Unstructured, notAfter time.Time) time.Time {
+	renewalTime, found, _ := unstructured.NestedString(certificate.Object, "status", "renewalTime")
+	if !found {
+		return notAfter
+	}
+
+	parsedRenewalTime, err := time.Parse(time.RFC3339, renewalTime)
+	if err != nil {
+		return notAfter
+	}
+
+	return parsedRenewalTime
+}
+
+// enforcerTlsSecretValues returns the values of the enforcer tls secret, which is either created by the operator or issued
+// by cert-manager. A missing secret has no values.
+func enforcerTlsSecretValues(agentSpec *cbcontainersv1.CBContainersAgentSpec, tlsSecret *coreV1.Secret) models.TlsSecretValues {
+	if tlsSecret == nil {
+		return models.TlsSecretValues{}
+	}
+
+	if isCertManagerEnabled(agentSpec) {
+		return models.TlsSecretValuesFromCertManagerSecretData(tlsSecret.Data)
+	}
+
+	return models.TlsSecretValuesFromSecretData(tlsSecret.Data)
+}
+
+func isIssuedByCertManager(tlsSecret *coreV1.Secret) bool {
+	_, ok := tlsSecret.Annotations[components.CertManagerCertificateNameAnnotation]
+	return ok
+}
+
+func isCertManagerEnabled(agentSpec *cbcontainersv1.CBContainersAgentSpec) bool {
+	return agentSpec.Components.Basic.Enforcer.CertManager != nil
+}
@@ -0,0 +1,156 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/events"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/metrics"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
+	applymentOptions "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/status"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/utils/certificates"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const defaultEnforcerTlsRenewBefore = 720 * time.Hour
+
+// applyEnforcerTls applies the secret that the enforcer webhooks are served with, and renews its certificates before they
+// expire. With cert-manager, it applies the Certificate that cert-manager issues the secret for instead, and returns a nil
+// secret until the secret is issued.
+func (c *StateApplier) applyEnforcerTls(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, applyOptions *applymentOptions.ApplyOptions) (bool, *coreV1.Secret, error) {
+	if isCertManagerEnabled(&agent.Spec) {
+		return c.applyEnforcerCertificate(ctx, agent, applyOptions)
+	}
+	status.RemoveAgentEnforcerCertificatesPendingCondition(&agent.Status)
+
+	mutatedSecret, secretK8sObject, err := c.applier.Apply(ctx, c.enforcerTlsSecret, &agent.Spec, applyOptions, applymentOptions.NewApplyOptions().SetCreateOnly(true))
+	if err != nil {
+		return false, nil, err
+	}
+	c.log.Info("Applied enforcer tls secret", "Mutated", mutatedSecret)
+
+	tlsSecret, ok := secretK8sObject.(*coreV1.Secret)
+	if !ok {
+		return false, nil, fmt.Errorf("expected Secret K8s object")
+	}
+
+	if isIssuedByCertManager(tlsSecret) {
+		return c.stopUsingCertManager(ctx, agent)
+	}
+
+	if c.rendering || c.planning {
+		return mutatedSecret, tlsSecret, nil
+	}
+
+	rotatedSecret, tlsSecret, err := c.rotateEnforcerTls(ctx, agent, tlsSecret, applyOptions)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return mutatedSecret || rotatedSecret, tlsSecret, nil
+}
+
+// rotateEnforcerTls renews the enforcer certificates when they expire within the renewal window of the enforcer spec,
+// or when they can't be parsed. The previous CA is kept in the secret, so the webhooks trust both the renewed and the
+// previous certificate while the enforcer pods are rolled.
+func (c *StateApplier) rotateEnforcerTls(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, tlsSecret *coreV1.Secret, applyOptions *applymentOptions.ApplyOptions) (bool, *coreV1.Secret, error) {
+	tlsSecretValues := models.TlsSecretValuesFromSecretData(tlsSecret.Data)
+	renewBefore := enforcerTlsRenewBefore(&agent.Spec)
+
+	notAfter, err := certificates.GetCertificatesExpiry(tlsSecretValues)
+	if err == nil && time.Until(notAfter) > renewBefore {
+		c.reportEnforcerCertificates(agent, notAfter, notAfter.Add(-renewBefore))
+		return false, tlsSecret, nil
+	}
+
+	renewedValues, createErr := c.enforcerTlsSecret.CreateTlsSecretValues()
+	if createErr != nil {
+		return false, nil, fmt.Errorf("failed renewing the enforcer certificates: %w", createErr)
+	}
+	if err != nil {
+		c.log.Error(err, "Failed reading the enforcer certificates, renewing them")
+	} else {
+		renewedValues.PreviousCaCert = tlsSecretValues.CaCert
+	}
+
+	_, renewedSecret, err := c.applyEnforcerTlsSecret(ctx, agent, renewedValues, applyOptions)
+	if err != nil {
+		return false, nil, err
+	}
+
+	renewedNotAfter, err := certificates.GetCertificatesExpiry(renewedValues)
+	if err != nil {
+		return false, nil, err
+	}
+	c.reportEnforcerCertificates(agent, renewedNotAfter, renewedNotAfter.Add(-renewBefore))
+	now := metav1.Now()
+	agent.Status.EnforcerCertificates.LastRotationTime = &now
+
+	c.eventRecorder.Eventf(agent, coreV1.EventTypeNormal, events.ReasonCertificatesRenewed, "Renewed the enforcer TLS certificates, the renewed certificates expire at %v", renewedNotAfter.UTC())
+	c.log.Info("Renewed the enforcer TLS certificates", "expiry", renewedNotAfter)
+
+	return true, renewedSecret, nil
+}
+
+// completeEnforcerTlsRotation removes the previous CA from the secret, once the enforcer pods were rolled to the
+// renewed certificate.
+func (c *StateApplier) completeEnforcerTlsRotation(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, tlsSecret *coreV1.Secret, enforcerDeployment *appsV1.Deployment, applyOptions *applymentOptions.ApplyOptions) (bool, *coreV1.Secret, error) {
+	tlsSecretValues := models.TlsSecretValuesFromSecretData(tlsSecret.Data)
+	if len(tlsSecretValues.PreviousCaCert) == 0 {
+		return false, tlsSecret, nil
+	}
+
+	rolled := enforcerDeployment.Spec.Template.Annotations[components.EnforcerTlsChecksumAnnotation] == components.EnforcerTlsChecksum(tlsSecretValues)
+	if !rolled || !status.IsComponentReady(&agent.Status, components.EnforcerName) {
+		c.log.Info("Waiting for the enforcer pods to use the renewed certificates")
+		return false, tlsSecret, nil
+	}
+
+	tlsSecretValues.PreviousCaCert = nil
+	return c.applyEnforcerTlsSecret(ctx, agent, tlsSecretValues, applyOptions)
+}
+
+func (c *StateApplier) applyEnforcerTlsSecret(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, tlsSecretValues models.TlsSecretValues, applyOptions *applymentOptions.ApplyOptions) (bool, *coreV1.Secret, error) {
+	c.enforcerTlsSecret.UpdateTlsSecretValues(&tlsSecretValues)
+	defer c.enforcerTlsSecret.UpdateTlsSecretValues(nil)
+
+	mutated, k8sObject, err := c.applier.Apply(ctx, c.enforcerTlsSecret, &agent.Spec, applyOptions)
+	if err != nil {
+		return false, nil, err
+	}
+
+	tlsSecret, ok := k8sObject.(*coreV1.Secret)
+	if !ok {
+		return false, nil, fmt.Errorf("expected Secret K8s object")
+	}
+
+	return mutated, tlsSecret, nil
+}
+
+// reportEnforcerCertificates reports the expiry of the enforcer certificates in the agent status, and in the metrics
+// unless the desired state is planned or rendered.
+func (c *StateApplier) reportEnforcerCertificates(agent *cbcontainersv1.CBContainersAgent, notAfter, renewalTime time.Time) {
+	if agent.Status.EnforcerCertificates == nil {
+		agent.Status.EnforcerCertificates = &cbcontainersv1.CBContainersCertificatesStatus{}
+	}
+	agent.Status.EnforcerCertificates.NotAfter = metav1.NewTime(notAfter)
+	agent.Status.EnforcerCertificates.RenewalTime = metav1.NewTime(renewalTime)
+
+	if !c.planning && !c.rendering {
+		metrics.EnforcerCertificatesExpiry.Set(float64(notAfter.Unix()))
+	}
+}
+
+func enforcerTlsRenewBefore(agentSpec *cbcontainersv1.CBContainersAgentSpec) time.Duration {
+	if renewBefore := agentSpec.Components.Basic.Enforcer.TlsRenewBefore; renewBefore != nil {
+		return renewBefore.Duration
+	}
+
+	return defaultEnforcerTlsRenewBefore
+}
@@ -60,16 +60,21 @@ func (mr *MockAgentComponentApplierMockRecorder) Apply(arg0, arg1, arg2 interfac
 }
 
 // Delete mocks base method.
-func (m *MockAgentComponentApplier) Delete(arg0 context.Context, arg1 agent_applyment.AgentComponentBuilder, arg2 *v1.CBContainersAgentSpec) (bool, error) {
+func (m *MockAgentComponentApplier) Delete(arg0 context.Context, arg1 agent_applyment.AgentComponentBuilder, arg2 *v1.CBContainersAgentSpec, arg3 ...client.DeleteOption) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0, arg1, arg2)
+	varargs := []interface{}{arg0, arg1, arg2}
+	for _, a := range arg3 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Delete", varargs...)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockAgentComponentApplierMockRecorder) Delete(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockAgentComponentApplierMockRecorder) Delete(arg0, arg1, arg2 interface{}, arg3 ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockAgentComponentApplier)(nil).Delete), arg0, arg1, arg2)
+	varargs := append([]interface{}{arg0, arg1, arg2}, arg3...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockAgentComponentApplier)(nil).Delete), varargs...)
 }
@@ -0,0 +1,159 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/events"
+	applymentOptions "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// markSensorNodesForCleanup adds the nodes that run the CNDR sensor to the pending nodes of the node cleanup status.
+// A new cleanup resets the result of the previous one. It returns true when nodes that weren't pending were marked.
+// The daemon set pods are the only record of the nodes that ran the sensor, so the status should be persisted before
+// the daemon set is changed, otherwise the nodes are lost when applying fails after they were marked.
+func (c *StateApplier) markSensorNodesForCleanup(ctx context.Context, agent *cbcontainersv1.CBContainersAgent) (bool, error) {
+	sensorNodes, err := c.listSensorNodes(ctx)
+	if err != nil {
+		return false, err
+	}
+	if len(sensorNodes) == 0 {
+		return false, nil
+	}
+
+	if agent.Status.NodeCleanup == nil {
+		agent.Status.NodeCleanup = &cbcontainersv1.CBContainersNodeCleanupStatus{}
+	}
+	nodeCleanup := agent.Status.NodeCleanup
+	if len(nodeCleanup.PendingNodes) == 0 {
+		nodeCleanup.FailedNodes = nil
+		nodeCleanup.CompletionTime = nil
+	}
+
+	marked := false
+	for nodeName := range sensorNodes {
+		if !containsString(nodeCleanup.PendingNodes, nodeName) {
+			nodeCleanup.PendingNodes = append(nodeCleanup.PendingNodes, nodeName)
+			marked = true
+		}
+	}
+	sort.Strings(nodeCleanup.PendingNodes)
+
+	return marked, nil
+}
+
+// cleanupSensorNodes runs a cleanup Job on every pending node once the CNDR sensor doesn't run on it anymore, and deletes
+// the Job once it has finished. It returns true when there are no pending nodes. The Jobs aren't owned when setOwner is nil.
+func (c *StateApplier) cleanupSensorNodes(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, setOwner applymentOptions.OwnerSetter) (bool, error) {
+	nodeCleanup := agent.Status.NodeCleanup
+	if nodeCleanup == nil || len(nodeCleanup.PendingNodes) == 0 {
+		return true, nil
+	}
+
+	sensorNodes, err := c.listSensorNodes(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// The Jobs are not reported as agent components, so they are not recorded as events
+	applyOptions := applymentOptions.NewApplyOptions().SetOwnerSetter(setOwner).SetCreateOnly(true)
+	var pendingNodes []string
+	for _, nodeName := range nodeCleanup.PendingNodes {
+		if _, ok := sensorNodes[nodeName]; ok {
+			pendingNodes = append(pendingNodes, nodeName)
+			continue
+		}
+
+		c.nodeCleanupJob.UpdateNodeName(nodeName)
+		_, k8sObject, err := c.applier.Apply(ctx, c.nodeCleanupJob, &agent.Spec, applyOptions)
+		if err != nil {
+			return false, err
+		}
+
+		job, ok := k8sObject.(*batchV1.Job)
+		if !ok {
+			return false, fmt.Errorf("expected Job K8s object")
+		}
+
+		if !components.IsNodeCleanupJobFinished(job) {
+			pendingNodes = append(pendingNodes, nodeName)
+			continue
+		}
+
+		if components.IsNodeCleanupJobFailed(job) {
+			nodeCleanup.FailedNodes = append(nodeCleanup.FailedNodes, nodeName)
+		}
+
+		// Jobs don't delete their pods by default
+		if _, err := c.applier.Delete(ctx, c.nodeCleanupJob, &agent.Spec, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+			return false, err
+		}
+	}
+
+	nodeCleanup.PendingNodes = pendingNodes
+	if len(pendingNodes) > 0 {
+		c.log.Info("Waiting for the nodes to be cleaned up", "nodes", pendingNodes)
+		return false, nil
+	}
+
+	now := metav1.Now()
+	nodeCleanup.CompletionTime = &now
+	if len(nodeCleanup.FailedNodes) > 0 {
+		c.eventRecorder.Eventf(agent, coreV1.EventTypeWarning, events.ReasonNodeCleanupFailed, "Failed removing the CNDR sensor data from nodes: %v", nodeCleanup.FailedNodes)
+	} else {
+		c.eventRecorder.Event(agent, coreV1.EventTypeNormal, events.ReasonNodeCleanupCompleted, "Removed the CNDR sensor data from all the nodes that ran it")
+	}
+	c.log.Info("Cleaned up the nodes that ran the CNDR sensor", "failed", nodeCleanup.FailedNodes)
+
+	return true, nil
+}
+
+// listSensorNodes returns the nodes on which a components daemon set pod runs the CNDR sensor container.
+func (c *StateApplier) listSensorNodes(ctx context.Context) (map[string]struct{}, error) {
+	daemonSetPods, err := c.listDaemonSetPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sensorNodes := make(map[string]struct{})
+	for _, pod := range daemonSetPods {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+
+		for _, container := range pod.Spec.Containers {
+			if container.Name == components.CndrContainerName {
+				sensorNodes[pod.Spec.NodeName] = struct{}{}
+				break
+			}
+		}
+	}
+
+	return sensorNodes, nil
+}
+
+func (c *StateApplier) listDaemonSetPods(ctx context.Context) ([]coreV1.Pod, error) {
+	daemonSetPods := &coreV1.PodList{}
+	if err := c.apiReader.List(ctx, daemonSetPods, client.InNamespace(c.sensorDaemonSet.Namespace), client.MatchingLabels{components.DaemonSetLabelKey: components.DaemonSetName}); err != nil {
+		return nil, fmt.Errorf("failed listing the components daemon set pods: %w", err)
+	}
+
+	return daemonSetPods.Items, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package state
+
+import (
+	"context"
+	"fmt"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
+	applymentOptions "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reportNodeCoverage compares the nodes with the pods of the components daemon set. It reports a summary of the nodes
+// that the daemon set covers in the agent status, and the reason that each of the other nodes is not covered in the
+// node coverage ConfigMap.
+func (c *StateApplier) reportNodeCoverage(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, k8sObject client.Object, nodes []coreV1.Node, setOwner applymentOptions.OwnerSetter) error {
+	daemonSet, ok := k8sObject.(*appsV1.DaemonSet)
+	if !ok {
+		return fmt.Errorf("expected DaemonSet K8s object")
+	}
+
+	daemonSetPods, err := c.listDaemonSetPods(ctx)
+	if err != nil {
+		return err
+	}
+
+	nodeCoverage := &cbcontainersv1.CBContainersNodeCoverageStatus{Nodes: int32(len(nodes))}
+	var uncoveredNodes []models.NodeCoverage
+	for _, coverage := range c.sensorDaemonSet.NodeCoverage(daemonSet, nodes, daemonSetPods) {
+		if coverage.Reason == models.NodeCoverageReasonCovered {
+			nodeCoverage.CoveredNodes++
+			continue
+		}
+
+		if nodeCoverage.UncoveredNodes == nil {
+			nodeCoverage.UncoveredNodes = make(map[string]int32)
+		}
+		nodeCoverage.UncoveredNodes[string(coverage.Reason)]++
+		uncoveredNodes = append(uncoveredNodes, coverage)
+	}
+	agent.Status.NodeCoverage = nodeCoverage
+
+	if err := c.reportNodesExcludedByArchitecture(agent, nodes); err != nil {
+		return err
+	}
+
+	// The report is not an agent component, so its changes are not recorded as events
+	c.nodeCoverageConfigMap.UpdateUncoveredNodes(uncoveredNodes)
+	if _, _, err := c.applier.Apply(ctx, c.nodeCoverageConfigMap, &agent.Spec, applymentOptions.NewApplyOptions().SetOwnerSetter(setOwner)); err != nil {
+		return err
+	}
+
+	if len(uncoveredNodes) > 0 {
+		c.log.Info("Nodes are not covered by the components daemon set", "nodes", len(uncoveredNodes), "reasons", nodeCoverage.UncoveredNodes)
+	}
+
+	return nil
+}
+
+// reportNodesExcludedByArchitecture reports the number of nodes that the components daemon set isn't scheduled on,
+// because their architecture isn't one of the daemon set architectures. Nodes without an architecture label are excluded.
+func (c *StateApplier) reportNodesExcludedByArchitecture(agent *cbcontainersv1.CBContainersAgent, nodes []coreV1.Node) error {
+	architectures, err := c.sensorDaemonSet.Architectures(&agent.Spec)
+	if err != nil {
+		return err
+	}
+
+	excludedNodes := int32(0)
+	for _, node := range nodes {
+		if !slices.Contains(architectures, node.Labels[coreV1.LabelArchStable]) {
+			excludedNodes++
+		}
+	}
+	agent.Status.NodesExcludedByArchitecture = &excludedNodes
+
+	return nil
+}
+
+// deleteNodeCoverage deletes the node coverage ConfigMap and removes the node coverage from the agent status.
+func (c *StateApplier) deleteNodeCoverage(ctx context.Context, agent *cbcontainersv1.CBContainersAgent) error {
+	if _, err := c.applier.Delete(ctx, c.nodeCoverageConfigMap, &agent.Spec); err != nil {
+		return err
+	}
+	agent.Status.NodeCoverage = nil
+	agent.Status.NodesExcludedByArchitecture = nil
+
+	return nil
+}
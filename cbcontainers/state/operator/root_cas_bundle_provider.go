@@ -0,0 +1,111 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type ConfigRootCAsBundleProvider struct {
+	k8sClient client.Client
+}
+
+func NewConfigRootCAsBundleProvider(k8sClient client.Client) *ConfigRootCAsBundleProvider {
+	return &ConfigRootCAsBundleProvider{k8sClient: k8sClient}
+}
+
+// GetRootCAsBundle returns the inline root CAs bundle of the provided Custom resource, followed by the bundles that its
+// rootCAsBundleRef refers to in the deployed namespace.
+// A missing ConfigMap, Secret or key is an error, unless the reference to it is optional.
+func (provider *ConfigRootCAsBundleProvider) GetRootCAsBundle(
+	ctx context.Context,
+	cbContainersCluster *cbcontainersv1.CBContainersAgent,
+	deployedNamespace string,
+) ([]byte, error) {
+	gatewayTLS := cbContainersCluster.Spec.Gateways.GatewayTLS
+	bundleRef := gatewayTLS.RootCAsBundleRef
+	if bundleRef == nil {
+		return gatewayTLS.RootCAsBundle, nil
+	}
+
+	bundles := [][]byte{gatewayTLS.RootCAsBundle}
+	if keyRef := bundleRef.ConfigMapKeyRef; keyRef != nil {
+		bundle, err := provider.getConfigMapBundle(ctx, types.NamespacedName{Name: keyRef.Name, Namespace: deployedNamespace}, keyRef.Key, commonState.IsEnabled(keyRef.Optional))
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, bundle)
+	}
+	if keyRef := bundleRef.SecretKeyRef; keyRef != nil {
+		bundle, err := provider.getSecretBundle(ctx, types.NamespacedName{Name: keyRef.Name, Namespace: deployedNamespace}, keyRef.Key, commonState.IsEnabled(keyRef.Optional))
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, bundle)
+	}
+
+	return mergeBundles(bundles...), nil
+}
+
+func (provider *ConfigRootCAsBundleProvider) getConfigMapBundle(ctx context.Context, namespacedName types.NamespacedName, key string, optional bool) ([]byte, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := provider.k8sClient.Get(ctx, namespacedName, configMap); err != nil {
+		if k8sErrors.IsNotFound(err) && optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("couldn't find root CAs bundle ConfigMap k8s object: %v", err)
+	}
+
+	if bundle, ok := configMap.Data[key]; ok {
+		return []byte(bundle), nil
+	}
+	if bundle, ok := configMap.BinaryData[key]; ok {
+		return bundle, nil
+	}
+	if optional {
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("the k8s ConfigMap %v is missing the root CAs bundle key %v", namespacedName, key)
+}
+
+func (provider *ConfigRootCAsBundleProvider) getSecretBundle(ctx context.Context, namespacedName types.NamespacedName, key string, optional bool) ([]byte, error) {
+	secret := &corev1.Secret{}
+	if err := provider.k8sClient.Get(ctx, namespacedName, secret); err != nil {
+		if k8sErrors.IsNotFound(err) && optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("couldn't find root CAs bundle secret k8s object: %v", err)
+	}
+
+	if bundle, ok := secret.Data[key]; ok {
+		return bundle, nil
+	}
+	if optional {
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("the k8s secret %v is missing the root CAs bundle key %v", namespacedName, key)
+}
+
+// mergeBundles concatenates the PEM bundles, separated by new lines.
+func mergeBundles(bundles ...[]byte) []byte {
+	var merged []byte
+	for _, bundle := range bundles {
+		if len(bundle) == 0 {
+			continue
+		}
+		if len(merged) > 0 && merged[len(merged)-1] != '\n' {
+			merged = append(merged, '\n')
+		}
+		merged = append(merged, bundle...)
+	}
+
+	return merged
+}
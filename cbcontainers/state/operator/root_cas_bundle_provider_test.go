@@ -0,0 +1,134 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	testUtilsMocks "github.com/vmware/cbcontainers-operator/cbcontainers/test_utils/mocks"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	bundleNamespace  = "dummy-namespace"
+	bundleObjectName = "root-cas"
+	bundleKey        = "ca.crt"
+	inlineBundle     = "-----BEGIN CERTIFICATE-----\ninline\n-----END CERTIFICATE-----"
+	referencedBundle = "-----BEGIN CERTIFICATE-----\nreferenced\n-----END CERTIFICATE-----\n"
+)
+
+var (
+	trueRef = true
+
+	bundleNamespacedName = types.NamespacedName{Name: bundleObjectName, Namespace: bundleNamespace}
+)
+
+func agentWithRootCAsBundle(inline string, bundleRef *cbcontainersv1.CBContainersRootCAsBundleRef) *cbcontainersv1.CBContainersAgent {
+	agent := &cbcontainersv1.CBContainersAgent{}
+	agent.Spec.Gateways.GatewayTLS.RootCAsBundle = []byte(inline)
+	agent.Spec.Gateways.GatewayTLS.RootCAsBundleRef = bundleRef
+	return agent
+}
+
+func configMapKeyRef(optional *bool) *cbcontainersv1.CBContainersRootCAsBundleRef {
+	return &cbcontainersv1.CBContainersRootCAsBundleRef{
+		ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: bundleObjectName}, Key: bundleKey, Optional: optional},
+	}
+}
+
+func secretKeyRef(optional *bool) *cbcontainersv1.CBContainersRootCAsBundleRef {
+	return &cbcontainersv1.CBContainersRootCAsBundleRef{
+		SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: bundleObjectName}, Key: bundleKey, Optional: optional},
+	}
+}
+
+func TestGetRootCAsBundle(t *testing.T) {
+	t.Run("Without a reference, should return the inline bundle", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		k8sClient := testUtilsMocks.NewMockClient(ctrl)
+
+		bundle, err := NewConfigRootCAsBundleProvider(k8sClient).GetRootCAsBundle(context.TODO(), agentWithRootCAsBundle(inlineBundle, nil), bundleNamespace)
+
+		require.NoError(t, err)
+		require.Equal(t, inlineBundle, string(bundle))
+	})
+
+	t.Run("With a ConfigMap reference, should append its bundle to the inline bundle", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		k8sClient := testUtilsMocks.NewMockClient(ctrl)
+		k8sClient.EXPECT().Get(gomock.Any(), bundleNamespacedName, gomock.AssignableToTypeOf(&corev1.ConfigMap{})).
+			Do(func(_ context.Context, _ types.NamespacedName, configMap *corev1.ConfigMap, _ ...interface{}) {
+				configMap.Data = map[string]string{bundleKey: referencedBundle}
+			}).
+			Return(nil)
+
+		bundle, err := NewConfigRootCAsBundleProvider(k8sClient).GetRootCAsBundle(context.TODO(), agentWithRootCAsBundle(inlineBundle, configMapKeyRef(nil)), bundleNamespace)
+
+		require.NoError(t, err)
+		require.Equal(t, inlineBundle+"\n"+referencedBundle, string(bundle))
+	})
+
+	t.Run("With a Secret reference and without an inline bundle, should return its bundle", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		k8sClient := testUtilsMocks.NewMockClient(ctrl)
+		k8sClient.EXPECT().Get(gomock.Any(), bundleNamespacedName, gomock.AssignableToTypeOf(&corev1.Secret{})).
+			Do(func(_ context.Context, _ types.NamespacedName, secret *corev1.Secret, _ ...interface{}) {
+				secret.Data = map[string][]byte{bundleKey: []byte(referencedBundle)}
+			}).
+			Return(nil)
+
+		bundle, err := NewConfigRootCAsBundleProvider(k8sClient).GetRootCAsBundle(context.TODO(), agentWithRootCAsBundle("", secretKeyRef(nil)), bundleNamespace)
+
+		require.NoError(t, err)
+		require.Equal(t, referencedBundle, string(bundle))
+	})
+
+	t.Run("When the referenced ConfigMap is missing, should return error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		k8sClient := testUtilsMocks.NewMockClient(ctrl)
+		k8sClient.EXPECT().Get(gomock.Any(), bundleNamespacedName, gomock.AssignableToTypeOf(&corev1.ConfigMap{})).
+			Return(k8sErrors.NewNotFound(schema.GroupResource{}, bundleObjectName))
+
+		_, err := NewConfigRootCAsBundleProvider(k8sClient).GetRootCAsBundle(context.TODO(), agentWithRootCAsBundle(inlineBundle, configMapKeyRef(nil)), bundleNamespace)
+
+		require.Error(t, err)
+	})
+
+	t.Run("When the optional referenced ConfigMap is missing, should return the inline bundle", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		k8sClient := testUtilsMocks.NewMockClient(ctrl)
+		k8sClient.EXPECT().Get(gomock.Any(), bundleNamespacedName, gomock.AssignableToTypeOf(&corev1.ConfigMap{})).
+			Return(k8sErrors.NewNotFound(schema.GroupResource{}, bundleObjectName))
+
+		bundle, err := NewConfigRootCAsBundleProvider(k8sClient).GetRootCAsBundle(context.TODO(), agentWithRootCAsBundle(inlineBundle, configMapKeyRef(&trueRef)), bundleNamespace)
+
+		require.NoError(t, err)
+		require.Equal(t, inlineBundle, string(bundle))
+	})
+
+	t.Run("When the referenced Secret is missing the key, should return error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		k8sClient := testUtilsMocks.NewMockClient(ctrl)
+		k8sClient.EXPECT().Get(gomock.Any(), bundleNamespacedName, gomock.AssignableToTypeOf(&corev1.Secret{})).Return(nil)
+
+		_, err := NewConfigRootCAsBundleProvider(k8sClient).GetRootCAsBundle(context.TODO(), agentWithRootCAsBundle(inlineBundle, secretKeyRef(nil)), bundleNamespace)
+
+		require.Error(t, err)
+	})
+
+	t.Run("When reading the referenced Secret fails, should return error even if it is optional", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		k8sClient := testUtilsMocks.NewMockClient(ctrl)
+		k8sClient.EXPECT().Get(gomock.Any(), bundleNamespacedName, gomock.AssignableToTypeOf(&corev1.Secret{})).Return(fmt.Errorf("some error"))
+
+		_, err := NewConfigRootCAsBundleProvider(k8sClient).GetRootCAsBundle(context.TODO(), agentWithRootCAsBundle(inlineBundle, secretKeyRef(&trueRef)), bundleNamespace)
+
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"context"
+	"fmt"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// scaleResolver derives the replicas count of the resolver from the number of nodes, unless the replicas count is set
+// or the resolver is autoscaled, and reports it in the agent status. The replicas count is derived again only once the
+// scale threshold of nodes were added or removed, and it's changed only once the scale up or scale down cooldown since
+// the last scaling has passed.
+func (c *StateApplier) scaleResolver(ctx context.Context, agent *cbcontainersv1.CBContainersAgent) error {
+	resolver := &agent.Spec.Components.RuntimeProtection.Resolver
+	if resolver.ReplicasCount != nil || common.IsEnabled(resolver.Autoscaling.Enabled) {
+		agent.Status.RuntimeResolverScaling = nil
+		return nil
+	}
+
+	nodes := &coreV1.NodeList{}
+	if err := c.nodesReader.List(ctx, nodes); err != nil {
+		return fmt.Errorf("failed listing the nodes: %w", err)
+	}
+
+	nodesCount := components.ResolverNodesCountForScaling(resolver, agent.Status.RuntimeResolverScaling, len(nodes.Items))
+	desiredReplicas := components.ResolverReplicasForNodes(resolver, nodesCount)
+	scaling := &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: desiredReplicas, Nodes: int32(nodesCount), LastScaleTime: metav1.Now()}
+	if lastScaling := agent.Status.RuntimeResolverScaling; lastScaling != nil {
+		scaling.LastScaleTime = lastScaling.LastScaleTime
+		switch {
+		case desiredReplicas != lastScaling.Replicas && components.UntilResolverScaling(agent, desiredReplicas) > 0:
+			c.log.Info("Delaying the scaling of the runtime resolver until the cooldown passes", "replicas", lastScaling.Replicas, "desiredReplicas", desiredReplicas)
+			scaling.Replicas = lastScaling.Replicas
+		case desiredReplicas != lastScaling.Replicas:
+			c.log.Info("Scaling the runtime resolver by the number of nodes", "nodes", nodesCount, "replicas", desiredReplicas)
+			scaling.LastScaleTime = metav1.Now()
+		}
+	}
+	agent.Status.RuntimeResolverScaling = scaling
+	c.resolverDeployment.UpdateReplicasCount(scaling.Replicas)
+
+	return nil
+}
@@ -2,71 +2,151 @@ package state
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/go-logr/logr"
 	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/events"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/agent_applyment"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment"
 	applymentOptions "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/capabilities"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/status"
 	appsV1 "k8s.io/api/apps/v1"
+	batchV1 "k8s.io/api/batch/v1"
 	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type AgentComponentApplier interface {
 	Apply(ctx context.Context, builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec, applyOptionsList ...*applymentOptions.ApplyOptions) (bool, client.Object, error)
-	Delete(ctx context.Context, builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec) (bool, error)
+	Delete(ctx context.Context, builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec, deleteOptions ...client.DeleteOption) (bool, error)
 }
 
 type StateApplier struct {
-	desiredConfigMap                *components.ConfigurationK8sObject
-	desiredRegistrySecret           *components.RegistrySecretK8sObject
-	desiredPriorityClass            *components.PriorityClassK8sObject
-	desiredMonitorDeployment        *components.MonitorDeploymentK8sObject
-	enforcerTlsSecret               *components.EnforcerTlsK8sObject
-	enforcerDeployment              *components.EnforcerDeploymentK8sObject
-	enforcerService                 *components.EnforcerServiceK8sObject
-	enforcerValidatingWebhook       *components.EnforcerValidatingWebhookK8sObject
-	enforcerMutatingWebhook         *components.EnforcerMutatingWebhookK8sObject
-	stateReporterDeployment         *components.StateReporterDeploymentK8sObject
-	resolverDeployment              *components.ResolverDeploymentK8sObject
-	resolverService                 *components.ResolverServiceK8sObject
-	sensorDaemonSet                 *components.SensorDaemonSetK8sObject
-	imageScanningReporterDeployment *components.ImageScanningReporterDeploymentK8sObject
-	imageScanningReporterService    *components.ImageScanningReporterServiceK8sObject
-	applier                         AgentComponentApplier
-	log                             logr.Logger
+	desiredConfigMap                      *components.ConfigurationK8sObject
+	desiredRegistrySecret                 *components.RegistrySecretK8sObject
+	desiredPriorityClass                  *components.PriorityClassK8sObject
+	desiredMonitorDeployment              *components.MonitorDeploymentK8sObject
+	enforcerTlsSecret                     *components.EnforcerTlsK8sObject
+	enforcerCertificate                   *components.EnforcerCertificateK8sObject
+	enforcerDeployment                    *components.EnforcerDeploymentK8sObject
+	enforcerService                       *components.EnforcerServiceK8sObject
+	enforcerValidatingWebhook             *components.EnforcerValidatingWebhookK8sObject
+	enforcerMutatingWebhook               *components.EnforcerMutatingWebhookK8sObject
+	enforcerAutoscaler                    *components.HorizontalPodAutoscalerK8sObject
+	enforcerDisruptionBudget              *components.PodDisruptionBudgetK8sObject
+	stateReporterDeployment               *components.StateReporterDeploymentK8sObject
+	resolverDeployment                    *components.ResolverDeploymentK8sObject
+	resolverService                       *components.ResolverServiceK8sObject
+	resolverAutoscaler                    *components.HorizontalPodAutoscalerK8sObject
+	resolverDisruptionBudget              *components.PodDisruptionBudgetK8sObject
+	sensorDaemonSet                       *components.SensorDaemonSetK8sObject
+	nodeCoverageConfigMap                 *components.NodeCoverageConfigMapK8sObject
+	imageScanningReporterDeployment       *components.ImageScanningReporterDeploymentK8sObject
+	imageScanningReporterService          *components.ImageScanningReporterServiceK8sObject
+	imageScanningReporterAutoscaler       *components.HorizontalPodAutoscalerK8sObject
+	imageScanningReporterDisruptionBudget *components.PodDisruptionBudgetK8sObject
+	nodeCleanupJob                        *components.NodeCleanupJobK8sObject
+	applier                               AgentComponentApplier
+	newPlanner                            func() *applyment.ComponentPlanner
+	// planning is set while the desired state is planned, so the plan covers all the changes that applying would make,
+	// even those that applying makes only once the nodes that ran the CNDR sensor are persisted.
+	planning bool
+	// rendering is set while the desired state is rendered, without a cluster. The enforcer is treated as ready then,
+	// and the given enforcer certificates, which may be placeholders, are not renewed.
+	rendering bool
+	// configObjects holds the ConfigMaps and Secrets that the workloads consume, so each of them is read once while
+	// the desired state is applied. A missing ConfigMap or Secret is held as nil.
+	configObjects        map[configObjectKey]client.Object
+	capabilitiesProvider capabilities.Provider
+	apiReader            client.Reader
+	nodesReader          client.Reader
+	eventRecorder        record.EventRecorder
+	log                  logr.Logger
+
+	// The arguments that the builders are created with, so planning and rendering use builders of their own
+	agentNamespace          string
+	clusterID               string
+	tlsSecretsValuesCreator components.TlsSecretsValuesCreator
 }
 
+// NewStateApplier returns a StateApplier that reads the nodes with the nodes reader, which is expected to be served from
+// the cache of the controller that watches them, and all the other k8s objects with the API reader.
 func NewStateApplier(
 	apiReader client.Reader,
+	nodesReader client.Reader,
 	agentComponentApplier AgentComponentApplier,
-	k8sVersion, agentNamespace, clusterID string,
+	capabilitiesProvider capabilities.Provider,
+	agentNamespace, clusterID string,
 	tlsSecretsValuesCreator components.TlsSecretsValuesCreator,
+	eventRecorder record.EventRecorder,
 	log logr.Logger,
 ) *StateApplier {
 	return &StateApplier{
-		desiredConfigMap:                components.NewConfigurationK8sObject(agentNamespace, clusterID),
-		desiredRegistrySecret:           components.NewRegistrySecretK8sObject(agentNamespace),
-		desiredPriorityClass:            components.NewPriorityClassK8sObject(k8sVersion),
-		desiredMonitorDeployment:        components.NewMonitorDeploymentK8sObject(agentNamespace),
-		enforcerTlsSecret:               components.NewEnforcerTlsK8sObject(agentNamespace, tlsSecretsValuesCreator),
-		enforcerDeployment:              components.NewEnforcerDeploymentK8sObject(agentNamespace),
-		enforcerService:                 components.NewEnforcerServiceK8sObject(agentNamespace),
-		enforcerValidatingWebhook:       components.NewEnforcerValidatingWebhookK8sObject(agentNamespace, k8sVersion),
-		enforcerMutatingWebhook:         components.NewEnforcerMutatingWebhookK8sObject(agentNamespace, k8sVersion),
-		stateReporterDeployment:         components.NewStateReporterDeploymentK8sObject(agentNamespace),
-		resolverDeployment:              components.NewResolverDeploymentK8sObject(agentNamespace, apiReader),
-		resolverService:                 components.NewResolverServiceK8sObject(agentNamespace),
-		sensorDaemonSet:                 components.NewSensorDaemonSetK8sObject(agentNamespace),
-		imageScanningReporterDeployment: components.NewImageScanningReporterDeploymentK8sObject(agentNamespace),
-		imageScanningReporterService:    components.NewImageScanningReporterServiceK8sObject(agentNamespace),
-		applier:                         agentComponentApplier,
-		log:                             log,
+		desiredConfigMap:                      components.NewConfigurationK8sObject(agentNamespace, clusterID),
+		desiredRegistrySecret:                 components.NewRegistrySecretK8sObject(agentNamespace),
+		desiredPriorityClass:                  components.NewPriorityClassK8sObject(capabilitiesProvider),
+		desiredMonitorDeployment:              components.NewMonitorDeploymentK8sObject(agentNamespace),
+		enforcerTlsSecret:                     components.NewEnforcerTlsK8sObject(agentNamespace, tlsSecretsValuesCreator),
+		enforcerCertificate:                   components.NewEnforcerCertificateK8sObject(agentNamespace),
+		enforcerDeployment:                    components.NewEnforcerDeploymentK8sObject(agentNamespace),
+		enforcerService:                       components.NewEnforcerServiceK8sObject(agentNamespace),
+		enforcerValidatingWebhook:             components.NewEnforcerValidatingWebhookK8sObject(agentNamespace, capabilitiesProvider),
+		enforcerMutatingWebhook:               components.NewEnforcerMutatingWebhookK8sObject(agentNamespace, capabilitiesProvider),
+		enforcerAutoscaler:                    components.NewEnforcerHorizontalPodAutoscalerK8sObject(agentNamespace),
+		enforcerDisruptionBudget:              components.NewEnforcerPodDisruptionBudgetK8sObject(agentNamespace),
+		stateReporterDeployment:               components.NewStateReporterDeploymentK8sObject(agentNamespace),
+		resolverDeployment:                    components.NewResolverDeploymentK8sObject(agentNamespace),
+		resolverService:                       components.NewResolverServiceK8sObject(agentNamespace),
+		resolverAutoscaler:                    components.NewResolverHorizontalPodAutoscalerK8sObject(agentNamespace),
+		resolverDisruptionBudget:              components.NewResolverPodDisruptionBudgetK8sObject(agentNamespace),
+		sensorDaemonSet:                       components.NewSensorDaemonSetK8sObject(agentNamespace),
+		nodeCoverageConfigMap:                 components.NewNodeCoverageConfigMapK8sObject(agentNamespace),
+		imageScanningReporterDeployment:       components.NewImageScanningReporterDeploymentK8sObject(agentNamespace),
+		imageScanningReporterService:          components.NewImageScanningReporterServiceK8sObject(agentNamespace),
+		imageScanningReporterAutoscaler:       components.NewImageScanningReporterHorizontalPodAutoscalerK8sObject(agentNamespace),
+		imageScanningReporterDisruptionBudget: components.NewImageScanningReporterPodDisruptionBudgetK8sObject(agentNamespace),
+		nodeCleanupJob:                        components.NewNodeCleanupJobK8sObject(agentNamespace),
+		applier:                               agentComponentApplier,
+		newPlanner:                            func() *applyment.ComponentPlanner { return applyment.NewComponentPlanner(apiReader) },
+		capabilitiesProvider:                  capabilitiesProvider,
+		apiReader:                             apiReader,
+		nodesReader:                           nodesReader,
+		eventRecorder:                         eventRecorder,
+		log:                                   log,
+		agentNamespace:                        agentNamespace,
+		clusterID:                             clusterID,
+		tlsSecretsValuesCreator:               tlsSecretsValuesCreator,
+	}
+}
+
+// newDerivedStateApplier returns a StateApplier with builders of its own, which applies through the given applier and
+// reads through the given readers. The builders keep state between the steps of the flow, e.g. the renewed enforcer
+// certificates or the derived resolver replicas count, so planning and rendering don't change the builders of c.
+func (c *StateApplier) newDerivedStateApplier(apiReader, nodesReader client.Reader, agentComponentApplier AgentComponentApplier, log logr.Logger) *StateApplier {
+	derived := NewStateApplier(apiReader, nodesReader, agentComponentApplier, c.capabilitiesProvider, c.agentNamespace, c.clusterID, c.tlsSecretsValuesCreator, discardingEventRecorder{}, log)
+	derived.newPlanner = c.newPlanner
+	return derived
+}
+
+// UseServerSideDryRunPlanning makes PlanDesiredState apply the desired state with a server-side dry run, as expected
+// when the agent component applier uses server-side apply with the given field manager.
+func (c *StateApplier) UseServerSideDryRunPlanning(k8sClient client.Client, fieldManager string) {
+	c.newPlanner = func() *applyment.ComponentPlanner {
+		return applyment.NewServerSideDryRunComponentPlanner(k8sClient, fieldManager)
 	}
 }
 
@@ -74,26 +154,54 @@ func (c *StateApplier) GetPriorityClassEmptyK8sObject() client.Object {
 	return c.desiredPriorityClass.EmptyK8sObject()
 }
 
+// ShouldProcessEvent returns true for the agent workloads, so changes to their status (e.g. ready replicas) are
+// reflected in the webhooks and in the components conditions, and for the node cleanup Jobs, so they are tracked until they finish.
 func (c *StateApplier) ShouldProcessEvent(obj client.Object) bool {
+	if _, ok := obj.(*batchV1.Job); ok {
+		return obj.GetNamespace() == c.nodeCleanupJob.Namespace && obj.GetLabels()[components.NodeCleanupLabelKey] == components.NodeCleanupName
+	}
+
 	objNamespacedName := types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}
-	return c.enforcerDeployment.NamespacedName() == objNamespacedName
+	for _, workload := range c.workloads() {
+		if workload.NamespacedName() == objNamespacedName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *StateApplier) workloads() []agent_applyment.AgentComponentBuilder {
+	return []agent_applyment.AgentComponentBuilder{
+		c.desiredMonitorDeployment,
+		c.enforcerDeployment,
+		c.stateReporterDeployment,
+		c.resolverDeployment,
+		c.sensorDaemonSet,
+		c.imageScanningReporterDeployment,
+	}
 }
 
-func (c *StateApplier) ApplyDesiredState(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec, registrySecret *models.RegistrySecretValues, setOwner applymentOptions.OwnerSetter) (bool, error) {
-	applyOptions := applymentOptions.NewApplyOptions().SetOwnerSetter(setOwner)
+// ApplyDesiredState applies all the agent components, reports the state of the agent workloads in the agent status
+// and records an event on the agent for every component that was created, updated or deleted.
+func (c *StateApplier) ApplyDesiredState(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, registrySecret *models.RegistrySecretValues, setOwner applymentOptions.OwnerSetter) (bool, error) {
+	agentSpec := &agent.Spec
+	applyOptions := c.newApplyOptions(agent, setOwner)
+	c.configObjects = make(map[configObjectKey]client.Object)
+	defer func() { c.configObjects = nil }()
 
-	coreMutated, err := c.applyCoreComponents(ctx, agentSpec, registrySecret, applyOptions)
+	coreMutated, err := c.applyCoreComponents(ctx, agent, registrySecret, applyOptions)
 	if err != nil {
 		return false, err
 	}
 
-	mutatedEnforcer, err := c.applyEnforcer(ctx, agentSpec, applyOptions)
+	mutatedEnforcer, err := c.applyEnforcer(ctx, agent, applyOptions)
 	if err != nil {
 		return false, err
 	}
 	c.log.Info("Applied enforcer objects", "Mutated", mutatedEnforcer)
 
-	mutatedStateReporter, err := c.applyStateReporter(ctx, agentSpec, applyOptions)
+	mutatedStateReporter, err := c.applyStateReporter(ctx, agent, applyOptions)
 	if err != nil {
 		return false, err
 	}
@@ -103,54 +211,238 @@ func (c *StateApplier) ApplyDesiredState(ctx context.Context, agentSpec *cbconta
 	mutatedComponentsDaemonSet, componentsDamonSetDeleted := false, false
 	var deleteErr error
 
-	if common.IsEnabled(agentSpec.Components.RuntimeProtection.Enabled) {
-		mutatedRuntimeResolver, err = c.applyResolver(ctx, agentSpec, applyOptions)
+	if isResolverEnabled(agentSpec) {
+		mutatedRuntimeResolver, err = c.applyResolver(ctx, agent, applyOptions)
 		if err != nil {
 			return false, err
 		}
 		c.log.Info("Applied runtime kubernetes resolver objects", "Mutated", mutatedRuntimeResolver)
 
 	} else {
-		runtimeResolverDeleted, deleteErr = c.deleteResolver(ctx, agentSpec)
+		runtimeResolverDeleted, deleteErr = c.deleteResolver(ctx, agent)
 		if deleteErr != nil {
 			return false, deleteErr
 		}
 	}
 
 	mutatedImageScanningReporter, imageScanningReporterDeleted := false, false
-	if common.IsEnabled(agentSpec.Components.ClusterScanning.Enabled) {
-		mutatedImageScanningReporter, err = c.applyImageScanningReporter(ctx, agentSpec, applyOptions)
+	if isImageScanningReporterEnabled(agentSpec) {
+		mutatedImageScanningReporter, err = c.applyImageScanningReporter(ctx, agent, applyOptions)
 		if err != nil {
 			return false, err
 		}
 
 		c.log.Info("Applied image scanning reporter objects", "Mutated", mutatedImageScanningReporter)
 	} else {
-		imageScanningReporterDeleted, err = c.deleteImageScanningReporter(ctx, agentSpec)
+		imageScanningReporterDeleted, err = c.deleteImageScanningReporter(ctx, agent)
 		if err != nil {
 			return false, err
 		}
 	}
 
-	if common.IsEnabled(agentSpec.Components.ClusterScanning.Enabled) ||
-		common.IsEnabled(agentSpec.Components.RuntimeProtection.Enabled) ||
-		(agentSpec.Components.Cndr != nil && common.IsEnabled(agentSpec.Components.Cndr.Enabled)) {
-		mutatedComponentsDaemonSet, err = c.applyComponentsDamonSet(ctx, agentSpec, applyOptions)
+	// The nodes are marked before the daemon set is changed, as its pods are the only record of the nodes that ran the sensor.
+	// Newly marked nodes are persisted in the agent status before the daemon set is changed, by returning a changed state.
+	if !isCndrEnabled(agentSpec) {
+		marked, err := c.markSensorNodesForCleanup(ctx, agent)
+		if err != nil {
+			return false, err
+		}
+		if marked && !c.planning {
+			c.log.Info("Marked the nodes that run the CNDR sensor for cleanup, the daemon set is applied once they are persisted", "nodes", agent.Status.NodeCleanup.PendingNodes)
+			return true, nil
+		}
+	}
+
+	if isComponentsDaemonSetEnabled(agentSpec) {
+		mutatedComponentsDaemonSet, err = c.applyComponentsDamonSet(ctx, agent, applyOptions)
 		if err != nil {
 			return false, err
 		}
 		c.log.Info("Applied featured components daemon set objects", "Mutated", mutatedComponentsDaemonSet)
 	} else {
-		componentsDamonSetDeleted, err = c.deleteComponentsDamonSet(ctx, agentSpec)
+		componentsDamonSetDeleted, err = c.deleteComponentsDamonSet(ctx, agent)
 		if err != nil {
 			return false, err
 		}
 	}
 
-	return coreMutated || mutatedEnforcer || mutatedStateReporter || mutatedRuntimeResolver || mutatedComponentsDaemonSet || runtimeResolverDeleted || mutatedImageScanningReporter || imageScanningReporterDeleted || componentsDamonSetDeleted, nil
+	nodesCleanedUp, err := c.cleanupSensorNodes(ctx, agent, setOwner)
+	if err != nil {
+		return false, err
+	}
+
+	return coreMutated || mutatedEnforcer || mutatedStateReporter || mutatedRuntimeResolver || mutatedComponentsDaemonSet || runtimeResolverDeleted || mutatedImageScanningReporter || imageScanningReporterDeleted || componentsDamonSetDeleted || !nodesCleanedUp, nil
+}
+
+// PlanDesiredState runs the same flow as ApplyDesiredState against the live k8s objects, but nothing is written.
+// Instead, it returns the changes that applying the desired state would make. The given agent is not modified.
+// The enforcer certificates aren't renewed while planning, as the renewed certificates are random and are always a
+// change, and neither is their expiry reported in the metrics.
+func (c *StateApplier) PlanDesiredState(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, registrySecret *models.RegistrySecretValues, setOwner applymentOptions.OwnerSetter) ([]applyment.PlannedChange, error) {
+	planner := c.newPlanner()
+
+	planningStateApplier := c.newDerivedStateApplier(c.apiReader, c.nodesReader, agent_applyment.NewAgentComponent(planner), c.log.WithName("plan"))
+	planningStateApplier.planning = true
+
+	if _, err := planningStateApplier.ApplyDesiredState(ctx, agent.DeepCopy(), registrySecret, setOwner); err != nil {
+		return nil, err
+	}
+
+	return planner.Changes(), nil
+}
+
+// TeardownState deletes all the agent components in order: the enforcer webhooks first, so they don't block API
+// requests once the enforcer is gone, then the enforcer and then the rest of the components.
+// Once the components daemon set pods are gone, it cleans up the nodes that ran the CNDR sensor.
+// It should be called until it returns true, which means that all the nodes were cleaned up.
+// The cleanup Jobs aren't owned by the agent, as the garbage collector would delete them before they finish when the
+// agent is deleted with the foreground propagation policy. They are deleted once they finish, before the teardown is done.
+func (c *StateApplier) TeardownState(ctx context.Context, agent *cbcontainersv1.CBContainersAgent) (bool, error) {
+	if _, err := c.deleteAllEnforcerWebhooks(ctx, agent); err != nil {
+		return false, err
+	}
+
+	for _, autoscaler := range []*components.HorizontalPodAutoscalerK8sObject{c.enforcerAutoscaler, c.resolverAutoscaler, c.imageScanningReporterAutoscaler} {
+		if _, err := c.deleteAutoscaler(ctx, agent, autoscaler); err != nil {
+			return false, err
+		}
+	}
+
+	for _, disruptionBudget := range []*components.PodDisruptionBudgetK8sObject{c.enforcerDisruptionBudget, c.resolverDisruptionBudget, c.imageScanningReporterDisruptionBudget} {
+		if _, err := c.deleteDisruptionBudget(ctx, agent, disruptionBudget); err != nil {
+			return false, err
+		}
+	}
+
+	for _, builder := range []agent_applyment.AgentComponentBuilder{c.enforcerDeployment, c.enforcerService} {
+		if _, err := c.deleteComponent(ctx, agent, builder); err != nil {
+			return false, err
+		}
+	}
+
+	// The Certificate is deleted before the secret, so cert-manager doesn't issue the secret again
+	if isCertManagerEnabled(&agent.Spec) {
+		if _, err := c.deleteEnforcerCertificate(ctx, agent); err != nil {
+			return false, err
+		}
+	}
+	if _, err := c.deleteComponent(ctx, agent, c.enforcerTlsSecret); err != nil {
+		return false, err
+	}
+
+	// The nodes are marked before the daemon set is deleted, as its pods are the only record of the nodes that ran the sensor.
+	// Newly marked nodes are persisted in the agent status before the daemon set is deleted.
+	marked, err := c.markSensorNodesForCleanup(ctx, agent)
+	if err != nil {
+		return false, err
+	}
+	if marked {
+		c.log.Info("Marked the nodes that run the CNDR sensor for cleanup, the daemon set is deleted once they are persisted", "nodes", agent.Status.NodeCleanup.PendingNodes)
+		return false, nil
+	}
+
+	for _, builder := range []agent_applyment.AgentComponentBuilder{
+		c.stateReporterDeployment,
+		c.resolverDeployment,
+		c.resolverService,
+		c.imageScanningReporterDeployment,
+		c.imageScanningReporterService,
+		c.sensorDaemonSet,
+		c.desiredMonitorDeployment,
+		c.desiredPriorityClass,
+		c.desiredRegistrySecret,
+		c.desiredConfigMap,
+	} {
+		if _, err := c.deleteComponent(ctx, agent, builder); err != nil {
+			return false, err
+		}
+	}
+
+	// The daemon set pods use the node data until they are terminated
+	daemonSetPods, err := c.listDaemonSetPods(ctx)
+	if err != nil {
+		return false, err
+	}
+	if len(daemonSetPods) > 0 {
+		c.log.Info("Waiting for the components daemon set pods to terminate", "pods", len(daemonSetPods))
+		return false, nil
+	}
+
+	return c.cleanupSensorNodes(ctx, agent, nil)
+}
+
+// ReportState reports the state of the workloads of all the enabled agent components in the agent status, without
+// applying anything. It is used instead of ApplyDesiredState while the reconciliation of the agent is paused.
+func (c *StateApplier) ReportState(ctx context.Context, agent *cbcontainersv1.CBContainersAgent) error {
+	agentSpec := &agent.Spec
+
+	workloads := []agent_applyment.AgentComponentBuilder{c.desiredMonitorDeployment, c.enforcerDeployment, c.stateReporterDeployment}
+	if isResolverEnabled(agentSpec) {
+		workloads = append(workloads, c.resolverDeployment)
+	}
+	if isImageScanningReporterEnabled(agentSpec) {
+		workloads = append(workloads, c.imageScanningReporterDeployment)
+	}
+	if isComponentsDaemonSetEnabled(agentSpec) {
+		workloads = append(workloads, c.sensorDaemonSet)
+	}
+
+	for _, workload := range workloads {
+		k8sObject, err := c.readWorkload(ctx, workload)
+		if err != nil {
+			return err
+		}
+
+		if err := c.reportWorkload(agent, workload, k8sObject); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (c *StateApplier) applyCoreComponents(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec, registrySecret *models.RegistrySecretValues, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
+// RenderDesiredState runs the same flow as ApplyDesiredState against an empty cluster, without reading or writing
+// anything in the cluster, and returns the k8s objects that applying the desired state would create.
+// The enforcer webhooks are rendered as if the enforcer is ready. The given agent is not modified.
+func (c *StateApplier) RenderDesiredState(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, registrySecret *models.RegistrySecretValues) ([]client.Object, error) {
+	recorder := applyment.NewComponentRecorder()
+
+	renderingStateApplier := c.newDerivedStateApplier(recorder, recorder, agent_applyment.NewAgentComponent(recorder), c.log.WithName("render"))
+	renderingStateApplier.rendering = true
+
+	renderedAgent := agent.DeepCopy()
+	if _, err := renderingStateApplier.ApplyDesiredState(ctx, renderedAgent, registrySecret, nil); err != nil {
+		return nil, err
+	}
+
+	// A workload with an invalid pod template patch is skipped when applying, so the live workload keeps running,
+	// but there is no live workload to render
+	for _, componentStatus := range renderedAgent.Status.Components {
+		if invalidCondition := meta.FindStatusCondition(componentStatus.Conditions, cbcontainersv1.ConditionTypePodTemplatePatchInvalid); invalidCondition != nil && invalidCondition.Status == metav1.ConditionTrue {
+			return nil, fmt.Errorf("failed rendering `%v`: %v", componentStatus.Name, invalidCondition.Message)
+		}
+	}
+
+	return recorder.K8sObjects(), nil
+}
+
+// newApplyOptions returns the options to apply the agent components with, which record an event on the agent for every
+// component that was created or updated.
+func (c *StateApplier) newApplyOptions(agent *cbcontainersv1.CBContainersAgent, setOwner applymentOptions.OwnerSetter) *applymentOptions.ApplyOptions {
+	return applymentOptions.NewApplyOptions().
+		SetOwnerSetter(setOwner).
+		SetChangeRecorder(func(changedResource metav1.Object, created bool) {
+			if created {
+				c.eventRecorder.Eventf(agent, coreV1.EventTypeNormal, events.ReasonComponentCreated, "Created %v %v", kindOf(changedResource), nameOf(changedResource))
+			} else {
+				c.eventRecorder.Eventf(agent, coreV1.EventTypeNormal, events.ReasonComponentUpdated, "Updated %v %v", kindOf(changedResource), nameOf(changedResource))
+			}
+		})
+}
+
+func (c *StateApplier) applyCoreComponents(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, registrySecret *models.RegistrySecretValues, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
+	agentSpec := &agent.Spec
+
 	mutatedConfigmap, _, err := c.applier.Apply(ctx, c.desiredConfigMap, agentSpec, applyOptions)
 	if err != nil {
 		return false, err
@@ -173,7 +465,7 @@ func (c *StateApplier) applyCoreComponents(ctx context.Context, agentSpec *cbcon
 	}
 	c.log.Info("Applied priority class", "Mutated", mutatedPriorityClass)
 
-	mutatedMonitor, _, err := c.applier.Apply(ctx, c.desiredMonitorDeployment, agentSpec, applyOptions)
+	mutatedMonitor, _, err := c.applyWorkload(ctx, agent, c.desiredMonitorDeployment, applyOptions)
 	if err != nil {
 		return false, err
 	}
@@ -182,22 +474,22 @@ func (c *StateApplier) applyCoreComponents(ctx context.Context, agentSpec *cbcon
 	return mutatedConfigmap || mutatedRegistrySecret || mutatedPriorityClass || mutatedMonitor, nil
 }
 
-func (c *StateApplier) applyEnforcer(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
-	mutatedSecret, secretK8sObject, err := c.applier.Apply(ctx, c.enforcerTlsSecret, agentSpec, applyOptions, applymentOptions.NewApplyOptions().SetCreateOnly(true))
+func (c *StateApplier) applyEnforcer(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
+	agentSpec := &agent.Spec
+
+	mutatedSecret, tlsSecret, err := c.applyEnforcerTls(ctx, agent, applyOptions)
 	if err != nil {
 		return false, err
 	}
-	c.log.Info("Applied enforcer tls secret", "Mutated", mutatedSecret)
-
-	tlsSecret, ok := secretK8sObject.(*coreV1.Secret)
-	if !ok {
-		return false, fmt.Errorf("expected Secret K8s object")
-	}
+	c.enforcerDeployment.UpdateTlsSecretValues(enforcerTlsSecretValues(agentSpec, tlsSecret))
 
 	mutatedService, _, err := c.applier.Apply(ctx, c.enforcerService, agentSpec, applyOptions)
 	if err != nil {
-		deleted, deleteErr := c.deleteAllEnforcerWebhooks(ctx, agentSpec)
+		deleted, deleteErr := c.deleteAllEnforcerWebhooks(ctx, agent)
 		c.log.Info("Deleted enforcer webhooks because of an error while applying enforcer service", "deleted", deleted, "deletion-error", deleteErr)
+		if deleted {
+			c.eventRecorder.Eventf(agent, coreV1.EventTypeWarning, events.ReasonWebhooksRemoved, "Removed enforcer webhooks because of an error while applying enforcer service: %v", err)
+		}
 		if deleteErr != nil {
 			return deleted, deleteErr
 		}
@@ -206,10 +498,13 @@ func (c *StateApplier) applyEnforcer(ctx context.Context, agentSpec *cbcontainer
 	}
 	c.log.Info("Applied enforcer service", "Mutated", mutatedService)
 
-	mutatedDeployment, deploymentK8sObject, err := c.applier.Apply(ctx, c.enforcerDeployment, agentSpec, applyOptions)
+	mutatedDeployment, deploymentK8sObject, err := c.applyWorkload(ctx, agent, c.enforcerDeployment, applyOptions)
 	if err != nil {
-		deleted, deleteErr := c.deleteAllEnforcerWebhooks(ctx, agentSpec)
+		deleted, deleteErr := c.deleteAllEnforcerWebhooks(ctx, agent)
 		c.log.Info("Deleted enforcer webhooks because of an error while applying enforcer deployment", "deleted", deleted, "deletion-error", deleteErr)
+		if deleted {
+			c.eventRecorder.Eventf(agent, coreV1.EventTypeWarning, events.ReasonWebhooksRemoved, "Removed enforcer webhooks because of an error while applying enforcer deployment: %v", err)
+		}
 		if deleteErr != nil {
 			return deleted, deleteErr
 		}
@@ -218,131 +513,240 @@ func (c *StateApplier) applyEnforcer(ctx context.Context, agentSpec *cbcontainer
 	}
 	c.log.Info("Applied enforcer deployment", "Mutated", mutatedDeployment)
 
+	mutatedAutoscaler, err := c.applyAutoscaler(ctx, agent, c.enforcerAutoscaler, applyOptions)
+	if err != nil {
+		return false, err
+	}
+
+	c.enforcerDisruptionBudget.UpdateMaxReplicasCount(components.DeploymentMaxReplicas(agentSpec.Components.Basic.Enforcer.ReplicasCount, &agentSpec.Components.Basic.Enforcer.Autoscaling, 1))
+	mutatedDisruptionBudget, err := c.applyDisruptionBudget(ctx, agent, c.enforcerDisruptionBudget, applyOptions)
+	if err != nil {
+		return false, err
+	}
+
 	enforcerDeployment, ok := deploymentK8sObject.(*appsV1.Deployment)
 	if !ok {
 		return false, fmt.Errorf("expected Deployment K8s object")
 	}
 
+	completedRotation := false
+	if tlsSecret != nil {
+		completedRotation, tlsSecret, err = c.completeEnforcerTlsRotation(ctx, agent, tlsSecret, enforcerDeployment, applyOptions)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	mutatedWebhooks := false
-	if enforcerDeployment.Status.ReadyReplicas < 1 {
-		if deleted, deleteErr := c.deleteAllEnforcerWebhooks(ctx, agentSpec); deleteErr != nil {
+	if tlsSecret == nil && !c.rendering {
+		// The webhooks can't be served before the certificates are issued
+		if deleted, deleteErr := c.deleteAllEnforcerWebhooks(ctx, agent); deleteErr != nil {
+			return false, deleteErr
+		} else if deleted {
+			c.eventRecorder.Event(agent, coreV1.EventTypeWarning, events.ReasonWebhooksRemoved, "Removed enforcer webhooks because the enforcer certificates weren't issued")
+			mutatedWebhooks = true
+		}
+	} else if enforcerDeployment.Status.ReadyReplicas < 1 && !c.rendering {
+		if deleted, deleteErr := c.deleteAllEnforcerWebhooks(ctx, agent); deleteErr != nil {
 			return false, deleteErr
 		} else if deleted {
+			c.eventRecorder.Event(agent, coreV1.EventTypeWarning, events.ReasonWebhooksRemoved, "Removed enforcer webhooks because the enforcer deployment has no ready replicas")
 			mutatedWebhooks = true
 		}
 	} else {
-		mutatedWebhooks, err = c.applyEnforcerWebhooks(ctx, agentSpec, tlsSecret, applyOptions)
+		mutatedWebhooks, err = c.applyEnforcerWebhooks(ctx, agent, tlsSecret, applyOptions)
 		if err != nil {
 			return false, err
 		}
 		c.log.Info("Applied enforcer webhooks", "Mutated", mutatedWebhooks)
 	}
 
-	return mutatedSecret || mutatedDeployment || mutatedService || mutatedWebhooks, nil
+	return mutatedSecret || completedRotation || mutatedDeployment || mutatedAutoscaler || mutatedDisruptionBudget || mutatedService || mutatedWebhooks, nil
 }
 
-func (c *StateApplier) applyStateReporter(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
-	mutatedDeployment, _, err := c.applier.Apply(ctx, c.stateReporterDeployment, agentSpec, applyOptions)
+func (c *StateApplier) applyStateReporter(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
+	mutatedDeployment, _, err := c.applyWorkload(ctx, agent, c.stateReporterDeployment, applyOptions)
 	if err != nil {
 		return false, err
 	}
 	c.log.Info("Applied state reporter deployment", "Mutated", mutatedDeployment)
+
 	return mutatedDeployment, nil
 }
 
-func (c *StateApplier) applyResolver(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
+func (c *StateApplier) applyResolver(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
+	agentSpec := &agent.Spec
+
 	mutatedService, _, err := c.applier.Apply(ctx, c.resolverService, agentSpec, applyOptions)
 	if err != nil {
 		return false, err
 	}
 	c.log.Info("Applied kubernetes resolver service", "Mutated", mutatedService)
 
-	mutatedDeployment, _, err := c.applier.Apply(ctx, c.resolverDeployment, agentSpec, applyOptions)
+	if err := c.scaleResolver(ctx, agent); err != nil {
+		return false, err
+	}
+
+	mutatedDeployment, _, err := c.applyWorkload(ctx, agent, c.resolverDeployment, applyOptions)
 	if err != nil {
 		return false, err
 	}
 	c.log.Info("Applied runtime kubernetes resolver deployment", "Mutated", mutatedDeployment)
-	return mutatedService || mutatedDeployment, nil
+
+	mutatedAutoscaler, err := c.applyAutoscaler(ctx, agent, c.resolverAutoscaler, applyOptions)
+	if err != nil {
+		return false, err
+	}
+
+	derivedReplicasCount := int32(1)
+	if scaling := agent.Status.RuntimeResolverScaling; scaling != nil {
+		derivedReplicasCount = scaling.Replicas
+	}
+	c.resolverDisruptionBudget.UpdateMaxReplicasCount(components.DeploymentMaxReplicas(agentSpec.Components.RuntimeProtection.Resolver.ReplicasCount, &agentSpec.Components.RuntimeProtection.Resolver.Autoscaling, derivedReplicasCount))
+	mutatedDisruptionBudget, err := c.applyDisruptionBudget(ctx, agent, c.resolverDisruptionBudget, applyOptions)
+	if err != nil {
+		return false, err
+	}
+
+	return mutatedService || mutatedDeployment || mutatedAutoscaler || mutatedDisruptionBudget, nil
 }
 
 // applyComponentsDamonSet applies the daemon set that stores the runtime sensor and/or the cluster-scanning scanner containers.
 // the daemon set is set to be applied if either of the featured components are enabled.
-func (c *StateApplier) applyComponentsDamonSet(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
-	mutatedDaemonSet, _, err := c.applier.Apply(ctx, c.sensorDaemonSet, agentSpec, applyOptions)
+func (c *StateApplier) applyComponentsDamonSet(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
+	nodes := &coreV1.NodeList{}
+	if err := c.nodesReader.List(ctx, nodes); err != nil {
+		return false, fmt.Errorf("failed listing the nodes: %w", err)
+	}
+
+	tolerationsDiscovery := &agent.Spec.Components.Settings.DaemonSetsTolerationsDiscovery
+	if common.IsEnabled(tolerationsDiscovery.Enabled) {
+		c.sensorDaemonSet.UpdateDiscoveredTolerations(components.DiscoverTolerations(nodes.Items, tolerationsDiscovery))
+	}
+
+	mutatedDaemonSet, daemonSet, err := c.applyWorkload(ctx, agent, c.sensorDaemonSet, applyOptions)
 	if err != nil {
 		return false, err
 	}
 	c.log.Info("Applied daemon set featured components", "Mutated", mutatedDaemonSet)
+	agent.Status.DaemonSetsTolerations = c.sensorDaemonSet.Tolerations(&agent.Spec)
+
+	if err := c.reportNodeCoverage(ctx, agent, daemonSet, nodes.Items, applyOptions.OwnerSetter()); err != nil {
+		return false, err
+	}
+
 	return mutatedDaemonSet, nil
 }
 
-func (c *StateApplier) deleteResolver(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec) (bool, error) {
-	resolverServiceDeleted, deleteErr := c.applier.Delete(ctx, c.resolverService, agentSpec)
+func (c *StateApplier) deleteResolver(ctx context.Context, agent *cbcontainersv1.CBContainersAgent) (bool, error) {
+	resolverServiceDeleted, deleteErr := c.deleteComponent(ctx, agent, c.resolverService)
 	if deleteErr != nil {
 		return false, deleteErr
 	} else if resolverServiceDeleted {
 		c.log.Info("Deleted resolver service")
 	}
 
-	resolverDeploymentDeleted, deleteErr := c.applier.Delete(ctx, c.resolverDeployment, agentSpec)
+	resolverAutoscalerDeleted, deleteErr := c.deleteAutoscaler(ctx, agent, c.resolverAutoscaler)
+	if deleteErr != nil {
+		return false, deleteErr
+	}
+
+	resolverDisruptionBudgetDeleted, deleteErr := c.deleteDisruptionBudget(ctx, agent, c.resolverDisruptionBudget)
+	if deleteErr != nil {
+		return false, deleteErr
+	}
+
+	resolverDeploymentDeleted, deleteErr := c.deleteComponent(ctx, agent, c.resolverDeployment)
 	if deleteErr != nil {
 		return false, deleteErr
 	} else if resolverDeploymentDeleted {
 		c.log.Info("Deleted resolver deployment")
 	}
+	status.RemoveComponentStatus(&agent.Status, components.ResolverName)
+	agent.Status.RuntimeResolverScaling = nil
 
-	return resolverServiceDeleted || resolverDeploymentDeleted, nil
+	return resolverServiceDeleted || resolverAutoscalerDeleted || resolverDisruptionBudgetDeleted || resolverDeploymentDeleted, nil
 }
 
-func (c *StateApplier) applyImageScanningReporter(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
+func (c *StateApplier) applyImageScanningReporter(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
+	agentSpec := &agent.Spec
+
 	mutatedService, _, err := c.applier.Apply(ctx, c.imageScanningReporterService, agentSpec, applyOptions)
 	if err != nil {
 		return false, err
 	}
 	c.log.Info("Applied image scanning reporter service", "Mutated", mutatedService)
 
-	mutatedDeployment, _, err := c.applier.Apply(ctx, c.imageScanningReporterDeployment, agentSpec, applyOptions)
+	mutatedDeployment, _, err := c.applyWorkload(ctx, agent, c.imageScanningReporterDeployment, applyOptions)
 	if err != nil {
 		return false, err
 	}
 	c.log.Info("Applied image scanning reporter deployment", "Mutated", mutatedDeployment)
 
-	return mutatedService || mutatedDeployment, nil
+	mutatedAutoscaler, err := c.applyAutoscaler(ctx, agent, c.imageScanningReporterAutoscaler, applyOptions)
+	if err != nil {
+		return false, err
+	}
+
+	c.imageScanningReporterDisruptionBudget.UpdateMaxReplicasCount(components.DeploymentMaxReplicas(agentSpec.Components.ClusterScanning.ImageScanningReporter.ReplicasCount, &agentSpec.Components.ClusterScanning.ImageScanningReporter.Autoscaling, 1))
+	mutatedDisruptionBudget, err := c.applyDisruptionBudget(ctx, agent, c.imageScanningReporterDisruptionBudget, applyOptions)
+	if err != nil {
+		return false, err
+	}
+
+	return mutatedService || mutatedDeployment || mutatedAutoscaler || mutatedDisruptionBudget, nil
 }
 
-func (c *StateApplier) deleteImageScanningReporter(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec) (bool, error) {
-	imageScanningReporterServiceDeleted, deleteErr := c.applier.Delete(ctx, c.imageScanningReporterService, agentSpec)
+func (c *StateApplier) deleteImageScanningReporter(ctx context.Context, agent *cbcontainersv1.CBContainersAgent) (bool, error) {
+	imageScanningReporterServiceDeleted, deleteErr := c.deleteComponent(ctx, agent, c.imageScanningReporterService)
 	if deleteErr != nil {
 		return false, deleteErr
 	} else if imageScanningReporterServiceDeleted {
 		c.log.Info("Deleted image scanning reporter service")
 	}
 
-	imageScanningReporterDeploymentDeleted, deleteErr := c.applier.Delete(ctx, c.imageScanningReporterDeployment, agentSpec)
+	imageScanningReporterAutoscalerDeleted, deleteErr := c.deleteAutoscaler(ctx, agent, c.imageScanningReporterAutoscaler)
+	if deleteErr != nil {
+		return false, deleteErr
+	}
+
+	imageScanningReporterDisruptionBudgetDeleted, deleteErr := c.deleteDisruptionBudget(ctx, agent, c.imageScanningReporterDisruptionBudget)
+	if deleteErr != nil {
+		return false, deleteErr
+	}
+
+	imageScanningReporterDeploymentDeleted, deleteErr := c.deleteComponent(ctx, agent, c.imageScanningReporterDeployment)
 	if deleteErr != nil {
 		return false, deleteErr
 	} else if imageScanningReporterDeploymentDeleted {
 		c.log.Info("Deleted image scanning reporter deployment")
 	}
+	status.RemoveComponentStatus(&agent.Status, components.ImageScanningReporterName)
 
-	return imageScanningReporterServiceDeleted || imageScanningReporterDeploymentDeleted, nil
+	return imageScanningReporterServiceDeleted || imageScanningReporterAutoscalerDeleted || imageScanningReporterDisruptionBudgetDeleted || imageScanningReporterDeploymentDeleted, nil
 }
 
 // deleteComponentsDamonSet deletes the daemonset that runs the runtime sensor and/or the cluster-scanning scanner containers.
 // the daemon set is being deleted only if all the feature components are disabled (runtime & cluster-scanner)
-func (c *StateApplier) deleteComponentsDamonSet(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec) (bool, error) {
-	sensorDaemonSetDeleted, deleteErr := c.applier.Delete(ctx, c.sensorDaemonSet, agentSpec)
+func (c *StateApplier) deleteComponentsDamonSet(ctx context.Context, agent *cbcontainersv1.CBContainersAgent) (bool, error) {
+	sensorDaemonSetDeleted, deleteErr := c.deleteComponent(ctx, agent, c.sensorDaemonSet)
 	if deleteErr != nil {
 		return false, deleteErr
 	} else if sensorDaemonSetDeleted {
 		c.log.Info("Deleted featured components daemonset")
 	}
+	status.RemoveComponentStatus(&agent.Status, components.DaemonSetName)
+	agent.Status.DaemonSetsTolerations = nil
+	if err := c.deleteNodeCoverage(ctx, agent); err != nil {
+		return false, err
+	}
 
 	return sensorDaemonSetDeleted, nil
 }
 
-func (c *StateApplier) applyEnforcerWebhooks(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec, tlsSecret *coreV1.Secret, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
-	tlsSecretValues := models.TlsSecretValuesFromSecretData(tlsSecret.Data)
+func (c *StateApplier) applyEnforcerWebhooks(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, tlsSecret *coreV1.Secret, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
+	agentSpec := &agent.Spec
+	tlsSecretValues := enforcerTlsSecretValues(agentSpec, tlsSecret)
 
 	c.enforcerValidatingWebhook.UpdateTlsSecretValues(tlsSecretValues)
 	mutatedValidatingWebhook, _, err := c.applier.Apply(ctx, c.enforcerValidatingWebhook, agentSpec, applyOptions)
@@ -351,7 +755,7 @@ func (c *StateApplier) applyEnforcerWebhooks(ctx context.Context, agentSpec *cbc
 	}
 
 	mutatedMutatingWebhook := false
-	if agentSpec.Components.Basic.Enforcer.EnableEnforcementFeature != nil && *agentSpec.Components.Basic.Enforcer.EnableEnforcementFeature {
+	if isMutatingWebhookEnabled(agentSpec) {
 		c.enforcerMutatingWebhook.UpdateTlsSecretValues(tlsSecretValues)
 		mutatedMutatingWebhook, _, err = c.applier.Apply(ctx, c.enforcerMutatingWebhook, agentSpec, applyOptions)
 		if err != nil {
@@ -359,7 +763,7 @@ func (c *StateApplier) applyEnforcerWebhooks(ctx context.Context, agentSpec *cbc
 		}
 	} else {
 		// Note that if someone changes the flag in sequence false -> true -> false, we should delete the webhook again
-		deletedWebhookDueToDisabledFlag, deleteErr := c.deleteEnforcerWebhook(ctx, agentSpec, c.enforcerMutatingWebhook)
+		deletedWebhookDueToDisabledFlag, deleteErr := c.deleteEnforcerWebhook(ctx, agent, c.enforcerMutatingWebhook)
 		if deleteErr != nil {
 			return false, deleteErr
 		}
@@ -370,15 +774,15 @@ func (c *StateApplier) applyEnforcerWebhooks(ctx context.Context, agentSpec *cbc
 	return mutatedValidatingWebhook || mutatedMutatingWebhook, nil
 }
 
-func (c *StateApplier) deleteAllEnforcerWebhooks(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec) (bool, error) {
-	return c.deleteEnforcerWebhook(ctx, agentSpec, c.enforcerValidatingWebhook, c.enforcerMutatingWebhook)
+func (c *StateApplier) deleteAllEnforcerWebhooks(ctx context.Context, agent *cbcontainersv1.CBContainersAgent) (bool, error) {
+	return c.deleteEnforcerWebhook(ctx, agent, c.enforcerValidatingWebhook, c.enforcerMutatingWebhook)
 }
 
-func (c *StateApplier) deleteEnforcerWebhook(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec, webhooks ...agent_applyment.AgentComponentBuilder) (bool, error) {
+func (c *StateApplier) deleteEnforcerWebhook(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, webhooks ...agent_applyment.AgentComponentBuilder) (bool, error) {
 	deletedAnything := false
 
 	for _, webhook := range webhooks {
-		if deleted, deleteErr := c.applier.Delete(ctx, webhook, agentSpec); deleteErr != nil {
+		if deleted, deleteErr := c.deleteComponent(ctx, agent, webhook); deleteErr != nil {
 			return false, deleteErr
 		} else if deleted {
 			c.log.Info("Deleted webhook", "webhook-name", webhook.NamespacedName())
@@ -388,3 +792,241 @@ func (c *StateApplier) deleteEnforcerWebhook(ctx context.Context, agentSpec *cbc
 
 	return deletedAnything, nil
 }
+
+// applyWorkload applies the workload of a component and reports its state in the agent status.
+// The workload of a paused component is only read, so the changes that were made to it by hand are kept.
+func (c *StateApplier) applyWorkload(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, builder agent_applyment.AgentComponentBuilder, applyOptions *applymentOptions.ApplyOptions) (bool, client.Object, error) {
+	mutated := false
+	var k8sObject client.Object
+	var err error
+
+	var patchErr error
+	if isWorkloadPaused(&agent.Spec, builder.NamespacedName().Name) {
+		c.log.Info("Skipping paused workload", "name", builder.NamespacedName())
+		k8sObject, err = c.readWorkload(ctx, builder)
+	} else if patchErr = c.validatePodTemplatePatch(agent, builder); patchErr != nil {
+		c.log.Error(patchErr, "Skipping workload with an invalid pod template patch", "name", builder.NamespacedName())
+		k8sObject, err = c.readWorkload(ctx, builder)
+	} else if err = c.updateConfigChecksum(ctx, agent, builder); err == nil {
+		mutated, k8sObject, err = c.applier.Apply(ctx, builder, &agent.Spec, applyOptions)
+	}
+	if err != nil {
+		return false, nil, err
+	}
+
+	if err := c.reportWorkload(agent, builder, k8sObject); err != nil {
+		return false, nil, err
+	}
+	name := builder.NamespacedName().Name
+	status.SetComponentPodTemplatePatchCondition(&agent.Status, name, podTemplatePatch(&agent.Spec, name) != nil, patchErr, agent.Generation)
+
+	return mutated, k8sObject, nil
+}
+
+// validatePodTemplatePatch builds the workload from an empty object, the same way it is built when it is created, and
+// returns the error of its pod template patch when it can't be applied. The workload isn't applied then, so the live
+// workload keeps running as it is until the patch is fixed.
+func (c *StateApplier) validatePodTemplatePatch(agent *cbcontainersv1.CBContainersAgent, builder agent_applyment.AgentComponentBuilder) error {
+	if podTemplatePatch(&agent.Spec, builder.NamespacedName().Name) == nil {
+		return nil
+	}
+
+	var patchErr *components.PodTemplatePatchError
+	if err := builder.MutateK8sObject(builder.EmptyK8sObject(), &agent.Spec); errors.As(err, &patchErr) {
+		return patchErr
+	}
+
+	return nil
+}
+
+// readWorkload reads the live workload of a component. A missing workload is returned as an empty object.
+func (c *StateApplier) readWorkload(ctx context.Context, builder agent_applyment.AgentComponentBuilder) (client.Object, error) {
+	k8sObject := builder.EmptyK8sObject()
+	if err := c.apiReader.Get(ctx, builder.NamespacedName(), k8sObject); err != nil && !k8sErrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed reading workload `%v`: %w", builder.NamespacedName(), err)
+	}
+
+	return k8sObject, nil
+}
+
+func (c *StateApplier) reportWorkload(agent *cbcontainersv1.CBContainersAgent, builder agent_applyment.AgentComponentBuilder, k8sObject client.Object) error {
+	name := builder.NamespacedName().Name
+	if err := status.SetComponentStatus(&agent.Status, name, k8sObject, agent.Generation); err != nil {
+		return err
+	}
+	status.SetComponentPaused(&agent.Status, name, isWorkloadPaused(&agent.Spec, name), agent.Generation)
+
+	return nil
+}
+
+// applyAutoscaler applies the HorizontalPodAutoscaler of a deployment while its autoscaling is enabled, and deletes it otherwise.
+func (c *StateApplier) applyAutoscaler(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, autoscaler *components.HorizontalPodAutoscalerK8sObject, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
+	if common.IsDisabled(autoscaler.AutoscalingSpec(&agent.Spec).Enabled) {
+		return c.deleteAutoscaler(ctx, agent, autoscaler)
+	}
+
+	if !c.capabilitiesProvider.Capabilities().HasGroupVersion(capabilities.AutoscalingV2) {
+		return false, fmt.Errorf("the autoscaling of the %v deployment requires the %v API, which the cluster doesn't serve", autoscaler.DeploymentName, capabilities.AutoscalingV2)
+	}
+
+	mutated, _, err := c.applier.Apply(ctx, autoscaler, &agent.Spec, applyOptions)
+	if err != nil {
+		return false, err
+	}
+	c.log.Info("Applied horizontal pod autoscaler", "Deployment", autoscaler.DeploymentName, "Mutated", mutated)
+
+	return mutated, nil
+}
+
+// deleteAutoscaler deletes the HorizontalPodAutoscaler of a deployment. When the cluster doesn't serve the
+// autoscaling/v2 API, there is nothing to delete.
+func (c *StateApplier) deleteAutoscaler(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, autoscaler *components.HorizontalPodAutoscalerK8sObject) (bool, error) {
+	if !c.capabilitiesProvider.Capabilities().HasGroupVersion(capabilities.AutoscalingV2) {
+		return false, nil
+	}
+
+	return c.deleteComponent(ctx, agent, autoscaler)
+}
+
+// applyDisruptionBudget applies the PodDisruptionBudget of a deployment while it is required, and deletes it otherwise.
+// When the cluster doesn't serve the policy/v1 API, the deployment is left without a PodDisruptionBudget.
+func (c *StateApplier) applyDisruptionBudget(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, disruptionBudget *components.PodDisruptionBudgetK8sObject, applyOptions *applymentOptions.ApplyOptions) (bool, error) {
+	if !c.capabilitiesProvider.Capabilities().HasGroupVersion(capabilities.PolicyV1) {
+		return false, nil
+	}
+
+	if !disruptionBudget.IsRequired(&agent.Spec) {
+		return c.deleteComponent(ctx, agent, disruptionBudget)
+	}
+
+	mutated, _, err := c.applier.Apply(ctx, disruptionBudget, &agent.Spec, applyOptions)
+	if err != nil {
+		return false, err
+	}
+	c.log.Info("Applied pod disruption budget", "Deployment", disruptionBudget.DeploymentName, "Mutated", mutated)
+
+	return mutated, nil
+}
+
+// deleteDisruptionBudget deletes the PodDisruptionBudget of a deployment. When the cluster doesn't serve the policy/v1
+// API, there is nothing to delete.
+func (c *StateApplier) deleteDisruptionBudget(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, disruptionBudget *components.PodDisruptionBudgetK8sObject) (bool, error) {
+	if !c.capabilitiesProvider.Capabilities().HasGroupVersion(capabilities.PolicyV1) {
+		return false, nil
+	}
+
+	return c.deleteComponent(ctx, agent, disruptionBudget)
+}
+
+func (c *StateApplier) deleteComponent(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, builder agent_applyment.AgentComponentBuilder) (bool, error) {
+	deleted, err := c.applier.Delete(ctx, builder, &agent.Spec)
+	if err != nil {
+		return false, err
+	}
+
+	if deleted {
+		c.eventRecorder.Eventf(agent, coreV1.EventTypeNormal, events.ReasonComponentDeleted, "Deleted %v %v", kindOf(builder.EmptyK8sObject()), builder.NamespacedName().Name)
+	}
+
+	return deleted, nil
+}
+
+func isMutatingWebhookEnabled(agentSpec *cbcontainersv1.CBContainersAgentSpec) bool {
+	return agentSpec.Components.Basic.Enforcer.EnableEnforcementFeature != nil && *agentSpec.Components.Basic.Enforcer.EnableEnforcementFeature
+}
+
+func isResolverEnabled(agentSpec *cbcontainersv1.CBContainersAgentSpec) bool {
+	return common.IsEnabled(agentSpec.Components.RuntimeProtection.Enabled)
+}
+
+func isImageScanningReporterEnabled(agentSpec *cbcontainersv1.CBContainersAgentSpec) bool {
+	return common.IsEnabled(agentSpec.Components.ClusterScanning.Enabled)
+}
+
+func isCndrEnabled(agentSpec *cbcontainersv1.CBContainersAgentSpec) bool {
+	return agentSpec.Components.Cndr != nil && common.IsEnabled(agentSpec.Components.Cndr.Enabled)
+}
+
+// isComponentsDaemonSetEnabled returns true if any of the components that run in the daemon set is enabled.
+func isComponentsDaemonSetEnabled(agentSpec *cbcontainersv1.CBContainersAgentSpec) bool {
+	return common.IsEnabled(agentSpec.Components.ClusterScanning.Enabled) ||
+		common.IsEnabled(agentSpec.Components.RuntimeProtection.Enabled) ||
+		isCndrEnabled(agentSpec)
+}
+
+// isWorkloadPaused returns true if the component that runs in the given workload is paused.
+// The components daemon set is paused when any of the components that run in it is paused.
+func isWorkloadPaused(agentSpec *cbcontainersv1.CBContainersAgentSpec, workloadName string) bool {
+	switch workloadName {
+	case components.MonitorName:
+		return common.IsEnabled(agentSpec.Components.Basic.Monitor.Paused)
+	case components.EnforcerName:
+		return common.IsEnabled(agentSpec.Components.Basic.Enforcer.Paused)
+	case components.StateReporterName:
+		return common.IsEnabled(agentSpec.Components.Basic.StateReporter.Paused)
+	case components.ResolverName:
+		return common.IsEnabled(agentSpec.Components.RuntimeProtection.Resolver.Paused)
+	case components.ImageScanningReporterName:
+		return common.IsEnabled(agentSpec.Components.ClusterScanning.ImageScanningReporter.Paused)
+	case components.DaemonSetName:
+		return common.IsEnabled(agentSpec.Components.RuntimeProtection.Sensor.Paused) ||
+			common.IsEnabled(agentSpec.Components.ClusterScanning.ClusterScannerAgent.Paused) ||
+			(agentSpec.Components.Cndr != nil && common.IsEnabled(agentSpec.Components.Cndr.Sensor.Paused))
+	}
+
+	return false
+}
+
+// podTemplatePatch returns the pod template patch of a workload, or nil when it has none.
+func podTemplatePatch(agentSpec *cbcontainersv1.CBContainersAgentSpec, workloadName string) *runtime.RawExtension {
+	var patch *runtime.RawExtension
+	switch workloadName {
+	case components.MonitorName:
+		patch = agentSpec.Components.Basic.Monitor.PodTemplatePatch
+	case components.EnforcerName:
+		patch = agentSpec.Components.Basic.Enforcer.PodTemplatePatch
+	case components.StateReporterName:
+		patch = agentSpec.Components.Basic.StateReporter.PodTemplatePatch
+	case components.ResolverName:
+		patch = agentSpec.Components.RuntimeProtection.Resolver.PodTemplatePatch
+	case components.ImageScanningReporterName:
+		patch = agentSpec.Components.ClusterScanning.ImageScanningReporter.PodTemplatePatch
+	case components.DaemonSetName:
+		patch = agentSpec.Components.RuntimeProtection.Sensor.PodTemplatePatch
+	}
+
+	if patch == nil || len(patch.Raw) == 0 {
+		return nil
+	}
+	return patch
+}
+
+// kindOf returns the kind of typed k8s objects, which usually don't have their TypeMeta populated.
+func kindOf(k8sObject interface{}) string {
+	// Unstructured objects, e.g. of optional CRDs, are known only by the kind they hold
+	if runtimeObject, ok := k8sObject.(runtime.Object); ok {
+		if kind := runtimeObject.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+			return kind
+		}
+	}
+
+	return reflect.Indirect(reflect.ValueOf(k8sObject)).Type().Name()
+}
+
+func nameOf(k8sObject metav1.Object) string {
+	if k8sObject.GetNamespace() == "" {
+		return k8sObject.GetName()
+	}
+
+	return fmt.Sprintf("%v/%v", k8sObject.GetNamespace(), k8sObject.GetName())
+}
+
+// discardingEventRecorder is used while planning and rendering, as no component is really created, updated or deleted.
+type discardingEventRecorder struct{}
+
+func (discardingEventRecorder) Event(runtime.Object, string, string, string) {}
+
+func (discardingEventRecorder) Eventf(runtime.Object, string, string, string, ...interface{}) {}
+
+func (discardingEventRecorder) AnnotatedEventf(runtime.Object, map[string]string, string, string, string, ...interface{}) {
+}
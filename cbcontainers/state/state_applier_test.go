@@ -2,17 +2,39 @@ package state_test
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/events"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/metrics"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/agent_applyment"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/capabilities"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/mocks"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/status"
 	admissionsV1 "k8s.io/api/admissionregistration/v1"
 	admissionsV1Beta1 "k8s.io/api/admissionregistration/v1beta1"
 	appsV1 "k8s.io/api/apps/v1"
+	autoscalingV2 "k8s.io/api/autoscaling/v2"
+	batchV1 "k8s.io/api/batch/v1"
+	policyV1 "k8s.io/api/policy/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	logrTesting "github.com/go-logr/logr/testing"
@@ -23,6 +45,8 @@ import (
 	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/test_utils"
 	testUtilsMocks "github.com/vmware/cbcontainers-operator/cbcontainers/test_utils/mocks"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/utils/certificates"
+	"github.com/vmware/cbcontainers-operator/controllers"
 	coreV1 "k8s.io/api/core/v1"
 	schedulingV1 "k8s.io/api/scheduling/v1"
 	schedulingV1alpha1 "k8s.io/api/scheduling/v1alpha1"
@@ -30,9 +54,9 @@ import (
 )
 
 const (
-	DefaultKubeletVersion = "v1.20.2"
+	DefaultKubernetesVersion = "v1.20.2"
 
-	NumberOfExpectedAppliedObjects = 12
+	NumberOfExpectedAppliedObjects = 13
 )
 
 // namespacedTestCases is an array of test cases with different namespace names.
@@ -53,7 +77,8 @@ var namespacedTestCases = []struct {
 type AppliedK8sObjectsChanger func(K8sObjectDetails, client.Object)
 
 var (
-	trueRef bool = true
+	trueRef  bool = true
+	falseRef bool = false
 
 	Account                    = test_utils.RandomString()
 	Cluster                    = test_utils.RandomString()
@@ -127,10 +152,13 @@ func resolverDeploymentDetails(namespace string) K8sObjectDetails {
 
 type StateApplierTestMocks struct {
 	client              *testUtilsMocks.MockClient
+	apiReader           *testUtilsMocks.MockReader
+	nodesReader         *testUtilsMocks.MockReader
 	secretValuesCreator *mocks.MockTlsSecretsValuesCreator
 	componentApplier    *mocks.MockAgentComponentApplier
 	agentSpec           *cbcontainersv1.CBContainersAgentSpec
-	kubeletVersion      string
+	agentStatus         *cbcontainersv1.CBContainersAgentStatus
+	eventRecorder       *record.FakeRecorder
 }
 
 type StateApplierTestSetup func(*StateApplierTestMocks)
@@ -145,7 +173,7 @@ func testStateApplier(t *testing.T, setup StateApplierTestSetup, k8sVersion, nam
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	agentSpec := &cbcontainersv1.CBContainersAgentSpec{
+	agent := &cbcontainersv1.CBContainersAgent{Spec: cbcontainersv1.CBContainersAgentSpec{
 		Account:     Account,
 		ClusterName: Cluster,
 		Gateways: cbcontainersv1.CBContainersGatewaysSpec{
@@ -166,31 +194,86 @@ func testStateApplier(t *testing.T, setup StateApplierTestSetup, k8sVersion, nam
 			},
 		},
 		Components: cbcontainersv1.CBContainersComponentsSpec{
+			Basic: cbcontainersv1.CBContainersBasicSpec{
+				Enforcer: cbcontainersv1.CBContainersEnforcerSpec{
+					EnableEnforcementFeature: &falseRef,
+				},
+			},
 			RuntimeProtection: cbcontainersv1.CBContainersRuntimeProtectionSpec{
 				Enabled: &trueRef,
 			},
+			ClusterScanning: cbcontainersv1.CBContainersClusterScanningSpec{
+				Enabled: &falseRef,
+			},
 			Settings: cbcontainersv1.CBContainersComponentsSettings{
 				CreateDefaultImagePullSecrets: &trueRef,
 			},
 		},
-	}
+	}}
+
+	// The workloads are built to find the ConfigMaps and Secrets that they consume, so the agent is defaulted as the
+	// controller defaults it. The components that aren't enabled by the test agent are kept disabled.
+	require.NoError(t, controllers.SetAgentDefaults(&agent.Spec))
 
 	if k8sVersion == "" {
-		k8sVersion = DefaultKubeletVersion
+		k8sVersion = DefaultKubernetesVersion
 	}
 
 	mockObjects := &StateApplierTestMocks{
 		client:              testUtilsMocks.NewMockClient(ctrl),
+		apiReader:           testUtilsMocks.NewMockReader(ctrl),
+		nodesReader:         testUtilsMocks.NewMockReader(ctrl),
 		secretValuesCreator: mocks.NewMockTlsSecretsValuesCreator(ctrl),
 		componentApplier:    mocks.NewMockAgentComponentApplier(ctrl),
-		agentSpec:           agentSpec,
-		kubeletVersion:      k8sVersion,
+		agentSpec:           &agent.Spec,
+		agentStatus:         &agent.Status,
+		eventRecorder:       record.NewFakeRecorder(100),
 	}
 
 	setup(mockObjects)
+	// Unless a test expects otherwise, no pod runs the CNDR sensor, so there are no nodes to clean up
+	mockObjects.apiReader.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&coreV1.PodList{}), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	// Unless a test expects otherwise, there are no nodes
+	mockObjects.nodesReader.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&coreV1.NodeList{})).Return(nil).AnyTimes()
+	// Unless a test expects otherwise, the ConfigMaps and Secrets that the workloads consume are empty
+	mockObjects.apiReader.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&coreV1.ConfigMap{})).Return(nil).AnyTimes()
+	mockObjects.apiReader.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&coreV1.Secret{})).Return(nil).AnyTimes()
+
+	stateApplier := state.NewStateApplier(mockObjects.apiReader, mockObjects.nodesReader, mockObjects.componentApplier, capabilitiesForVersion(t, k8sVersion), namespace, clusterID, mockObjects.secretValuesCreator, mockObjects.eventRecorder, logrTesting.NewTestLogger(t))
+	return stateApplier.ApplyDesiredState(context.Background(), agent, &models.RegistrySecretValues{}, nil)
+}
+
+var (
+	testTlsSecretValues     models.TlsSecretValues
+	testTlsSecretValuesOnce sync.Once
+)
 
-	stateApplier := state.NewStateApplier(testUtilsMocks.NewMockReader(ctrl), mockObjects.componentApplier, k8sVersion, namespace, clusterID, mockObjects.secretValuesCreator, logrTesting.NewTestLogger(t))
-	return stateApplier.ApplyDesiredState(context.Background(), agentSpec, &models.RegistrySecretValues{}, nil)
+// validTlsSecretValues returns valid enforcer certificates, which are created once as creating them is slow.
+func validTlsSecretValues(t *testing.T) models.TlsSecretValues {
+	testTlsSecretValuesOnce.Do(func() {
+		var err error
+		testTlsSecretValues, err = certificates.NewCertificateCreator().CreateTlsSecretsValues(types.NamespacedName{Name: components.EnforcerName, Namespace: commonState.DataPlaneNamespaceName})
+		require.NoError(t, err)
+	})
+
+	return testTlsSecretValues
+}
+
+// appliedK8sObject returns the object that applying the given builder results in.
+// The enforcer TLS secret holds valid certificates, so they aren't renewed.
+func appliedK8sObject(t *testing.T, builder agent_applyment.AgentComponentBuilder) client.Object {
+	k8sObject := builder.EmptyK8sObject()
+	if secret, ok := k8sObject.(*coreV1.Secret); ok && builder.NamespacedName().Name == components.EnforcerTlsName {
+		secret.Data = validTlsSecretValues(t).ToDataMap()
+	}
+
+	return k8sObject
+}
+
+func capabilitiesForVersion(t *testing.T, k8sVersion string) capabilities.Provider {
+	apiCapabilities, err := capabilities.ForVersion(k8sVersion)
+	require.NoError(t, err)
+	return capabilities.NewStaticProvider(apiCapabilities)
 }
 
 func getAppliedAndDeletedObjects(t *testing.T, k8sVersion, namespace string, setup StateApplierTestSetup, appliedK8sObjectsChangers ...AppliedK8sObjectsChanger) ([]K8sObjectDetails, []K8sObjectDetails, error) {
@@ -204,7 +287,7 @@ func getAppliedAndDeletedObjects(t *testing.T, k8sVersion, namespace string, set
 		mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.Any(), mocks.agentSpec, gomock.Any()).
 			DoAndReturn(func(ctx context.Context, obj agent_applyment.AgentComponentBuilder, cr *cbcontainersv1.CBContainersAgentSpec, options ...*options.ApplyOptions) (bool, client.Object, error) {
 				namespacedName := obj.NamespacedName()
-				k8sObject := obj.EmptyK8sObject()
+				k8sObject := appliedK8sObject(t, obj)
 				objType := reflect.TypeOf(k8sObject)
 				objectDetails := K8sObjectDetails{Namespace: namespacedName.Namespace, Name: namespacedName.Name, ObjectType: objType}
 
@@ -218,7 +301,7 @@ func getAppliedAndDeletedObjects(t *testing.T, k8sVersion, namespace string, set
 			}).AnyTimes()
 
 		mocks.componentApplier.EXPECT().Delete(gomock.Any(), gomock.Any(), mocks.agentSpec).
-			DoAndReturn(func(ctx context.Context, obj agent_applyment.AgentComponentBuilder, cr *cbcontainersv1.CBContainersAgentSpec) (bool, error) {
+			DoAndReturn(func(ctx context.Context, obj agent_applyment.AgentComponentBuilder, cr *cbcontainersv1.CBContainersAgentSpec, _ ...client.DeleteOption) (bool, error) {
 				namespacedName := obj.NamespacedName()
 				objType := reflect.TypeOf(obj.EmptyK8sObject())
 				deletedObjects = append(deletedObjects, K8sObjectDetails{Namespace: namespacedName.Namespace, Name: namespacedName.Name, ObjectType: objType})
@@ -315,7 +398,7 @@ func TestResourcesAreApplied(t *testing.T) {
 				}
 
 				t.Run("With K8s version v1.14 or higher, should use `schedulingV1`", func(t *testing.T) {
-					testPriorityClassIsApplied(t, reflect.TypeOf(&schedulingV1.PriorityClass{}), DefaultKubeletVersion)
+					testPriorityClassIsApplied(t, reflect.TypeOf(&schedulingV1.PriorityClass{}), DefaultKubernetesVersion)
 				})
 
 				t.Run("With K8s version lower then v1.14 but higher or equal to v1.11, should use `schedulingV1beta1`", func(t *testing.T) {
@@ -415,3 +498,1133 @@ func TestEnforcerWebhooksAreDeleted(t *testing.T) {
 		})
 	}
 }
+
+func TestComponentsStatusIsReported(t *testing.T) {
+	for _, testCase := range namespacedTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var agentStatus *cbcontainersv1.CBContainersAgentStatus
+			_, _, err := getAppliedAndDeletedObjects(t, "", testCase.namespace, func(mocks *StateApplierTestMocks) {
+				agentStatus = mocks.agentStatus
+			}, MutateDeploymentsToBeWithReadyReplica(enforcerDeploymentDetails(testCase.namespace)))
+			require.NoError(t, err)
+
+			var reportedComponents []string
+			for _, component := range agentStatus.Components {
+				reportedComponents = append(reportedComponents, component.Name)
+			}
+			require.ElementsMatch(t, []string{
+				components.MonitorName,
+				components.EnforcerName,
+				components.StateReporterName,
+				components.ResolverName,
+				components.DaemonSetName,
+			}, reportedComponents)
+		})
+	}
+
+	t.Run("With disabled components, their status should be removed", func(t *testing.T) {
+		var agentStatus *cbcontainersv1.CBContainersAgentStatus
+		falseRef := false
+		_, _, err := getAppliedAndDeletedObjects(t, "", commonState.DataPlaneNamespaceName, func(mocks *StateApplierTestMocks) {
+			mocks.agentSpec.Components.RuntimeProtection.Enabled = &falseRef
+			mocks.agentSpec.Components.ClusterScanning.Enabled = &falseRef
+			mocks.agentStatus.Components = []cbcontainersv1.CBContainersComponentStatus{
+				{Name: components.ResolverName},
+				{Name: components.ImageScanningReporterName},
+				{Name: components.DaemonSetName},
+			}
+			agentStatus = mocks.agentStatus
+		})
+		require.NoError(t, err)
+
+		for _, component := range agentStatus.Components {
+			require.NotContains(t, []string{components.ResolverName, components.ImageScanningReporterName, components.DaemonSetName}, component.Name)
+		}
+	})
+}
+
+func TestEventsAreRecorded(t *testing.T) {
+	recordedEvents := func(eventRecorder *record.FakeRecorder) []string {
+		var recorded []string
+		for len(eventRecorder.Events) > 0 {
+			recorded = append(recorded, <-eventRecorder.Events)
+		}
+		return recorded
+	}
+
+	t.Run("With enforcer without ready replicas, should record webhooks removal", func(t *testing.T) {
+		var eventRecorder *record.FakeRecorder
+		_, _, err := getAppliedAndDeletedObjects(t, "", commonState.DataPlaneNamespaceName, func(mocks *StateApplierTestMocks) {
+			eventRecorder = mocks.eventRecorder
+		})
+		require.NoError(t, err)
+
+		recorded := recordedEvents(eventRecorder)
+		require.Contains(t, recorded, fmt.Sprintf("%v %v Removed enforcer webhooks because the enforcer deployment has no ready replicas", coreV1.EventTypeWarning, events.ReasonWebhooksRemoved))
+		require.Contains(t, strings.Join(recorded, "\n"), events.ReasonComponentDeleted)
+	})
+
+	t.Run("With enforcer with ready replicas, should not record webhooks removal", func(t *testing.T) {
+		var eventRecorder *record.FakeRecorder
+		_, _, err := getAppliedAndDeletedObjects(t, "", commonState.DataPlaneNamespaceName, func(mocks *StateApplierTestMocks) {
+			eventRecorder = mocks.eventRecorder
+		}, MutateDeploymentsToBeWithReadyReplica(enforcerDeploymentDetails(commonState.DataPlaneNamespaceName), resolverDeploymentDetails(commonState.DataPlaneNamespaceName)))
+		require.NoError(t, err)
+
+		require.NotContains(t, strings.Join(recordedEvents(eventRecorder), "\n"), events.ReasonWebhooksRemoved)
+	})
+}
+
+func TestPausedWorkloadsAreNotApplied(t *testing.T) {
+	var agentStatus *cbcontainersv1.CBContainersAgentStatus
+	appliedObjects, _, err := getAppliedAndDeletedObjects(t, "", commonState.DataPlaneNamespaceName, func(mocks *StateApplierTestMocks) {
+		mocks.agentSpec.Components.Basic.Enforcer.Paused = &trueRef
+		mocks.agentSpec.Components.RuntimeProtection.Sensor.Paused = &trueRef
+		agentStatus = mocks.agentStatus
+
+		mocks.apiReader.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: components.EnforcerName, Namespace: commonState.DataPlaneNamespaceName}, gomock.AssignableToTypeOf(&appsV1.Deployment{})).
+			DoAndReturn(func(_ context.Context, _ types.NamespacedName, deployment *appsV1.Deployment, _ ...client.GetOption) error {
+				deployment.Status.ReadyReplicas = 1
+				return nil
+			})
+		mocks.apiReader.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: components.DaemonSetName, Namespace: commonState.DataPlaneNamespaceName}, gomock.AssignableToTypeOf(&appsV1.DaemonSet{})).
+			Return(k8sErrors.NewNotFound(schema.GroupResource{}, components.DaemonSetName))
+	})
+	require.NoError(t, err)
+
+	require.NotContains(t, appliedObjects, enforcerDeploymentDetails(commonState.DataPlaneNamespaceName))
+	require.NotContains(t, appliedObjects, K8sObjectDetails{Namespace: commonState.DataPlaneNamespaceName, Name: components.DaemonSetName, ObjectType: reflect.TypeOf(&appsV1.DaemonSet{})})
+	// The webhooks follow the state of the live enforcer deployment
+	require.Contains(t, appliedObjects, EnforcerValidatingWebhookDetails)
+
+	for _, component := range agentStatus.Components {
+		paused := component.Name == components.EnforcerName || component.Name == components.DaemonSetName
+		require.Equal(t, paused, meta.IsStatusConditionTrue(component.Conditions, cbcontainersv1.ConditionTypePaused), component.Name)
+	}
+}
+
+func TestWorkloadsWithInvalidPodTemplatePatchAreNotApplied(t *testing.T) {
+	var agentStatus *cbcontainersv1.CBContainersAgentStatus
+	appliedObjects, _, err := getAppliedAndDeletedObjects(t, "", commonState.DataPlaneNamespaceName, func(mocks *StateApplierTestMocks) {
+		mocks.agentSpec.Components.Basic.StateReporter.PodTemplatePatch = &runtime.RawExtension{Raw: []byte(`{"spec": {"hostPID": true}}`)}
+		agentStatus = mocks.agentStatus
+
+		mocks.apiReader.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: components.StateReporterName, Namespace: commonState.DataPlaneNamespaceName}, gomock.AssignableToTypeOf(&appsV1.Deployment{})).
+			Return(nil)
+	})
+	require.NoError(t, err)
+
+	require.NotContains(t, appliedObjects, K8sObjectDetails{Namespace: commonState.DataPlaneNamespaceName, Name: components.StateReporterName, ObjectType: reflect.TypeOf(&appsV1.Deployment{})})
+	var invalidConditions []metav1.Condition
+	for _, component := range agentStatus.Components {
+		if invalidCondition := meta.FindStatusCondition(component.Conditions, cbcontainersv1.ConditionTypePodTemplatePatchInvalid); invalidCondition != nil {
+			require.Equal(t, components.StateReporterName, component.Name)
+			invalidConditions = append(invalidConditions, *invalidCondition)
+		}
+	}
+	require.Len(t, invalidConditions, 1)
+	require.Equal(t, metav1.ConditionTrue, invalidConditions[0].Status)
+	require.Contains(t, invalidConditions[0].Message, "host namespaces")
+}
+
+func TestPatchedPodTemplatesAreRebuiltOnEveryReconcile(t *testing.T) {
+	var appliedDeployment, reappliedDeployment *appsV1.Deployment
+	_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+		mocks.agentSpec.Components.Basic.StateReporter.PodTemplatePatch = &runtime.RawExtension{Raw: []byte(`{"spec": {"hostAliases": [{"ip": "10.0.0.1", "hostnames": ["proxy.internal"]}]}}`)}
+		mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.StateReporterDeploymentK8sObject{}), mocks.agentSpec, gomock.Any()).
+			DoAndReturn(func(_ context.Context, builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec, _ ...*options.ApplyOptions) (bool, client.Object, error) {
+				appliedDeployment = &appsV1.Deployment{}
+				require.NoError(t, builder.MutateK8sObject(appliedDeployment, agentSpec))
+
+				// The live pod template was changed by hand since it was applied with the same patch
+				reappliedDeployment = appliedDeployment.DeepCopy()
+				reappliedDeployment.Spec.Template.Spec.Containers[0].Image = "changed"
+				reappliedDeployment.Spec.Template.Spec.HostAliases = nil
+				require.NoError(t, builder.MutateK8sObject(reappliedDeployment, agentSpec))
+				return false, reappliedDeployment, nil
+			})
+		expectComponentsApplied(t, mocks)
+	}, "", commonState.DataPlaneNamespaceName, "")
+	require.NoError(t, err)
+
+	require.Equal(t, appliedDeployment.Spec.Template, reappliedDeployment.Spec.Template)
+	require.Equal(t, []string{"proxy.internal"}, reappliedDeployment.Spec.Template.Spec.HostAliases[0].Hostnames)
+	require.Equal(t, appliedDeployment.Annotations[components.PodTemplateChecksumAnnotation], reappliedDeployment.Annotations[components.PodTemplateChecksumAnnotation])
+}
+
+func TestConsumedConfigIsReadOncePerReconcile(t *testing.T) {
+	configMapReads := 0
+	_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+		mocks.apiReader.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: commonState.DataPlaneConfigmapName, Namespace: commonState.DataPlaneNamespaceName}, gomock.AssignableToTypeOf(&coreV1.ConfigMap{})).
+			DoAndReturn(func(_ context.Context, _ types.NamespacedName, _ *coreV1.ConfigMap, _ ...client.GetOption) error {
+				configMapReads++
+				return nil
+			}).AnyTimes()
+		expectComponentsApplied(t, mocks)
+	}, "", commonState.DataPlaneNamespaceName, "")
+
+	require.NoError(t, err)
+	require.Equal(t, 1, configMapReads)
+}
+
+func TestWorkloadsAreRolledWhenTheirConfigChanges(t *testing.T) {
+	const extraEnvSecretName = "extra-env-secret"
+
+	// appliedConfigChecksums returns the config checksums of the monitor and the state reporter pods, when the state
+	// reporter consumes a Secret key through an extra env var
+	appliedConfigChecksums := func(t *testing.T, configMapData map[string]string, secretData map[string][]byte) map[string]string {
+		configChecksums := make(map[string]string)
+		_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			mocks.agentSpec.Components.Basic.StateReporter.ExtraEnv = []coreV1.EnvVar{{
+				Name: "EXTRA_TOKEN",
+				ValueFrom: &coreV1.EnvVarSource{SecretKeyRef: &coreV1.SecretKeySelector{
+					LocalObjectReference: coreV1.LocalObjectReference{Name: extraEnvSecretName},
+					Key:                  "token",
+				}},
+			}}
+
+			mocks.apiReader.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: commonState.DataPlaneConfigmapName, Namespace: commonState.DataPlaneNamespaceName}, gomock.AssignableToTypeOf(&coreV1.ConfigMap{})).
+				DoAndReturn(func(_ context.Context, _ types.NamespacedName, configMap *coreV1.ConfigMap, _ ...client.GetOption) error {
+					configMap.Data = configMapData
+					return nil
+				}).AnyTimes()
+			mocks.apiReader.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: extraEnvSecretName, Namespace: commonState.DataPlaneNamespaceName}, gomock.AssignableToTypeOf(&coreV1.Secret{})).
+				DoAndReturn(func(_ context.Context, _ types.NamespacedName, secret *coreV1.Secret, _ ...client.GetOption) error {
+					secret.Data = secretData
+					return nil
+				}).AnyTimes()
+
+			for _, workload := range []agent_applyment.AgentComponentBuilder{&components.MonitorDeploymentK8sObject{}, &components.StateReporterDeploymentK8sObject{}} {
+				mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(workload), mocks.agentSpec, gomock.Any()).
+					DoAndReturn(func(_ context.Context, builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec, _ ...*options.ApplyOptions) (bool, client.Object, error) {
+						deployment := &appsV1.Deployment{}
+						if err := builder.MutateK8sObject(deployment, agentSpec); err != nil {
+							return false, nil, err
+						}
+						configChecksums[builder.NamespacedName().Name] = deployment.Spec.Template.Annotations[components.ConfigChecksumAnnotation]
+						return true, deployment, nil
+					}).AnyTimes()
+			}
+			expectComponentsApplied(t, mocks)
+		}, "", commonState.DataPlaneNamespaceName, "")
+
+		require.NoError(t, err)
+		require.NotEmpty(t, configChecksums[components.MonitorName])
+		require.NotEmpty(t, configChecksums[components.StateReporterName])
+		return configChecksums
+	}
+
+	configMapData := map[string]string{commonState.DataPlaneConfigmapAccountKey: Account}
+	secretData := map[string][]byte{"token": []byte("token")}
+	configChecksums := appliedConfigChecksums(t, configMapData, secretData)
+
+	t.Run("When the config doesn't change, should not roll the workloads", func(t *testing.T) {
+		require.Equal(t, configChecksums, appliedConfigChecksums(t, configMapData, secretData))
+	})
+
+	t.Run("When a key that the workloads don't consume changes, should not roll the workloads", func(t *testing.T) {
+		changedConfigMapData := map[string]string{commonState.DataPlaneConfigmapAccountKey: Account, "unused": "value"}
+		require.Equal(t, configChecksums, appliedConfigChecksums(t, changedConfigMapData, secretData))
+	})
+
+	t.Run("When the dataplane ConfigMap changes, should roll all the workloads that consume it", func(t *testing.T) {
+		changedConfigChecksums := appliedConfigChecksums(t, map[string]string{commonState.DataPlaneConfigmapAccountKey: "other-account"}, secretData)
+		require.NotEqual(t, configChecksums[components.MonitorName], changedConfigChecksums[components.MonitorName])
+		require.NotEqual(t, configChecksums[components.StateReporterName], changedConfigChecksums[components.StateReporterName])
+	})
+
+	t.Run("When the Secret of an extra env var changes, should roll only the workload that consumes it", func(t *testing.T) {
+		changedConfigChecksums := appliedConfigChecksums(t, configMapData, map[string][]byte{"token": []byte("rotated-token")})
+		require.Equal(t, configChecksums[components.MonitorName], changedConfigChecksums[components.MonitorName])
+		require.NotEqual(t, configChecksums[components.StateReporterName], changedConfigChecksums[components.StateReporterName])
+	})
+}
+
+func TestReportStateDoesNotApply(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	apiReader := testUtilsMocks.NewMockReader(ctrl)
+	agent := &cbcontainersv1.CBContainersAgent{Spec: cbcontainersv1.CBContainersAgentSpec{Account: Account, ClusterName: Cluster}}
+
+	// The component applier mock has no expectations, so the test fails if anything is applied or deleted through it
+	stateApplier := state.NewStateApplier(apiReader, apiReader, mocks.NewMockAgentComponentApplier(ctrl), capabilitiesForVersion(t, DefaultKubernetesVersion), commonState.DataPlaneNamespaceName, "", mocks.NewMockTlsSecretsValuesCreator(ctrl), record.NewFakeRecorder(10), logrTesting.NewTestLogger(t))
+
+	var readWorkloads []string
+	apiReader.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&appsV1.Deployment{})).
+		DoAndReturn(func(_ context.Context, namespacedName types.NamespacedName, _ client.Object, _ ...client.GetOption) error {
+			readWorkloads = append(readWorkloads, namespacedName.Name)
+			return nil
+		}).AnyTimes()
+
+	require.NoError(t, stateApplier.ReportState(context.Background(), agent))
+	require.ElementsMatch(t, []string{components.MonitorName, components.EnforcerName, components.StateReporterName}, readWorkloads)
+	require.Len(t, agent.Status.Components, 3)
+}
+
+func sensorPod(nodeName string, containerNames ...string) coreV1.Pod {
+	pod := coreV1.Pod{Spec: coreV1.PodSpec{NodeName: nodeName}}
+	for _, containerName := range containerNames {
+		pod.Spec.Containers = append(pod.Spec.Containers, coreV1.Container{Name: containerName})
+	}
+	return pod
+}
+
+func expectDaemonSetPods(apiReader *testUtilsMocks.MockReader, pods ...coreV1.Pod) *gomock.Call {
+	return apiReader.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&coreV1.PodList{}), gomock.Any(), client.MatchingLabels{components.DaemonSetLabelKey: components.DaemonSetName}).
+		Do(func(_ context.Context, list *coreV1.PodList, _ ...client.ListOption) {
+			list.Items = pods
+		}).Return(nil)
+}
+
+func finishedNodeCleanupJob(failed bool) func(context.Context, agent_applyment.AgentComponentBuilder, *cbcontainersv1.CBContainersAgentSpec, ...*options.ApplyOptions) (bool, client.Object, error) {
+	return func(_ context.Context, builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec, _ ...*options.ApplyOptions) (bool, client.Object, error) {
+		job := &batchV1.Job{}
+		if err := builder.MutateK8sObject(job, agentSpec); err != nil {
+			return false, nil, err
+		}
+
+		job.Status.Succeeded = 1
+		if failed {
+			job.Status.Succeeded = 0
+			job.Status.Conditions = []batchV1.JobCondition{{Type: batchV1.JobFailed, Status: coreV1.ConditionTrue}}
+		}
+		return false, job, nil
+	}
+}
+
+// expectComponentsApplied expects the agent components to be applied as they are and nothing to be deleted.
+func expectComponentsApplied(t *testing.T, mocks *StateApplierTestMocks) {
+	mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.Any(), mocks.agentSpec, gomock.Any()).
+		DoAndReturn(func(_ context.Context, builder agent_applyment.AgentComponentBuilder, _ *cbcontainersv1.CBContainersAgentSpec, _ ...*options.ApplyOptions) (bool, client.Object, error) {
+			return false, appliedK8sObject(t, builder), nil
+		}).AnyTimes()
+	mocks.componentApplier.EXPECT().Delete(gomock.Any(), gomock.Any(), mocks.agentSpec).Return(false, nil).AnyTimes()
+}
+
+func TestCndrNodesAreCleanedUp(t *testing.T) {
+	expectNodeCleanupJobDeleted := func(mocks *StateApplierTestMocks) *gomock.Call {
+		return mocks.componentApplier.EXPECT().Delete(gomock.Any(), gomock.AssignableToTypeOf(&components.NodeCleanupJobK8sObject{}), mocks.agentSpec, client.PropagationPolicy(metav1.DeletePropagationBackground)).Return(true, nil)
+	}
+
+	t.Run("When CNDR is disabled, should mark the nodes that run the sensor and return before applying the daemon set, so they are persisted first", func(t *testing.T) {
+		var agentStatus *cbcontainersv1.CBContainersAgentStatus
+		appliedObjects, _, err := getAppliedAndDeletedObjects(t, "", commonState.DataPlaneNamespaceName, func(mocks *StateApplierTestMocks) {
+			agentStatus = mocks.agentStatus
+			expectDaemonSetPods(mocks.apiReader, sensorPod("node-b", components.CndrContainerName), sensorPod("node-a", components.CndrContainerName), sensorPod("node-c"))
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"node-a", "node-b"}, agentStatus.NodeCleanup.PendingNodes)
+		for _, appliedObject := range appliedObjects {
+			require.NotEqual(t, reflect.TypeOf(&batchV1.Job{}), appliedObject.ObjectType)
+			require.NotEqual(t, reflect.TypeOf(&appsV1.DaemonSet{}), appliedObject.ObjectType)
+		}
+	})
+
+	t.Run("When the marked nodes were persisted and applying the daemon set fails, should keep them pending", func(t *testing.T) {
+		var agentStatus *cbcontainersv1.CBContainersAgentStatus
+		applyErr := fmt.Errorf("apply error")
+		_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			agentStatus = mocks.agentStatus
+			mocks.agentStatus.NodeCleanup = &cbcontainersv1.CBContainersNodeCleanupStatus{PendingNodes: []string{"node-a", "node-b"}}
+			expectDaemonSetPods(mocks.apiReader, sensorPod("node-a", components.CndrContainerName), sensorPod("node-b", components.CndrContainerName))
+			mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.SensorDaemonSetK8sObject{}), mocks.agentSpec, gomock.Any()).Return(false, nil, applyErr)
+			expectComponentsApplied(t, mocks)
+		}, "", commonState.DataPlaneNamespaceName, "")
+
+		require.ErrorIs(t, err, applyErr)
+		require.Equal(t, []string{"node-a", "node-b"}, agentStatus.NodeCleanup.PendingNodes)
+	})
+
+	t.Run("When the sensor doesn't run on the pending nodes, should clean them up and delete the Jobs", func(t *testing.T) {
+		var agentStatus *cbcontainersv1.CBContainersAgentStatus
+		var eventRecorder *record.FakeRecorder
+		stateChanged, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			agentStatus, eventRecorder = mocks.agentStatus, mocks.eventRecorder
+			mocks.agentStatus.NodeCleanup = &cbcontainersv1.CBContainersNodeCleanupStatus{PendingNodes: []string{"node-a", "node-b"}}
+			expectDaemonSetPods(mocks.apiReader).AnyTimes()
+			mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.NodeCleanupJobK8sObject{}), mocks.agentSpec, gomock.Any()).
+				DoAndReturn(finishedNodeCleanupJob(false))
+			mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.NodeCleanupJobK8sObject{}), mocks.agentSpec, gomock.Any()).
+				DoAndReturn(finishedNodeCleanupJob(true))
+			expectNodeCleanupJobDeleted(mocks).Times(2)
+			expectComponentsApplied(t, mocks)
+		}, "", commonState.DataPlaneNamespaceName, "")
+
+		require.NoError(t, err)
+		require.False(t, stateChanged)
+		require.Empty(t, agentStatus.NodeCleanup.PendingNodes)
+		require.Equal(t, []string{"node-b"}, agentStatus.NodeCleanup.FailedNodes)
+		require.NotNil(t, agentStatus.NodeCleanup.CompletionTime)
+		require.Contains(t, <-eventRecorder.Events, events.ReasonNodeCleanupFailed)
+	})
+
+	t.Run("Should run the cleanup Jobs with the node cleanup image and pull secrets of the settings", func(t *testing.T) {
+		var job *batchV1.Job
+		_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			mocks.agentSpec.Components.Settings.NodeCleanupImage = cbcontainersv1.CBContainersImageSpec{Repository: "mirror/photon", Tag: "5.0", PullPolicy: coreV1.PullAlways, PullSecrets: []string{"mirror-secret"}}
+			mocks.agentStatus.NodeCleanup = &cbcontainersv1.CBContainersNodeCleanupStatus{PendingNodes: []string{"node-a"}}
+			expectDaemonSetPods(mocks.apiReader).AnyTimes()
+			mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.NodeCleanupJobK8sObject{}), mocks.agentSpec, gomock.Any()).
+				DoAndReturn(func(_ context.Context, builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec, _ ...*options.ApplyOptions) (bool, client.Object, error) {
+					job = &batchV1.Job{}
+					return true, job, builder.MutateK8sObject(job, agentSpec)
+				})
+			expectComponentsApplied(t, mocks)
+		}, "", commonState.DataPlaneNamespaceName, "")
+
+		require.NoError(t, err)
+		require.Equal(t, "mirror/photon:5.0", job.Spec.Template.Spec.Containers[0].Image)
+		require.Equal(t, coreV1.PullAlways, job.Spec.Template.Spec.Containers[0].ImagePullPolicy)
+		require.Contains(t, job.Spec.Template.Spec.ImagePullSecrets, coreV1.LocalObjectReference{Name: "mirror-secret"})
+		require.Contains(t, job.Spec.Template.Spec.ImagePullSecrets, coreV1.LocalObjectReference{Name: commonState.RegistrySecretName})
+	})
+
+	t.Run("When the cleanup Job is running, the node should stay pending", func(t *testing.T) {
+		var agentStatus *cbcontainersv1.CBContainersAgentStatus
+		stateChanged, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			agentStatus = mocks.agentStatus
+			mocks.agentStatus.NodeCleanup = &cbcontainersv1.CBContainersNodeCleanupStatus{PendingNodes: []string{"node-a"}}
+			expectDaemonSetPods(mocks.apiReader).AnyTimes()
+			mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.NodeCleanupJobK8sObject{}), mocks.agentSpec, gomock.Any()).
+				Return(true, &batchV1.Job{}, nil)
+			expectComponentsApplied(t, mocks)
+		}, "", commonState.DataPlaneNamespaceName, "")
+
+		require.NoError(t, err)
+		require.True(t, stateChanged)
+		require.Equal(t, []string{"node-a"}, agentStatus.NodeCleanup.PendingNodes)
+	})
+}
+
+func TestNodeCoverageIsReported(t *testing.T) {
+	readyCondition := coreV1.PodCondition{Type: coreV1.PodReady, Status: coreV1.ConditionTrue}
+	linuxNode := func(name, architecture string, taints ...coreV1.Taint) coreV1.Node {
+		return coreV1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{coreV1.LabelOSStable: "linux", coreV1.LabelArchStable: architecture}},
+			Spec:       coreV1.NodeSpec{Taints: taints},
+		}
+	}
+	nodes := []coreV1.Node{
+		linuxNode("covered", "amd64"),
+		linuxNode("gpu", "amd64", coreV1.Taint{Key: "nvidia.com/gpu", Value: "true", Effect: coreV1.TaintEffectNoSchedule}),
+		linuxNode("graviton", "arm64"),
+		linuxNode("crashing", "amd64"),
+		linuxNode("new", "amd64", coreV1.Taint{Key: coreV1.TaintNodeNotReady, Effect: coreV1.TaintEffectNoExecute}),
+	}
+	coveredPod := sensorPod("covered")
+	coveredPod.Status.Conditions = []coreV1.PodCondition{readyCondition}
+	crashingPod := sensorPod("crashing")
+	crashingPod.Status.ContainerStatuses = []coreV1.ContainerStatus{{Name: components.RuntimeContainerName, RestartCount: 5, State: coreV1.ContainerState{Waiting: &coreV1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}}}
+
+	var agentStatus *cbcontainersv1.CBContainersAgentStatus
+	var uncoveredNodes []models.NodeCoverage
+	_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+		agentStatus = mocks.agentStatus
+		mocks.nodesReader.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&coreV1.NodeList{})).
+			Do(func(_ context.Context, list *coreV1.NodeList, _ ...client.ListOption) {
+				list.Items = nodes
+			}).Return(nil).AnyTimes()
+		expectDaemonSetPods(mocks.apiReader, coveredPod, crashingPod).AnyTimes()
+		mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.SensorDaemonSetK8sObject{}), mocks.agentSpec, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ agent_applyment.AgentComponentBuilder, _ *cbcontainersv1.CBContainersAgentSpec, _ ...*options.ApplyOptions) (bool, client.Object, error) {
+				daemonSet := &appsV1.DaemonSet{}
+				commonState.NewNodeTermsBuilder(&daemonSet.Spec.Template.Spec, commonState.DefaultArchitectures).Build()
+				return false, daemonSet, nil
+			})
+		mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.NodeCoverageConfigMapK8sObject{}), mocks.agentSpec, gomock.Any()).
+			DoAndReturn(func(_ context.Context, builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec, _ ...*options.ApplyOptions) (bool, client.Object, error) {
+				configMap := &coreV1.ConfigMap{}
+				require.NoError(t, builder.MutateK8sObject(configMap, agentSpec))
+				require.NoError(t, json.Unmarshal([]byte(configMap.Data[components.NodeCoverageConfigMapKey]), &uncoveredNodes))
+				return true, configMap, nil
+			})
+		expectComponentsApplied(t, mocks)
+	}, "", commonState.DataPlaneNamespaceName, "")
+
+	require.NoError(t, err)
+	require.Equal(t, &cbcontainersv1.CBContainersNodeCoverageStatus{
+		Nodes:        5,
+		CoveredNodes: 1,
+		UncoveredNodes: map[string]int32{
+			string(models.NodeCoverageReasonCrashLoopBackOff):        1,
+			string(models.NodeCoverageReasonUntoleratedTaint):        1,
+			string(models.NodeCoverageReasonUnsupportedArchitecture): 1,
+			string(models.NodeCoverageReasonPodMissing):              1,
+		},
+	}, agentStatus.NodeCoverage)
+	require.Equal(t, int32(1), *agentStatus.NodesExcludedByArchitecture)
+
+	uncoveredNodeReasons := make(map[string]models.NodeCoverageReason)
+	for _, uncoveredNode := range uncoveredNodes {
+		require.NotEmpty(t, uncoveredNode.Message)
+		uncoveredNodeReasons[uncoveredNode.Node] = uncoveredNode.Reason
+	}
+	require.Equal(t, map[string]models.NodeCoverageReason{
+		"crashing": models.NodeCoverageReasonCrashLoopBackOff,
+		"gpu":      models.NodeCoverageReasonUntoleratedTaint,
+		"graviton": models.NodeCoverageReasonUnsupportedArchitecture,
+		// The daemon set controller tolerates the not-ready taint, so the pod wasn't created yet
+		"new": models.NodeCoverageReasonPodMissing,
+	}, uncoveredNodeReasons)
+}
+
+func TestDaemonSetsTolerationsAreDiscovered(t *testing.T) {
+	taintedNode := func(name string, taints ...coreV1.Taint) coreV1.Node {
+		return coreV1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: coreV1.NodeSpec{Taints: taints}}
+	}
+	nodes := []coreV1.Node{
+		taintedNode("gpu", coreV1.Taint{Key: "nvidia.com/gpu", Value: "true", Effect: coreV1.TaintEffectNoSchedule}),
+		taintedNode("infra", coreV1.Taint{Key: "dedicated", Value: "infra", Effect: coreV1.TaintEffectNoSchedule}, coreV1.Taint{Key: "spot", Effect: coreV1.TaintEffectPreferNoSchedule}),
+		taintedNode("control-plane", coreV1.Taint{Key: "node-role.kubernetes.io/control-plane", Effect: coreV1.TaintEffectNoSchedule}),
+		taintedNode("not-ready", coreV1.Taint{Key: coreV1.TaintNodeNotReady, Effect: coreV1.TaintEffectNoExecute}),
+		taintedNode("other-infra", coreV1.Taint{Key: "dedicated", Value: "infra", Effect: coreV1.TaintEffectNoSchedule}),
+	}
+	teamToleration := coreV1.Toleration{Key: "team", Operator: coreV1.TolerationOpExists}
+
+	testDiscovery := func(t *testing.T, discoverySpec cbcontainersv1.CBContainersTolerationsDiscoverySpec) []coreV1.Toleration {
+		var agentStatus *cbcontainersv1.CBContainersAgentStatus
+		_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			agentStatus = mocks.agentStatus
+			mocks.agentSpec.Components.Settings.DaemonSetsTolerations = []coreV1.Toleration{{Operator: coreV1.TolerationOpExists}, teamToleration}
+			mocks.agentSpec.Components.Settings.DaemonSetsTolerationsDiscovery = discoverySpec
+			mocks.nodesReader.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&coreV1.NodeList{})).
+				Do(func(_ context.Context, list *coreV1.NodeList, _ ...client.ListOption) {
+					list.Items = nodes
+				}).Return(nil).AnyTimes()
+			expectComponentsApplied(t, mocks)
+		}, "", commonState.DataPlaneNamespaceName, "")
+
+		require.NoError(t, err)
+		return agentStatus.DaemonSetsTolerations
+	}
+
+	t.Run("When the discovery is disabled, should use the tolerations of the settings", func(t *testing.T) {
+		require.Equal(t, []coreV1.Toleration{{Operator: coreV1.TolerationOpExists}, teamToleration}, testDiscovery(t, cbcontainersv1.CBContainersTolerationsDiscoverySpec{}))
+	})
+
+	t.Run("When the discovery is enabled, should tolerate the taints of the nodes except for the denied ones", func(t *testing.T) {
+		tolerations := testDiscovery(t, cbcontainersv1.CBContainersTolerationsDiscoverySpec{Enabled: &trueRef, DeniedTaintKeys: []string{"nvidia.com/gpu"}})
+		require.Equal(t, []coreV1.Toleration{
+			teamToleration,
+			{Key: "dedicated", Operator: coreV1.TolerationOpEqual, Value: "infra", Effect: coreV1.TaintEffectNoSchedule},
+			{Key: "node-role.kubernetes.io/control-plane", Operator: coreV1.TolerationOpExists, Effect: coreV1.TaintEffectNoSchedule},
+		}, tolerations)
+	})
+
+	t.Run("When the allowed taint keys are set, should only tolerate their taints", func(t *testing.T) {
+		tolerations := testDiscovery(t, cbcontainersv1.CBContainersTolerationsDiscoverySpec{Enabled: &trueRef, AllowedTaintKeys: []string{"dedicated"}})
+		require.Equal(t, []coreV1.Toleration{
+			teamToleration,
+			{Key: "dedicated", Operator: coreV1.TolerationOpEqual, Value: "infra", Effect: coreV1.TaintEffectNoSchedule},
+		}, tolerations)
+	})
+}
+
+func TestResolverIsScaledByTheNumberOfNodes(t *testing.T) {
+	cooldown := &metav1.Duration{Duration: 5 * time.Minute}
+	recentScaleTime := metav1.NewTime(time.Now().Add(-time.Minute))
+	pastScaleTime := metav1.NewTime(time.Now().Add(-time.Hour))
+
+	testScaling := func(t *testing.T, nodesCount int, updateResolver func(*cbcontainersv1.CBContainersRuntimeResolverSpec), lastScaling *cbcontainersv1.CBContainersReplicasScalingStatus) (*int32, *cbcontainersv1.CBContainersReplicasScalingStatus) {
+		var agentStatus *cbcontainersv1.CBContainersAgentStatus
+		var replicas *int32
+		_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			agentStatus = mocks.agentStatus
+			agentStatus.RuntimeResolverScaling = lastScaling
+			resolver := &mocks.agentSpec.Components.RuntimeProtection.Resolver
+			resolver.NodesToReplicasRatio = 5
+			resolver.ScaleDownCooldown = cooldown
+			resolver.Prometheus.Enabled = &falseRef
+			updateResolver(resolver)
+			mocks.nodesReader.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&coreV1.NodeList{})).
+				Do(func(_ context.Context, list *coreV1.NodeList, _ ...client.ListOption) {
+					list.Items = make([]coreV1.Node, nodesCount)
+				}).Return(nil).AnyTimes()
+			mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.ResolverDeploymentK8sObject{}), mocks.agentSpec, gomock.Any()).
+				DoAndReturn(func(_ context.Context, builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec, _ ...*options.ApplyOptions) (bool, client.Object, error) {
+					deployment := &appsV1.Deployment{}
+					require.NoError(t, builder.MutateK8sObject(deployment, agentSpec))
+					replicas = deployment.Spec.Replicas
+					return false, deployment, nil
+				})
+			expectComponentsApplied(t, mocks)
+		}, "", commonState.DataPlaneNamespaceName, "")
+
+		require.NoError(t, err)
+		return replicas, agentStatus.RuntimeResolverScaling
+	}
+
+	t.Run("Should derive the replicas count from the number of nodes", func(t *testing.T) {
+		replicas, scaling := testScaling(t, 12, func(*cbcontainersv1.CBContainersRuntimeResolverSpec) {}, nil)
+		require.Equal(t, int32(3), *replicas)
+		require.Equal(t, int32(3), scaling.Replicas)
+		require.Equal(t, int32(12), scaling.Nodes)
+	})
+
+	t.Run("Should bound the replicas count by the min and max replicas", func(t *testing.T) {
+		minReplicas, maxReplicas := int32(2), int32(4)
+		bound := func(resolver *cbcontainersv1.CBContainersRuntimeResolverSpec) {
+			resolver.MinReplicas = &minReplicas
+			resolver.MaxReplicas = &maxReplicas
+		}
+
+		replicas, _ := testScaling(t, 1, bound, nil)
+		require.Equal(t, minReplicas, *replicas)
+		replicas, _ = testScaling(t, 100, bound, nil)
+		require.Equal(t, maxReplicas, *replicas)
+	})
+
+	t.Run("Should scale up during the cooldown", func(t *testing.T) {
+		replicas, scaling := testScaling(t, 20, func(*cbcontainersv1.CBContainersRuntimeResolverSpec) {}, &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: 2, Nodes: 10, LastScaleTime: recentScaleTime})
+		require.Equal(t, int32(4), *replicas)
+		require.True(t, scaling.LastScaleTime.After(recentScaleTime.Time))
+	})
+
+	t.Run("Should not scale down during the cooldown", func(t *testing.T) {
+		replicas, scaling := testScaling(t, 5, func(*cbcontainersv1.CBContainersRuntimeResolverSpec) {}, &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: 4, Nodes: 20, LastScaleTime: recentScaleTime})
+		require.Equal(t, int32(4), *replicas)
+		require.Equal(t, &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: 4, Nodes: 5, LastScaleTime: recentScaleTime}, scaling)
+	})
+
+	t.Run("Should scale down after the cooldown", func(t *testing.T) {
+		replicas, _ := testScaling(t, 5, func(*cbcontainersv1.CBContainersRuntimeResolverSpec) {}, &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: 4, Nodes: 20, LastScaleTime: pastScaleTime})
+		require.Equal(t, int32(1), *replicas)
+	})
+
+	t.Run("Should not scale up during the scale up cooldown", func(t *testing.T) {
+		replicas, scaling := testScaling(t, 20, func(resolver *cbcontainersv1.CBContainersRuntimeResolverSpec) {
+			resolver.ScaleUpCooldown = cooldown
+		}, &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: 2, Nodes: 10, LastScaleTime: recentScaleTime})
+		require.Equal(t, int32(2), *replicas)
+		require.Equal(t, &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: 2, Nodes: 20, LastScaleTime: recentScaleTime}, scaling)
+	})
+
+	t.Run("Should scale up once the scale threshold of nodes were added", func(t *testing.T) {
+		threshold := func(resolver *cbcontainersv1.CBContainersRuntimeResolverSpec) {
+			resolver.ScaleNodesThreshold = 3
+		}
+
+		replicas, scaling := testScaling(t, 12, threshold, &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: 2, Nodes: 10, LastScaleTime: pastScaleTime})
+		require.Equal(t, int32(2), *replicas)
+		require.Equal(t, &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: 2, Nodes: 10, LastScaleTime: pastScaleTime}, scaling)
+
+		replicas, scaling = testScaling(t, 13, threshold, &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: 2, Nodes: 10, LastScaleTime: pastScaleTime})
+		require.Equal(t, int32(3), *replicas)
+		require.Equal(t, int32(13), scaling.Nodes)
+	})
+
+	t.Run("Should scale down once the scale threshold of nodes were removed", func(t *testing.T) {
+		threshold := func(resolver *cbcontainersv1.CBContainersRuntimeResolverSpec) {
+			resolver.ScaleNodesThreshold = 3
+		}
+
+		replicas, scaling := testScaling(t, 9, threshold, &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: 3, Nodes: 11, LastScaleTime: pastScaleTime})
+		require.Equal(t, int32(3), *replicas)
+		require.Equal(t, &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: 3, Nodes: 11, LastScaleTime: pastScaleTime}, scaling)
+
+		replicas, scaling = testScaling(t, 8, threshold, &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: 3, Nodes: 11, LastScaleTime: pastScaleTime})
+		require.Equal(t, int32(2), *replicas)
+		require.Equal(t, int32(8), scaling.Nodes)
+	})
+
+	t.Run("When the replicas count is set, should not derive it from the number of nodes", func(t *testing.T) {
+		replicasCount := int32(7)
+		replicas, scaling := testScaling(t, 100, func(resolver *cbcontainersv1.CBContainersRuntimeResolverSpec) {
+			resolver.ReplicasCount = &replicasCount
+		}, &cbcontainersv1.CBContainersReplicasScalingStatus{Replicas: 4, Nodes: 20, LastScaleTime: pastScaleTime})
+		require.Equal(t, replicasCount, *replicas)
+		require.Nil(t, scaling)
+	})
+}
+
+func TestComponentsAreAutoscaled(t *testing.T) {
+	t.Run("When the autoscaling is enabled, should apply a HorizontalPodAutoscaler instead of deriving the replicas", func(t *testing.T) {
+		var agentStatus *cbcontainersv1.CBContainersAgentStatus
+		var horizontalPodAutoscaler *autoscalingV2.HorizontalPodAutoscaler
+		_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			agentStatus = mocks.agentStatus
+			minReplicas := int32(2)
+			mocks.agentSpec.Components.RuntimeProtection.Resolver.Autoscaling = cbcontainersv1.CBContainersAutoscalingSpec{Enabled: &trueRef, MinReplicas: &minReplicas, MaxReplicas: 8}
+			mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.HorizontalPodAutoscalerK8sObject{}), mocks.agentSpec, gomock.Any()).
+				DoAndReturn(func(_ context.Context, builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec, _ ...*options.ApplyOptions) (bool, client.Object, error) {
+					horizontalPodAutoscaler = &autoscalingV2.HorizontalPodAutoscaler{}
+					require.NoError(t, builder.MutateK8sObject(horizontalPodAutoscaler, agentSpec))
+					return true, horizontalPodAutoscaler, nil
+				})
+			expectComponentsApplied(t, mocks)
+		}, "v1.29.1", commonState.DataPlaneNamespaceName, "")
+
+		require.NoError(t, err)
+		require.Equal(t, components.ResolverName, horizontalPodAutoscaler.Spec.ScaleTargetRef.Name)
+		require.Equal(t, int32(2), *horizontalPodAutoscaler.Spec.MinReplicas)
+		require.Equal(t, int32(8), horizontalPodAutoscaler.Spec.MaxReplicas)
+		require.Equal(t, coreV1.ResourceCPU, horizontalPodAutoscaler.Spec.Metrics[0].Resource.Name)
+		require.Nil(t, agentStatus.RuntimeResolverScaling)
+	})
+
+	t.Run("When the autoscaling is disabled, should delete the HorizontalPodAutoscalers", func(t *testing.T) {
+		var deletedAutoscalers []string
+		_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			mocks.componentApplier.EXPECT().Delete(gomock.Any(), gomock.AssignableToTypeOf(&components.HorizontalPodAutoscalerK8sObject{}), mocks.agentSpec).
+				DoAndReturn(func(_ context.Context, builder agent_applyment.AgentComponentBuilder, _ *cbcontainersv1.CBContainersAgentSpec, _ ...client.DeleteOption) (bool, error) {
+					deletedAutoscalers = append(deletedAutoscalers, builder.NamespacedName().Name)
+					return false, nil
+				}).AnyTimes()
+			expectComponentsApplied(t, mocks)
+		}, "v1.29.1", commonState.DataPlaneNamespaceName, "")
+
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{components.EnforcerName, components.ResolverName, components.ImageScanningReporterName}, deletedAutoscalers)
+	})
+
+	t.Run("When the cluster doesn't serve the autoscaling/v2 API, should return error", func(t *testing.T) {
+		_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			mocks.agentSpec.Components.Basic.Enforcer.Autoscaling = cbcontainersv1.CBContainersAutoscalingSpec{Enabled: &trueRef, MaxReplicas: 3}
+			expectComponentsApplied(t, mocks)
+		}, "v1.22.17", commonState.DataPlaneNamespaceName, "")
+
+		require.Error(t, err)
+	})
+}
+
+func TestDeploymentsHavePodDisruptionBudgets(t *testing.T) {
+	expectDisruptionBudgetsApplied := func(t *testing.T, mocks *StateApplierTestMocks, appliedDisruptionBudgets map[string]*policyV1.PodDisruptionBudget) {
+		mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.PodDisruptionBudgetK8sObject{}), mocks.agentSpec, gomock.Any()).
+			DoAndReturn(func(_ context.Context, builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec, _ ...*options.ApplyOptions) (bool, client.Object, error) {
+				podDisruptionBudget := &policyV1.PodDisruptionBudget{}
+				if err := builder.MutateK8sObject(podDisruptionBudget, agentSpec); err != nil {
+					return false, nil, err
+				}
+				appliedDisruptionBudgets[builder.NamespacedName().Name] = podDisruptionBudget
+				return true, podDisruptionBudget, nil
+			}).AnyTimes()
+	}
+
+	t.Run("When a deployment may run more than a single replica, should apply its PodDisruptionBudget", func(t *testing.T) {
+		appliedDisruptionBudgets := make(map[string]*policyV1.PodDisruptionBudget)
+		var deletedDisruptionBudgets []string
+		_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			enforcerReplicas, imageScanningReporterReplicas := int32(3), int32(1)
+			mocks.agentSpec.Components.Basic.Enforcer.ReplicasCount = &enforcerReplicas
+			mocks.agentSpec.Components.Basic.Enforcer.PodDisruptionBudget.Enabled = &trueRef
+			mocks.agentSpec.Components.RuntimeProtection.Resolver.Autoscaling = cbcontainersv1.CBContainersAutoscalingSpec{Enabled: &trueRef, MaxReplicas: 4}
+			mocks.agentSpec.Components.RuntimeProtection.Resolver.PodDisruptionBudget.Enabled = &trueRef
+			mocks.agentSpec.Components.ClusterScanning.ImageScanningReporter.ReplicasCount = &imageScanningReporterReplicas
+			mocks.agentSpec.Components.ClusterScanning.ImageScanningReporter.PodDisruptionBudget.Enabled = &trueRef
+			expectDisruptionBudgetsApplied(t, mocks, appliedDisruptionBudgets)
+			mocks.componentApplier.EXPECT().Delete(gomock.Any(), gomock.AssignableToTypeOf(&components.PodDisruptionBudgetK8sObject{}), mocks.agentSpec).
+				DoAndReturn(func(_ context.Context, builder agent_applyment.AgentComponentBuilder, _ *cbcontainersv1.CBContainersAgentSpec, _ ...client.DeleteOption) (bool, error) {
+					deletedDisruptionBudgets = append(deletedDisruptionBudgets, builder.NamespacedName().Name)
+					return false, nil
+				}).AnyTimes()
+			expectComponentsApplied(t, mocks)
+		}, "v1.29.1", commonState.DataPlaneNamespaceName, "")
+
+		require.NoError(t, err)
+		require.Len(t, appliedDisruptionBudgets, 2)
+		enforcerDisruptionBudget := appliedDisruptionBudgets[components.EnforcerName]
+		require.Equal(t, map[string]string{components.EnforcerLabelKey: components.EnforcerName}, enforcerDisruptionBudget.Spec.Selector.MatchLabels)
+		require.Equal(t, intstr.FromInt32(1), *enforcerDisruptionBudget.Spec.MaxUnavailable)
+		require.Nil(t, enforcerDisruptionBudget.Spec.MinAvailable)
+		require.Contains(t, appliedDisruptionBudgets, components.ResolverName)
+		require.Equal(t, []string{components.ImageScanningReporterName}, deletedDisruptionBudgets)
+	})
+
+	t.Run("When the min available pods are set, should apply the PodDisruptionBudget with them", func(t *testing.T) {
+		appliedDisruptionBudgets := make(map[string]*policyV1.PodDisruptionBudget)
+		minAvailable := intstr.FromString("50%")
+		_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			mocks.agentSpec.Components.Basic.Enforcer.PodDisruptionBudget = cbcontainersv1.CBContainersPodDisruptionBudgetSpec{Enabled: &trueRef, MinAvailable: &minAvailable}
+			expectDisruptionBudgetsApplied(t, mocks, appliedDisruptionBudgets)
+			expectComponentsApplied(t, mocks)
+		}, "v1.29.1", commonState.DataPlaneNamespaceName, "")
+
+		require.NoError(t, err)
+		require.Equal(t, minAvailable, *appliedDisruptionBudgets[components.EnforcerName].Spec.MinAvailable)
+		require.Nil(t, appliedDisruptionBudgets[components.EnforcerName].Spec.MaxUnavailable)
+	})
+
+	t.Run("When both the min available and the max unavailable pods are set, should return error", func(t *testing.T) {
+		minAvailable, maxUnavailable := intstr.FromInt32(1), intstr.FromInt32(1)
+		_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			mocks.agentSpec.Components.Basic.Enforcer.PodDisruptionBudget = cbcontainersv1.CBContainersPodDisruptionBudgetSpec{Enabled: &trueRef, MinAvailable: &minAvailable, MaxUnavailable: &maxUnavailable}
+			expectDisruptionBudgetsApplied(t, mocks, make(map[string]*policyV1.PodDisruptionBudget))
+			expectComponentsApplied(t, mocks)
+		}, "v1.29.1", commonState.DataPlaneNamespaceName, "")
+
+		require.Error(t, err)
+	})
+
+	t.Run("When the cluster doesn't serve the policy/v1 API, shouldn't apply PodDisruptionBudgets", func(t *testing.T) {
+		appliedDisruptionBudgets := make(map[string]*policyV1.PodDisruptionBudget)
+		_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			enforcerReplicas := int32(3)
+			mocks.agentSpec.Components.Basic.Enforcer.ReplicasCount = &enforcerReplicas
+			mocks.agentSpec.Components.Basic.Enforcer.PodDisruptionBudget.Enabled = &trueRef
+			expectDisruptionBudgetsApplied(t, mocks, appliedDisruptionBudgets)
+			expectComponentsApplied(t, mocks)
+		}, "v1.20.2", commonState.DataPlaneNamespaceName, "")
+
+		require.NoError(t, err)
+		require.Empty(t, appliedDisruptionBudgets)
+	})
+}
+
+func TestEnforcerTlsIsRotated(t *testing.T) {
+	expectTlsSecretApplied := func(mocks *StateApplierTestMocks, appliedValues *[]models.TlsSecretValues) *gomock.Call {
+		return mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.EnforcerTlsK8sObject{}), mocks.agentSpec, gomock.Any()).
+			DoAndReturn(func(_ context.Context, builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec, _ ...*options.ApplyOptions) (bool, client.Object, error) {
+				secret := &coreV1.Secret{}
+				require.NoError(t, builder.MutateK8sObject(secret, agentSpec))
+				*appliedValues = append(*appliedValues, models.TlsSecretValuesFromSecretData(secret.Data))
+				return true, secret, nil
+			})
+	}
+
+	t.Run("When the certificates don't expire within the renewal window, should only report their expiry", func(t *testing.T) {
+		var agentStatus *cbcontainersv1.CBContainersAgentStatus
+		_, _, err := getAppliedAndDeletedObjects(t, "", commonState.DataPlaneNamespaceName, func(mocks *StateApplierTestMocks) {
+			agentStatus = mocks.agentStatus
+		})
+		require.NoError(t, err)
+
+		notAfter, err := certificates.GetCertificatesExpiry(validTlsSecretValues(t))
+		require.NoError(t, err)
+		require.True(t, notAfter.Equal(agentStatus.EnforcerCertificates.NotAfter.Time))
+		require.True(t, notAfter.Add(-720*time.Hour).Equal(agentStatus.EnforcerCertificates.RenewalTime.Time))
+		require.Nil(t, agentStatus.EnforcerCertificates.LastRotationTime)
+	})
+
+	t.Run("When the certificates expire within the renewal window, should renew them and keep trusting the previous CA", func(t *testing.T) {
+		var agentStatus *cbcontainersv1.CBContainersAgentStatus
+		var eventRecorder *record.FakeRecorder
+		var appliedValues []models.TlsSecretValues
+		previousValues := validTlsSecretValues(t)
+		stateChanged, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			agentStatus, eventRecorder = mocks.agentStatus, mocks.eventRecorder
+			// The renewal window is longer than the certificates validity, so they are always renewed
+			mocks.agentSpec.Components.Basic.Enforcer.TlsRenewBefore = &metav1.Duration{Duration: 2 * 8760 * time.Hour}
+			mocks.secretValuesCreator.EXPECT().CreateTlsSecretsValues(gomock.Any()).Return(previousValues, nil)
+			mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.EnforcerTlsK8sObject{}), mocks.agentSpec, gomock.Any()).
+				Return(false, &coreV1.Secret{Data: previousValues.ToDataMap()}, nil)
+			expectTlsSecretApplied(mocks, &appliedValues)
+			expectComponentsApplied(t, mocks)
+		}, "", commonState.DataPlaneNamespaceName, "")
+		require.NoError(t, err)
+		require.True(t, stateChanged)
+
+		require.Len(t, appliedValues, 1)
+		require.Equal(t, previousValues.CaCert, appliedValues[0].PreviousCaCert)
+		require.NotNil(t, agentStatus.EnforcerCertificates.LastRotationTime)
+		require.Contains(t, <-eventRecorder.Events, events.ReasonCertificatesRenewed)
+	})
+
+	t.Run("When the enforcer pods use the renewed certificates, should stop trusting the previous CA", func(t *testing.T) {
+		var appliedValues []models.TlsSecretValues
+		rotatedValues := validTlsSecretValues(t)
+		rotatedValues.PreviousCaCert = []byte("previous CA")
+		_, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.EnforcerTlsK8sObject{}), mocks.agentSpec, gomock.Any()).
+				Return(false, &coreV1.Secret{Data: rotatedValues.ToDataMap()}, nil)
+			expectTlsSecretApplied(mocks, &appliedValues)
+			mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.EnforcerDeploymentK8sObject{}), mocks.agentSpec, gomock.Any()).
+				Return(false, &appsV1.Deployment{
+					Spec: appsV1.DeploymentSpec{Template: coreV1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{components.EnforcerTlsChecksumAnnotation: components.EnforcerTlsChecksum(rotatedValues)},
+					}}},
+					Status: appsV1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1, ReadyReplicas: 1},
+				}, nil)
+			expectComponentsApplied(t, mocks)
+		}, "", commonState.DataPlaneNamespaceName, "")
+		require.NoError(t, err)
+
+		require.Len(t, appliedValues, 1)
+		require.Empty(t, appliedValues[0].PreviousCaCert)
+		require.Equal(t, rotatedValues.CaCert, appliedValues[0].CaCert)
+	})
+}
+
+func TestEnforcerCertificatesAreIssuedByCertManager(t *testing.T) {
+	enforcerCertificateDetails := K8sObjectDetails{Namespace: commonState.DataPlaneNamespaceName, Name: components.EnforcerCertificateName, ObjectType: reflect.TypeOf(&unstructured.Unstructured{})}
+	enforcerTlsSecretDetails := K8sObjectDetails{Namespace: commonState.DataPlaneNamespaceName, Name: components.EnforcerTlsName, ObjectType: reflect.TypeOf(&coreV1.Secret{})}
+	withCertManager := func(mocks *StateApplierTestMocks) {
+		mocks.agentSpec.Components.Basic.Enforcer.CertManager = &cbcontainersv1.CBContainersCertManagerSpec{
+			IssuerRef: cbcontainersv1.CBContainersIssuerReference{Name: "issuer", Kind: "ClusterIssuer"},
+		}
+	}
+	expectIssuedTlsSecret := func(mocks *StateApplierTestMocks, err error) {
+		mocks.apiReader.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: components.EnforcerTlsName, Namespace: commonState.DataPlaneNamespaceName}, gomock.AssignableToTypeOf(&coreV1.Secret{})).
+			DoAndReturn(func(_ context.Context, _ types.NamespacedName, secret *coreV1.Secret, _ ...client.GetOption) error {
+				tlsSecretValues := validTlsSecretValues(t)
+				secret.Annotations = map[string]string{components.CertManagerCertificateNameAnnotation: components.EnforcerCertificateName}
+				secret.Data = map[string][]byte{models.CertManagerSignedCertKey: tlsSecretValues.SignedCert, models.CertManagerKeyKey: tlsSecretValues.Key}
+				return err
+			})
+	}
+
+	t.Run("When the certificates were issued, should apply the Certificate and the webhooks instead of the tls secret", func(t *testing.T) {
+		var agentStatus *cbcontainersv1.CBContainersAgentStatus
+		appliedObjects, _, err := getAppliedAndDeletedObjects(t, "", commonState.DataPlaneNamespaceName, func(mocks *StateApplierTestMocks) {
+			agentStatus = mocks.agentStatus
+			withCertManager(mocks)
+			expectIssuedTlsSecret(mocks, nil)
+		}, MutateDeploymentsToBeWithReadyReplica(enforcerDeploymentDetails(commonState.DataPlaneNamespaceName)))
+		require.NoError(t, err)
+
+		require.Contains(t, appliedObjects, enforcerCertificateDetails)
+		require.NotContains(t, appliedObjects, enforcerTlsSecretDetails)
+		require.Contains(t, appliedObjects, EnforcerValidatingWebhookDetails)
+		require.NotNil(t, agentStatus.EnforcerCertificates)
+		require.True(t, meta.IsStatusConditionFalse(agentStatus.Conditions, cbcontainersv1.ConditionTypeEnforcerCertificatesPending))
+	})
+
+	t.Run("When the certificates weren't issued yet, should delete the webhooks and wait without reporting a changed state", func(t *testing.T) {
+		var deletedObjects []K8sObjectDetails
+		var agentStatus *cbcontainersv1.CBContainersAgentStatus
+		stateChanged, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			agentStatus = mocks.agentStatus
+			withCertManager(mocks)
+			expectIssuedTlsSecret(mocks, k8sErrors.NewNotFound(schema.GroupResource{}, components.EnforcerTlsName))
+			mocks.componentApplier.EXPECT().Delete(gomock.Any(), gomock.Any(), mocks.agentSpec).
+				DoAndReturn(func(_ context.Context, builder agent_applyment.AgentComponentBuilder, _ *cbcontainersv1.CBContainersAgentSpec, _ ...client.DeleteOption) (bool, error) {
+					deletedObjects = append(deletedObjects, K8sObjectDetails{Name: builder.NamespacedName().Name, Namespace: builder.NamespacedName().Namespace, ObjectType: reflect.TypeOf(builder.EmptyK8sObject())})
+					return false, nil
+				}).AnyTimes()
+			expectComponentsApplied(t, mocks)
+		}, "", commonState.DataPlaneNamespaceName, "")
+		require.NoError(t, err)
+
+		require.False(t, stateChanged)
+		require.Contains(t, deletedObjects, EnforcerValidatingWebhookDetails)
+		pendingCondition := meta.FindStatusCondition(agentStatus.Conditions, cbcontainersv1.ConditionTypeEnforcerCertificatesPending)
+		require.Equal(t, metav1.ConditionTrue, pendingCondition.Status)
+		require.Equal(t, status.ReasonCertificatesNotIssued, pendingCondition.Reason)
+	})
+
+	expectCertManagerNotInstalled := func(mocks *StateApplierTestMocks) {
+		noMatchErr := &meta.NoKindMatchError{GroupKind: components.CertificateGroupVersionKind.GroupKind(), SearchedVersions: []string{components.CertificateGroupVersionKind.Version}}
+		mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.EnforcerCertificateK8sObject{}), mocks.agentSpec, gomock.Any()).
+			Return(false, nil, fmt.Errorf("failed getting K8s object: %w", noMatchErr))
+	}
+	recordedEvents := func(eventRecorder *record.FakeRecorder) string {
+		var recorded []string
+		for len(eventRecorder.Events) > 0 {
+			recorded = append(recorded, <-eventRecorder.Events)
+		}
+		return strings.Join(recorded, "\n")
+	}
+
+	t.Run("When the cert-manager CRDs are not installed, should record it and keep applying the other components", func(t *testing.T) {
+		var eventRecorder *record.FakeRecorder
+		var agentStatus *cbcontainersv1.CBContainersAgentStatus
+		appliedObjects, _, err := getAppliedAndDeletedObjects(t, "", commonState.DataPlaneNamespaceName, func(mocks *StateApplierTestMocks) {
+			eventRecorder, agentStatus = mocks.eventRecorder, mocks.agentStatus
+			withCertManager(mocks)
+			expectCertManagerNotInstalled(mocks)
+		})
+		require.NoError(t, err)
+
+		require.Contains(t, appliedObjects, K8sObjectDetails{Namespace: commonState.DataPlaneNamespaceName, Name: components.DaemonSetName, ObjectType: reflect.TypeOf(&appsV1.DaemonSet{})})
+		require.NotContains(t, appliedObjects, EnforcerValidatingWebhookDetails)
+		require.Contains(t, recordedEvents(eventRecorder), events.ReasonCertManagerNotInstalled)
+		pendingCondition := meta.FindStatusCondition(agentStatus.Conditions, cbcontainersv1.ConditionTypeEnforcerCertificatesPending)
+		require.Equal(t, metav1.ConditionTrue, pendingCondition.Status)
+		require.Equal(t, status.ReasonCertManagerNotInstalled, pendingCondition.Reason)
+	})
+
+	t.Run("When the cert-manager CRDs are still not installed, should not report a changed state nor record it again", func(t *testing.T) {
+		var eventRecorder *record.FakeRecorder
+		stateChanged, err := testStateApplier(t, func(mocks *StateApplierTestMocks) {
+			eventRecorder = mocks.eventRecorder
+			withCertManager(mocks)
+			status.SetAgentEnforcerCertificatesPendingCondition(mocks.agentStatus, status.ReasonCertManagerNotInstalled, "The enforcer certificates should be issued by cert-manager, but the cert-manager CRDs are not installed", 0)
+			expectCertManagerNotInstalled(mocks)
+			expectComponentsApplied(t, mocks)
+		}, "", commonState.DataPlaneNamespaceName, "")
+		require.NoError(t, err)
+
+		require.False(t, stateChanged)
+		require.NotContains(t, recordedEvents(eventRecorder), events.ReasonCertManagerNotInstalled)
+	})
+
+	t.Run("When cert-manager is disabled after it issued the certificates, should delete the Certificate and the secret", func(t *testing.T) {
+		_, deletedObjects, err := getAppliedAndDeletedObjects(t, "", commonState.DataPlaneNamespaceName, func(mocks *StateApplierTestMocks) {
+			mocks.componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.EnforcerTlsK8sObject{}), mocks.agentSpec, gomock.Any()).
+				Return(false, &coreV1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{components.CertManagerCertificateNameAnnotation: components.EnforcerCertificateName}}}, nil)
+		})
+		require.NoError(t, err)
+
+		require.Contains(t, deletedObjects, enforcerCertificateDetails)
+		require.Contains(t, deletedObjects, enforcerTlsSecretDetails)
+		require.Contains(t, deletedObjects, EnforcerValidatingWebhookDetails)
+	})
+}
+
+func TestTeardownState(t *testing.T) {
+	testTeardownState := func(t *testing.T, nodeCleanup *cbcontainersv1.CBContainersNodeCleanupStatus, setup func(componentApplier *mocks.MockAgentComponentApplier, apiReader *testUtilsMocks.MockReader)) (bool, []string, error) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		componentApplier := mocks.NewMockAgentComponentApplier(ctrl)
+		apiReader := testUtilsMocks.NewMockReader(ctrl)
+		agent := &cbcontainersv1.CBContainersAgent{Spec: cbcontainersv1.CBContainersAgentSpec{Account: Account, ClusterName: Cluster}}
+		agent.Status.NodeCleanup = nodeCleanup
+		stateApplier := state.NewStateApplier(apiReader, apiReader, componentApplier, capabilitiesForVersion(t, DefaultKubernetesVersion), commonState.DataPlaneNamespaceName, "", mocks.NewMockTlsSecretsValuesCreator(ctrl), record.NewFakeRecorder(100), logrTesting.NewTestLogger(t))
+
+		var deletedObjects []string
+		setup(componentApplier, apiReader)
+		componentApplier.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, builder agent_applyment.AgentComponentBuilder, _ *cbcontainersv1.CBContainersAgentSpec, _ ...client.DeleteOption) (bool, error) {
+				deletedObjects = append(deletedObjects, fmt.Sprintf("%T/%v", builder.EmptyK8sObject(), builder.NamespacedName().Name))
+				return true, nil
+			}).AnyTimes()
+
+		done, err := stateApplier.TeardownState(context.Background(), agent)
+		return done, deletedObjects, err
+	}
+
+	t.Run("Should delete the webhooks before the enforcer and the enforcer before the other components", func(t *testing.T) {
+		done, deletedObjects, err := testTeardownState(t, nil, func(componentApplier *mocks.MockAgentComponentApplier, apiReader *testUtilsMocks.MockReader) {
+			expectDaemonSetPods(apiReader).Times(2)
+		})
+
+		require.NoError(t, err)
+		require.True(t, done)
+		require.Greater(t, len(deletedObjects), 4)
+		for _, webhook := range deletedObjects[:2] {
+			require.Contains(t, webhook, "WebhookConfiguration")
+		}
+		require.Equal(t, fmt.Sprintf("*v1.Deployment/%v", components.EnforcerName), deletedObjects[2])
+		require.Contains(t, deletedObjects, fmt.Sprintf("*v1.DaemonSet/%v", components.DaemonSetName))
+	})
+
+	t.Run("When nodes that run the sensor are marked, should return before deleting the daemon set, so they are persisted first", func(t *testing.T) {
+		done, deletedObjects, err := testTeardownState(t, nil, func(componentApplier *mocks.MockAgentComponentApplier, apiReader *testUtilsMocks.MockReader) {
+			expectDaemonSetPods(apiReader, sensorPod("node-a", components.CndrContainerName))
+		})
+
+		require.NoError(t, err)
+		require.False(t, done)
+		require.NotContains(t, deletedObjects, fmt.Sprintf("*v1.DaemonSet/%v", components.DaemonSetName))
+	})
+
+	t.Run("When the daemon set pods are still running, should wait without cleaning up the nodes", func(t *testing.T) {
+		nodeCleanup := &cbcontainersv1.CBContainersNodeCleanupStatus{PendingNodes: []string{"node-a"}}
+		done, deletedObjects, err := testTeardownState(t, nodeCleanup, func(componentApplier *mocks.MockAgentComponentApplier, apiReader *testUtilsMocks.MockReader) {
+			expectDaemonSetPods(apiReader, sensorPod("node-a", components.CndrContainerName)).Times(2)
+		})
+
+		require.NoError(t, err)
+		require.False(t, done)
+		require.Contains(t, deletedObjects, fmt.Sprintf("*v1.DaemonSet/%v", components.DaemonSetName))
+	})
+
+	t.Run("When the daemon set pods are gone, should clean up the nodes that ran the sensor", func(t *testing.T) {
+		var cleanedUpNodes []string
+		nodeCleanup := &cbcontainersv1.CBContainersNodeCleanupStatus{PendingNodes: []string{"node-a", "node-b"}}
+		done, _, err := testTeardownState(t, nodeCleanup, func(componentApplier *mocks.MockAgentComponentApplier, apiReader *testUtilsMocks.MockReader) {
+			expectDaemonSetPods(apiReader).Times(3)
+			componentApplier.EXPECT().Apply(gomock.Any(), gomock.AssignableToTypeOf(&components.NodeCleanupJobK8sObject{}), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, builder agent_applyment.AgentComponentBuilder, agentSpec *cbcontainersv1.CBContainersAgentSpec, applyOptions ...*options.ApplyOptions) (bool, client.Object, error) {
+					cleanedUpNodes = append(cleanedUpNodes, builder.NamespacedName().Name)
+					// The Jobs aren't owned by the deleted agent, so the garbage collector doesn't delete them before they finish
+					require.Nil(t, applyOptions[0].OwnerSetter())
+					return finishedNodeCleanupJob(false)(ctx, builder, agentSpec, applyOptions...)
+				}).Times(2)
+			componentApplier.EXPECT().Delete(gomock.Any(), gomock.AssignableToTypeOf(&components.NodeCleanupJobK8sObject{}), gomock.Any(), gomock.Any()).Return(true, nil).Times(2)
+		})
+
+		require.NoError(t, err)
+		require.True(t, done)
+		require.Len(t, cleanedUpNodes, 2)
+		require.NotEqual(t, cleanedUpNodes[0], cleanedUpNodes[1])
+	})
+}
+
+func TestPlanDesiredStateDoesNotApply(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	apiReader := testUtilsMocks.NewMockReader(ctrl)
+	eventRecorder := record.NewFakeRecorder(10)
+	agent := &cbcontainersv1.CBContainersAgent{Spec: cbcontainersv1.CBContainersAgentSpec{Account: Account, ClusterName: Cluster}}
+	originalAgent := agent.DeepCopy()
+
+	// The component applier mock has no expectations, so the test fails if anything is applied or deleted through it
+	stateApplier := state.NewStateApplier(apiReader, apiReader, mocks.NewMockAgentComponentApplier(ctrl), capabilitiesForVersion(t, DefaultKubernetesVersion), commonState.DataPlaneNamespaceName, "", mocks.NewMockTlsSecretsValuesCreator(ctrl), eventRecorder, logrTesting.NewTestLogger(t))
+
+	readErr := fmt.Errorf("read error")
+	apiReader.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: commonState.DataPlaneConfigmapName, Namespace: commonState.DataPlaneNamespaceName}, gomock.AssignableToTypeOf(&coreV1.ConfigMap{})).Return(readErr)
+
+	_, err := stateApplier.PlanDesiredState(context.Background(), agent, &models.RegistrySecretValues{}, nil)
+	require.ErrorContains(t, err, readErr.Error())
+	require.Equal(t, originalAgent, agent)
+	require.Empty(t, eventRecorder.Events)
+}
+
+func TestPlanDesiredStateDoesNotRenewTheEnforcerCertificates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	apiReader := testUtilsMocks.NewMockReader(ctrl)
+	agent := &cbcontainersv1.CBContainersAgent{Spec: cbcontainersv1.CBContainersAgentSpec{Account: Account, ClusterName: Cluster}}
+	require.NoError(t, controllers.SetAgentDefaults(&agent.Spec))
+	// The renewal window is longer than the certificates validity, so applying would always renew them
+	agent.Spec.Components.Basic.Enforcer.TlsRenewBefore = &metav1.Duration{Duration: 2 * 8760 * time.Hour}
+
+	// The TLS secrets values creator mock has no expectations, so the test fails if the certificates are renewed
+	stateApplier := state.NewStateApplier(apiReader, apiReader, mocks.NewMockAgentComponentApplier(ctrl), capabilitiesForVersion(t, DefaultKubernetesVersion), commonState.DataPlaneNamespaceName, "", mocks.NewMockTlsSecretsValuesCreator(ctrl), record.NewFakeRecorder(10), logrTesting.NewTestLogger(t))
+
+	tlsSecretValues := validTlsSecretValues(t)
+	apiReader.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: components.EnforcerTlsName, Namespace: commonState.DataPlaneNamespaceName}, gomock.AssignableToTypeOf(&coreV1.Secret{})).
+		DoAndReturn(func(_ context.Context, key types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+			secret := obj.(*coreV1.Secret)
+			secret.SetNamespace(key.Namespace)
+			secret.SetName(key.Name)
+			secret.Data = tlsSecretValues.ToDataMap()
+			return nil
+		}).AnyTimes()
+	apiReader.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(k8sErrors.NewNotFound(schema.GroupResource{}, "")).AnyTimes()
+	apiReader.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	reportedExpiry := float64(time.Now().Unix())
+	metrics.EnforcerCertificatesExpiry.Set(reportedExpiry)
+
+	changes, err := stateApplier.PlanDesiredState(context.Background(), agent, &models.RegistrySecretValues{}, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, changes)
+	for _, change := range changes {
+		require.False(t, change.Kind == "Secret" && change.Name == components.EnforcerTlsName, "the enforcer TLS secret is planned to change: %+v", change)
+	}
+	require.Equal(t, reportedExpiry, testutil.ToFloat64(metrics.EnforcerCertificatesExpiry))
+}
+
+func TestShouldProcessEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	stateApplier := state.NewStateApplier(testUtilsMocks.NewMockReader(ctrl), testUtilsMocks.NewMockReader(ctrl), mocks.NewMockAgentComponentApplier(ctrl), capabilitiesForVersion(t, DefaultKubernetesVersion), commonState.DataPlaneNamespaceName, "", mocks.NewMockTlsSecretsValuesCreator(ctrl), record.NewFakeRecorder(10), logrTesting.NewTestLogger(t))
+
+	for _, name := range []string{components.MonitorName, components.EnforcerName, components.StateReporterName, components.ResolverName, components.DaemonSetName, components.ImageScanningReporterName} {
+		workload := &appsV1.Deployment{}
+		workload.SetNamespace(commonState.DataPlaneNamespaceName)
+		workload.SetName(name)
+		require.True(t, stateApplier.ShouldProcessEvent(workload), name)
+	}
+
+	nodeCleanupJob := &batchV1.Job{}
+	nodeCleanupJob.SetNamespace(commonState.DataPlaneNamespaceName)
+	nodeCleanupJob.SetLabels(map[string]string{components.NodeCleanupLabelKey: components.NodeCleanupName})
+	require.True(t, stateApplier.ShouldProcessEvent(nodeCleanupJob))
+
+	otherObject := &coreV1.ConfigMap{}
+	otherObject.SetNamespace(commonState.DataPlaneNamespaceName)
+	otherObject.SetName(commonState.DataPlaneConfigmapName)
+	require.False(t, stateApplier.ShouldProcessEvent(otherObject))
+
+	otherJob := &batchV1.Job{}
+	otherJob.SetNamespace(commonState.DataPlaneNamespaceName)
+	require.False(t, stateApplier.ShouldProcessEvent(otherJob))
+}
@@ -0,0 +1,309 @@
+package status
+
+import (
+	"fmt"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	ReasonRolloutComplete          = "RolloutComplete"
+	ReasonRolloutInProgress        = "RolloutInProgress"
+	ReasonReplicasUnavailable      = "ReplicasUnavailable"
+	ReasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+	ReasonReplicaFailure           = "ReplicaFailure"
+
+	ReasonAllComponentsReady      = "AllComponentsReady"
+	ReasonNoComponentsReconciled  = "NoComponentsReconciled"
+	ReasonComponentsNotReady      = "ComponentsNotReady"
+	ReasonComponentsProgressing   = "ComponentsProgressing"
+	ReasonNoComponentsProgressing = "NoComponentsProgressing"
+	ReasonComponentsDegraded      = "ComponentsDegraded"
+	ReasonNoComponentsDegraded    = "NoComponentsDegraded"
+
+	ReasonReconciliationPaused = "ReconciliationPaused"
+	ReasonComponentPaused      = "ComponentPaused"
+	ReasonComponentsPaused     = "ComponentsPaused"
+	ReasonNotPaused            = "NotPaused"
+
+	ReasonPodTemplatePatchApplied = "PodTemplatePatchApplied"
+	ReasonInvalidPodTemplatePatch = "InvalidPodTemplatePatch"
+
+	ReasonSecretsMissing  = "SecretsMissing"
+	ReasonAllSecretsFound = "AllSecretsFound"
+
+	ReasonCertificatesIssued      = "CertificatesIssued"
+	ReasonCertificatesNotIssued   = "CertificatesNotIssued"
+	ReasonCertManagerNotInstalled = "CertManagerNotInstalled"
+)
+
+// SetComponentStatus computes the conditions of the given workload (Deployment or DaemonSet) and stores them in the
+// agent status under the given component name, with the generation of the agent that they were computed for.
+func SetComponentStatus(agentStatus *cbcontainersv1.CBContainersAgentStatus, name string, k8sObject client.Object, generation int64) error {
+	var conditions []metav1.Condition
+
+	switch workload := k8sObject.(type) {
+	case *appsV1.Deployment:
+		conditions = deploymentConditions(workload)
+	case *appsV1.DaemonSet:
+		conditions = daemonSetConditions(workload)
+	default:
+		return fmt.Errorf("expected Deployment or DaemonSet K8s object")
+	}
+
+	componentStatus := getOrAddComponentStatus(agentStatus, name)
+	for _, condition := range conditions {
+		condition.ObservedGeneration = generation
+		meta.SetStatusCondition(&componentStatus.Conditions, condition)
+	}
+
+	return nil
+}
+
+// SetComponentPaused sets the Paused condition of a component, which is True while the component isn't reconciled.
+func SetComponentPaused(agentStatus *cbcontainersv1.CBContainersAgentStatus, name string, paused bool, generation int64) {
+	pausedCondition := newCondition(cbcontainersv1.ConditionTypePaused, paused, ReasonNotPaused, "The component is reconciled")
+	if paused {
+		pausedCondition.Reason = ReasonComponentPaused
+		pausedCondition.Message = "The reconciliation of the component is paused"
+	}
+
+	pausedCondition.ObservedGeneration = generation
+	meta.SetStatusCondition(&getOrAddComponentStatus(agentStatus, name).Conditions, pausedCondition)
+}
+
+// SetComponentPodTemplatePatchCondition sets the PodTemplatePatchInvalid condition of a component that has a pod
+// template patch, which is True when the patch can't be applied, and removes it from a component that has none.
+func SetComponentPodTemplatePatchCondition(agentStatus *cbcontainersv1.CBContainersAgentStatus, name string, patched bool, patchErr error, generation int64) {
+	componentStatus := getOrAddComponentStatus(agentStatus, name)
+	if !patched {
+		meta.RemoveStatusCondition(&componentStatus.Conditions, cbcontainersv1.ConditionTypePodTemplatePatchInvalid)
+		return
+	}
+
+	invalidCondition := newCondition(cbcontainersv1.ConditionTypePodTemplatePatchInvalid, patchErr != nil, ReasonPodTemplatePatchApplied, "The pod template patch is applied")
+	if patchErr != nil {
+		invalidCondition.Reason = ReasonInvalidPodTemplatePatch
+		invalidCondition.Message = patchErr.Error()
+	}
+
+	invalidCondition.ObservedGeneration = generation
+	meta.SetStatusCondition(&componentStatus.Conditions, invalidCondition)
+}
+
+// IsComponentReady returns true when the Ready condition of a component is True.
+func IsComponentReady(agentStatus *cbcontainersv1.CBContainersAgentStatus, name string) bool {
+	for i := range agentStatus.Components {
+		if agentStatus.Components[i].Name == name {
+			return meta.IsStatusConditionTrue(agentStatus.Components[i].Conditions, cbcontainersv1.ConditionTypeReady)
+		}
+	}
+
+	return false
+}
+
+// RemoveComponentStatus removes the status of a component that is no longer deployed.
+func RemoveComponentStatus(agentStatus *cbcontainersv1.CBContainersAgentStatus, name string) {
+	for i := range agentStatus.Components {
+		if agentStatus.Components[i].Name == name {
+			agentStatus.Components = append(agentStatus.Components[:i], agentStatus.Components[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetAgentConditions aggregates the conditions of all the components into the overall agent conditions.
+// The agent is Ready when it has reconciled components and all of them are ready, and Progressing/Degraded when any of
+// them is. A component whose pod template patch can't be applied is Degraded as well.
+func SetAgentConditions(agentStatus *cbcontainersv1.CBContainersAgentStatus, generation int64) {
+	var reconciled, notReady, progressing, degraded []string
+	for _, component := range agentStatus.Components {
+		if meta.FindStatusCondition(component.Conditions, cbcontainersv1.ConditionTypeReady) != nil {
+			reconciled = append(reconciled, component.Name)
+		}
+		if !meta.IsStatusConditionTrue(component.Conditions, cbcontainersv1.ConditionTypeReady) {
+			notReady = append(notReady, component.Name)
+		}
+		if meta.IsStatusConditionTrue(component.Conditions, cbcontainersv1.ConditionTypeProgressing) {
+			progressing = append(progressing, component.Name)
+		}
+		if meta.IsStatusConditionTrue(component.Conditions, cbcontainersv1.ConditionTypeDegraded) ||
+			meta.IsStatusConditionTrue(component.Conditions, cbcontainersv1.ConditionTypePodTemplatePatchInvalid) {
+			degraded = append(degraded, component.Name)
+		}
+	}
+
+	readyCondition := newCondition(cbcontainersv1.ConditionTypeReady, len(reconciled) > 0 && len(notReady) == 0, ReasonAllComponentsReady, "All components are ready")
+	if len(notReady) > 0 {
+		readyCondition.Reason = ReasonComponentsNotReady
+		readyCondition.Message = fmt.Sprintf("Components not ready: %v", notReady)
+	} else if len(reconciled) == 0 {
+		readyCondition.Reason = ReasonNoComponentsReconciled
+		readyCondition.Message = "No component was reconciled yet"
+	}
+
+	progressingCondition := newCondition(cbcontainersv1.ConditionTypeProgressing, len(progressing) > 0, ReasonNoComponentsProgressing, "No component is being rolled out")
+	if len(progressing) > 0 {
+		progressingCondition.Reason = ReasonComponentsProgressing
+		progressingCondition.Message = fmt.Sprintf("Components being rolled out: %v", progressing)
+	}
+
+	degradedCondition := newCondition(cbcontainersv1.ConditionTypeDegraded, len(degraded) > 0, ReasonNoComponentsDegraded, "No component is degraded")
+	if len(degraded) > 0 {
+		degradedCondition.Reason = ReasonComponentsDegraded
+		degradedCondition.Message = fmt.Sprintf("Degraded components: %v", degraded)
+	}
+
+	for _, condition := range []metav1.Condition{readyCondition, progressingCondition, degradedCondition} {
+		condition.ObservedGeneration = generation
+		meta.SetStatusCondition(&agentStatus.Conditions, condition)
+	}
+}
+
+// SetAgentPausedCondition sets the Paused condition of the agent, which is True when the reconciliation of the whole
+// agent is paused or when any of its components is paused.
+func SetAgentPausedCondition(agentStatus *cbcontainersv1.CBContainersAgentStatus, agentPaused bool, generation int64) {
+	var paused []string
+	for _, component := range agentStatus.Components {
+		if meta.IsStatusConditionTrue(component.Conditions, cbcontainersv1.ConditionTypePaused) {
+			paused = append(paused, component.Name)
+		}
+	}
+
+	pausedCondition := newCondition(cbcontainersv1.ConditionTypePaused, agentPaused || len(paused) > 0, ReasonNotPaused, "The agent is reconciled")
+	if agentPaused {
+		pausedCondition.Reason = ReasonReconciliationPaused
+		pausedCondition.Message = "The reconciliation of the agent is paused, no change is applied to its components"
+	} else if len(paused) > 0 {
+		pausedCondition.Reason = ReasonComponentsPaused
+		pausedCondition.Message = fmt.Sprintf("Paused components: %v", paused)
+	}
+
+	pausedCondition.ObservedGeneration = generation
+	meta.SetStatusCondition(&agentStatus.Conditions, pausedCondition)
+}
+
+// SetAgentSecretsMissingCondition sets the SecretsMissing condition of the agent, which is True when any of the secrets
+// that the agent refers to doesn't exist.
+func SetAgentSecretsMissingCondition(agentStatus *cbcontainersv1.CBContainersAgentStatus, missingSecrets []string, generation int64) {
+	missingCondition := newCondition(cbcontainersv1.ConditionTypeSecretsMissing, len(missingSecrets) > 0, ReasonAllSecretsFound, "All the referenced secrets exist")
+	if len(missingSecrets) > 0 {
+		missingCondition.Reason = ReasonSecretsMissing
+		missingCondition.Message = fmt.Sprintf("Missing secrets: %v", missingSecrets)
+	}
+
+	missingCondition.ObservedGeneration = generation
+	meta.SetStatusCondition(&agentStatus.Conditions, missingCondition)
+}
+
+// SetAgentEnforcerCertificatesPendingCondition sets the EnforcerCertificatesPending condition of the agent, which is
+// True with the given reason until cert-manager issues the enforcer certificates. It returns true when the condition
+// was changed.
+func SetAgentEnforcerCertificatesPendingCondition(agentStatus *cbcontainersv1.CBContainersAgentStatus, pendingReason, message string, generation int64) bool {
+	pendingCondition := newCondition(cbcontainersv1.ConditionTypeEnforcerCertificatesPending, pendingReason != ReasonCertificatesIssued, pendingReason, message)
+	pendingCondition.ObservedGeneration = generation
+	return meta.SetStatusCondition(&agentStatus.Conditions, pendingCondition)
+}
+
+// RemoveAgentEnforcerCertificatesPendingCondition removes the EnforcerCertificatesPending condition of an agent whose
+// enforcer certificates are not issued by cert-manager.
+func RemoveAgentEnforcerCertificatesPendingCondition(agentStatus *cbcontainersv1.CBContainersAgentStatus) {
+	meta.RemoveStatusCondition(&agentStatus.Conditions, cbcontainersv1.ConditionTypeEnforcerCertificatesPending)
+}
+
+func getOrAddComponentStatus(agentStatus *cbcontainersv1.CBContainersAgentStatus, name string) *cbcontainersv1.CBContainersComponentStatus {
+	for i := range agentStatus.Components {
+		if agentStatus.Components[i].Name == name {
+			return &agentStatus.Components[i]
+		}
+	}
+
+	agentStatus.Components = append(agentStatus.Components, cbcontainersv1.CBContainersComponentStatus{Name: name})
+	return &agentStatus.Components[len(agentStatus.Components)-1]
+}
+
+func deploymentConditions(deployment *appsV1.Deployment) []metav1.Condition {
+	desiredReplicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desiredReplicas = *deployment.Spec.Replicas
+	}
+
+	deploymentStatus := &deployment.Status
+	rolledOut := deploymentStatus.ObservedGeneration >= deployment.Generation &&
+		deploymentStatus.UpdatedReplicas >= desiredReplicas &&
+		deploymentStatus.Replicas <= deploymentStatus.UpdatedReplicas
+	available := deploymentStatus.AvailableReplicas >= desiredReplicas
+	message := fmt.Sprintf("%d/%d replicas available, %d updated", deploymentStatus.AvailableReplicas, desiredReplicas, deploymentStatus.UpdatedReplicas)
+
+	failureReason, failureMessage := "", ""
+	for _, condition := range deploymentStatus.Conditions {
+		if condition.Type == appsV1.DeploymentProgressing && condition.Status == coreV1.ConditionFalse {
+			failureReason, failureMessage = ReasonProgressDeadlineExceeded, condition.Message
+		} else if condition.Type == appsV1.DeploymentReplicaFailure && condition.Status == coreV1.ConditionTrue {
+			failureReason, failureMessage = ReasonReplicaFailure, condition.Message
+		}
+	}
+
+	return workloadConditions(rolledOut, available, failureReason, failureMessage, message)
+}
+
+func daemonSetConditions(daemonSet *appsV1.DaemonSet) []metav1.Condition {
+	daemonSetStatus := &daemonSet.Status
+	desiredPods := daemonSetStatus.DesiredNumberScheduled
+
+	rolledOut := daemonSetStatus.ObservedGeneration >= daemonSet.Generation &&
+		daemonSetStatus.UpdatedNumberScheduled >= desiredPods
+	available := daemonSetStatus.NumberAvailable >= desiredPods
+	message := fmt.Sprintf("%d/%d pods available, %d updated", daemonSetStatus.NumberAvailable, desiredPods, daemonSetStatus.UpdatedNumberScheduled)
+
+	return workloadConditions(rolledOut, available, "", "", message)
+}
+
+// workloadConditions builds the Ready, Progressing and Degraded conditions of a single workload.
+// A failure reason reported by the workload controller (e.g. an exceeded progress deadline) always marks it as Degraded,
+// otherwise it is Degraded only when the rollout has completed but not all the replicas are available.
+func workloadConditions(rolledOut, available bool, failureReason, failureMessage, message string) []metav1.Condition {
+	ready := rolledOut && available
+	progressing := !rolledOut && failureReason == ""
+	degraded := failureReason != "" || (rolledOut && !available)
+
+	readyCondition := newCondition(cbcontainersv1.ConditionTypeReady, ready, ReasonRolloutComplete, message)
+	progressingCondition := newCondition(cbcontainersv1.ConditionTypeProgressing, progressing, ReasonRolloutComplete, message)
+	degradedCondition := newCondition(cbcontainersv1.ConditionTypeDegraded, degraded, ReasonRolloutComplete, message)
+
+	if !rolledOut {
+		readyCondition.Reason = ReasonRolloutInProgress
+		progressingCondition.Reason = ReasonRolloutInProgress
+		degradedCondition.Reason = ReasonRolloutInProgress
+	} else if !available {
+		readyCondition.Reason = ReasonReplicasUnavailable
+		degradedCondition.Reason = ReasonReplicasUnavailable
+	}
+
+	if failureReason != "" {
+		readyCondition.Reason, readyCondition.Message = failureReason, failureMessage
+		progressingCondition.Reason, progressingCondition.Message = failureReason, failureMessage
+		degradedCondition.Reason, degradedCondition.Message = failureReason, failureMessage
+	}
+
+	return []metav1.Condition{readyCondition, progressingCondition, degradedCondition}
+}
+
+func newCondition(conditionType string, isTrue bool, reason, message string) metav1.Condition {
+	conditionStatus := metav1.ConditionFalse
+	if isTrue {
+		conditionStatus = metav1.ConditionTrue
+	}
+
+	return metav1.Condition{
+		Type:    conditionType,
+		Status:  conditionStatus,
+		Reason:  reason,
+		Message: message,
+	}
+}
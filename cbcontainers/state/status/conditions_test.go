@@ -0,0 +1,233 @@
+package status_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/status"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const componentName = "component"
+
+type expectedConditions struct {
+	ready       bool
+	progressing bool
+	degraded    bool
+}
+
+func requireComponentConditions(t *testing.T, k8sObject client.Object, expected expectedConditions) {
+	agentStatus := &cbcontainersv1.CBContainersAgentStatus{}
+	require.NoError(t, status.SetComponentStatus(agentStatus, componentName, k8sObject, 1))
+	require.Len(t, agentStatus.Components, 1)
+
+	conditions := agentStatus.Components[0].Conditions
+	require.Equal(t, expected.ready, meta.IsStatusConditionTrue(conditions, cbcontainersv1.ConditionTypeReady))
+	require.Equal(t, expected.progressing, meta.IsStatusConditionTrue(conditions, cbcontainersv1.ConditionTypeProgressing))
+	require.Equal(t, expected.degraded, meta.IsStatusConditionTrue(conditions, cbcontainersv1.ConditionTypeDegraded))
+}
+
+func TestDeploymentConditions(t *testing.T) {
+	replicas := int32(2)
+	newDeployment := func(deploymentStatus appsV1.DeploymentStatus) *appsV1.Deployment {
+		return &appsV1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Generation: 2},
+			Spec:       appsV1.DeploymentSpec{Replicas: &replicas},
+			Status:     deploymentStatus,
+		}
+	}
+
+	t.Run("With all replicas updated and available, should be ready", func(t *testing.T) {
+		requireComponentConditions(t, newDeployment(appsV1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}),
+			expectedConditions{ready: true})
+	})
+
+	t.Run("With a generation that wasn't observed yet, should be progressing", func(t *testing.T) {
+		requireComponentConditions(t, newDeployment(appsV1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}),
+			expectedConditions{progressing: true})
+	})
+
+	t.Run("With old replicas still running, should be progressing", func(t *testing.T) {
+		requireComponentConditions(t, newDeployment(appsV1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 2}),
+			expectedConditions{progressing: true})
+	})
+
+	t.Run("With a completed rollout but unavailable replicas, should be degraded", func(t *testing.T) {
+		requireComponentConditions(t, newDeployment(appsV1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 1}),
+			expectedConditions{degraded: true})
+	})
+
+	t.Run("With an exceeded progress deadline, should be degraded", func(t *testing.T) {
+		requireComponentConditions(t, newDeployment(appsV1.DeploymentStatus{
+			ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 1,
+			Conditions: []appsV1.DeploymentCondition{{Type: appsV1.DeploymentProgressing, Status: coreV1.ConditionFalse, Reason: "ProgressDeadlineExceeded"}},
+		}), expectedConditions{degraded: true})
+	})
+}
+
+func TestDaemonSetConditions(t *testing.T) {
+	newDaemonSet := func(daemonSetStatus appsV1.DaemonSetStatus) *appsV1.DaemonSet {
+		return &appsV1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Generation: 2},
+			Status:     daemonSetStatus,
+		}
+	}
+
+	t.Run("With all pods updated and available, should be ready", func(t *testing.T) {
+		requireComponentConditions(t, newDaemonSet(appsV1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3}),
+			expectedConditions{ready: true})
+	})
+
+	t.Run("With pods still being updated, should be progressing", func(t *testing.T) {
+		requireComponentConditions(t, newDaemonSet(appsV1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 1, NumberAvailable: 3}),
+			expectedConditions{progressing: true})
+	})
+
+	t.Run("With a completed rollout but unavailable pods, should be degraded", func(t *testing.T) {
+		requireComponentConditions(t, newDaemonSet(appsV1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 2}),
+			expectedConditions{degraded: true})
+	})
+}
+
+func TestSetComponentStatusWithUnexpectedObjectShouldReturnError(t *testing.T) {
+	require.Error(t, status.SetComponentStatus(&cbcontainersv1.CBContainersAgentStatus{}, componentName, &coreV1.Service{}, 1))
+}
+
+func TestSetComponentStatusShouldKeepTransitionTimeWhenUnchanged(t *testing.T) {
+	deployment := &appsV1.Deployment{Status: appsV1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}}
+	agentStatus := &cbcontainersv1.CBContainersAgentStatus{}
+
+	require.NoError(t, status.SetComponentStatus(agentStatus, componentName, deployment, 1))
+	before := agentStatus.DeepCopy()
+	require.NoError(t, status.SetComponentStatus(agentStatus, componentName, deployment, 1))
+	require.Equal(t, before, agentStatus)
+}
+
+func TestComponentConditionsShouldHaveTheAgentGeneration(t *testing.T) {
+	agentStatus := &cbcontainersv1.CBContainersAgentStatus{}
+	require.NoError(t, status.SetComponentStatus(agentStatus, componentName, &appsV1.DaemonSet{}, 4))
+	status.SetComponentPaused(agentStatus, componentName, false, 4)
+	status.SetComponentPodTemplatePatchCondition(agentStatus, componentName, true, nil, 4)
+
+	require.NotEmpty(t, agentStatus.Components[0].Conditions)
+	for _, condition := range agentStatus.Components[0].Conditions {
+		require.Equal(t, int64(4), condition.ObservedGeneration, condition.Type)
+	}
+}
+
+func TestSetAgentConditions(t *testing.T) {
+	componentStatus := func(name string, conditionType string) cbcontainersv1.CBContainersComponentStatus {
+		return cbcontainersv1.CBContainersComponentStatus{
+			Name:       name,
+			Conditions: []metav1.Condition{{Type: conditionType, Status: metav1.ConditionTrue}},
+		}
+	}
+
+	t.Run("With all components ready, agent should be ready", func(t *testing.T) {
+		agentStatus := &cbcontainersv1.CBContainersAgentStatus{Components: []cbcontainersv1.CBContainersComponentStatus{
+			componentStatus("a", cbcontainersv1.ConditionTypeReady),
+			componentStatus("b", cbcontainersv1.ConditionTypeReady),
+		}}
+		status.SetAgentConditions(agentStatus, 3)
+
+		require.True(t, meta.IsStatusConditionTrue(agentStatus.Conditions, cbcontainersv1.ConditionTypeReady))
+		require.True(t, meta.IsStatusConditionFalse(agentStatus.Conditions, cbcontainersv1.ConditionTypeProgressing))
+		require.True(t, meta.IsStatusConditionFalse(agentStatus.Conditions, cbcontainersv1.ConditionTypeDegraded))
+		require.Equal(t, int64(3), meta.FindStatusCondition(agentStatus.Conditions, cbcontainersv1.ConditionTypeReady).ObservedGeneration)
+	})
+
+	t.Run("Without reconciled components, agent should not be ready", func(t *testing.T) {
+		agentStatus := &cbcontainersv1.CBContainersAgentStatus{}
+		status.SetAgentConditions(agentStatus, 1)
+
+		readyCondition := meta.FindStatusCondition(agentStatus.Conditions, cbcontainersv1.ConditionTypeReady)
+		require.Equal(t, metav1.ConditionFalse, readyCondition.Status)
+		require.Equal(t, status.ReasonNoComponentsReconciled, readyCondition.Reason)
+	})
+
+	t.Run("With a degraded component, agent should be degraded and not ready", func(t *testing.T) {
+		agentStatus := &cbcontainersv1.CBContainersAgentStatus{Components: []cbcontainersv1.CBContainersComponentStatus{
+			componentStatus("a", cbcontainersv1.ConditionTypeReady),
+			componentStatus("b", cbcontainersv1.ConditionTypeDegraded),
+		}}
+		status.SetAgentConditions(agentStatus, 1)
+
+		require.True(t, meta.IsStatusConditionFalse(agentStatus.Conditions, cbcontainersv1.ConditionTypeReady))
+		require.True(t, meta.IsStatusConditionTrue(agentStatus.Conditions, cbcontainersv1.ConditionTypeDegraded))
+		require.Contains(t, meta.FindStatusCondition(agentStatus.Conditions, cbcontainersv1.ConditionTypeDegraded).Message, "b")
+	})
+}
+
+func TestRemoveComponentStatus(t *testing.T) {
+	agentStatus := &cbcontainersv1.CBContainersAgentStatus{Components: []cbcontainersv1.CBContainersComponentStatus{{Name: "a"}, {Name: "b"}}}
+	status.RemoveComponentStatus(agentStatus, "a")
+	status.RemoveComponentStatus(agentStatus, "not-existing")
+
+	require.Equal(t, []cbcontainersv1.CBContainersComponentStatus{{Name: "b"}}, agentStatus.Components)
+}
+
+func TestSetAgentPausedCondition(t *testing.T) {
+	t.Run("With nothing paused, agent should not be paused", func(t *testing.T) {
+		agentStatus := &cbcontainersv1.CBContainersAgentStatus{}
+		status.SetComponentPaused(agentStatus, "a", false, 1)
+		status.SetAgentPausedCondition(agentStatus, false, 2)
+
+		require.True(t, meta.IsStatusConditionFalse(agentStatus.Conditions, cbcontainersv1.ConditionTypePaused))
+		require.Equal(t, int64(2), meta.FindStatusCondition(agentStatus.Conditions, cbcontainersv1.ConditionTypePaused).ObservedGeneration)
+	})
+
+	t.Run("With a paused component, agent should be paused", func(t *testing.T) {
+		agentStatus := &cbcontainersv1.CBContainersAgentStatus{}
+		status.SetComponentPaused(agentStatus, "reconciled", false, 1)
+		status.SetComponentPaused(agentStatus, "edited", true, 1)
+		status.SetAgentPausedCondition(agentStatus, false, 1)
+
+		pausedCondition := meta.FindStatusCondition(agentStatus.Conditions, cbcontainersv1.ConditionTypePaused)
+		require.Equal(t, metav1.ConditionTrue, pausedCondition.Status)
+		require.Equal(t, status.ReasonComponentsPaused, pausedCondition.Reason)
+		require.Contains(t, pausedCondition.Message, "edited")
+		require.NotContains(t, pausedCondition.Message, "reconciled")
+	})
+
+	t.Run("With the agent paused, agent should be paused", func(t *testing.T) {
+		agentStatus := &cbcontainersv1.CBContainersAgentStatus{}
+		status.SetAgentPausedCondition(agentStatus, true, 1)
+
+		pausedCondition := meta.FindStatusCondition(agentStatus.Conditions, cbcontainersv1.ConditionTypePaused)
+		require.Equal(t, metav1.ConditionTrue, pausedCondition.Status)
+		require.Equal(t, status.ReasonReconciliationPaused, pausedCondition.Reason)
+	})
+}
+
+func TestSetComponentPodTemplatePatchCondition(t *testing.T) {
+	t.Run("With an invalid patch, component and agent should be degraded", func(t *testing.T) {
+		agentStatus := &cbcontainersv1.CBContainersAgentStatus{}
+		status.SetComponentPodTemplatePatchCondition(agentStatus, "patched", true, nil, 1)
+		status.SetComponentPodTemplatePatchCondition(agentStatus, "invalid", true, fmt.Errorf("the service account can't be changed"), 1)
+		status.SetAgentConditions(agentStatus, 1)
+
+		invalidCondition := meta.FindStatusCondition(agentStatus.Components[1].Conditions, cbcontainersv1.ConditionTypePodTemplatePatchInvalid)
+		require.Equal(t, metav1.ConditionTrue, invalidCondition.Status)
+		require.Equal(t, status.ReasonInvalidPodTemplatePatch, invalidCondition.Reason)
+		require.True(t, meta.IsStatusConditionFalse(agentStatus.Components[0].Conditions, cbcontainersv1.ConditionTypePodTemplatePatchInvalid))
+
+		degradedCondition := meta.FindStatusCondition(agentStatus.Conditions, cbcontainersv1.ConditionTypeDegraded)
+		require.Equal(t, metav1.ConditionTrue, degradedCondition.Status)
+		require.Contains(t, degradedCondition.Message, "invalid")
+		require.NotContains(t, degradedCondition.Message, "patched")
+	})
+
+	t.Run("Without a patch, should remove the condition", func(t *testing.T) {
+		agentStatus := &cbcontainersv1.CBContainersAgentStatus{}
+		status.SetComponentPodTemplatePatchCondition(agentStatus, "a", true, fmt.Errorf("invalid"), 1)
+		status.SetComponentPodTemplatePatchCondition(agentStatus, "a", false, nil, 1)
+
+		require.Nil(t, meta.FindStatusCondition(agentStatus.Components[0].Conditions, cbcontainersv1.ConditionTypePodTemplatePatchInvalid))
+	})
+}
@@ -0,0 +1,37 @@
+package certificates
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cloudflare/cfssl/helpers"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
+)
+
+// GetCertificatesExpiry returns the time at which the first of the CA and the signed certificate expires.
+func GetCertificatesExpiry(tlsSecretValues models.TlsSecretValues) (time.Time, error) {
+	caCert, err := helpers.ParseCertificatePEM(tlsSecretValues.CaCert)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed parsing the CA certificate: %w", err)
+	}
+
+	signedCertNotAfter, err := GetSignedCertificateExpiry(tlsSecretValues)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if signedCertNotAfter.Before(caCert.NotAfter) {
+		return signedCertNotAfter, nil
+	}
+	return caCert.NotAfter, nil
+}
+
+// GetSignedCertificateExpiry returns the time at which the signed certificate expires.
+func GetSignedCertificateExpiry(tlsSecretValues models.TlsSecretValues) (time.Time, error) {
+	signedCert, err := helpers.ParseCertificatePEM(tlsSecretValues.SignedCert)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed parsing the signed certificate: %w", err)
+	}
+
+	return signedCert.NotAfter, nil
+}
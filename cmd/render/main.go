@@ -0,0 +1,218 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// render prints the manifests that the operator produces for a CBContainersAgent resource, without a cluster.
+//
+// Usage:
+//
+//	render --agent-file agent.yaml --kubernetes-version v1.29.1 > manifests.yaml
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-logr/logr"
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/capabilities"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
+	"github.com/vmware/cbcontainers-operator/controllers"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	placeholderValue = "PLACEHOLDER"
+	stdinFileName    = "-"
+)
+
+var placeholderDockerConfig = []byte(`{"auths":{}}`)
+
+type renderOptions struct {
+	agentFile          string
+	kubernetesVersion  string
+	namespace          string
+	clusterIdentifier  string
+	registrySecretFile string
+	tlsCaCertFile      string
+	tlsCaKeyFile       string
+	tlsCertFile        string
+	tlsKeyFile         string
+}
+
+func main() {
+	options := renderOptions{}
+	flag.StringVar(&options.agentFile, "agent-file", stdinFileName, "The CBContainersAgent resource YAML file to render, or - to read it from stdin.")
+	flag.StringVar(&options.kubernetesVersion, "kubernetes-version", "v1.29.1", "The Kubernetes version of the cluster, which decides the API versions and the fields of the rendered objects.")
+	flag.StringVar(&options.namespace, "namespace", common.DataPlaneNamespaceName, "The namespace in which the operator and the agent are deployed.")
+	flag.StringVar(&options.clusterIdentifier, "cluster-identifier", placeholderValue, "The cluster identifier, which is the uid of the default namespace.")
+	flag.StringVar(&options.registrySecretFile, "registry-secret-file", "", "A docker config JSON file for the default registry secret. A placeholder is used when not set.")
+	flag.StringVar(&options.tlsCaCertFile, "tls-ca-cert-file", "", "The enforcer CA certificate PEM file. A placeholder is used when not set.")
+	flag.StringVar(&options.tlsCaKeyFile, "tls-ca-key-file", "", "The enforcer CA key PEM file. A placeholder is used when not set.")
+	flag.StringVar(&options.tlsCertFile, "tls-cert-file", "", "The enforcer certificate PEM file, signed by the CA. A placeholder is used when not set.")
+	flag.StringVar(&options.tlsKeyFile, "tls-key-file", "", "The enforcer key PEM file. A placeholder is used when not set.")
+	flag.Parse()
+
+	if err := render(options, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "failed rendering the agent manifests: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func render(options renderOptions, out io.Writer) error {
+	agent, err := readAgent(options.agentFile)
+	if err != nil {
+		return err
+	}
+
+	if err := controllers.SetAgentDefaults(&agent.Spec); err != nil {
+		return fmt.Errorf("failed to set defaults to the agent: %v", err)
+	}
+
+	var registrySecret *models.RegistrySecretValues
+	if agent.Spec.Components.Settings.ShouldCreateDefaultImagePullSecrets() {
+		dockerConfig, err := readFileOrPlaceholder(options.registrySecretFile, placeholderDockerConfig)
+		if err != nil {
+			return err
+		}
+		registrySecret = &models.RegistrySecretValues{
+			Type: coreV1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{coreV1.DockerConfigJsonKey: dockerConfig},
+		}
+	}
+
+	tlsSecretValues, err := readTlsSecretValues(options)
+	if err != nil {
+		return err
+	}
+
+	apiCapabilities, err := capabilities.ForVersion(options.kubernetesVersion)
+	if err != nil {
+		return err
+	}
+
+	stateApplier := state.NewStateApplier(nil, nil, nil, capabilities.NewStaticProvider(apiCapabilities), options.namespace, options.clusterIdentifier,
+		staticTlsSecretsValuesCreator(tlsSecretValues), &record.FakeRecorder{}, logr.Discard())
+	k8sObjects, err := stateApplier.RenderDesiredState(context.Background(), agent, registrySecret)
+	if err != nil {
+		return err
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return err
+	}
+
+	for _, k8sObject := range k8sObjects {
+		manifest, err := toManifest(k8sObject, scheme)
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(out, "---\n%s", manifest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readAgent(agentFile string) (*cbcontainersv1.CBContainersAgent, error) {
+	var rawAgent []byte
+	var err error
+	if agentFile == stdinFileName {
+		rawAgent, err = io.ReadAll(os.Stdin)
+	} else {
+		rawAgent, err = os.ReadFile(agentFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed reading the agent file: %v", err)
+	}
+
+	agent := &cbcontainersv1.CBContainersAgent{}
+	if err := yaml.Unmarshal(rawAgent, agent); err != nil {
+		return nil, fmt.Errorf("failed parsing the agent file: %v", err)
+	}
+
+	if agent.Kind != "CBContainersAgent" {
+		return nil, fmt.Errorf("expected a CBContainersAgent resource, got kind %q", agent.Kind)
+	}
+
+	return agent, nil
+}
+
+func readTlsSecretValues(options renderOptions) (models.TlsSecretValues, error) {
+	values := make([][]byte, 0, 4)
+	for _, fileName := range []string{options.tlsCaCertFile, options.tlsCaKeyFile, options.tlsCertFile, options.tlsKeyFile} {
+		value, err := readFileOrPlaceholder(fileName, []byte(placeholderValue))
+		if err != nil {
+			return models.TlsSecretValues{}, err
+		}
+		values = append(values, value)
+	}
+
+	return models.NewTlsSecretValues(values[0], values[1], values[2], values[3]), nil
+}
+
+func readFileOrPlaceholder(fileName string, placeholder []byte) ([]byte, error) {
+	if fileName == "" {
+		return placeholder, nil
+	}
+
+	value, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading %v: %v", fileName, err)
+	}
+
+	return value, nil
+}
+
+// toManifest marshals the k8s object to YAML, without the fields that are set by the cluster.
+func toManifest(k8sObject client.Object, scheme *runtime.Scheme) ([]byte, error) {
+	gvk, err := apiutil.GVKForObject(k8sObject, scheme)
+	if err != nil {
+		return nil, err
+	}
+	k8sObject.GetObjectKind().SetGroupVersionKind(gvk)
+
+	unstructuredObject, err := runtime.DefaultUnstructuredConverter.ToUnstructured(k8sObject)
+	if err != nil {
+		return nil, err
+	}
+	unstructured.RemoveNestedField(unstructuredObject, "status")
+	unstructured.RemoveNestedField(unstructuredObject, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(unstructuredObject, "spec", "template", "metadata", "creationTimestamp")
+
+	return yaml.Marshal(unstructuredObject)
+}
+
+type staticTlsSecretsValuesCreator models.TlsSecretValues
+
+func (values staticTlsSecretsValuesCreator) CreateTlsSecretsValues(_ types.NamespacedName) (models.TlsSecretValues, error) {
+	return models.TlsSecretValues(values), nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	admissionsV1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const testAgent = `apiVersion: operator.containers.carbonblack.io/v1
+kind: CBContainersAgent
+metadata:
+  name: cbcontainers-agent
+spec:
+  account: account
+  clusterName: cluster
+  version: 3.0.0
+  gateways:
+    apiGateway:
+      host: api.example.com
+    coreEventsGateway:
+      host: core.example.com
+    hardeningEventsGateway:
+      host: hardening.example.com
+    runtimeEventsGateway:
+      host: runtime.example.com
+`
+
+func renderTestAgent(t *testing.T, agent string) (*bytes.Buffer, error) {
+	agentFile := filepath.Join(t.TempDir(), "agent.yaml")
+	require.NoError(t, os.WriteFile(agentFile, []byte(agent), 0600))
+
+	out := &bytes.Buffer{}
+	return out, render(renderOptions{agentFile: agentFile, kubernetesVersion: "v1.29.1", namespace: "dataplane", clusterIdentifier: "cluster-id"}, out)
+}
+
+func TestRenderPrintsAllComponents(t *testing.T) {
+	out, err := renderTestAgent(t, testAgent)
+	require.NoError(t, err)
+
+	var kinds []string
+	for _, document := range bytes.Split(out.Bytes(), []byte("---\n"))[1:] {
+		manifest := map[string]interface{}{}
+		require.NoError(t, yaml.Unmarshal(document, &manifest))
+		require.NotContains(t, manifest, "status")
+		require.NotContains(t, manifest["metadata"], "creationTimestamp")
+		kinds = append(kinds, manifest["kind"].(string))
+	}
+
+	require.Contains(t, kinds, "ConfigMap")
+	require.Contains(t, kinds, "ValidatingWebhookConfiguration")
+	require.Contains(t, kinds, "DaemonSet")
+}
+
+func TestRenderWithOtherKindShouldReturnError(t *testing.T) {
+	_, err := renderTestAgent(t, "apiVersion: v1\nkind: ConfigMap\n")
+	require.Error(t, err)
+}
+
+func TestRenderWithCertManagerPrintsCertificateInsteadOfTlsSecret(t *testing.T) {
+	out, err := renderTestAgent(t, testAgent+`  components:
+    basic:
+      enforcer:
+        certManager:
+          issuerRef:
+            name: issuer
+`)
+	require.NoError(t, err)
+
+	manifests := renderedManifests(t, out)
+	require.Contains(t, manifests, "Certificate/cbcontainers-hardening-enforcer")
+	require.NotContains(t, manifests, "Secret/cbcontainers-hardening-enforcer-tls")
+	webhookConfiguration := &admissionsV1.ValidatingWebhookConfiguration{}
+	require.NoError(t, yaml.Unmarshal(manifests["ValidatingWebhookConfiguration/cbcontainers-hardening-enforcer"], webhookConfiguration))
+	require.Equal(t, "dataplane/cbcontainers-hardening-enforcer", webhookConfiguration.Annotations["cert-manager.io/inject-ca-from"])
+}
+
+// renderedManifests returns the rendered documents by their kind and name.
+func renderedManifests(t *testing.T, out *bytes.Buffer) map[string][]byte {
+	manifests := map[string][]byte{}
+	for _, document := range bytes.Split(out.Bytes(), []byte("---\n"))[1:] {
+		manifest := &metav1.PartialObjectMetadata{}
+		require.NoError(t, yaml.Unmarshal(document, manifest))
+		manifests[manifest.Kind+"/"+manifest.Name] = document
+	}
+	return manifests
+}
@@ -4,6 +4,11 @@ import (
 	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
 )
 
+// SetAgentDefaults sets the same defaults to the agent spec as the controller does before reconciling it.
+func SetAgentDefaults(agentSpec *cbcontainersv1.CBContainersAgentSpec) error {
+	return (&CBContainersAgentController{}).setAgentDefaults(agentSpec)
+}
+
 func (r *CBContainersAgentController) setAgentDefaults(agentSpec *cbcontainersv1.CBContainersAgentSpec) error {
 	if agentSpec.AccessTokenSecretName == "" {
 		agentSpec.AccessTokenSecretName = defaultAccessToken
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/events"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment"
+	applymentOptions "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
+	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	planConfigMapKey = "plan.json"
+)
+
+// agentPlan is the content of the plan ConfigMap.
+type agentPlan struct {
+	// Generation is the generation of the CBContainersAgent resource that was planned.
+	Generation int64                     `json:"generation"`
+	Changes    []applyment.PlannedChange `json:"changes"`
+}
+
+func isPlanMode(cbContainersAgent *cbcontainersv1.CBContainersAgent) bool {
+	return cbContainersAgent.Annotations[cbcontainersv1.PlanAnnotation] == "true"
+}
+
+// publishPlan plans the desired state, without applying it, and publishes the planned changes to the plan ConfigMap.
+func (r *CBContainersAgentController) publishPlan(ctx context.Context, cbContainersAgent *cbcontainersv1.CBContainersAgent, registrySecret *models.RegistrySecretValues, setOwner applymentOptions.OwnerSetter) error {
+	changes, err := r.StateApplier.PlanDesiredState(ctx, cbContainersAgent, registrySecret, setOwner)
+	if err != nil {
+		r.Recorder.Event(cbContainersAgent, corev1.EventTypeWarning, events.ReasonPlanFailed, err.Error())
+		return err
+	}
+
+	if changes == nil {
+		changes = []applyment.PlannedChange{}
+	}
+	rawPlan, err := json.MarshalIndent(agentPlan{Generation: cbContainersAgent.Generation, Changes: changes}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed marshaling the plan: %w", err)
+	}
+
+	planConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: commonState.PlanConfigMapName, Namespace: r.Namespace}}
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, planConfigMap, func() error {
+		planConfigMap.Data = map[string]string{planConfigMapKey: string(rawPlan)}
+		return setOwner(planConfigMap)
+	})
+	if err != nil {
+		return fmt.Errorf("failed publishing the plan: %w", err)
+	}
+
+	if result != controllerutil.OperationResultNone {
+		r.Recorder.Eventf(cbContainersAgent, corev1.EventTypeNormal, events.ReasonPlanPublished, "Published a plan of %d changes to ConfigMap %s/%s", len(changes), r.Namespace, commonState.PlanConfigMapName)
+	}
+
+	return nil
+}
+
+// deletePlan deletes the plan ConfigMap that was left from the last time the agent was in plan mode, if there is one.
+func (r *CBContainersAgentController) deletePlan(ctx context.Context) error {
+	planConfigMap := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: commonState.PlanConfigMapName, Namespace: r.Namespace}, planConfigMap); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed getting the plan ConfigMap: %w", err)
+	}
+
+	if err := r.Client.Delete(ctx, planConfigMap); err != nil && !k8sErrors.IsNotFound(err) {
+		return fmt.Errorf("failed deleting the plan ConfigMap: %w", err)
+	}
+
+	return nil
+}
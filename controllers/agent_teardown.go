@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/events"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// teardownRetryTime is used while waiting for the agent components to be deleted and for the nodes to be cleaned up
+	teardownRetryTime = 5 * time.Second
+)
+
+func isBeingDeleted(cbContainersAgent *cbcontainersv1.CBContainersAgent) bool {
+	return !cbContainersAgent.ObjectMeta.DeletionTimestamp.IsZero()
+}
+
+// isDeletedInForeground returns whether the agent was deleted with the foreground propagation policy, in which case the
+// garbage collector deletes the components that the agent owns without waiting for the teardown.
+func isDeletedInForeground(cbContainersAgent *cbcontainersv1.CBContainersAgent) bool {
+	return controllerutil.ContainsFinalizer(cbContainersAgent, metav1.FinalizerDeleteDependents)
+}
+
+// addTeardownFinalizer adds the teardown finalizer to the agent, so it isn't removed before its components are torn down.
+func (r *CBContainersAgentController) addTeardownFinalizer(ctx context.Context, cbContainersAgent *cbcontainersv1.CBContainersAgent) error {
+	if controllerutil.ContainsFinalizer(cbContainersAgent, cbcontainersv1.TeardownFinalizer) {
+		return nil
+	}
+
+	patch := client.MergeFromWithOptions(cbContainersAgent.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	controllerutil.AddFinalizer(cbContainersAgent, cbcontainersv1.TeardownFinalizer)
+	if err := r.Client.Patch(ctx, cbContainersAgent, patch); err != nil {
+		return fmt.Errorf("failed adding the teardown finalizer: %w", err)
+	}
+
+	return nil
+}
+
+// teardown tears down the agent components of a deleted agent and removes the teardown finalizer once it is done.
+func (r *CBContainersAgentController) teardown(ctx context.Context, cbContainersAgent *cbcontainersv1.CBContainersAgent) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(cbContainersAgent, cbcontainersv1.TeardownFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	// The teardown still deletes the components and cleans up the nodes, but the webhooks may outlive the enforcer, and
+	// the nodes whose sensor pods were already deleted aren't cleaned up
+	if isDeletedInForeground(cbContainersAgent) {
+		r.Recorder.Event(cbContainersAgent, corev1.EventTypeWarning, events.ReasonForegroundDeletion,
+			"The agent was deleted with the foreground propagation policy, so its components are deleted in parallel with the teardown and not in order")
+	}
+
+	originalStatus := cbContainersAgent.Status.DeepCopy()
+	done, err := r.StateApplier.TeardownState(ctx, cbContainersAgent)
+	if err != nil {
+		r.Recorder.Event(cbContainersAgent, corev1.EventTypeWarning, events.ReasonTeardownFailed, err.Error())
+		return ctrl.Result{}, err
+	}
+
+	// The status keeps track of the nodes that should be cleaned up, as the pods that ran on them are gone after the first pass
+	if !reflect.DeepEqual(originalStatus, &cbContainersAgent.Status) {
+		if err := r.Client.Status().Update(ctx, cbContainersAgent); err != nil {
+			return r.handleStatusUpdateError(err)
+		}
+	}
+
+	if !done {
+		r.Log.Info("Agent teardown is in progress")
+		return ctrl.Result{RequeueAfter: teardownRetryTime}, nil
+	}
+
+	patch := client.MergeFromWithOptions(cbContainersAgent.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	controllerutil.RemoveFinalizer(cbContainersAgent, cbcontainersv1.TeardownFinalizer)
+	if err := r.Client.Patch(ctx, cbContainersAgent, patch); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed removing the teardown finalizer: %w", err)
+	}
+
+	r.Log.Info("Agent teardown is complete")
+	return ctrl.Result{}, nil
+}
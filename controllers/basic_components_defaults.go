@@ -1,6 +1,11 @@
 package controllers
 
-import cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+import (
+	"time"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 func (r *CBContainersAgentController) setBasicComponentsDefaults(basic *cbcontainersv1.CBContainersBasicSpec) error {
 	if err := r.setMonitorDefaults(&basic.Monitor); err != nil {
@@ -70,6 +75,9 @@ func (r *CBContainersAgentController) setEnforcerDefaults(enforcer *cbcontainers
 
 	setDefaultPrometheus(&enforcer.Prometheus)
 
+	setDefaultAutoscaling(&enforcer.Autoscaling)
+	setDefaultPodDisruptionBudget(&enforcer.PodDisruptionBudget)
+
 	setDefaultImage(&enforcer.Image, "cbartifactory/guardrails-enforcer")
 
 	if err := setDefaultResourceRequirements(&enforcer.Resources, "256Mi", "100m", "512Mi", "200m"); err != nil {
@@ -90,6 +98,10 @@ func (r *CBContainersAgentController) setEnforcerDefaults(enforcer *cbcontainers
 		enforcer.EnableEnforcementFeature = &trueRef
 	}
 
+	if enforcer.TlsRenewBefore == nil {
+		enforcer.TlsRenewBefore = &metav1.Duration{Duration: 720 * time.Hour}
+	}
+
 	return nil
 }
 
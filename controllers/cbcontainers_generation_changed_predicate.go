@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -22,13 +24,39 @@ func NewCBContainersGenerationChangedPredicate(statePredicate StateGenerationCha
 }
 
 func (p CBContainersGenerationChangedPredicate) Create(e event.CreateEvent) bool {
-	return p.statePredicate.ShouldProcessEvent(e.Object) || p.GenerationChangedPredicate.Create(e)
+	return isWatchedWithOwnPredicates(e.Object) || p.statePredicate.ShouldProcessEvent(e.Object) || p.GenerationChangedPredicate.Create(e)
 }
 
 func (p CBContainersGenerationChangedPredicate) Update(e event.UpdateEvent) bool {
-	return p.statePredicate.ShouldProcessEvent(e.ObjectNew) || p.GenerationChangedPredicate.Update(e)
+	return isWatchedWithOwnPredicates(e.ObjectNew) || p.statePredicate.ShouldProcessEvent(e.ObjectNew) || p.GenerationChangedPredicate.Update(e) || planModeChanged(e)
 }
 
 func (p CBContainersGenerationChangedPredicate) Delete(e event.DeleteEvent) bool {
-	return p.statePredicate.ShouldProcessEvent(e.Object) || p.GenerationChangedPredicate.Delete(e)
+	return isWatchedWithOwnPredicates(e.Object) || p.statePredicate.ShouldProcessEvent(e.Object) || p.GenerationChangedPredicate.Delete(e)
+}
+
+// isWatchedWithOwnPredicates returns true for the objects whose events are filtered by the predicates of their watches.
+func isWatchedWithOwnPredicates(obj client.Object) bool {
+	return isNode(obj) || isUncontrolledConfig(obj)
+}
+
+// isNode returns true for the nodes, which don't have a generation. Their events are filtered by the predicates of the node watch.
+func isNode(obj client.Object) bool {
+	_, ok := obj.(*corev1.Node)
+	return ok
+}
+
+// planModeChanged returns true when the plan annotation of the CBContainersAgent resource was changed,
+// which doesn't change its generation.
+func planModeChanged(e event.UpdateEvent) bool {
+	oldAgent, ok := e.ObjectOld.(*cbcontainersv1.CBContainersAgent)
+	if !ok {
+		return false
+	}
+	newAgent, ok := e.ObjectNew.(*cbcontainersv1.CBContainersAgent)
+	if !ok {
+		return false
+	}
+
+	return isPlanMode(oldAgent) != isPlanMode(newAgent)
 }
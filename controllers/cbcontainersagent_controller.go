@@ -18,21 +18,35 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"reflect"
 	"time"
 
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/adapters"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/capabilities"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
 	appsV1 "k8s.io/api/apps/v1"
+	autoscalingV2 "k8s.io/api/autoscaling/v2"
+	batchV1 "k8s.io/api/batch/v1"
+	policyV1 "k8s.io/api/policy/v1"
 
 	"github.com/go-logr/logr"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/events"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/models"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment"
 	applymentOptions "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/status"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
 )
@@ -41,10 +55,19 @@ const (
 	// conflictRetryTime should be used when hitting 409 status from the API Server on CR updates
 	// for this case it is better to use a fixed requeue duration instead of the default exponential backoff to prevent multiple concurrent changes from holding back the reconcile queue without reason
 	conflictRetryTime = 3 * time.Second
+	// certificatesIssuanceRetryTime is used while waiting for cert-manager to issue the enforcer certificates, in case the
+	// event of the issued secret is missed
+	certificatesIssuanceRetryTime = time.Minute
+	// certManagerInstallationRetryTime is used while the cert-manager CRDs are not installed, as no event is watched for
+	// their installation
+	certManagerInstallationRetryTime = 5 * time.Minute
 )
 
 type StateApplier interface {
-	ApplyDesiredState(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec, secret *models.RegistrySecretValues, setOwner applymentOptions.OwnerSetter) (bool, error)
+	ApplyDesiredState(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, secret *models.RegistrySecretValues, setOwner applymentOptions.OwnerSetter) (bool, error)
+	PlanDesiredState(ctx context.Context, agent *cbcontainersv1.CBContainersAgent, secret *models.RegistrySecretValues, setOwner applymentOptions.OwnerSetter) ([]applyment.PlannedChange, error)
+	ReportState(ctx context.Context, agent *cbcontainersv1.CBContainersAgent) error
+	TeardownState(ctx context.Context, agent *cbcontainersv1.CBContainersAgent) (bool, error)
 	ShouldProcessEvent(client.Object) bool
 }
 
@@ -56,16 +79,25 @@ type AccessTokenProvider interface {
 	GetCBAccessToken(ctx context.Context, cbContainersCluster *cbcontainersv1.CBContainersAgent, namespace string) (string, error)
 }
 
+type RootCAsBundleProvider interface {
+	GetRootCAsBundle(ctx context.Context, cbContainersCluster *cbcontainersv1.CBContainersAgent, namespace string) ([]byte, error)
+}
+
 type CBContainersAgentController struct {
 	client.Client
 	Log              logr.Logger
 	Scheme           *runtime.Scheme
 	ClusterProcessor AgentProcessor
 	StateApplier     StateApplier
-	K8sVersion       string
+	// CapabilitiesProvider provides the API groups and the fields that the Kubernetes API server supports
+	CapabilitiesProvider capabilities.Provider
 	// Namespace is the kubernetes namespace for all agent components
 	Namespace           string
 	AccessTokenProvider AccessTokenProvider
+	// RootCAsBundleProvider merges the inline root CAs bundle with the bundle that the agent refers to
+	RootCAsBundleProvider RootCAsBundleProvider
+	// Recorder records events on the CBContainersAgent resource
+	Recorder record.EventRecorder
 }
 
 func (r *CBContainersAgentController) getContainersAgentObject(ctx context.Context) (*cbcontainersv1.CBContainersAgent, error) {
@@ -97,11 +129,17 @@ func (r *CBContainersAgentController) getContainersAgentObject(ctx context.Conte
 // +kubebuilder:rbac:groups=scheduling.k8s.io,resources=priorityclasses,verbs=create;list;watch
 // +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources={validatingwebhookconfigurations,mutatingwebhookconfigurations},verbs=delete;get;patch;update,resourceNames=cbcontainers-hardening-enforcer
 // +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources={validatingwebhookconfigurations,mutatingwebhookconfigurations},verbs=create;list;watch
-// +kubebuilder:rbac:groups={core},resources={nodes},verbs=list
+// +kubebuilder:rbac:groups={core},resources={nodes},verbs=get;list;watch
 // +kubebuilder:rbac:groups={core},resources={namespaces},verbs=get
 // +kubebuilder:rbac:groups={policy},resources={podsecuritypolicies},verbs=use,resourceNames={cbcontainers-manager-psp}
 // +kubebuilder:rbac:groups={apps,core},resources={deployments,services,daemonsets},namespace=cbcontainers-dataplane,verbs=get;list;watch;create;update;patch;delete;deletecollection
 // +kubebuilder:rbac:groups=core,resources={configmaps,secrets},namespace=cbcontainers-dataplane,verbs=get;list;watch;create;update;patch;delete;deletecollection
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,namespace=cbcontainers-dataplane,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,namespace=cbcontainers-dataplane,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,namespace=cbcontainers-dataplane,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,namespace=cbcontainers-dataplane,verbs=list
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,namespace=cbcontainers-dataplane,verbs=get;list;watch;create;update;patch;delete
 
 func (r *CBContainersAgentController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	r.Log.Info("\n\n")
@@ -119,6 +157,12 @@ func (r *CBContainersAgentController) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.Result{}, nil
 	}
 
+	if !isBeingDeleted(cbContainersAgent) {
+		if err := r.addTeardownFinalizer(ctx, cbContainersAgent); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	if err := r.setAgentDefaults(&cbContainersAgent.Spec); err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to set defaults to cluster CR: %v", err)
 	}
@@ -127,72 +171,246 @@ func (r *CBContainersAgentController) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.SetControllerReference(cbContainersAgent, controlledResource, r.Scheme)
 	}
 
+	if isBeingDeleted(cbContainersAgent) {
+		r.Log.Info("Tearing down the agent, as it is being deleted")
+		return r.teardown(ctx, cbContainersAgent)
+	}
+
+	if isPaused(cbContainersAgent) {
+		r.Log.Info("Skipping applying desired state, as the reconciliation of the agent is paused")
+		originalStatus := cbContainersAgent.Status.DeepCopy()
+		if err := r.StateApplier.ReportState(ctx, cbContainersAgent); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := r.updateCRStatus(ctx, cbContainersAgent, originalStatus, false); err != nil {
+			return r.handleStatusUpdateError(err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	originalStatus := cbContainersAgent.Status.DeepCopy()
+	missingSecrets, err := r.findMissingSecrets(ctx, &cbContainersAgent.Spec)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	status.SetAgentSecretsMissingCondition(&cbContainersAgent.Status, missingSecrets, cbContainersAgent.ObjectMeta.Generation)
+	if len(missingSecrets) > 0 {
+		r.Log.Info("Some of the referenced secrets are missing", "secrets", missingSecrets)
+	}
+
+	// Nothing can be applied without the access token, so the agent is reconciled again once the secret is created
+	if containsName(missingSecrets, cbContainersAgent.Spec.AccessTokenSecretName) {
+		message := fmt.Sprintf("The access token secret `%v` doesn't exist in the `%v` namespace", cbContainersAgent.Spec.AccessTokenSecretName, r.Namespace)
+		r.Recorder.Event(cbContainersAgent, corev1.EventTypeWarning, events.ReasonAccessTokenInvalid, message)
+		if !reflect.DeepEqual(originalStatus, &cbContainersAgent.Status) {
+			if err := r.Client.Status().Update(ctx, cbContainersAgent); err != nil {
+				return r.handleStatusUpdateError(err)
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
 	accessToken, err := r.AccessTokenProvider.GetCBAccessToken(ctx, cbContainersAgent, r.Namespace)
 	if err != nil {
+		r.Recorder.Event(cbContainersAgent, corev1.EventTypeWarning, events.ReasonAccessTokenInvalid, err.Error())
 		return ctrl.Result{}, err
 	}
 	if accessToken == "" {
+		r.Recorder.Event(cbContainersAgent, corev1.EventTypeWarning, events.ReasonAccessTokenInvalid, "CB access token has empty value")
 		return ctrl.Result{}, fmt.Errorf("CB access token has empty value, cannot continue")
 	}
 
+	// The merged bundle is used by the operator's calls to the backend and is propagated to the components through the
+	// dataplane ConfigMap, so it's set on a copy of the agent that is only used to build the desired state, leaving the
+	// reconciled resource, whose status is updated below, as it was read
+	rootCAsBundle, err := r.RootCAsBundleProvider.GetRootCAsBundle(ctx, cbContainersAgent, r.Namespace)
+	if err != nil {
+		r.Recorder.Event(cbContainersAgent, corev1.EventTypeWarning, events.ReasonRootCAsBundleInvalid, err.Error())
+		return ctrl.Result{}, err
+	}
+	desiredAgent := cbContainersAgent.DeepCopy()
+	desiredAgent.Spec.Gateways.GatewayTLS.RootCAsBundle = rootCAsBundle
+
 	var registrySecret *models.RegistrySecretValues
 	if cbContainersAgent.Spec.Components.Settings.ShouldCreateDefaultImagePullSecrets() {
 		r.Log.Info("Getting registry secret values")
-		registrySecret, err = r.getRegistrySecretValues(ctx, cbContainersAgent, accessToken)
+		registrySecret, err = r.getRegistrySecretValues(ctx, desiredAgent, accessToken)
 		if err != nil {
+			if errors.As(err, &models.IncompatibleVersionsError{}) {
+				r.Recorder.Event(cbContainersAgent, corev1.EventTypeWarning, events.ReasonCompatibilityCheckFailed, err.Error())
+			} else {
+				r.Recorder.Event(cbContainersAgent, corev1.EventTypeWarning, events.ReasonProcessingFailed, err.Error())
+			}
 			return ctrl.Result{}, err
 		}
 	} else {
 		r.Log.Info(`Skipping default image pull secrets creation, because "spec.components.basic.createImagePullSecrets" is set to "false"`)
 	}
 
+	if isPlanMode(cbContainersAgent) {
+		r.Log.Info("Planning desired state, as the agent is in plan mode")
+		if err := r.publishPlan(ctx, desiredAgent, registrySecret, setOwner); err != nil {
+			return ctrl.Result{}, err
+		}
+		cbContainersAgent.Status = desiredAgent.Status
+		cbContainersAgent.Status.PlanPublished = true
+		if !reflect.DeepEqual(originalStatus, &cbContainersAgent.Status) {
+			if err := r.Client.Status().Update(ctx, cbContainersAgent); err != nil {
+				return r.handleStatusUpdateError(err)
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// The plan ConfigMap is looked for only if it was published, and it's marked as deleted by the status update below
+	if cbContainersAgent.Status.PlanPublished {
+		if err := r.deletePlan(ctx); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	r.Log.Info("Applying desired state")
-	stateWasChanged, err := r.StateApplier.ApplyDesiredState(ctx, &cbContainersAgent.Spec, registrySecret, setOwner)
+	stateWasChanged, err := r.StateApplier.ApplyDesiredState(ctx, desiredAgent, registrySecret, setOwner)
 	if err != nil {
+		r.Recorder.Event(cbContainersAgent, corev1.EventTypeWarning, events.ReasonApplyFailed, err.Error())
 		return ctrl.Result{}, err
 	}
+	// The state applier reports the state of the components in the status of the agent it is given
+	cbContainersAgent.Status = desiredAgent.Status
+	cbContainersAgent.Status.PlanPublished = false
 
 	r.Log.Info("Finished reconciling", "Requiring", stateWasChanged)
 
-	if err = r.updateCRStatus(ctx, cbContainersAgent, stateWasChanged); err != nil {
-		if k8sErrors.IsConflict(err) {
-			r.Log.Info("Custom resource was changed during reconciliation, scheduling another iteration to fully update status")
-			// Something changed in the CR while we were doing updates, requeue in a bit to get fresh data
-			// Note: this is the recommended approach for operator-sdk instead of retrying the Get->Update cycle within a reconciliation
-			return ctrl.Result{RequeueAfter: conflictRetryTime}, nil
-		}
-		return ctrl.Result{}, fmt.Errorf("failed to update CBContainersAgent status: %w", err)
+	if err = r.updateCRStatus(ctx, cbContainersAgent, originalStatus, stateWasChanged); err != nil {
+		return r.handleStatusUpdateError(err)
 	}
 
 	r.Log.Info("\n\n")
-	return ctrl.Result{Requeue: stateWasChanged}, nil
+	if stateWasChanged {
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: earliestRequeue(untilCertificatesRenewal(cbContainersAgent), untilCertificatesIssuance(cbContainersAgent), untilResolverScaling(cbContainersAgent))}, nil
+}
+
+// earliestRequeue returns the shortest of the durations that are set, or 0 when none of them is.
+func earliestRequeue(durations ...time.Duration) time.Duration {
+	earliest := time.Duration(0)
+	for _, duration := range durations {
+		if duration > 0 && (earliest == 0 || duration < earliest) {
+			earliest = duration
+		}
+	}
+	return earliest
+}
+
+// untilResolverScaling returns how long until the runtime resolver may be scaled to the replicas count derived from the
+// current number of nodes, as no other event triggers the reconciliation then.
+func untilResolverScaling(agent *cbcontainersv1.CBContainersAgent) time.Duration {
+	scaling := agent.Status.RuntimeResolverScaling
+	if scaling == nil {
+		return 0
+	}
+
+	desiredReplicas := components.ResolverReplicasForNodes(&agent.Spec.Components.RuntimeProtection.Resolver, int(scaling.Nodes))
+	return components.UntilResolverScaling(agent, desiredReplicas)
+}
+
+// untilCertificatesRenewal returns how long until the enforcer certificates should be renewed, as no other event
+// triggers the reconciliation then.
+func untilCertificatesRenewal(agent *cbcontainersv1.CBContainersAgent) time.Duration {
+	if agent.Status.EnforcerCertificates == nil {
+		return 0
+	}
+
+	// The certificates may be renewed by cert-manager after their renewal time, so they are checked again periodically
+	untilRenewal := time.Until(agent.Status.EnforcerCertificates.RenewalTime.Time)
+	if untilRenewal < time.Minute {
+		return time.Minute
+	}
+	return untilRenewal
+}
+
+// untilCertificatesIssuance returns how long until checking again whether cert-manager issued the enforcer certificates,
+// while they are pending.
+func untilCertificatesIssuance(agent *cbcontainersv1.CBContainersAgent) time.Duration {
+	pendingCondition := meta.FindStatusCondition(agent.Status.Conditions, cbcontainersv1.ConditionTypeEnforcerCertificatesPending)
+	if pendingCondition == nil || pendingCondition.Status != metav1.ConditionTrue {
+		return 0
+	}
+
+	if pendingCondition.Reason == status.ReasonCertManagerNotInstalled {
+		return certManagerInstallationRetryTime
+	}
+	return certificatesIssuanceRetryTime
+}
+
+func (r *CBContainersAgentController) handleStatusUpdateError(err error) (ctrl.Result, error) {
+	if k8sErrors.IsConflict(err) {
+		r.Log.Info("Custom resource was changed during reconciliation, scheduling another iteration to fully update status")
+		// Something changed in the CR while we were doing updates, requeue in a bit to get fresh data
+		// Note: this is the recommended approach for operator-sdk instead of retrying the Get->Update cycle within a reconciliation
+		return ctrl.Result{RequeueAfter: conflictRetryTime}, nil
+	}
+	return ctrl.Result{}, fmt.Errorf("failed to update CBContainersAgent status: %w", err)
 }
 
 func (r *CBContainersAgentController) getRegistrySecretValues(ctx context.Context, cbContainersCluster *cbcontainersv1.CBContainersAgent, accessToken string) (*models.RegistrySecretValues, error) {
 	return r.ClusterProcessor.Process(cbContainersCluster, accessToken)
 }
 
-func (r *CBContainersAgentController) updateCRStatus(ctx context.Context, cbContainersCluster *cbcontainersv1.CBContainersAgent, agentStateWasChanged bool) error {
+func (r *CBContainersAgentController) updateCRStatus(ctx context.Context, cbContainersCluster *cbcontainersv1.CBContainersAgent, originalStatus *cbcontainersv1.CBContainersAgentStatus, agentStateWasChanged bool) error {
 	// If we don't expect more changes (i.e. nothing changed in reality) and we haven't updated the status, we do so now.
-	if !agentStateWasChanged && cbContainersCluster.Status.ObservedGeneration < cbContainersCluster.ObjectMeta.Generation {
-		r.Log.Info("Updating CBContainersAgent status")
+	// A paused agent is not reconciled, so its generation is not observed until it is resumed.
+	if !agentStateWasChanged && !isPaused(cbContainersCluster) && cbContainersCluster.Status.ObservedGeneration < cbContainersCluster.ObjectMeta.Generation {
 		cbContainersCluster.Status.ObservedGeneration = cbContainersCluster.ObjectMeta.Generation
-		return r.Client.Status().Update(ctx, cbContainersCluster)
 	}
-	return nil
+
+	status.SetAgentConditions(&cbContainersCluster.Status, cbContainersCluster.ObjectMeta.Generation)
+	status.SetAgentPausedCondition(&cbContainersCluster.Status, isPaused(cbContainersCluster), cbContainersCluster.ObjectMeta.Generation)
+
+	// The conditions keep their transition time as long as they don't change, so there is nothing to update when the state is stable.
+	if reflect.DeepEqual(originalStatus, &cbContainersCluster.Status) {
+		return nil
+	}
+
+	r.Log.Info("Updating CBContainersAgent status")
+	return r.Client.Status().Update(ctx, cbContainersCluster)
+}
+
+// isPaused returns true when the reconciliation of the whole agent is paused.
+func isPaused(cbContainersAgent *cbcontainersv1.CBContainersAgent) bool {
+	return cbContainersAgent.Spec.Paused != nil && *cbContainersAgent.Spec.Paused
 }
 
 func (r *CBContainersAgentController) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	// The owned objects are watched in the versions that the API server serves when the operator starts
+	apiCapabilities := r.CapabilitiesProvider.Capabilities()
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
 		For(&cbcontainersv1.CBContainersAgent{}).
 		WithEventFilter(NewCBContainersGenerationChangedPredicate(r.StateApplier)).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Secret{}).
-		Owns(adapters.EmptyPriorityClassForVersion(r.K8sVersion)).
+		Owns(adapters.EmptyPriorityClassForCapabilities(apiCapabilities)).
 		Owns(&appsV1.Deployment{}).
 		Owns(&corev1.Service{}).
 		Owns(&appsV1.DaemonSet{}).
-		Owns(adapters.EmptyValidatingWebhookConfigForVersion(r.K8sVersion)).
-		Owns(adapters.EmptyMutatingWebhookConfigForVersion(r.K8sVersion)).
-		Complete(r)
+		Owns(&batchV1.Job{}).
+		Owns(adapters.EmptyValidatingWebhookConfigForCapabilities(apiCapabilities)).
+		Owns(adapters.EmptyMutatingWebhookConfigForCapabilities(apiCapabilities)).
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.agentRequestsForNode), builder.WithPredicates(nodeTaintsChangedPredicate())).
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.agentRequestsForNodesCount), builder.WithPredicates(nodesCountChangedPredicate())).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.agentRequestsForSecret), builder.WithPredicates(secretDataChangedPredicate())).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.agentRequestsForConfigMap), builder.WithPredicates(configMapDataChangedPredicate()))
+
+	// The components can only be autoscaled when the API server serves the autoscaling/v2 API
+	if apiCapabilities.HasGroupVersion(capabilities.AutoscalingV2) {
+		controllerBuilder = controllerBuilder.Owns(&autoscalingV2.HorizontalPodAutoscaler{})
+	}
+	if apiCapabilities.HasGroupVersion(capabilities.PolicyV1) {
+		controllerBuilder = controllerBuilder.Owns(&policyV1.PodDisruptionBudget{})
+	}
+
+	return controllerBuilder.Complete(r)
 }
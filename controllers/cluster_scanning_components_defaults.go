@@ -49,6 +49,9 @@ func (r *CBContainersAgentController) setImageScanningReporterDefaults(imageScan
 
 	setDefaultPrometheus(&imageScanningReporter.Prometheus)
 
+	setDefaultAutoscaling(&imageScanningReporter.Autoscaling)
+	setDefaultPodDisruptionBudget(&imageScanningReporter.PodDisruptionBudget)
+
 	setDefaultImage(&imageScanningReporter.Image, "cbartifactory/image-scanning-reporter")
 
 	if err := setDefaultResourceRequirements(&imageScanningReporter.Resources, "64Mi", "200m", "1024Mi", "900m"); err != nil {
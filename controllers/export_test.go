@@ -0,0 +1,11 @@
+package controllers
+
+// AgentRequestsForSecret exposes the mapping of the watched secrets to the agents that refer to them to the tests.
+var AgentRequestsForSecret = (*CBContainersAgentController).agentRequestsForSecret
+
+// AgentRequestsForNodesCount exposes the mapping of the node events to the agents whose resolver should be scaled to
+// the tests.
+var AgentRequestsForNodesCount = (*CBContainersAgentController).agentRequestsForNodesCount
+
+// AgentRequestsForConfigMap exposes the mapping of the watched ConfigMaps to the agents that refer to them to the tests.
+var AgentRequestsForConfigMap = (*CBContainersAgentController).agentRequestsForConfigMap
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/vmware/cbcontainers-operator/controllers (interfaces: RootCAsBundleProvider)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	v1 "github.com/vmware/cbcontainers-operator/api/v1"
+)
+
+// MockRootCAsBundleProvider is a mock of RootCAsBundleProvider interface.
+type MockRootCAsBundleProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockRootCAsBundleProviderMockRecorder
+}
+
+// MockRootCAsBundleProviderMockRecorder is the mock recorder for MockRootCAsBundleProvider.
+type MockRootCAsBundleProviderMockRecorder struct {
+	mock *MockRootCAsBundleProvider
+}
+
+// NewMockRootCAsBundleProvider creates a new mock instance.
+func NewMockRootCAsBundleProvider(ctrl *gomock.Controller) *MockRootCAsBundleProvider {
+	mock := &MockRootCAsBundleProvider{ctrl: ctrl}
+	mock.recorder = &MockRootCAsBundleProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRootCAsBundleProvider) EXPECT() *MockRootCAsBundleProviderMockRecorder {
+	return m.recorder
+}
+
+// GetRootCAsBundle mocks base method.
+func (m *MockRootCAsBundleProvider) GetRootCAsBundle(arg0 context.Context, arg1 *v1.CBContainersAgent, arg2 string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRootCAsBundle", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRootCAsBundle indicates an expected call of GetRootCAsBundle.
+func (mr *MockRootCAsBundleProviderMockRecorder) GetRootCAsBundle(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRootCAsBundle", reflect.TypeOf((*MockRootCAsBundleProvider)(nil).GetRootCAsBundle), arg0, arg1, arg2)
+}
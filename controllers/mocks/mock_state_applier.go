@@ -11,6 +11,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	v1 "github.com/vmware/cbcontainers-operator/api/v1"
 	models "github.com/vmware/cbcontainers-operator/cbcontainers/models"
+	applyment "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment"
 	options "github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment/options"
 	client "sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -39,7 +40,7 @@ func (m *MockStateApplier) EXPECT() *MockStateApplierMockRecorder {
 }
 
 // ApplyDesiredState mocks base method.
-func (m *MockStateApplier) ApplyDesiredState(arg0 context.Context, arg1 *v1.CBContainersAgentSpec, arg2 *models.RegistrySecretValues, arg3 options.OwnerSetter) (bool, error) {
+func (m *MockStateApplier) ApplyDesiredState(arg0 context.Context, arg1 *v1.CBContainersAgent, arg2 *models.RegistrySecretValues, arg3 options.OwnerSetter) (bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ApplyDesiredState", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(bool)
@@ -53,6 +54,35 @@ func (mr *MockStateApplierMockRecorder) ApplyDesiredState(arg0, arg1, arg2, arg3
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyDesiredState", reflect.TypeOf((*MockStateApplier)(nil).ApplyDesiredState), arg0, arg1, arg2, arg3)
 }
 
+// PlanDesiredState mocks base method.
+func (m *MockStateApplier) PlanDesiredState(arg0 context.Context, arg1 *v1.CBContainersAgent, arg2 *models.RegistrySecretValues, arg3 options.OwnerSetter) ([]applyment.PlannedChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PlanDesiredState", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]applyment.PlannedChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PlanDesiredState indicates an expected call of PlanDesiredState.
+func (mr *MockStateApplierMockRecorder) PlanDesiredState(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlanDesiredState", reflect.TypeOf((*MockStateApplier)(nil).PlanDesiredState), arg0, arg1, arg2, arg3)
+}
+
+// ReportState mocks base method.
+func (m *MockStateApplier) ReportState(arg0 context.Context, arg1 *v1.CBContainersAgent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReportState", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReportState indicates an expected call of ReportState.
+func (mr *MockStateApplierMockRecorder) ReportState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReportState", reflect.TypeOf((*MockStateApplier)(nil).ReportState), arg0, arg1)
+}
+
 // ShouldProcessEvent mocks base method.
 func (m *MockStateApplier) ShouldProcessEvent(arg0 client.Object) bool {
 	m.ctrl.T.Helper()
@@ -66,3 +96,18 @@ func (mr *MockStateApplierMockRecorder) ShouldProcessEvent(arg0 interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShouldProcessEvent", reflect.TypeOf((*MockStateApplier)(nil).ShouldProcessEvent), arg0)
 }
+
+// TeardownState mocks base method.
+func (m *MockStateApplier) TeardownState(arg0 context.Context, arg1 *v1.CBContainersAgent) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TeardownState", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TeardownState indicates an expected call of TeardownState.
+func (mr *MockStateApplierMockRecorder) TeardownState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TeardownState", reflect.TypeOf((*MockStateApplier)(nil).TeardownState), arg0, arg1)
+}
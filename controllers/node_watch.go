@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// nodeTaintsChangedPredicate passes the events of the nodes whose taints were changed, and of the tainted nodes that
+// were added or removed.
+func nodeTaintsChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isTaintedNode(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			newNode, ok := e.ObjectNew.(*corev1.Node)
+			if !ok {
+				return false
+			}
+
+			return !equality.Semantic.DeepEqual(oldNode.Spec.Taints, newNode.Spec.Taints)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isTaintedNode(e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+func isTaintedNode(obj client.Object) bool {
+	node, ok := obj.(*corev1.Node)
+	return ok && len(node.Spec.Taints) > 0
+}
+
+// agentRequestsForNode maps a node event to the agents that discover the tolerations of the daemon sets from the
+// taints of the nodes.
+func (r *CBContainersAgentController) agentRequestsForNode(ctx context.Context, _ client.Object) []reconcile.Request {
+	agents := &cbcontainersv1.CBContainersAgentList{}
+	if err := r.List(ctx, agents); err != nil {
+		r.Log.Error(err, "Failed listing the CBContainersAgent k8s objects for a node event")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, agent := range agents.Items {
+		if commonState.IsEnabled(agent.Spec.Components.Settings.DaemonSetsTolerationsDiscovery.Enabled) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: agent.Name}})
+		}
+	}
+
+	return requests
+}
+
+// nodesCountChangedPredicate passes the events of the nodes that were added or removed. The scale threshold of each
+// agent is checked when the events are mapped to the agents, as it depends on the agent spec.
+func nodesCountChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return true
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return false
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return true
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// agentRequestsForNodesCount maps a node event to the agents whose runtime resolver replicas count, which is derived
+// from the number of nodes, should be changed. The nodes are counted from the cache, so most events are mapped to none.
+func (r *CBContainersAgentController) agentRequestsForNodesCount(ctx context.Context, _ client.Object) []reconcile.Request {
+	agents := &cbcontainersv1.CBContainersAgentList{}
+	if err := r.List(ctx, agents); err != nil {
+		r.Log.Error(err, "Failed listing the CBContainersAgent k8s objects for a node event")
+		return nil
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := r.List(ctx, nodes); err != nil {
+		r.Log.Error(err, "Failed listing the nodes for a node event")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, agent := range agents.Items {
+		scaling := agent.Status.RuntimeResolverScaling
+		if scaling == nil || agent.Spec.Components.RuntimeProtection.Resolver.ReplicasCount != nil {
+			continue
+		}
+
+		resolver := &agent.Spec.Components.RuntimeProtection.Resolver
+		nodesCount := components.ResolverNodesCountForScaling(resolver, scaling, len(nodes.Items))
+		if components.ResolverReplicasForNodes(resolver, nodesCount) != scaling.Replicas {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: agent.Name}})
+		}
+	}
+
+	return requests
+}
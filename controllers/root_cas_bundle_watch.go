@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"context"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// configMapDataChangedPredicate passes the events of the ConfigMaps that were added or removed, and of the ConfigMaps
+// whose data was changed.
+func configMapDataChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return true
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldConfigMap, ok := e.ObjectOld.(*corev1.ConfigMap)
+			if !ok {
+				return false
+			}
+			newConfigMap, ok := e.ObjectNew.(*corev1.ConfigMap)
+			if !ok {
+				return false
+			}
+
+			return !equality.Semantic.DeepEqual(oldConfigMap.Data, newConfigMap.Data) ||
+				!equality.Semantic.DeepEqual(oldConfigMap.BinaryData, newConfigMap.BinaryData)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return true
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// agentRequestsForConfigMap maps a ConfigMap event to the agents whose root CAs bundle or extra env vars refer to the
+// ConfigMap, so a rotated bundle is used by the operator and is propagated to the components, and the pods that consume
+// a changed ConfigMap through an extra env var are rolled.
+func (r *CBContainersAgentController) agentRequestsForConfigMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	if obj.GetNamespace() != r.Namespace {
+		return nil
+	}
+
+	agents := &cbcontainersv1.CBContainersAgentList{}
+	if err := r.List(ctx, agents); err != nil {
+		r.Log.Error(err, "Failed listing the CBContainersAgent k8s objects for a ConfigMap event")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, agent := range agents.Items {
+		agentSpec := agent.Spec.DeepCopy()
+		if err := r.setAgentDefaults(agentSpec); err != nil {
+			r.Log.Error(err, "Failed setting the defaults of a CBContainersAgent k8s object for a ConfigMap event")
+			continue
+		}
+
+		bundleRef := agentSpec.Gateways.GatewayTLS.RootCAsBundleRef
+		refersToBundle := bundleRef != nil && bundleRef.ConfigMapKeyRef != nil && bundleRef.ConfigMapKeyRef.Name == obj.GetName()
+		if refersToBundle || containsName(extraEnvConfigMapNames(agentSpec), obj.GetName()) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: agent.Name}})
+		}
+	}
+
+	return requests
+}
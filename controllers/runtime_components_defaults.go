@@ -1,7 +1,10 @@
 package controllers
 
 import (
+	"time"
+
 	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func (r *CBContainersAgentController) setRuntimeProtectionComponentsDefaults(runtime *cbcontainersv1.CBContainersRuntimeProtectionSpec) error {
@@ -49,8 +52,28 @@ func (r *CBContainersAgentController) setRuntimeResolverDefaults(runtimeResolver
 		runtimeResolver.NodesToReplicasRatio = 5
 	}
 
+	if runtimeResolver.MinReplicas == nil {
+		defaultMinReplicas := int32(1)
+		runtimeResolver.MinReplicas = &defaultMinReplicas
+	}
+
+	if runtimeResolver.ScaleNodesThreshold == 0 {
+		runtimeResolver.ScaleNodesThreshold = 1
+	}
+
+	if runtimeResolver.ScaleUpCooldown == nil {
+		runtimeResolver.ScaleUpCooldown = &metav1.Duration{}
+	}
+
+	if runtimeResolver.ScaleDownCooldown == nil {
+		runtimeResolver.ScaleDownCooldown = &metav1.Duration{Duration: 5 * time.Minute}
+	}
+
 	setDefaultPrometheus(&runtimeResolver.Prometheus)
 
+	setDefaultAutoscaling(&runtimeResolver.Autoscaling)
+	setDefaultPodDisruptionBudget(&runtimeResolver.PodDisruptionBudget)
+
 	setDefaultImage(&runtimeResolver.Image, "cbartifactory/runtime-kubernetes-resolver")
 
 	if err := setDefaultResourceRequirements(&runtimeResolver.Resources, "512Mi", "200m", "2Gi", "900m"); err != nil {
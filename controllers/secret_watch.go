@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/components"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// referencedSecretNames returns the names of the secrets that the user creates for the agent, sorted and without
+// duplicates: the access token secret, the CNDR company code secret and the image pull secrets of the enabled components.
+// The agent spec should be defaulted.
+func referencedSecretNames(agentSpec *cbcontainersv1.CBContainersAgentSpec) []string {
+	components := &agentSpec.Components
+	names := []string{agentSpec.AccessTokenSecretName}
+	names = append(names, components.Settings.ImagePullSecrets...)
+	names = append(names, components.Basic.Monitor.Image.PullSecrets...)
+	names = append(names, components.Basic.Enforcer.Image.PullSecrets...)
+	names = append(names, components.Basic.StateReporter.Image.PullSecrets...)
+	if commonState.IsEnabled(components.RuntimeProtection.Enabled) {
+		names = append(names, components.RuntimeProtection.Resolver.Image.PullSecrets...)
+		names = append(names, components.RuntimeProtection.Sensor.Image.PullSecrets...)
+	}
+	if commonState.IsEnabled(components.ClusterScanning.Enabled) {
+		names = append(names, components.ClusterScanning.ImageScanningReporter.Image.PullSecrets...)
+		names = append(names, components.ClusterScanning.ClusterScannerAgent.Image.PullSecrets...)
+	}
+	if components.Cndr != nil && commonState.IsEnabled(components.Cndr.Enabled) {
+		names = append(names, components.Cndr.CompanyCodeSecretName)
+		names = append(names, components.Cndr.Sensor.Image.PullSecrets...)
+	}
+
+	uniqueNames := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if name != "" {
+			uniqueNames[name] = struct{}{}
+		}
+	}
+
+	sortedNames := make([]string, 0, len(uniqueNames))
+	for name := range uniqueNames {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	return sortedNames
+}
+
+// extraEnvVars returns the extra env vars of the enabled components, which can be sourced from the keys of Secrets and
+// ConfigMaps that the user creates. Those aren't referenced secrets, as a missing optional key doesn't stop the agent.
+func extraEnvVars(agentSpec *cbcontainersv1.CBContainersAgentSpec) []corev1.EnvVar {
+	components := &agentSpec.Components
+	var envVars []corev1.EnvVar
+	envVars = append(envVars, components.Basic.Monitor.ExtraEnv...)
+	envVars = append(envVars, components.Basic.Enforcer.ExtraEnv...)
+	envVars = append(envVars, components.Basic.StateReporter.ExtraEnv...)
+	if commonState.IsEnabled(components.RuntimeProtection.Enabled) {
+		envVars = append(envVars, components.RuntimeProtection.Resolver.ExtraEnv...)
+		envVars = append(envVars, components.RuntimeProtection.Sensor.ExtraEnv...)
+	}
+	if commonState.IsEnabled(components.ClusterScanning.Enabled) {
+		envVars = append(envVars, components.ClusterScanning.ImageScanningReporter.ExtraEnv...)
+		envVars = append(envVars, components.ClusterScanning.ClusterScannerAgent.ExtraEnv...)
+	}
+	if components.Cndr != nil && commonState.IsEnabled(components.Cndr.Enabled) {
+		envVars = append(envVars, components.Cndr.Sensor.ExtraEnv...)
+	}
+
+	return envVars
+}
+
+// extraEnvSecretNames returns the names of the secrets that the extra env vars of the enabled components are sourced from.
+func extraEnvSecretNames(agentSpec *cbcontainersv1.CBContainersAgentSpec) []string {
+	var names []string
+	for _, envVar := range extraEnvVars(agentSpec) {
+		if envVar.ValueFrom != nil && envVar.ValueFrom.SecretKeyRef != nil {
+			names = append(names, envVar.ValueFrom.SecretKeyRef.Name)
+		}
+	}
+
+	return names
+}
+
+// extraEnvConfigMapNames returns the names of the ConfigMaps that the extra env vars of the enabled components are
+// sourced from.
+func extraEnvConfigMapNames(agentSpec *cbcontainersv1.CBContainersAgentSpec) []string {
+	var names []string
+	for _, envVar := range extraEnvVars(agentSpec) {
+		if envVar.ValueFrom != nil && envVar.ValueFrom.ConfigMapKeyRef != nil {
+			names = append(names, envVar.ValueFrom.ConfigMapKeyRef.Name)
+		}
+	}
+
+	return names
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isUncontrolledConfig returns true for the secrets and ConfigMaps that the agent doesn't control, which don't have a
+// generation. Their events are filtered by the predicates of the referenced secrets and ConfigMaps watches.
+func isUncontrolledConfig(obj client.Object) bool {
+	switch obj.(type) {
+	case *corev1.Secret, *corev1.ConfigMap:
+		return metav1.GetControllerOf(obj) == nil
+	default:
+		return false
+	}
+}
+
+// secretDataChangedPredicate passes the events of the secrets that were added or removed, and of the secrets whose
+// data was changed.
+func secretDataChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return true
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldSecret, ok := e.ObjectOld.(*corev1.Secret)
+			if !ok {
+				return false
+			}
+			newSecret, ok := e.ObjectNew.(*corev1.Secret)
+			if !ok {
+				return false
+			}
+
+			return oldSecret.Type != newSecret.Type || !equality.Semantic.DeepEqual(oldSecret.Data, newSecret.Data)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return true
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// agentRequestsForSecret maps a secret event to the agents that refer to the secret, e.g. so a rotated access token
+// is applied to the components, the pods that consume a changed secret through an extra env var are rolled, or the
+// agent is reconciled once its missing access token secret is created.
+func (r *CBContainersAgentController) agentRequestsForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	if obj.GetNamespace() != r.Namespace {
+		return nil
+	}
+
+	agents := &cbcontainersv1.CBContainersAgentList{}
+	if err := r.List(ctx, agents); err != nil {
+		r.Log.Error(err, "Failed listing the CBContainersAgent k8s objects for a secret event")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, agent := range agents.Items {
+		agentSpec := agent.Spec.DeepCopy()
+		if err := r.setAgentDefaults(agentSpec); err != nil {
+			r.Log.Error(err, "Failed setting the defaults of a CBContainersAgent k8s object for a secret event")
+			continue
+		}
+
+		bundleRef := agentSpec.Gateways.GatewayTLS.RootCAsBundleRef
+		refersToBundle := bundleRef != nil && bundleRef.SecretKeyRef != nil && bundleRef.SecretKeyRef.Name == obj.GetName()
+		// The secret that cert-manager issues isn't controlled by the agent, so it's watched while the certificates are pending
+		isIssuedTlsSecret := agentSpec.Components.Basic.Enforcer.CertManager != nil && obj.GetName() == components.EnforcerTlsName
+		if refersToBundle || isIssuedTlsSecret || containsName(referencedSecretNames(agentSpec), obj.GetName()) ||
+			containsName(extraEnvSecretNames(agentSpec), obj.GetName()) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: agent.Name}})
+		}
+	}
+
+	return requests
+}
+
+// findMissingSecrets returns the names of the secrets that the agent refers to, which don't exist in the agent namespace.
+func (r *CBContainersAgentController) findMissingSecrets(ctx context.Context, agentSpec *cbcontainersv1.CBContainersAgentSpec) ([]string, error) {
+	var missingSecrets []string
+	for _, name := range referencedSecretNames(agentSpec) {
+		namespacedName := types.NamespacedName{Name: name, Namespace: r.Namespace}
+		if err := r.Get(ctx, namespacedName, &corev1.Secret{}); err != nil {
+			if !k8sErrors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed reading secret `%v`: %w", namespacedName, err)
+			}
+			missingSecrets = append(missingSecrets, name)
+		}
+	}
+
+	return missingSecrets, nil
+}
@@ -2,10 +2,16 @@ package controllers
 
 import (
 	cbcontainersv1 "github.com/vmware/cbcontainers-operator/api/v1"
+	commonState "github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
 	coreV1 "k8s.io/api/core/v1"
 	"strings"
 )
 
+const (
+	defaultNodeCleanupImageRepository = "photon"
+	defaultNodeCleanupImageTag        = "4.0"
+)
+
 func (r *CBContainersAgentController) setSettingsComponentsDefaults(settings *cbcontainersv1.CBContainersComponentsSettings) error {
 	if settings.Proxy == nil {
 		settings.Proxy = new(cbcontainersv1.CBContainersProxySettings)
@@ -24,6 +30,20 @@ func (r *CBContainersAgentController) setSettingsComponentsDefaults(settings *cb
 		}
 	}
 
+	if settings.DaemonSetsTolerationsDiscovery.Enabled == nil {
+		settings.DaemonSetsTolerationsDiscovery.Enabled = &falseRef
+	}
+
+	if len(settings.Architectures) == 0 {
+		settings.Architectures = append([]string{}, commonState.DefaultArchitectures...)
+	}
+
+	// The node cleanup image isn't versioned with the agent, so its tag is defaulted along with its repository
+	if settings.NodeCleanupImage.Repository == "" && settings.NodeCleanupImage.Tag == "" {
+		settings.NodeCleanupImage.Tag = defaultNodeCleanupImageTag
+	}
+	setDefaultImage(&settings.NodeCleanupImage, defaultNodeCleanupImageRepository)
+
 	return nil
 }
 
@@ -87,6 +87,21 @@ func setDefaultPrometheusWithPort(prometheusSpec *v1.CBContainersPrometheusSpec,
 	}
 }
 
+func setDefaultAutoscaling(autoscalingSpec *v1.CBContainersAutoscalingSpec) {
+	if autoscalingSpec.Enabled == nil {
+		autoscalingSpec.Enabled = &falseRef
+	}
+
+	if autoscalingSpec.MinReplicas == nil {
+		defaultMinReplicas := int32(1)
+		autoscalingSpec.MinReplicas = &defaultMinReplicas
+	}
+
+	if autoscalingSpec.MaxReplicas == 0 {
+		autoscalingSpec.MaxReplicas = 5
+	}
+}
+
 func setDefaultImage(imageSpec *v1.CBContainersImageSpec, imageName string) {
 	if imageSpec.Repository == "" {
 		imageSpec.Repository = imageName
@@ -169,3 +184,9 @@ func GetDefaultNoProxyValue(namespace string) (string, error) {
 	noProxyItems = append(noProxyItems, namespace+".svc.cluster.local")
 	return strings.Join(noProxyItems, ","), nil
 }
+
+func setDefaultPodDisruptionBudget(podDisruptionBudgetSpec *v1.CBContainersPodDisruptionBudgetSpec) {
+	if podDisruptionBudgetSpec.Enabled == nil {
+		podDisruptionBudgetSpec.Enabled = &trueRef
+	}
+}
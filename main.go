@@ -26,9 +26,11 @@ import (
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/agent_applyment"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/applyment"
+	"github.com/vmware/cbcontainers-operator/cbcontainers/state/capabilities"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/common"
 	"github.com/vmware/cbcontainers-operator/cbcontainers/state/operator"
 	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/discovery"
 	"os"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -67,6 +69,7 @@ const (
 	httpsProxyEnv       = "HTTPS_PROXY"
 	noProxyEnv          = "NO_PROXY"
 	namespaceEnv        = "OPERATOR_NAMESPACE"
+	eventsSourceName    = "cbcontainers-operator"
 )
 
 func init() {
@@ -104,11 +107,15 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var serverSideApply bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", true,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&serverSideApply, "server-side-apply", false,
+		"Apply the agent components using server-side apply, so the operator owns only the fields it sets "+
+			"and leaves the fields set by other controllers untouched.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -147,22 +154,36 @@ func main() {
 		os.Exit(1)
 	}
 
-	clusterIdentifier, k8sVersion := extractConfigurationVariables(mgr)
+	clusterIdentifier := extractConfigurationVariables(mgr)
+	capabilitiesProvider := setupCapabilitiesProvider(mgr)
 	operatorVersionProvider := operator.NewEnvVersionProvider()
 	var processorGatewayCreator processors.APIGatewayCreator = func(cbContainersCluster *operatorcontainerscarbonblackiov1.CBContainersAgent, accessToken string) (processors.APIGateway, error) {
 		return gateway.NewDefaultGatewayCreator().CreateGateway(cbContainersCluster, accessToken)
 	}
+	rootCAsBundleProvider := operator.NewConfigRootCAsBundleProvider(mgr.GetClient())
 	cbContainersAgentLogger := ctrl.Log.WithName("controllers").WithName("CBContainersAgent")
+	eventRecorder := mgr.GetEventRecorderFor(eventsSourceName)
+	componentApplier := applyment.NewComponentApplier(mgr.GetClient())
+	if serverSideApply {
+		setupLog.Info(fmt.Sprintf("Using server-side apply with field manager %s", applyment.DefaultFieldManager))
+		componentApplier = applyment.NewServerSideComponentApplier(mgr.GetClient(), applyment.DefaultFieldManager)
+	}
+	stateApplier := state.NewStateApplier(mgr.GetAPIReader(), mgr.GetClient(), agent_applyment.NewAgentComponent(componentApplier), capabilitiesProvider, operatorNamespace, clusterIdentifier, certificatesUtils.NewCertificateCreator(), eventRecorder, cbContainersAgentLogger)
+	if serverSideApply {
+		stateApplier.UseServerSideDryRunPlanning(mgr.GetClient(), applyment.DefaultFieldManager)
+	}
 
 	if err = (&controllers.CBContainersAgentController{
-		Client:              mgr.GetClient(),
-		Log:                 cbContainersAgentLogger,
-		Scheme:              mgr.GetScheme(),
-		K8sVersion:          k8sVersion,
-		Namespace:           operatorNamespace,
-		AccessTokenProvider: operator.NewSecretAccessTokenProvider(mgr.GetClient()),
-		ClusterProcessor:    processors.NewAgentProcessor(cbContainersAgentLogger, processorGatewayCreator, operatorVersionProvider, clusterIdentifier),
-		StateApplier:        state.NewStateApplier(mgr.GetAPIReader(), agent_applyment.NewAgentComponent(applyment.NewComponentApplier(mgr.GetClient())), k8sVersion, operatorNamespace, clusterIdentifier, certificatesUtils.NewCertificateCreator(), cbContainersAgentLogger),
+		Client:                mgr.GetClient(),
+		Log:                   cbContainersAgentLogger,
+		Scheme:                mgr.GetScheme(),
+		CapabilitiesProvider:  capabilitiesProvider,
+		Namespace:             operatorNamespace,
+		AccessTokenProvider:   operator.NewSecretAccessTokenProvider(mgr.GetClient()),
+		RootCAsBundleProvider: rootCAsBundleProvider,
+		Recorder:              eventRecorder,
+		ClusterProcessor:      processors.NewAgentProcessor(cbContainersAgentLogger, processorGatewayCreator, operatorVersionProvider, clusterIdentifier),
+		StateApplier:          stateApplier,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CBContainersAgent")
 		os.Exit(1)
@@ -200,6 +221,13 @@ func main() {
 	}
 
 	var configuratorGatewayCreator remote_configuration.ApiCreator = func(cbContainersCluster *operatorcontainerscarbonblackiov1.CBContainersAgent, accessToken string) (remote_configuration.ApiGateway, error) {
+		// The configurator updates the CBContainersAgent resource, so the merged root CAs bundle is set on a copy of it
+		rootCAsBundle, err := rootCAsBundleProvider.GetRootCAsBundle(context.Background(), cbContainersCluster, operatorNamespace)
+		if err != nil {
+			return nil, err
+		}
+		cbContainersCluster = cbContainersCluster.DeepCopy()
+		cbContainersCluster.Spec.Gateways.GatewayTLS.RootCAsBundle = rootCAsBundle
 		return gateway.NewDefaultGatewayCreator().CreateGateway(cbContainersCluster, accessToken)
 	}
 
@@ -209,6 +237,7 @@ func main() {
 		log,
 		operator.NewSecretAccessTokenProvider(k8sClient),
 		validatorCreator,
+		eventRecorder,
 		operatorNamespace,
 		clusterIdentifier,
 	)
@@ -237,7 +266,7 @@ func main() {
 	wg.Wait()
 }
 
-func extractConfigurationVariables(mgr manager.Manager) (clusterIdentifier string, k8sVersion string) {
+func extractConfigurationVariables(mgr manager.Manager) (clusterIdentifier string) {
 	setupLog.Info(fmt.Sprintf("Getting Cluster Identifier: %v uid", NamespaceIdentifier))
 	namespace := &coreV1.Namespace{}
 	apiReader := mgr.GetAPIReader()
@@ -249,14 +278,30 @@ func extractConfigurationVariables(mgr manager.Manager) (clusterIdentifier strin
 
 	setupLog.Info(fmt.Sprintf("Cluster Identifier: %v", clusterIdentifier))
 
-	setupLog.Info("Getting Nodes list")
-	nodesList := &coreV1.NodeList{}
-	if err := apiReader.List(context.Background(), nodesList); err != nil || nodesList.Items == nil || len(nodesList.Items) < 1 {
-		setupLog.Error(err, "couldn't get nodes list")
+	return
+}
+
+// setupCapabilitiesProvider discovers the API groups and the fields that the API server supports, and keeps refreshing
+// them while the manager runs, so control plane upgrades are noticed.
+func setupCapabilitiesProvider(mgr manager.Manager) capabilities.Provider {
+	setupLog.Info("Discovering the API server capabilities")
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create the discovery client")
+		os.Exit(1)
+	}
+
+	capabilitiesProvider := capabilities.NewDiscoveryProvider(discoveryClient, capabilities.DefaultRefreshInterval, ctrl.Log.WithName("capabilities"))
+	if err := capabilitiesProvider.Refresh(); err != nil {
+		setupLog.Error(err, "unable to discover the API server capabilities")
 		os.Exit(1)
 	}
-	k8sVersion = nodesList.Items[0].Status.NodeInfo.KubeletVersion
-	setupLog.Info(fmt.Sprintf("K8s version is: %v", k8sVersion))
+	setupLog.Info(fmt.Sprintf("K8s version is: %v", capabilitiesProvider.Capabilities().Version))
 
-	return
+	if err := mgr.Add(capabilitiesProvider); err != nil {
+		setupLog.Error(err, "unable to set up the capabilities refresh")
+		os.Exit(1)
+	}
+
+	return capabilitiesProvider
 }